@@ -43,6 +43,10 @@ var (
 	// ErrNoCodeAfterDeploy is returned by WaitDeployed if contract creation leaves
 	// an empty contract behind.
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
+
+	// ErrNotMocked is returned by the methods of generated fake contract bindings
+	// which have no mock implementation set.
+	ErrNotMocked = errors.New("method not mocked")
 )
 
 // ContractCaller defines the methods needed to allow operating with a contract on a read
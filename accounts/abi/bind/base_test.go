@@ -17,8 +17,10 @@
 package bind_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 	"reflect"
 	"strings"
@@ -579,6 +581,36 @@ func TestCall(t *testing.T) {
 	}
 }
 
+type mockDataError struct {
+	data interface{}
+}
+
+func (e *mockDataError) Error() string          { return "execution reverted" }
+func (e *mockDataError) ErrorData() interface{} { return e.data }
+
+func TestRevertData(t *testing.T) {
+	t.Parallel()
+	revert := []byte{0xde, 0xad, 0xbe, 0xef, 0x01}
+	tests := []struct {
+		err  error
+		want []byte
+		ok   bool
+	}{
+		{errors.New("no data"), nil, false},
+		{&mockDataError{hexutil.Encode(revert)}, revert, true},
+		{fmt.Errorf("wrapped: %w", &mockDataError{hexutil.Encode(revert)}), revert, true},
+		{&mockDataError{revert}, revert, true},
+		{&mockDataError{"not hex"}, nil, false},
+		{&mockDataError{42}, nil, false},
+	}
+	for i, test := range tests {
+		data, ok := bind.RevertData(test.err)
+		if ok != test.ok || !bytes.Equal(data, test.want) {
+			t.Errorf("test %d: got %x (%v), want %x (%v)", i, data, ok, test.want, test.ok)
+		}
+	}
+}
+
 // TestCrashers contains some strings which previously caused the abi codec to crash.
 func TestCrashers(t *testing.T) {
 	t.Parallel()
@@ -1877,6 +1877,7 @@ var bindTests = []struct {
 		[]string{`[{"inputs":[{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError","type":"error"},{"inputs":[{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError1","type":"error"},{"inputs":[{"internalType":"uint256","name":"","type":"uint256"},{"internalType":"uint256","name":"","type":"uint256"}],"name":"MyError2","type":"error"},{"inputs":[{"internalType":"uint256","name":"a","type":"uint256"},{"internalType":"uint256","name":"b","type":"uint256"},{"internalType":"uint256","name":"c","type":"uint256"}],"name":"MyError3","type":"error"},{"inputs":[],"name":"Error","outputs":[],"stateMutability":"pure","type":"function"}]`},
 		`
 			"context"
+			"errors"
 			"math/big"
 	
 			"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -1902,11 +1903,24 @@ var bindTests = []struct {
 			if err != nil {
 				t.Error(err)
 			}
-			if err := contract.Error(new(bind.CallOpts)); err == nil {
+			err = contract.Error(new(bind.CallOpts))
+			if err == nil {
 				t.Fatalf("expected contract to throw error")
 			}
-			// TODO (MariusVanDerWijden unpack error using abigen
-			// once that is implemented
+			var myErr *NewErrorsMyError3
+			if !errors.As(NewErrorsUnpackError(err), &myErr) {
+				t.Fatalf("failed to unpack error %v", err)
+			}
+			if myErr.A.Cmp(big.NewInt(1)) != 0 || myErr.B.Cmp(big.NewInt(2)) != 0 || myErr.C.Cmp(big.NewInt(3)) != 0 {
+				t.Fatalf("wrong error arguments: %v", myErr)
+			}
+			if want := "execution reverted: MyError3(1, 2, 3)"; myErr.Error() != want {
+				t.Fatalf("wrong error message %q, want %q", myErr.Error(), want)
+			}
+			// Errors without revert data are passed through.
+			if err := NewErrorsUnpackError(bind.ErrNoCode); err != bind.ErrNoCode {
+				t.Fatalf("unexpected error %v", err)
+			}
 	   `,
 		nil,
 		nil,
@@ -2068,6 +2082,79 @@ var bindTests = []struct {
 			}
 `,
 	},
+	// Test that the generated fake binding can stand in for the real one
+	{
+		name: "Mockable",
+		contract: `
+		// SPDX-License-Identifier: GPL-3.0
+		pragma solidity >0.8.4;
+
+		contract Mockable {
+			event Transfer(address indexed from, address indexed to, uint256 value);
+			error Transfer(address to);
+			error InsufficientBalance(uint256 available, uint256 required);
+
+			function balanceOf(address owner) public view returns (uint256) {}
+			function transfer(address to, uint256 value) public {}
+			receive() external payable {}
+		}
+		`,
+		bytecode: []string{""},
+		abi:      []string{`[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"from","type":"address"},{"indexed":true,"internalType":"address","name":"to","type":"address"},{"indexed":false,"internalType":"uint256","name":"value","type":"uint256"}],"name":"Transfer","type":"event"},{"inputs":[{"internalType":"address","name":"to","type":"address"}],"name":"Transfer","type":"error"},{"inputs":[{"internalType":"uint256","name":"available","type":"uint256"},{"internalType":"uint256","name":"required","type":"uint256"}],"name":"InsufficientBalance","type":"error"},{"inputs":[{"internalType":"address","name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"}],"name":"transfer","outputs":[],"stateMutability":"nonpayable","type":"function"},{"stateMutability":"payable","type":"receive"}]`},
+		imports: `
+			"errors"
+			"math/big"
+
+			"github.com/ethereum/go-ethereum/accounts/abi/bind"
+			"github.com/ethereum/go-ethereum/common"
+		`,
+		tester: `
+			owner := common.HexToAddress("0x01")
+			fake := &FakeMockable{
+				BalanceOfFunc: func(opts *bind.CallOpts, addr common.Address) (*big.Int, error) {
+					if addr != owner {
+						return nil, &MockableInsufficientBalance{Available: big.NewInt(0), Required: big.NewInt(1)}
+					}
+					return big.NewInt(42), nil
+				},
+				FilterTransferFunc: func(opts *bind.FilterOpts, from []common.Address, to []common.Address) (*MockableTransferIterator, error) {
+					return NewMockableTransferIterator(&MockableTransfer{From: owner, Value: big.NewInt(1)}, &MockableTransfer{To: owner, Value: big.NewInt(2)}), nil
+				},
+			}
+			var contract MockableInterface = fake
+
+			if balance, err := contract.BalanceOf(nil, owner); err != nil || balance.Int64() != 42 {
+				t.Fatalf("wrong balance %v, error %v", balance, err)
+			}
+			var balanceErr *MockableInsufficientBalance
+			if _, err := contract.BalanceOf(nil, common.Address{}); !errors.As(err, &balanceErr) {
+				t.Fatalf("wrong error %v", err)
+			}
+			if _, err := contract.Transfer(nil, owner, big.NewInt(1)); err != bind.ErrNotMocked {
+				t.Fatalf("wrong error for unmocked method: %v", err)
+			}
+			if _, err := contract.Receive(nil); err != bind.ErrNotMocked {
+				t.Fatalf("wrong error for unmocked receive: %v", err)
+			}
+			it, err := contract.FilterTransfer(nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer it.Close()
+
+			var total int64
+			for it.Next() {
+				total += it.Event.Value.Int64()
+			}
+			if it.Error() != nil || total != 3 {
+				t.Fatalf("wrong iteration result %d, error %v", total, it.Error())
+			}
+			// The error sharing its name with the event is suffixed.
+			if msg := (&MockableTransferError{To: owner}).Error(); msg != "execution reverted: Transfer(0x0000000000000000000000000000000000000001)" {
+				t.Fatalf("wrong error message %q", msg)
+			}
+		`,
+	},
 }
 
 // Tests that packages generated by the binder can be successfully compiled and
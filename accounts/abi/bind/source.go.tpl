@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"strings"
 	"errors"
+	"fmt"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -19,6 +20,7 @@ import (
 // Reference imports to suppress errors if they are not otherwise used.
 var (
 	_ = errors.New
+	_ = fmt.Sprintf
 	_ = big.NewInt
 	_ = strings.NewReader
 	_ = ethereum.NotFound
@@ -353,7 +355,16 @@ var (
 			sub  ethereum.Subscription // Subscription for errors, completion and termination
 			done bool                  // Whether the subscription completed delivering logs
 			fail error                 // Occurred error to stop iteration
+
+			events []*{{$contract.Type}}{{.Normalized.Name}} // Preset events to deliver instead of filtered logs
+		}
+
+		// New{{$contract.Type}}{{.Normalized.Name}}Iterator creates an iterator over the given events, e.g. to be
+		// returned from a fake Filter{{.Normalized.Name}} implementation.
+		func New{{$contract.Type}}{{.Normalized.Name}}Iterator(events ...*{{$contract.Type}}{{.Normalized.Name}}) *{{$contract.Type}}{{.Normalized.Name}}Iterator {
+			return &{{$contract.Type}}{{.Normalized.Name}}Iterator{events: events}
 		}
+
 		// Next advances the iterator to the subsequent event, returning whether there
 		// are any more events found. In case of a retrieval or parsing error, false is
 		// returned and Error() can be queried for the exact failure.
@@ -362,6 +373,14 @@ var (
 			if (it.fail != nil) {
 				return false
 			}
+			// If the iterator was created from preset events, deliver those
+			if it.sub == nil {
+				if len(it.events) == 0 {
+					return false
+				}
+				it.Event, it.events = it.events[0], it.events[1:]
+				return true
+			}
 			// If the iterator completed, deliver directly whatever's available
 			if (it.done) {
 				select {
@@ -402,7 +421,9 @@ var (
 		// Close terminates the iteration process, releasing any pending underlying
 		// resources.
 		func (it *{{$contract.Type}}{{.Normalized.Name}}Iterator) Close() error {
-			it.sub.Unsubscribe()
+			if it.sub != nil {
+				it.sub.Unsubscribe()
+			}
 			return nil
 		}
 
@@ -484,4 +505,182 @@ var (
 		}
 
  	{{end}}
+
+	{{range .Errors}}
+		// {{$contract.Type}}{{.Normalized.Name}} represents a {{.Original.Name}} error raised by the {{$contract.Type}} contract.
+		//
+		// Solidity: {{.Original.String}}
+		type {{$contract.Type}}{{.Normalized.Name}} struct { {{range .Normalized.Inputs}}
+			{{capitalise .Name}} {{bindtype .Type $structs}}; {{end}}
+		}
+
+		// Error implements the error interface, formatting the error with its arguments.
+		func (e *{{$contract.Type}}{{.Normalized.Name}}) Error() string {
+			return fmt.Sprintf("execution reverted: {{.Original.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}}, {{end}}%v{{end}})"{{range .Normalized.Inputs}}, e.{{capitalise .Name}}{{end}})
+		}
+	{{end}}
+
+	{{if .Errors}}
+		// {{.Type}}UnpackError decodes the revert data carried by err into the typed error
+		// of the {{.Type}} contract it encodes, which can be inspected using errors.As.
+		// If err carries no revert data of a known error, it is returned as is.
+		func {{.Type}}UnpackError(err error) error {
+			data, ok := bind.RevertData(err)
+			if !ok || len(data) < 4 {
+				return err
+			}
+			parsed, perr := {{.Type}}MetaData.GetAbi()
+			if perr != nil {
+				return err
+			}
+			var id [4]byte
+			copy(id[:], data)
+			abiErr, perr := parsed.ErrorByID(id)
+			if perr != nil {
+				return err
+			}
+			out, perr := abiErr.Inputs.Unpack(data[4:])
+			if perr != nil {
+				return err
+			}
+			switch abiErr.Name {
+			{{range .Errors}}case "{{.Original.Name}}":
+				return &{{$contract.Type}}{{.Normalized.Name}}{ {{range $i, $t := .Normalized.Inputs}}
+					{{capitalise .Name}}: *abi.ConvertType(out[{{$i}}], new({{bindtype .Type $structs}})).(*{{bindtype .Type $structs}}),{{end}}
+				}
+			{{end -}}
+			}
+			return err
+		}
+	{{end}}
+
+	// {{.Type}}Interface is the method set of the {{.Type}} binding. It is implemented by
+	// both {{.Type}} and Fake{{.Type}}, so code interacting with the contract can be
+	// unit-tested without a backend.
+	type {{.Type}}Interface interface {
+		{{range .Calls}}
+			{{.Normalized.Name}}(opts *bind.CallOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type $structs}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type $structs}};{{end}} },{{else}}{{range .Normalized.Outputs}}{{bindtype .Type $structs}},{{end}}{{end}} error)
+		{{end}}
+		{{range .Transacts}}
+			{{.Normalized.Name}}(opts *bind.TransactOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type $structs}} {{end}}) (*types.Transaction, error)
+		{{end}}
+		{{if .Fallback}}
+			Fallback(opts *bind.TransactOpts, calldata []byte) (*types.Transaction, error)
+		{{end}}
+		{{if .Receive}}
+			Receive(opts *bind.TransactOpts) (*types.Transaction, error)
+		{{end}}
+		{{range .Events}}
+			Filter{{.Normalized.Name}}(opts *bind.FilterOpts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (*{{$contract.Type}}{{.Normalized.Name}}Iterator, error)
+			Watch{{.Normalized.Name}}(opts *bind.WatchOpts, sink chan<- *{{$contract.Type}}{{.Normalized.Name}}{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (event.Subscription, error)
+			Parse{{.Normalized.Name}}(log types.Log) (*{{$contract.Type}}{{.Normalized.Name}}, error)
+		{{end}}
+	}
+
+	var (
+		_ {{.Type}}Interface = (*{{.Type}})(nil)
+		_ {{.Type}}Interface = (*Fake{{.Type}})(nil)
+	)
+
+	// Fake{{.Type}} is a mockable implementation of {{.Type}}Interface. Each method calls
+	// the function in the corresponding field, or returns bind.ErrNotMocked if it is nil.
+	type Fake{{.Type}} struct {
+		{{range .Calls}}
+			{{.Normalized.Name}}Func func(opts *bind.CallOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type $structs}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type $structs}};{{end}} },{{else}}{{range .Normalized.Outputs}}{{bindtype .Type $structs}},{{end}}{{end}} error)
+		{{end}}
+		{{range .Transacts}}
+			{{.Normalized.Name}}Func func(opts *bind.TransactOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type $structs}} {{end}}) (*types.Transaction, error)
+		{{end}}
+		{{if .Fallback}}
+			FallbackFunc func(opts *bind.TransactOpts, calldata []byte) (*types.Transaction, error)
+		{{end}}
+		{{if .Receive}}
+			ReceiveFunc func(opts *bind.TransactOpts) (*types.Transaction, error)
+		{{end}}
+		{{range .Events}}
+			Filter{{.Normalized.Name}}Func func(opts *bind.FilterOpts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (*{{$contract.Type}}{{.Normalized.Name}}Iterator, error)
+			Watch{{.Normalized.Name}}Func func(opts *bind.WatchOpts, sink chan<- *{{$contract.Type}}{{.Normalized.Name}}{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (event.Subscription, error)
+			Parse{{.Normalized.Name}}Func func(log types.Log) (*{{$contract.Type}}{{.Normalized.Name}}, error)
+		{{end}}
+	}
+
+	{{range .Calls}}
+		// {{.Normalized.Name}} calls {{.Normalized.Name}}Func, mocking the contract method 0x{{printf "%x" .Original.ID}}.
+		//
+		// Solidity: {{.Original.String}}
+		func (_Fake{{$contract.Type}} *Fake{{$contract.Type}}) {{.Normalized.Name}}(opts *bind.CallOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type $structs}} {{end}}) ({{if .Structured}}struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type $structs}};{{end}} },{{else}}{{range .Normalized.Outputs}}{{bindtype .Type $structs}},{{end}}{{end}} error) {
+			if _Fake{{$contract.Type}}.{{.Normalized.Name}}Func == nil {
+				return {{if .Structured}}*new(struct{ {{range .Normalized.Outputs}}{{.Name}} {{bindtype .Type $structs}};{{end}} }), {{else}}{{range .Normalized.Outputs}}*new({{bindtype .Type $structs}}), {{end}}{{end}} bind.ErrNotMocked
+			}
+			return _Fake{{$contract.Type}}.{{.Normalized.Name}}Func(opts {{range .Normalized.Inputs}}, {{.Name}}{{end}})
+		}
+	{{end}}
+
+	{{range .Transacts}}
+		// {{.Normalized.Name}} calls {{.Normalized.Name}}Func, mocking the contract method 0x{{printf "%x" .Original.ID}}.
+		//
+		// Solidity: {{.Original.String}}
+		func (_Fake{{$contract.Type}} *Fake{{$contract.Type}}) {{.Normalized.Name}}(opts *bind.TransactOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type $structs}} {{end}}) (*types.Transaction, error) {
+			if _Fake{{$contract.Type}}.{{.Normalized.Name}}Func == nil {
+				return nil, bind.ErrNotMocked
+			}
+			return _Fake{{$contract.Type}}.{{.Normalized.Name}}Func(opts {{range .Normalized.Inputs}}, {{.Name}}{{end}})
+		}
+	{{end}}
+
+	{{if .Fallback}}
+		// Fallback calls FallbackFunc, mocking the contract fallback function.
+		//
+		// Solidity: {{.Fallback.Original.String}}
+		func (_Fake{{$contract.Type}} *Fake{{$contract.Type}}) Fallback(opts *bind.TransactOpts, calldata []byte) (*types.Transaction, error) {
+			if _Fake{{$contract.Type}}.FallbackFunc == nil {
+				return nil, bind.ErrNotMocked
+			}
+			return _Fake{{$contract.Type}}.FallbackFunc(opts, calldata)
+		}
+	{{end}}
+
+	{{if .Receive}}
+		// Receive calls ReceiveFunc, mocking the contract receive function.
+		//
+		// Solidity: {{.Receive.Original.String}}
+		func (_Fake{{$contract.Type}} *Fake{{$contract.Type}}) Receive(opts *bind.TransactOpts) (*types.Transaction, error) {
+			if _Fake{{$contract.Type}}.ReceiveFunc == nil {
+				return nil, bind.ErrNotMocked
+			}
+			return _Fake{{$contract.Type}}.ReceiveFunc(opts)
+		}
+	{{end}}
+
+	{{range .Events}}
+		// Filter{{.Normalized.Name}} calls Filter{{.Normalized.Name}}Func, mocking the log retrieval of the contract event 0x{{printf "%x" .Original.ID}}.
+		//
+		// Solidity: {{.Original.String}}
+		func (_Fake{{$contract.Type}} *Fake{{$contract.Type}}) Filter{{.Normalized.Name}}(opts *bind.FilterOpts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (*{{$contract.Type}}{{.Normalized.Name}}Iterator, error) {
+			if _Fake{{$contract.Type}}.Filter{{.Normalized.Name}}Func == nil {
+				return nil, bind.ErrNotMocked
+			}
+			return _Fake{{$contract.Type}}.Filter{{.Normalized.Name}}Func(opts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}{{end}}{{end}})
+		}
+
+		// Watch{{.Normalized.Name}} calls Watch{{.Normalized.Name}}Func, mocking the log subscription of the contract event 0x{{printf "%x" .Original.ID}}.
+		//
+		// Solidity: {{.Original.String}}
+		func (_Fake{{$contract.Type}} *Fake{{$contract.Type}}) Watch{{.Normalized.Name}}(opts *bind.WatchOpts, sink chan<- *{{$contract.Type}}{{.Normalized.Name}}{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (event.Subscription, error) {
+			if _Fake{{$contract.Type}}.Watch{{.Normalized.Name}}Func == nil {
+				return nil, bind.ErrNotMocked
+			}
+			return _Fake{{$contract.Type}}.Watch{{.Normalized.Name}}Func(opts, sink{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}{{end}}{{end}})
+		}
+
+		// Parse{{.Normalized.Name}} calls Parse{{.Normalized.Name}}Func, mocking the log parsing of the contract event 0x{{printf "%x" .Original.ID}}.
+		//
+		// Solidity: {{.Original.String}}
+		func (_Fake{{$contract.Type}} *Fake{{$contract.Type}}) Parse{{.Normalized.Name}}(log types.Log) (*{{$contract.Type}}{{.Normalized.Name}}, error) {
+			if _Fake{{$contract.Type}}.Parse{{.Normalized.Name}}Func == nil {
+				return nil, bind.ErrNotMocked
+			}
+			return _Fake{{$contract.Type}}.Parse{{.Normalized.Name}}Func(log)
+		}
+	{{end}}
 {{end}}
\ No newline at end of file
@@ -178,7 +178,7 @@ func storeNewKey(ks keyStore, rand io.Reader, auth string) (*Key, accounts.Accou
 	}
 	a := accounts.Account{
 		Address: key.Address,
-		URL:     accounts.URL{Scheme: KeyStoreScheme, Path: ks.JoinPath(keyFileName(key.Address))},
+		URL:     accounts.URL{Scheme: KeyStoreScheme, Path: ks.JoinPath(KeyFileName(key.Address))},
 	}
 	if err := ks.StoreKey(a.URL.Path, key, auth); err != nil {
 		zeroKey(key.PrivateKey)
@@ -209,7 +209,9 @@ func writeTemporaryKeyFile(file string, content []byte) (string, error) {
 	return f.Name(), nil
 }
 
-func writeKeyFile(file string, content []byte) error {
+// WriteKeyFile atomically writes a keyfile by writing the content into a
+// temporary file first, which then replaces the destination.
+func WriteKeyFile(file string, content []byte) error {
 	name, err := writeTemporaryKeyFile(file, content)
 	if err != nil {
 		return err
@@ -217,9 +219,9 @@ func writeKeyFile(file string, content []byte) error {
 	return os.Rename(name, file)
 }
 
-// keyFileName implements the naming convention for keyfiles:
+// KeyFileName implements the naming convention for keyfiles:
 // UTC--<created_at UTC ISO8601>-<address hex>
-func keyFileName(keyAddr common.Address) string {
+func KeyFileName(keyAddr common.Address) string {
 	ts := time.Now().UTC()
 	return fmt.Sprintf("UTC--%s--%s", toISO8601(ts), hex.EncodeToString(keyAddr[:]))
 }
@@ -50,7 +50,7 @@ func (ks keyStorePlain) StoreKey(filename string, key *Key, auth string) error {
 	if err != nil {
 		return err
 	}
-	return writeKeyFile(filename, content)
+	return WriteKeyFile(filename, content)
 }
 
 func (ks keyStorePlain) JoinPath(filename string) string {
@@ -45,7 +45,7 @@ func importPreSaleKey(keyStore keyStore, keyJSON []byte, password string) (accou
 		Address: key.Address,
 		URL: accounts.URL{
 			Scheme: KeyStoreScheme,
-			Path:   keyStore.JoinPath(keyFileName(key.Address)),
+			Path:   keyStore.JoinPath(KeyFileName(key.Address)),
 		},
 	}
 	err = keyStore.StoreKey(a.URL.Path, key, password)
@@ -55,6 +55,12 @@ func newBeaconBlockSync(headTracker headTracker) *beaconBlockSync {
 	}
 }
 
+// getBlock returns a recently retrieved beacon block, or nil if it is unknown.
+func (s *beaconBlockSync) getBlock(blockRoot common.Hash) *types.BeaconBlock {
+	block, _ := s.recentBlocks.Get(blockRoot)
+	return block
+}
+
 func (s *beaconBlockSync) SubscribeChainHead(ch chan<- types.ChainHeadEvent) event.Subscription {
 	return s.chainHeadFeed.Subscribe(ch)
 }
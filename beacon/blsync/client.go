@@ -17,6 +17,7 @@
 package blsync
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/beacon/light"
@@ -26,8 +27,11 @@ import (
 	"github.com/ethereum/go-ethereum/beacon/types"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/urfave/cli/v2"
 )
@@ -36,12 +40,19 @@ type Client struct {
 	urls         []string
 	customHeader map[string]string
 	chainConfig  *lightClientConfig
+	db           ethdb.KeyValueStore
 	scheduler    *request.Scheduler
 	blockSync    *beaconBlockSync
+	lightServer  *api.LightServer
 	engineRPC    *rpc.Client
+	upstreamRPC  *rpc.Client
 
 	chainHeadSub event.Subscription
 	engineClient *engineClient
+
+	proxy     *ProxyAPI
+	proxySub  event.Subscription
+	proxyQuit chan struct{}
 }
 
 func NewClient(ctx *cli.Context) *Client {
@@ -62,7 +73,7 @@ func NewClient(ctx *cli.Context) *Client {
 
 	// create data structures
 	var (
-		db             = memorydb.New()
+		db             = openDatabase(ctx)
 		threshold      = ctx.Int(utils.BeaconThresholdFlag.Name)
 		committeeChain = light.NewCommitteeChain(db, chainConfig.ChainConfig, threshold, !ctx.Bool(utils.BeaconNoFilterFlag.Name))
 		headTracker    = light.NewHeadTracker(committeeChain, threshold)
@@ -72,6 +83,11 @@ func NewClient(ctx *cli.Context) *Client {
 	// set up scheduler and sync modules
 	scheduler := request.NewScheduler()
 	checkpointInit := sync.NewCheckpointInit(committeeChain, chainConfig.Checkpoint)
+	if committeeChain.CanResume(chainConfig.Checkpoint) {
+		period, _ := committeeChain.NextSyncPeriod()
+		log.Info("Resuming light client from local database", "checkpoint", chainConfig.Checkpoint, "period", period)
+		checkpointInit.SetResumed()
+	}
 	forwardSync := sync.NewForwardUpdateSync(committeeChain)
 	beaconBlockSync := newBeaconBlockSync(headTracker)
 	scheduler.RegisterTarget(headTracker)
@@ -81,24 +97,69 @@ func NewClient(ctx *cli.Context) *Client {
 	scheduler.RegisterModule(headSync, "headSync")
 	scheduler.RegisterModule(beaconBlockSync, "beaconBlockSync")
 
+	var lightServer *api.LightServer
+	if ctx.IsSet(utils.BlsyncLightApiAddrFlag.Name) {
+		lightServer = api.NewLightServer(chainConfig.ChainConfig, committeeChain, headTracker, beaconBlockSync.getBlock)
+		scheduler.RegisterModule(lightServer, "lightServer")
+	}
 	return &Client{
 		scheduler:    scheduler,
 		urls:         ctx.StringSlice(utils.BeaconApiFlag.Name),
 		customHeader: customHeader,
 		chainConfig:  &chainConfig,
+		db:           db,
 		blockSync:    beaconBlockSync,
+		lightServer:  lightServer,
+	}
+}
+
+// openDatabase opens the persistent database of the light client in the data
+// directory if specified, or an in-memory database otherwise.
+func openDatabase(ctx *cli.Context) ethdb.KeyValueStore {
+	if !ctx.IsSet(utils.DataDirFlag.Name) {
+		return memorydb.New()
 	}
+	db, err := pebble.New(filepath.Join(ctx.String(utils.DataDirFlag.Name), "blsync"), 16, 16, "blsync/db/", false, false)
+	if err != nil {
+		utils.Fatalf("Could not open light client database: %v", err)
+	}
+	return db
 }
 
 func (c *Client) SetEngineRPC(engine *rpc.Client) {
 	c.engineRPC = engine
 }
 
+// SetProxyUpstream sets the untrusted execution RPC endpoint the verified proxy
+// forwards its requests to. The proxy is only available if an upstream is set.
+func (c *Client) SetProxyUpstream(upstream *rpc.Client) {
+	c.upstreamRPC = upstream
+}
+
+// LightServer returns the light client REST API server serving the locally
+// verified data, or nil if it is not enabled.
+func (c *Client) LightServer() *api.LightServer {
+	return c.lightServer
+}
+
+// Proxy returns the verified execution RPC proxy, or nil if no upstream is set.
+func (c *Client) Proxy() *ProxyAPI {
+	return c.proxy
+}
+
 func (c *Client) Start() error {
 	headCh := make(chan types.ChainHeadEvent, 16)
 	c.chainHeadSub = c.blockSync.SubscribeChainHead(headCh)
 	c.engineClient = startEngineClient(c.chainConfig, c.engineRPC, headCh)
 
+	if c.upstreamRPC != nil {
+		proxyCh := make(chan types.ChainHeadEvent, 16)
+		c.proxy = NewProxyAPI(c.chainConfig.Execution, c.upstreamRPC)
+		c.proxySub = c.blockSync.SubscribeChainHead(proxyCh)
+		c.proxyQuit = make(chan struct{})
+		go c.proxy.updateLoop(proxyCh, c.proxyQuit)
+	}
+
 	c.scheduler.Start()
 	for _, url := range c.urls {
 		beaconApi := api.NewBeaconLightApi(url, c.customHeader)
@@ -110,6 +171,11 @@ func (c *Client) Start() error {
 func (c *Client) Stop() error {
 	c.engineClient.stop()
 	c.chainHeadSub.Unsubscribe()
+	if c.proxy != nil {
+		close(c.proxyQuit)
+		c.proxySub.Unsubscribe()
+	}
 	c.scheduler.Stop()
+	c.db.Close()
 	return nil
 }
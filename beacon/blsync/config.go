@@ -21,6 +21,7 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/urfave/cli/v2"
 )
 
@@ -28,6 +29,7 @@ import (
 type lightClientConfig struct {
 	*types.ChainConfig
 	Checkpoint common.Hash
+	Execution  *params.ChainConfig // Execution layer config, nil if unknown
 }
 
 var (
@@ -42,6 +44,7 @@ var (
 			AddFork("CAPELLA", 194048, []byte{3, 0, 0, 0}).
 			AddFork("DENEB", 269568, []byte{4, 0, 0, 0}),
 		Checkpoint: common.HexToHash("0x388be41594ec7d6a6894f18c73f3469f07e2c19a803de4755d335817ed8e2e5a"),
+		Execution:  params.MainnetChainConfig,
 	}
 
 	SepoliaConfig = lightClientConfig{
@@ -55,6 +58,7 @@ var (
 			AddFork("CAPELLA", 56832, []byte{144, 0, 0, 114}).
 			AddFork("DENEB", 132608, []byte{144, 0, 0, 115}),
 		Checkpoint: common.HexToHash("0x1005a6d9175e96bfbce4d35b80f468e9bff0b674e1e861d16e09e10005a58e81"),
+		Execution:  params.SepoliaChainConfig,
 	}
 
 	GoerliConfig = lightClientConfig{
@@ -68,6 +72,7 @@ var (
 			AddFork("CAPELLA", 162304, []byte{3, 0, 16, 32}).
 			AddFork("DENEB", 231680, []byte{4, 0, 16, 32}),
 		Checkpoint: common.HexToHash("0x53a0f4f0a378e2c4ae0a9ee97407eb69d0d737d8d8cd0a5fb1093f42f7b81c49"),
+		Execution:  params.GoerliChainConfig,
 	}
 )
 
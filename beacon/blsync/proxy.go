@@ -0,0 +1,570 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blsync
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ctypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+const (
+	// maxProxyBlocks is the number of recent verified execution blocks the proxy
+	// keeps around for serving requests.
+	maxProxyBlocks = 128
+
+	// proxyCallGasCap is the gas limit applied to eth_call requests.
+	proxyCallGasCap = 50_000_000
+
+	// proxyCallTimeout is the maximum duration of an eth_call execution.
+	proxyCallTimeout = 5 * time.Second
+)
+
+var (
+	errNoVerifiedHead  = errors.New("no verified head block yet")
+	errUnverifiedBlock = errors.New("block is not among the recently verified ones")
+	errNoChainConfig   = errors.New("execution chain config is unknown")
+)
+
+// verifiedChain keeps track of the recent execution blocks authenticated by the
+// beacon light client.
+type verifiedChain struct {
+	lock      sync.RWMutex
+	blocks    map[common.Hash]*ctypes.Block
+	canonical map[uint64]common.Hash
+	head      *ctypes.Block
+	finalized common.Hash
+}
+
+func newVerifiedChain() *verifiedChain {
+	return &verifiedChain{
+		blocks:    make(map[common.Hash]*ctypes.Block),
+		canonical: make(map[uint64]common.Hash),
+	}
+}
+
+// add inserts a newly verified head block, updating the canonical mapping of
+// the cached ancestors and evicting the blocks that got too old.
+func (vc *verifiedChain) add(block *ctypes.Block, finalized common.Hash) {
+	vc.lock.Lock()
+	defer vc.lock.Unlock()
+
+	number := block.NumberU64()
+	vc.blocks[block.Hash()] = block
+	vc.head, vc.finalized = block, finalized
+
+	// Drop the number mappings above the new head in case of a reorg
+	for n := range vc.canonical {
+		if n > number {
+			delete(vc.canonical, n)
+		}
+	}
+	// Remap the cached ancestors of the new head as canonical
+	for b := block; b != nil; b = vc.blocks[b.ParentHash()] {
+		if vc.canonical[b.NumberU64()] == b.Hash() {
+			break
+		}
+		vc.canonical[b.NumberU64()] = b.Hash()
+	}
+	// Evict the blocks falling out of the retained window
+	if number >= maxProxyBlocks {
+		for hash, b := range vc.blocks {
+			if b.NumberU64() <= number-maxProxyBlocks {
+				delete(vc.blocks, hash)
+				if vc.canonical[b.NumberU64()] == hash {
+					delete(vc.canonical, b.NumberU64())
+				}
+			}
+		}
+	}
+}
+
+// block resolves the given block specifier to one of the verified blocks.
+func (vc *verifiedChain) block(blockNrOrHash rpc.BlockNumberOrHash) (*ctypes.Block, error) {
+	vc.lock.RLock()
+	defer vc.lock.RUnlock()
+
+	if vc.head == nil {
+		return nil, errNoVerifiedHead
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block := vc.blocks[hash]
+		if block == nil {
+			return nil, errUnverifiedBlock
+		}
+		if blockNrOrHash.RequireCanonical && vc.canonical[block.NumberU64()] != hash {
+			return nil, fmt.Errorf("hash %#x is not currently canonical", hash)
+		}
+		return block, nil
+	}
+	number, _ := blockNrOrHash.Number()
+	switch number {
+	case rpc.LatestBlockNumber, rpc.PendingBlockNumber:
+		return vc.head, nil
+	case rpc.FinalizedBlockNumber, rpc.SafeBlockNumber:
+		if block := vc.blocks[vc.finalized]; block != nil {
+			return block, nil
+		}
+		return nil, errUnverifiedBlock
+	}
+	if number < 0 {
+		return nil, errUnverifiedBlock
+	}
+	if block := vc.blocks[vc.canonical[uint64(number)]]; block != nil {
+		return block, nil
+	}
+	return nil, errUnverifiedBlock
+}
+
+// transaction looks up a transaction in the verified canonical blocks.
+func (vc *verifiedChain) transaction(hash common.Hash) (*ctypes.Block, int) {
+	vc.lock.RLock()
+	defer vc.lock.RUnlock()
+
+	for _, blockHash := range vc.canonical {
+		block := vc.blocks[blockHash]
+		for i, tx := range block.Transactions() {
+			if tx.Hash() == hash {
+				return block, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// GetHeader implements core.ChainContext, providing the block hashes accessible
+// to the EVM during eth_call execution.
+func (vc *verifiedChain) GetHeader(hash common.Hash, number uint64) *ctypes.Header {
+	vc.lock.RLock()
+	defer vc.lock.RUnlock()
+
+	if block := vc.blocks[hash]; block != nil && block.NumberU64() == number {
+		return block.Header()
+	}
+	return nil
+}
+
+// Engine implements core.ChainContext. The consensus engine is never needed as
+// the block author is always passed explicitly.
+func (vc *verifiedChain) Engine() consensus.Engine {
+	return nil
+}
+
+// ProxyAPI serves a subset of the eth namespace by forwarding the requests to an
+// untrusted execution RPC endpoint and verifying the answers against the blocks
+// authenticated by the beacon light client. Any answer that fails verification
+// is rejected.
+type ProxyAPI struct {
+	config   *params.ChainConfig
+	upstream *rpc.Client
+	chain    *verifiedChain
+}
+
+// NewProxyAPI creates a verifying proxy in front of the given upstream endpoint.
+// The chain config is needed for eth_call and receipt retrieval, without it only
+// the account queries are served.
+func NewProxyAPI(config *params.ChainConfig, upstream *rpc.Client) *ProxyAPI {
+	return &ProxyAPI{
+		config:   config,
+		upstream: upstream,
+		chain:    newVerifiedChain(),
+	}
+}
+
+// updateLoop feeds the verified head blocks into the proxy until the head event
+// subscription is terminated.
+func (api *ProxyAPI) updateLoop(headCh <-chan types.ChainHeadEvent, quit <-chan struct{}) {
+	for {
+		select {
+		case event := <-headCh:
+			api.chain.add(event.Block, event.Finalized)
+			log.Debug("Verified proxy head updated", "number", event.Block.NumberU64(), "hash", event.Block.Hash())
+		case <-quit:
+			return
+		}
+	}
+}
+
+// BlockNumber returns the number of the latest verified block.
+func (api *ProxyAPI) BlockNumber() (hexutil.Uint64, error) {
+	block, err := api.chain.block(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.NumberU64()), nil
+}
+
+// GetBalance returns the verified balance of the account at the given block.
+func (api *ProxyAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	block, err := api.chain.block(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	account, _, err := api.fetchAccount(ctx, block.Header(), address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return new(hexutil.Big), nil
+	}
+	return (*hexutil.Big)(account.Balance.ToBig()), nil
+}
+
+// GetCode returns the verified code of the account at the given block.
+func (api *ProxyAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	block, err := api.chain.block(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	account, _, err := api.fetchAccount(ctx, block.Header(), address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return hexutil.Bytes{}, nil
+	}
+	return api.fetchCode(ctx, block.Hash(), address, common.BytesToHash(account.CodeHash))
+}
+
+// GetStorageAt returns the verified value of the storage slot of the account at
+// the given block.
+func (api *ProxyAPI) GetStorageAt(ctx context.Context, address common.Address, hexKey string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	key, err := decodeStorageKey(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode storage key: %s", err)
+	}
+	block, err := api.chain.block(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	_, values, err := api.fetchAccount(ctx, block.Header(), address, []common.Hash{key})
+	if err != nil {
+		return nil, err
+	}
+	return values[0][:], nil
+}
+
+// Call executes the given call locally on top of the state of the given block,
+// where every account, storage slot and code accessed by the execution is
+// retrieved from the upstream and verified against the state root.
+func (api *ProxyAPI) Call(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	if api.config == nil {
+		return nil, errNoChainConfig
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	block, err := api.chain.block(*blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	header := block.Header()
+
+	ctx, cancel := context.WithTimeout(ctx, proxyCallTimeout)
+	defer cancel()
+
+	statedb, err := state.New(header.Root, newProofDatabase(ctx, api, header), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := args.CallDefaults(proxyCallGasCap, header.BaseFee, api.config.ChainID); err != nil {
+		return nil, err
+	}
+	var (
+		msg      = args.ToMessage(header.BaseFee)
+		blockCtx = core.NewEVMBlockContext(header, api.chain, &header.Coinbase)
+		evm      = vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.config, vm.Config{NoBaseFee: true})
+	)
+	// Abort the execution if the request is cancelled or times out
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if err := statedb.Error(); err != nil {
+		return nil, fmt.Errorf("state verification failed: %w", err)
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", proxyCallTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("err: %w (supplied gas %d)", err, msg.GasLimit)
+	}
+	if len(result.Revert()) > 0 {
+		return nil, newRevertError(result.Revert())
+	}
+	return result.Return(), result.Err
+}
+
+// GetTransactionReceipt returns the receipt of a transaction included in one of
+// the recently verified blocks. The receipts of the whole block are retrieved
+// from the upstream and verified against the receipt root, the non-consensus
+// fields are derived locally. Transactions not found in the verified blocks are
+// reported as unknown.
+func (api *ProxyAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	if api.config == nil {
+		return nil, errNoChainConfig
+	}
+	block, index := api.chain.transaction(hash)
+	if block == nil {
+		return nil, nil
+	}
+	receipts, err := api.fetchReceipts(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tx      = block.Transactions()[index]
+		receipt = receipts[index]
+		signer  = ctypes.MakeSigner(api.config, block.Number(), block.Time())
+		from, _ = ctypes.Sender(signer, tx)
+	)
+	fields := map[string]interface{}{
+		"blockHash":         block.Hash(),
+		"blockNumber":       hexutil.Uint64(block.NumberU64()),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(index),
+		"from":              from,
+		"to":                tx.To(),
+		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"contractAddress":   nil,
+		"logs":              receipt.Logs,
+		"logsBloom":         receipt.Bloom,
+		"type":              hexutil.Uint(tx.Type()),
+		"effectiveGasPrice": (*hexutil.Big)(receipt.EffectiveGasPrice),
+	}
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["status"] = hexutil.Uint(receipt.Status)
+	}
+	if receipt.Logs == nil {
+		fields["logs"] = []*ctypes.Log{}
+	}
+	if tx.Type() == ctypes.BlobTxType {
+		fields["blobGasUsed"] = hexutil.Uint64(receipt.BlobGasUsed)
+		fields["blobGasPrice"] = (*hexutil.Big)(receipt.BlobGasPrice)
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		fields["contractAddress"] = receipt.ContractAddress
+	}
+	return fields, nil
+}
+
+// fetchAccount retrieves the account and the requested storage slots at the
+// given block from the upstream, verifying them against the state root.
+func (api *ProxyAPI) fetchAccount(ctx context.Context, header *ctypes.Header, address common.Address, keys []common.Hash) (*ctypes.StateAccount, []common.Hash, error) {
+	hexKeys := make([]string, len(keys))
+	for i, key := range keys {
+		hexKeys[i] = key.Hex()
+	}
+	var res ethapi.AccountResult
+	if err := api.upstream.CallContext(ctx, &res, "eth_getProof", address, hexKeys, rpc.BlockNumberOrHashWithHash(header.Hash(), false)); err != nil {
+		return nil, nil, err
+	}
+	return verifyAccountProof(header.Root, address, keys, &res)
+}
+
+// fetchCode retrieves the contract code at the given block from the upstream,
+// verifying it against the code hash of the account.
+func (api *ProxyAPI) fetchCode(ctx context.Context, blockHash common.Hash, address common.Address, codeHash common.Hash) ([]byte, error) {
+	if codeHash == ctypes.EmptyCodeHash {
+		return []byte{}, nil
+	}
+	var code hexutil.Bytes
+	if err := api.upstream.CallContext(ctx, &code, "eth_getCode", address, rpc.BlockNumberOrHashWithHash(blockHash, false)); err != nil {
+		return nil, err
+	}
+	if hash := crypto.Keccak256Hash(code); hash != codeHash {
+		return nil, fmt.Errorf("code hash mismatch for %#x: have %#x, want %#x", address, hash, codeHash)
+	}
+	return code, nil
+}
+
+// fetchReceipts retrieves the receipts of the given block from the upstream,
+// verifying them against the receipt root and deriving the non-consensus fields.
+func (api *ProxyAPI) fetchReceipts(ctx context.Context, block *ctypes.Block) (ctypes.Receipts, error) {
+	var receipts ctypes.Receipts
+	if err := api.upstream.CallContext(ctx, &receipts, "eth_getBlockReceipts", rpc.BlockNumberOrHashWithHash(block.Hash(), false)); err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipt count mismatch: have %d, want %d", len(receipts), len(txs))
+	}
+	for i, receipt := range receipts {
+		if receipt == nil {
+			return nil, fmt.Errorf("missing receipt %d", i)
+		}
+		receipt.Type = txs[i].Type()
+	}
+	if hash := ctypes.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+		return nil, fmt.Errorf("receipt root mismatch: have %#x, want %#x", hash, block.ReceiptHash())
+	}
+	var blobGasPrice *big.Int
+	if excess := block.ExcessBlobGas(); excess != nil {
+		blobGasPrice = eip4844.CalcBlobFee(*excess)
+	}
+	if err := receipts.DeriveFields(api.config, block.Hash(), block.NumberU64(), block.Time(), block.BaseFee(), blobGasPrice, txs); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// verifyAccountProof checks the account and storage proofs of an eth_getProof
+// response against the given state root. The returned account and slot values
+// are taken from the proofs, the values reported by the upstream are ignored.
+// The account is nil if it's proven to be non-existent.
+func verifyAccountProof(root common.Hash, address common.Address, keys []common.Hash, res *ethapi.AccountResult) (*ctypes.StateAccount, []common.Hash, error) {
+	if res.Address != address {
+		return nil, nil, fmt.Errorf("proof address mismatch: have %#x, want %#x", res.Address, address)
+	}
+	var account *ctypes.StateAccount
+	if root != ctypes.EmptyRootHash {
+		proof, err := proofSet(res.AccountProof)
+		if err != nil {
+			return nil, nil, err
+		}
+		blob, err := trie.VerifyProof(root, crypto.Keccak256(address[:]), proof)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid account proof for %#x: %w", address, err)
+		}
+		if blob != nil {
+			account = new(ctypes.StateAccount)
+			if err := rlp.DecodeBytes(blob, account); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if len(res.StorageProof) != len(keys) {
+		return nil, nil, fmt.Errorf("storage proof count mismatch: have %d, want %d", len(res.StorageProof), len(keys))
+	}
+	values := make([]common.Hash, len(keys))
+	for i, key := range keys {
+		if have, err := decodeStorageKey(res.StorageProof[i].Key); err != nil || have != key {
+			return nil, nil, fmt.Errorf("storage proof key mismatch: have %s, want %#x", res.StorageProof[i].Key, key)
+		}
+		if account == nil || account.Root == ctypes.EmptyRootHash {
+			continue
+		}
+		proof, err := proofSet(res.StorageProof[i].Proof)
+		if err != nil {
+			return nil, nil, err
+		}
+		blob, err := trie.VerifyProof(account.Root, crypto.Keccak256(key[:]), proof)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid storage proof for %#x of %#x: %w", key, address, err)
+		}
+		if blob != nil {
+			_, content, _, err := rlp.Split(blob)
+			if err != nil {
+				return nil, nil, err
+			}
+			values[i] = common.BytesToHash(content)
+		}
+	}
+	return account, values, nil
+}
+
+// proofSet converts the hex encoded proof nodes into a proof database.
+func proofSet(nodes []string) (*trienode.ProofSet, error) {
+	set := trienode.NewProofSet()
+	for _, node := range nodes {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node: %w", err)
+		}
+		set.Put(crypto.Keccak256(blob), blob)
+	}
+	return set, nil
+}
+
+// decodeStorageKey parses a hex-encoded storage key of at most 32 bytes.
+func decodeStorageKey(s string) (common.Hash, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if (len(s) & 1) > 0 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return common.Hash{}, errors.New("hex string invalid")
+	}
+	if len(b) > 32 {
+		return common.Hash{}, errors.New("hex string too long, want at most 32 bytes")
+	}
+	return common.BytesToHash(b), nil
+}
+
+// revertError is an API error that encompasses an EVM revert with JSON error
+// code and a binary data blob.
+type revertError struct {
+	error
+	reason string // revert reason hex encoded
+}
+
+// ErrorCode returns the JSON error code for a revert.
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+// ErrorData returns the hex encoded revert reason.
+func (e *revertError) ErrorData() interface{} {
+	return e.reason
+}
+
+// newRevertError creates a revertError instance with the provided revert data.
+func newRevertError(revert []byte) *revertError {
+	err := vm.ErrExecutionReverted
+
+	reason, errUnpack := abi.UnpackRevert(revert)
+	if errUnpack == nil {
+		err = fmt.Errorf("%w: %v", vm.ErrExecutionReverted, reason)
+	}
+	return &revertError{
+		error:  err,
+		reason: hexutil.Encode(revert),
+	}
+}
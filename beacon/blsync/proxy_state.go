@@ -0,0 +1,189 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+var errProofTrieUnsupported = errors.New("not supported by proof backed state")
+
+// proofDatabase is a state.Database backed by the upstream RPC endpoint of the
+// proxy. Accounts, storage slots and contract codes are retrieved lazily while
+// executing a call, and are only used after being verified against the state
+// root of the associated block. Modifications are kept in the state object
+// layer and are never written anywhere.
+type proofDatabase struct {
+	ctx    context.Context
+	api    *ProxyAPI
+	header *types.Header
+
+	accounts map[common.Address]*types.StateAccount
+	slots    map[common.Address]map[common.Hash]common.Hash
+	codes    map[common.Hash][]byte
+}
+
+func newProofDatabase(ctx context.Context, api *ProxyAPI, header *types.Header) *proofDatabase {
+	return &proofDatabase{
+		ctx:      ctx,
+		api:      api,
+		header:   header,
+		accounts: make(map[common.Address]*types.StateAccount),
+		slots:    make(map[common.Address]map[common.Hash]common.Hash),
+		codes:    make(map[common.Hash][]byte),
+	}
+}
+
+// account retrieves a verified account, caching it for later accesses.
+func (db *proofDatabase) account(address common.Address) (*types.StateAccount, error) {
+	if account, ok := db.accounts[address]; ok {
+		return account, nil
+	}
+	account, _, err := db.api.fetchAccount(db.ctx, db.header, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	db.accounts[address] = account
+	return account, nil
+}
+
+// storage retrieves a verified storage slot, caching it for later accesses.
+func (db *proofDatabase) storage(address common.Address, root common.Hash, key common.Hash) (common.Hash, error) {
+	if value, ok := db.slots[address][key]; ok {
+		return value, nil
+	}
+	account, values, err := db.api.fetchAccount(db.ctx, db.header, address, []common.Hash{key})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	have := types.EmptyRootHash
+	if account != nil {
+		have = account.Root
+	}
+	if have != root {
+		return common.Hash{}, fmt.Errorf("storage root mismatch for %#x: have %#x, want %#x", address, have, root)
+	}
+	if db.slots[address] == nil {
+		db.slots[address] = make(map[common.Hash]common.Hash)
+	}
+	db.slots[address][key] = values[0]
+	return values[0], nil
+}
+
+// OpenTrie opens the account trie of the block the database is associated with.
+func (db *proofDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	if root != db.header.Root {
+		return nil, fmt.Errorf("state root mismatch: have %#x, want %#x", root, db.header.Root)
+	}
+	return &proofTrie{db: db, root: root}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account.
+func (db *proofDatabase) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, _ state.Trie) (state.Trie, error) {
+	if stateRoot != db.header.Root {
+		return nil, fmt.Errorf("state root mismatch: have %#x, want %#x", stateRoot, db.header.Root)
+	}
+	return &proofTrie{db: db, root: root}, nil
+}
+
+// CopyTrie returns the given trie, proof tries are never modified.
+func (db *proofDatabase) CopyTrie(t state.Trie) state.Trie {
+	return t
+}
+
+// ContractCode retrieves a verified contract code.
+func (db *proofDatabase) ContractCode(address common.Address, codeHash common.Hash) ([]byte, error) {
+	if code, ok := db.codes[codeHash]; ok {
+		return code, nil
+	}
+	code, err := db.api.fetchCode(db.ctx, db.header.Hash(), address, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	db.codes[codeHash] = code
+	return code, nil
+}
+
+// ContractCodeSize retrieves the size of a verified contract code.
+func (db *proofDatabase) ContractCodeSize(address common.Address, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(address, codeHash)
+	return len(code), err
+}
+
+// DiskDB returns nil as the proof backed state has no disk storage.
+func (db *proofDatabase) DiskDB() ethdb.KeyValueStore { return nil }
+
+// PointCache returns nil as verkle tries are not supported.
+func (db *proofDatabase) PointCache() *utils.PointCache { return nil }
+
+// TrieDB returns nil as the proof backed state has no trie database.
+func (db *proofDatabase) TrieDB() *triedb.Database { return nil }
+
+// proofTrie is a read-only state.Trie whose leaves are retrieved and verified
+// through the proof database on demand. Writes are silently discarded as the
+// state is never committed.
+type proofTrie struct {
+	db   *proofDatabase
+	root common.Hash
+}
+
+func (t *proofTrie) GetKey([]byte) []byte { return nil }
+
+func (t *proofTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	account, err := t.db.account(address)
+	if err != nil || account == nil {
+		return nil, err
+	}
+	return account.Copy(), nil
+}
+
+func (t *proofTrie) GetStorage(address common.Address, key []byte) ([]byte, error) {
+	value, err := t.db.storage(address, t.root, common.BytesToHash(key))
+	if err != nil {
+		return nil, err
+	}
+	return common.TrimLeftZeroes(value[:]), nil
+}
+
+func (t *proofTrie) UpdateAccount(common.Address, *types.StateAccount) error      { return nil }
+func (t *proofTrie) UpdateStorage(common.Address, []byte, []byte) error           { return nil }
+func (t *proofTrie) DeleteAccount(common.Address) error                           { return nil }
+func (t *proofTrie) DeleteStorage(common.Address, []byte) error                   { return nil }
+func (t *proofTrie) UpdateContractCode(common.Address, common.Hash, []byte) error { return nil }
+func (t *proofTrie) Hash() common.Hash                                            { return t.root }
+func (t *proofTrie) Commit(bool) (common.Hash, *trienode.NodeSet)                 { return t.root, nil }
+func (t *proofTrie) Witness() map[string]struct{}                                 { return nil }
+func (t *proofTrie) IsVerkle() bool                                               { return false }
+
+func (t *proofTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errProofTrieUnsupported
+}
+
+func (t *proofTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errProofTrieUnsupported
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blsync
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	proxyTestKey, _  = crypto.GenerateKey()
+	proxyTestSender  = crypto.PubkeyToAddress(proxyTestKey.PublicKey)
+	proxyTestTarget  = common.HexToAddress("0xbeef")
+	proxyTestStorage = common.HexToAddress("0xc0de")
+)
+
+// testUpstream is a minimal execution RPC endpoint serving the requests needed
+// by the proxy from a local chain, optionally tampering with the responses.
+type testUpstream struct {
+	chain  *core.BlockChain
+	tamper bool
+}
+
+func (u *testUpstream) header(blockNrOrHash rpc.BlockNumberOrHash) *types.Header {
+	hash, _ := blockNrOrHash.Hash()
+	return u.chain.GetHeaderByHash(hash)
+}
+
+func (u *testUpstream) GetProof(address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error) {
+	root := u.header(blockNrOrHash).Root
+	if u.tamper {
+		// Serve the proofs of a different state
+		root = u.chain.Genesis().Root()
+	}
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), u.chain.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	var accountProof proofList
+	if err := tr.Prove(crypto.Keccak256(address[:]), &accountProof); err != nil {
+		return nil, err
+	}
+	res := &ethapi.AccountResult{Address: address, AccountProof: accountProof}
+	account, err := tr.GetAccount(address)
+	if err != nil {
+		return nil, err
+	}
+	for _, hexKey := range storageKeys {
+		key, err := decodeStorageKey(hexKey)
+		if err != nil {
+			return nil, err
+		}
+		var proof proofList
+		if account != nil && account.Root != types.EmptyRootHash {
+			st, err := trie.NewStateTrie(trie.StorageTrieID(root, crypto.Keccak256Hash(address[:]), account.Root), u.chain.TrieDB())
+			if err != nil {
+				return nil, err
+			}
+			if err := st.Prove(crypto.Keccak256(key[:]), &proof); err != nil {
+				return nil, err
+			}
+		}
+		res.StorageProof = append(res.StorageProof, ethapi.StorageResult{Key: hexKey, Value: new(hexutil.Big), Proof: proof})
+	}
+	return res, nil
+}
+
+func (u *testUpstream) GetCode(address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	statedb, err := u.chain.StateAt(u.header(blockNrOrHash).Root)
+	if err != nil {
+		return nil, err
+	}
+	code := statedb.GetCode(address)
+	if u.tamper {
+		code = append(common.CopyBytes(code), 0x00)
+	}
+	return code, nil
+}
+
+func (u *testUpstream) GetBlockReceipts(blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	hash, _ := blockNrOrHash.Hash()
+	receipts := u.chain.GetReceiptsByHash(hash)
+	if u.tamper {
+		receipts[0].CumulativeGasUsed++
+	}
+	return receipts, nil
+}
+
+// proofList collects the hex encoded proof nodes of a trie.
+type proofList []string
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, hexutil.Encode(value))
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// newTestProxy creates a proxy in front of a test upstream, with all the blocks
+// of a generated chain marked as verified.
+func newTestProxy(t *testing.T) (*ProxyAPI, *testUpstream, []*types.Block) {
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			proxyTestSender: {Balance: big.NewInt(params.Ether)},
+			// Returns the value of slot 1
+			proxyTestStorage: {
+				Code:    common.FromHex("60015460005260206000f3"),
+				Storage: map[common.Hash]common.Hash{common.HexToHash("0x1"): {0x2a}, common.HexToHash("0x2"): {0x2b}},
+			},
+		},
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 4, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(proxyTestSender), proxyTestTarget, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, proxyTestKey)
+		b.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("Failed to insert chain: %v", err)
+	}
+	upstream := &testUpstream{chain: chain}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", upstream); err != nil {
+		t.Fatalf("Failed to register upstream: %v", err)
+	}
+	t.Cleanup(server.Stop)
+
+	api := NewProxyAPI(params.TestChainConfig, rpc.DialInProc(server))
+	for _, block := range blocks {
+		api.chain.add(block, common.Hash{})
+	}
+	return api, upstream, blocks
+}
+
+func TestProxyAccounts(t *testing.T) {
+	api, upstream, blocks := newTestProxy(t)
+	var (
+		ctx    = context.Background()
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		second = rpc.BlockNumberOrHashWithHash(blocks[1].Hash(), false)
+	)
+	if balance, err := api.GetBalance(ctx, proxyTestTarget, latest); err != nil || balance.ToInt().Uint64() != 4000 {
+		t.Fatalf("Unexpected latest balance: %v, %v", balance, err)
+	}
+	if balance, err := api.GetBalance(ctx, proxyTestTarget, second); err != nil || balance.ToInt().Uint64() != 2000 {
+		t.Fatalf("Unexpected historic balance: %v, %v", balance, err)
+	}
+	if balance, err := api.GetBalance(ctx, common.Address{0x1}, latest); err != nil || balance.ToInt().Sign() != 0 {
+		t.Fatalf("Unexpected missing account balance: %v, %v", balance, err)
+	}
+	if code, err := api.GetCode(ctx, proxyTestStorage, latest); err != nil || !bytes.Equal(code, common.FromHex("60015460005260206000f3")) {
+		t.Fatalf("Unexpected code: %x, %v", code, err)
+	}
+	if value, err := api.GetStorageAt(ctx, proxyTestStorage, "0x2", latest); err != nil || common.BytesToHash(value) != (common.Hash{0x2b}) {
+		t.Fatalf("Unexpected storage: %x, %v", value, err)
+	}
+	if value, err := api.GetStorageAt(ctx, proxyTestStorage, "0x3", latest); err != nil || common.BytesToHash(value) != (common.Hash{}) {
+		t.Fatalf("Unexpected missing storage: %x, %v", value, err)
+	}
+	// Blocks not verified by the light client are not served
+	if _, err := api.GetBalance(ctx, proxyTestTarget, rpc.BlockNumberOrHashWithHash(common.Hash{0x1}, false)); err != errUnverifiedBlock {
+		t.Fatalf("Unverified block served: %v", err)
+	}
+	// Answers not matching the verified state are rejected
+	upstream.tamper = true
+	if _, err := api.GetBalance(ctx, proxyTestTarget, latest); err == nil {
+		t.Fatal("Tampered balance accepted")
+	}
+	if _, err := api.GetStorageAt(ctx, proxyTestStorage, "0x2", latest); err == nil {
+		t.Fatal("Tampered storage accepted")
+	}
+}
+
+func TestProxyCall(t *testing.T) {
+	api, upstream, _ := newTestProxy(t)
+
+	var (
+		ctx    = context.Background()
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		args   = ethapi.TransactionArgs{From: &proxyTestSender, To: &proxyTestStorage}
+	)
+	ret, err := api.Call(ctx, args, &latest)
+	if err != nil {
+		t.Fatalf("Failed to execute call: %v", err)
+	}
+	if common.BytesToHash(ret) != (common.Hash{0x2a}) {
+		t.Fatalf("Unexpected call result: %x", ret)
+	}
+	upstream.tamper = true
+	if _, err := api.Call(ctx, args, &latest); err == nil {
+		t.Fatal("Call on tampered state succeeded")
+	}
+}
+
+func TestProxyReceipt(t *testing.T) {
+	api, upstream, blocks := newTestProxy(t)
+
+	ctx := context.Background()
+	tx := blocks[2].Transactions()[0]
+	receipt, err := api.GetTransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatalf("Failed to retrieve receipt: %v", err)
+	}
+	if receipt["blockHash"] != blocks[2].Hash() || receipt["from"] != proxyTestSender || receipt["gasUsed"] != hexutil.Uint64(params.TxGas) {
+		t.Fatalf("Unexpected receipt: %v", receipt)
+	}
+	if receipt, err := api.GetTransactionReceipt(ctx, common.Hash{0x1}); receipt != nil || err != nil {
+		t.Fatalf("Unknown transaction served: %v, %v", receipt, err)
+	}
+	upstream.tamper = true
+	if _, err := api.GetTransactionReceipt(ctx, tx.Hash()); err == nil {
+		t.Fatal("Tampered receipts accepted")
+	}
+}
+
+func TestVerifiedChainReorg(t *testing.T) {
+	_, _, blocks := newTestProxy(t)
+
+	vc := newVerifiedChain()
+	for _, block := range blocks {
+		vc.add(block, blocks[0].Hash())
+	}
+	// Reorg to a shorter sibling chain
+	sibling := types.NewBlockWithHeader(&types.Header{ParentHash: blocks[1].Hash(), Number: big.NewInt(3), Extra: []byte("sibling")})
+	vc.add(sibling, blocks[0].Hash())
+
+	if block, err := vc.block(rpc.BlockNumberOrHashWithNumber(3)); err != nil || block.Hash() != sibling.Hash() {
+		t.Fatalf("Unexpected block 3: %v", err)
+	}
+	if _, err := vc.block(rpc.BlockNumberOrHashWithNumber(4)); err != errUnverifiedBlock {
+		t.Fatalf("Reorged block still served: %v", err)
+	}
+	if _, err := vc.block(rpc.BlockNumberOrHashWithHash(blocks[3].Hash(), true)); err == nil {
+		t.Fatal("Non-canonical block served as canonical")
+	}
+	if block, err := vc.block(rpc.BlockNumberOrHashWithNumber(rpc.FinalizedBlockNumber)); err != nil || block.Hash() != blocks[0].Hash() {
+		t.Fatalf("Unexpected finalized block: %v", err)
+	}
+}
@@ -0,0 +1,421 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/beacon/light"
+	"github.com/ethereum/go-ethereum/beacon/light/request"
+	"github.com/ethereum/go-ethereum/beacon/merkle"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// maxUpdatesRequest is the maximum number of committee updates served in a
+// single request, see MAX_REQUEST_LIGHT_CLIENT_UPDATES in the consensus specs.
+const maxUpdatesRequest = 128
+
+// serverEvent is an event published on the event stream of LightServer.
+type serverEvent struct {
+	topic string
+	data  []byte
+}
+
+// LightServer serves the light client subset of the beacon chain REST API from
+// the data verified by a local light client, allowing other light clients to
+// sync from it. It implements request.Module in order to publish the newly
+// validated heads on its event stream.
+type LightServer struct {
+	config *types.ChainConfig
+	chain  *light.CommitteeChain
+	head   *light.HeadTracker
+	blocks func(common.Hash) *types.BeaconBlock
+
+	lock                         sync.Mutex
+	subs                         map[chan serverEvent]struct{}
+	lastOptimistic, lastFinality types.SignedHeader
+}
+
+// NewLightServer creates a new LightServer. The blocks function provides the
+// recently retrieved beacon blocks.
+func NewLightServer(config *types.ChainConfig, chain *light.CommitteeChain, head *light.HeadTracker, blocks func(common.Hash) *types.BeaconBlock) *LightServer {
+	return &LightServer{
+		config: config,
+		chain:  chain,
+		head:   head,
+		blocks: blocks,
+		subs:   make(map[chan serverEvent]struct{}),
+	}
+}
+
+// Process implements request.Module, publishing the newly validated heads.
+func (s *LightServer) Process(requester request.Requester, events []request.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if update, ok := s.head.ValidatedOptimistic(); ok && update.SignedHeader() != s.lastOptimistic {
+		s.lastOptimistic = update.SignedHeader()
+		if head, err := json.Marshal(headEventJson{Slot: common.Decimal(update.Attested.Slot), Block: update.Attested.Hash()}); err == nil {
+			s.publish("head", head)
+		}
+		if enc, err := s.encodeOptimisticUpdate(update); err == nil {
+			s.publish("light_client_optimistic_update", enc)
+		}
+	}
+	if update, ok := s.head.ValidatedFinality(); ok && update.SignedHeader() != s.lastFinality {
+		s.lastFinality = update.SignedHeader()
+		if enc, err := s.encodeFinalityUpdate(update); err == nil {
+			s.publish("light_client_finality_update", enc)
+		}
+	}
+}
+
+// publish sends an event to all subscribers, dropping it for the ones which are
+// not keeping up.
+func (s *LightServer) publish(topic string, data []byte) {
+	for ch := range s.subs {
+		select {
+		case ch <- serverEvent{topic: topic, data: data}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *LightServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var (
+		resp any
+		err  error
+		path = r.URL.Path
+	)
+	switch {
+	case path == "/eth/v1/beacon/light_client/updates":
+		resp, err = s.serveUpdates(r.URL.Query())
+	case strings.HasPrefix(path, "/eth/v1/beacon/light_client/bootstrap/"):
+		resp, err = s.serveBootstrap(strings.TrimPrefix(path, "/eth/v1/beacon/light_client/bootstrap/"))
+	case path == "/eth/v1/beacon/light_client/optimistic_update":
+		update, ok := s.head.ValidatedOptimistic()
+		if !ok {
+			err = ErrNotFound
+			break
+		}
+		resp, err = s.encodeOptimisticUpdate(update)
+	case path == "/eth/v1/beacon/light_client/finality_update":
+		update, ok := s.head.ValidatedFinality()
+		if !ok {
+			err = ErrNotFound
+			break
+		}
+		resp, err = s.encodeFinalityUpdate(update)
+	case strings.HasPrefix(path, "/eth/v1/beacon/headers/"):
+		resp, err = s.serveHeader(strings.TrimPrefix(path, "/eth/v1/beacon/headers/"))
+	case strings.HasPrefix(path, "/eth/v2/beacon/blocks/"):
+		resp, err = s.serveBlock(strings.TrimPrefix(path, "/eth/v2/beacon/blocks/"))
+	case path == "/eth/v1/events":
+		s.serveEvents(w, r)
+		return
+	default:
+		err = ErrNotFound
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		var enc []byte
+		if raw, ok := resp.([]byte); ok {
+			enc = raw
+		} else if enc, err = json.Marshal(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(enc)
+	}
+}
+
+// serveUpdates serves the best committee updates of the requested periods.
+func (s *LightServer) serveUpdates(query url.Values) (any, error) {
+	start, err := strconv.ParseUint(query.Get("start_period"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_period: %v", err)
+	}
+	count, err := strconv.ParseUint(query.Get("count"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid count: %v", err)
+	}
+	if count > maxUpdatesRequest {
+		count = maxUpdatesRequest
+	}
+	updates := make([]committeeUpdateJson, 0, count)
+	for period := start; period < start+count; period++ {
+		update, committee, ok := s.chain.Update(period)
+		if !ok {
+			break
+		}
+		data := committeeUpdateData{
+			Header:                  jsonBeaconHeader{Beacon: update.AttestedHeader.Header},
+			NextSyncCommittee:       *committee,
+			NextSyncCommitteeBranch: update.NextSyncCommitteeBranch,
+			FinalityBranch:          update.FinalityBranch,
+			SyncAggregate:           update.AttestedHeader.Signature,
+			SignatureSlot:           common.Decimal(update.AttestedHeader.SignatureSlot),
+		}
+		if update.FinalizedHeader != nil {
+			data.FinalizedHeader = &jsonBeaconHeader{Beacon: *update.FinalizedHeader}
+		}
+		updates = append(updates, committeeUpdateJson{Version: s.version(update.AttestedHeader.Header), Data: data})
+	}
+	return updates, nil
+}
+
+// serveBootstrap serves the bootstrap data of the checkpoint the local chain was
+// initialized from.
+func (s *LightServer) serveBootstrap(blockId string) (any, error) {
+	checkpoint, ok := s.chain.Checkpoint()
+	if !ok || blockId != checkpoint.Header.Hash().Hex() {
+		return nil, ErrNotFound
+	}
+	var resp struct {
+		Version string `json:"version"`
+		Data    struct {
+			Header          jsonBeaconHeader               `json:"header"`
+			Committee       *types.SerializedSyncCommittee `json:"current_sync_committee"`
+			CommitteeBranch merkle.Values                  `json:"current_sync_committee_branch"`
+		} `json:"data"`
+	}
+	resp.Version = s.version(checkpoint.Header)
+	resp.Data.Header = jsonBeaconHeader{Beacon: checkpoint.Header}
+	resp.Data.Committee = checkpoint.Committee
+	resp.Data.CommitteeBranch = checkpoint.CommitteeBranch
+	return &resp, nil
+}
+
+// serveHeader serves one of the headers known to be verified by the local chain.
+func (s *LightServer) serveHeader(blockId string) (any, error) {
+	var (
+		headers   []types.Header
+		finalized = make(map[common.Hash]bool)
+	)
+	optimistic, hasOptimistic := s.head.ValidatedOptimistic()
+	if hasOptimistic {
+		headers = append(headers, optimistic.Attested.Header)
+	}
+	if update, ok := s.head.ValidatedFinality(); ok {
+		headers = append(headers, update.Attested.Header, update.Finalized.Header)
+		finalized[update.Finalized.Hash()] = true
+	}
+	if header, ok := s.chain.Finalized(); ok {
+		headers = append(headers, header)
+		finalized[header.Hash()] = true
+	}
+	if checkpoint, ok := s.chain.Checkpoint(); ok {
+		headers = append(headers, checkpoint.Header)
+		finalized[checkpoint.Header.Hash()] = true
+	}
+	var header *types.Header
+	switch blockId {
+	case "head":
+		if hasOptimistic {
+			header = &optimistic.Attested.Header
+		}
+	default:
+		root := common.HexToHash(blockId)
+		for i := range headers {
+			if headers[i].Hash() == root {
+				header = &headers[i]
+				break
+			}
+		}
+		if header == nil {
+			if block := s.blocks(root); block != nil {
+				h := block.Header()
+				header = &h
+			}
+		}
+	}
+	if header == nil {
+		return nil, ErrNotFound
+	}
+	var resp struct {
+		Finalized bool `json:"finalized"`
+		Data      struct {
+			Root      common.Hash `json:"root"`
+			Canonical bool        `json:"canonical"`
+			Header    struct {
+				Message   types.Header  `json:"message"`
+				Signature hexutil.Bytes `json:"signature"`
+			} `json:"header"`
+		} `json:"data"`
+	}
+	resp.Finalized = finalized[header.Hash()]
+	resp.Data.Root = header.Hash()
+	resp.Data.Canonical = true
+	resp.Data.Header.Message = *header
+	resp.Data.Header.Signature = hexutil.Bytes{}
+	return &resp, nil
+}
+
+// serveBlock serves one of the recently retrieved beacon blocks.
+func (s *LightServer) serveBlock(blockId string) (any, error) {
+	block := s.blocks(common.HexToHash(blockId))
+	if block == nil {
+		return nil, ErrNotFound
+	}
+	var resp struct {
+		Version string `json:"version"`
+		Data    struct {
+			Message *types.BeaconBlock `json:"message"`
+		} `json:"data"`
+	}
+	resp.Version = s.version(block.Header())
+	resp.Data.Message = block
+	return &resp, nil
+}
+
+// serveEvents streams the requested event topics as server-sent events.
+func (s *LightServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	topics := make(map[string]bool)
+	for _, topic := range r.URL.Query()["topics"] {
+		for _, t := range strings.Split(topic, ",") {
+			topics[t] = true
+		}
+	}
+	ch := make(chan serverEvent, 16)
+	s.lock.Lock()
+	s.subs[ch] = struct{}{}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.subs, ch)
+		s.lock.Unlock()
+	}()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-ch:
+			if !topics[event.topic] {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.topic, event.data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// encodeOptimisticUpdate encodes an optimistic update in the REST API format.
+func (s *LightServer) encodeOptimisticUpdate(update types.OptimisticUpdate) ([]byte, error) {
+	attested, err := encodeHeaderWithExecProof(update.Attested)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Version string `json:"version"`
+		Data    struct {
+			Attested      jsonHeaderWithExecProof `json:"attested_header"`
+			Aggregate     types.SyncAggregate     `json:"sync_aggregate"`
+			SignatureSlot common.Decimal          `json:"signature_slot"`
+		} `json:"data"`
+	}
+	resp.Version = s.version(update.Attested.Header)
+	resp.Data.Attested = attested
+	resp.Data.Aggregate = update.Signature
+	resp.Data.SignatureSlot = common.Decimal(update.SignatureSlot)
+	return json.Marshal(&resp)
+}
+
+// encodeFinalityUpdate encodes a finality update in the REST API format.
+func (s *LightServer) encodeFinalityUpdate(update types.FinalityUpdate) ([]byte, error) {
+	attested, err := encodeHeaderWithExecProof(update.Attested)
+	if err != nil {
+		return nil, err
+	}
+	finalized, err := encodeHeaderWithExecProof(update.Finalized)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Version string `json:"version"`
+		Data    struct {
+			Attested       jsonHeaderWithExecProof `json:"attested_header"`
+			Finalized      jsonHeaderWithExecProof `json:"finalized_header"`
+			FinalityBranch merkle.Values           `json:"finality_branch"`
+			Aggregate      types.SyncAggregate     `json:"sync_aggregate"`
+			SignatureSlot  common.Decimal          `json:"signature_slot"`
+		} `json:"data"`
+	}
+	resp.Version = s.version(update.Attested.Header)
+	resp.Data.Attested = attested
+	resp.Data.Finalized = finalized
+	resp.Data.FinalityBranch = update.FinalityBranch
+	resp.Data.Aggregate = update.Signature
+	resp.Data.SignatureSlot = common.Decimal(update.SignatureSlot)
+	return json.Marshal(&resp)
+}
+
+// version returns the name of the fork the given header belongs to, used as the
+// version of the served data.
+func (s *LightServer) version(header types.Header) string {
+	return strings.ToLower(s.config.ForkAtEpoch(header.Epoch()).Name)
+}
+
+// encodeHeaderWithExecProof converts a header with execution proof into the
+// REST API format.
+func encodeHeaderWithExecProof(header types.HeaderWithExecProof) (jsonHeaderWithExecProof, error) {
+	execution, err := json.Marshal(header.PayloadHeader)
+	if err != nil {
+		return jsonHeaderWithExecProof{}, err
+	}
+	return jsonHeaderWithExecProof{
+		Beacon:          header.Header,
+		Execution:       execution,
+		ExecutionBranch: header.PayloadBranch,
+	}, nil
+}
+
+type headEventJson struct {
+	Slot  common.Decimal `json:"slot"`
+	Block common.Hash    `json:"block"`
+}
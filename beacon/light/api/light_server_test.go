@@ -0,0 +1,66 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/light"
+	"github.com/ethereum/go-ethereum/beacon/params"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func TestLightServerBootstrap(t *testing.T) {
+	var (
+		config types.ChainConfig
+		clock  = &mclock.Simulated{}
+	)
+	config.AddFork("GENESIS", 0, []byte{0, 0, 0, 0})
+	clock.Run(time.Duration(params.SyncPeriodLength) * 12 * time.Second * 4)
+
+	chain := light.NewTestCommitteeChain(memorydb.New(), &config, 1, true, clock)
+	checkpoint := light.GenerateTestCheckpoint(3, light.GenerateTestCommittee())
+	if err := chain.CheckpointInit(*checkpoint); err != nil {
+		t.Fatalf("Failed to initialize from checkpoint: %v", err)
+	}
+	server := NewLightServer(&config, chain, light.NewHeadTracker(chain, 1), func(common.Hash) *types.BeaconBlock { return nil })
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client := NewBeaconLightApi(httpServer.URL, nil)
+	bootstrap, err := client.GetCheckpointData(checkpoint.Header.Hash())
+	if err != nil {
+		t.Fatalf("Failed to retrieve bootstrap data: %v", err)
+	}
+	if bootstrap.Header != checkpoint.Header || bootstrap.CommitteeRoot != checkpoint.CommitteeRoot {
+		t.Fatal("Bootstrap data mismatch")
+	}
+	if err := bootstrap.Validate(); err != nil {
+		t.Fatalf("Invalid bootstrap data: %v", err)
+	}
+	if _, err := client.GetCheckpointData(common.Hash{0x1}); err != ErrNotFound {
+		t.Fatalf("Unknown checkpoint served: %v", err)
+	}
+	if updates, _, err := client.GetBestUpdatesAndCommittees(3, 1); err == nil && len(updates) != 0 {
+		t.Fatalf("Unexpected updates served: %d", len(updates))
+	}
+}
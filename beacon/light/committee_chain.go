@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 var (
@@ -70,6 +71,8 @@ type CommitteeChain struct {
 	committees          *canonicalStore[*types.SerializedSyncCommittee]
 	fixedCommitteeRoots *canonicalStore[common.Hash]
 	committeeCache      *lru.Cache[uint64, syncCommittee] // cache deserialized committees
+	checkpoint          *types.BootstrapData              // checkpoint the chain was initialized from
+	finalized           *types.Header                     // latest verified finalized header
 	changeCounter       uint64
 
 	clock       mclock.Clock         // monotonic clock (simulated clock in tests)
@@ -124,6 +127,7 @@ func newCommitteeChain(db ethdb.KeyValueStore, config *types.ChainConfig, signer
 		log.Info("Resetting invalid committee chain")
 		s.Reset()
 	}
+	s.loadCheckpoint()
 	// roll back invalid updates (might be necessary if forks have been changed since last time)
 	for !s.updates.periods.isEmpty() {
 		update, ok := s.updates.get(s.db, s.updates.periods.End-1)
@@ -184,12 +188,139 @@ func (s *CommitteeChain) Reset() {
 	s.chainmu.Lock()
 	defer s.chainmu.Unlock()
 
+	s.reset()
+}
+
+// reset resets the committee chain, along with the persisted checkpoint and
+// finalized header. The caller must hold the chain lock.
+func (s *CommitteeChain) reset() {
 	if err := s.rollback(0); err != nil {
 		log.Error("Error writing batch into chain database", "error", err)
 	}
+	batch := s.db.NewBatch()
+	batch.Delete(rawdb.BeaconCheckpointKey)
+	batch.Delete(rawdb.BeaconFinalizedKey)
+	if err := batch.Write(); err != nil {
+		log.Error("Error writing batch into chain database", "error", err)
+	}
+	s.checkpoint, s.finalized = nil, nil
 	s.changeCounter++
 }
 
+// loadCheckpoint loads the persisted checkpoint and finalized header, if they
+// are consistent with the committee chain.
+func (s *CommitteeChain) loadCheckpoint() {
+	if enc, err := s.db.Get(rawdb.BeaconCheckpointKey); err == nil {
+		var checkpoint types.BootstrapData
+		if err := rlp.DecodeBytes(enc, &checkpoint); err != nil {
+			log.Error("Error decoding persisted checkpoint", "error", err)
+		} else if committee, ok := s.committees.get(s.db, checkpoint.Header.SyncPeriod()); ok && committee.Root() == checkpoint.CommitteeRoot {
+			checkpoint.Committee = committee
+			s.checkpoint = &checkpoint
+		}
+	}
+	if enc, err := s.db.Get(rawdb.BeaconFinalizedKey); err == nil {
+		var header types.Header
+		if err := rlp.DecodeBytes(enc, &header); err != nil {
+			log.Error("Error decoding persisted finalized header", "error", err)
+		} else {
+			s.finalized = &header
+		}
+	}
+}
+
+// Checkpoint returns the bootstrap data the committee chain was initialized from.
+func (s *CommitteeChain) Checkpoint() (*types.BootstrapData, bool) {
+	s.chainmu.RLock()
+	defer s.chainmu.RUnlock()
+
+	return s.checkpoint, s.checkpoint != nil
+}
+
+// Finalized returns the latest verified finalized header.
+func (s *CommitteeChain) Finalized() (types.Header, bool) {
+	s.chainmu.RLock()
+	defer s.chainmu.RUnlock()
+
+	if s.finalized == nil {
+		return types.Header{}, false
+	}
+	return *s.finalized, true
+}
+
+// SetFinalized persists the given verified finalized header if it is newer than
+// the previously stored one.
+func (s *CommitteeChain) SetFinalized(header types.Header) error {
+	s.chainmu.Lock()
+	defer s.chainmu.Unlock()
+
+	if s.finalized != nil && header.Slot <= s.finalized.Slot {
+		return nil
+	}
+	enc, err := rlp.EncodeToBytes(&header)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Put(rawdb.BeaconFinalizedKey, enc); err != nil {
+		return err
+	}
+	s.finalized = &header
+	return nil
+}
+
+// CanResume reports whether the persisted committee chain can be used without
+// retrieving the given checkpoint again. This requires the chain to be initialized
+// from the same checkpoint. A chain whose latest verified header is older than
+// the weak subjectivity period can not be trusted anymore and is reset.
+func (s *CommitteeChain) CanResume(checkpoint common.Hash) bool {
+	s.chainmu.Lock()
+	defer s.chainmu.Unlock()
+
+	if s.committees.periods.isEmpty() {
+		return false
+	}
+	var slot uint64
+	if s.checkpoint != nil {
+		slot = s.checkpoint.Header.Slot
+	}
+	if s.finalized != nil && s.finalized.Slot > slot {
+		slot = s.finalized.Slot
+	}
+	if !s.updates.periods.isEmpty() {
+		if update, ok := s.updates.get(s.db, s.updates.periods.End-1); ok && update.AttestedHeader.Header.Slot > slot {
+			slot = update.AttestedHeader.Header.Slot
+		}
+	}
+	if age := s.slotAge(slot); age > time.Duration(params.WeakSubjectivityPeriod)*12*time.Second {
+		log.Warn("Persisted committee chain is beyond the weak subjectivity period", "slot", slot, "age", common.PrettyDuration(age))
+		s.reset()
+		return false
+	}
+	return s.checkpoint != nil && s.checkpoint.Header.Hash() == checkpoint
+}
+
+// slotAge returns the time elapsed since the beginning of the given slot.
+func (s *CommitteeChain) slotAge(slot uint64) time.Duration {
+	return time.Duration(s.unixNano() - int64(time.Second)*int64(s.config.GenesisTime+slot*12))
+}
+
+// Update returns the best update of the given period, along with the committee
+// of the next period proven by it.
+func (s *CommitteeChain) Update(period uint64) (*types.LightClientUpdate, *types.SerializedSyncCommittee, bool) {
+	s.chainmu.RLock()
+	defer s.chainmu.RUnlock()
+
+	update, ok := s.updates.get(s.db, period)
+	if !ok {
+		return nil, nil, false
+	}
+	committee, ok := s.committees.get(s.db, period+1)
+	if !ok {
+		return nil, nil, false
+	}
+	return update, committee, true
+}
+
 // CheckpointInit initializes a CommitteeChain based on a checkpoint.
 // Note: if the chain is already initialized and the committees proven by the
 // checkpoint do match the existing chain then the chain is retained and the
@@ -203,24 +334,32 @@ func (s *CommitteeChain) CheckpointInit(bootstrap types.BootstrapData) error {
 	}
 	period := bootstrap.Header.SyncPeriod()
 	if err := s.deleteFixedCommitteeRootsFrom(period + 2); err != nil {
-		s.Reset()
+		s.reset()
 		return err
 	}
 	if s.addFixedCommitteeRoot(period, bootstrap.CommitteeRoot) != nil {
-		s.Reset()
+		s.reset()
 		if err := s.addFixedCommitteeRoot(period, bootstrap.CommitteeRoot); err != nil {
-			s.Reset()
+			s.reset()
 			return err
 		}
 	}
 	if err := s.addFixedCommitteeRoot(period+1, common.Hash(bootstrap.CommitteeBranch[0])); err != nil {
-		s.Reset()
+		s.reset()
 		return err
 	}
 	if err := s.addCommittee(period, bootstrap.Committee); err != nil {
-		s.Reset()
+		s.reset()
+		return err
+	}
+	enc, err := rlp.EncodeToBytes(&bootstrap)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Put(rawdb.BeaconCheckpointKey, enc); err != nil {
 		return err
 	}
+	s.checkpoint = &bootstrap
 	s.changeCounter++
 	return nil
 }
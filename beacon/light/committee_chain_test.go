@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/beacon/params"
 	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 )
@@ -222,6 +223,52 @@ func TestCommitteeChainFork(t *testing.T) {
 	c.verifyRange(tcBase, 0, 10)
 }
 
+func TestCommitteeChainResume(t *testing.T) {
+	c := newCommitteeChainTest(t, tfBase, 300, true)
+	c.setClockPeriod(6)
+	checkpoint := GenerateTestCheckpoint(3, tcBase.periods[3].committee)
+	if err := c.chain.CheckpointInit(*checkpoint); err != nil {
+		t.Fatalf("Failed to initialize from checkpoint: %v", err)
+	}
+	finalized := types.Header{Slot: types.SyncPeriodStart(3) + 300}
+	if err := c.chain.SetFinalized(finalized); err != nil {
+		t.Fatalf("Failed to set finalized header: %v", err)
+	}
+	if err := c.chain.SetFinalized(types.Header{Slot: finalized.Slot - 1}); err != nil {
+		t.Fatalf("Failed to set finalized header: %v", err)
+	}
+	// The persisted chain is resumed with the same checkpoint only
+	c.reloadChain()
+	if c.chain.CanResume(common.Hash{0x1}) {
+		t.Fatal("Resumed with a different checkpoint")
+	}
+	if !c.chain.CanResume(checkpoint.Header.Hash()) {
+		t.Fatal("Failed to resume with the persisted checkpoint")
+	}
+	if cp, ok := c.chain.Checkpoint(); !ok || cp.Header.Hash() != checkpoint.Header.Hash() || cp.Committee.Root() != checkpoint.CommitteeRoot {
+		t.Fatal("Persisted checkpoint mismatch")
+	}
+	if header, ok := c.chain.Finalized(); !ok || header != finalized {
+		t.Fatalf("Persisted finalized header mismatch: have %v, want %v", header, finalized)
+	}
+	c.verifySignedHeader(tcBase, 3.5, true)
+
+	// A chain beyond the weak subjectivity period is reset
+	c.clock.Run(time.Duration(params.WeakSubjectivityPeriod) * 12 * time.Second)
+	c.reloadChain()
+	if c.chain.CanResume(checkpoint.Header.Hash()) {
+		t.Fatal("Resumed beyond the weak subjectivity period")
+	}
+	if _, ok := c.chain.Checkpoint(); ok {
+		t.Fatal("Checkpoint retained after reset")
+	}
+	c.reloadChain()
+	if _, ok := c.chain.Finalized(); ok {
+		t.Fatal("Finalized header retained after reset")
+	}
+	c.verifySignedHeader(tcBase, 3.5, false)
+}
+
 type committeeChainTest struct {
 	t               *testing.T
 	db              *memorydb.Database
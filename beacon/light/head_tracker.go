@@ -98,6 +98,9 @@ func (h *HeadTracker) ValidateFinality(update types.FinalityUpdate) (bool, error
 	if replace {
 		h.finalityUpdate, h.hasFinalityUpdate = update, true
 		h.changeCounter++
+		if err := h.committeeChain.SetFinalized(update.Finalized.Header); err != nil {
+			log.Error("Failed to persist finalized header", "slot", update.Finalized.Slot, "error", err)
+		}
 	}
 	return replace, err
 }
@@ -79,6 +79,12 @@ func NewCheckpointInit(chain committeeChain, checkpointHash common.Hash) *Checkp
 	}
 }
 
+// SetResumed marks the committee chain as initialized from a persisted database,
+// making the retrieval of the checkpoint unnecessary.
+func (s *CheckpointInit) SetResumed() {
+	s.initialized = true
+}
+
 // Process implements request.Module.
 func (s *CheckpointInit) Process(requester request.Requester, events []request.Event) {
 	if s.initialized {
@@ -26,6 +26,11 @@ const (
 	SyncCommitteeSize          = 512
 	SyncCommitteeBitmaskSize   = SyncCommitteeSize / 8
 	SyncCommitteeSupermajority = (SyncCommitteeSize*2 + 2) / 3
+
+	// WeakSubjectivityPeriod is a conservative estimate of the weak subjectivity
+	// period in slots. Light client data older than this is not trusted when
+	// resuming from a local database, a fresh checkpoint is required instead.
+	WeakSubjectivityPeriod = SyncPeriodLength * 12
 )
 
 const (
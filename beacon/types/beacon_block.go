@@ -68,6 +68,11 @@ func NewBeaconBlock(obj blockObject) *BeaconBlock {
 	}
 }
 
+// MarshalJSON encodes the block in the format of the beacon chain API.
+func (b *BeaconBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.blockObj)
+}
+
 // Slot returns the slot number of the block.
 func (b *BeaconBlock) Slot() uint64 {
 	switch obj := b.blockObj.(type) {
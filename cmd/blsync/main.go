@@ -19,6 +19,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 
 	"github.com/ethereum/go-ethereum/beacon/blsync"
@@ -42,12 +44,15 @@ func main() {
 		utils.BeaconGenesisRootFlag,
 		utils.BeaconGenesisTimeFlag,
 		utils.BeaconCheckpointFlag,
-		//TODO datadir for optional permanent database
+		utils.DataDirFlag,
 		utils.MainnetFlag,
 		utils.SepoliaFlag,
 		utils.GoerliFlag,
 		utils.BlsyncApiFlag,
 		utils.BlsyncJWTSecretFlag,
+		utils.BlsyncLightApiAddrFlag,
+		utils.BlsyncProxyUpstreamFlag,
+		utils.BlsyncProxyAddrFlag,
 	},
 		debug.Flags,
 	)
@@ -71,14 +76,53 @@ func sync(ctx *cli.Context) error {
 	// set up blsync
 	client := blsync.NewClient(ctx)
 	client.SetEngineRPC(makeRPCClient(ctx))
+	if ctx.IsSet(utils.BlsyncProxyUpstreamFlag.Name) {
+		upstream, err := rpc.Dial(ctx.String(utils.BlsyncProxyUpstreamFlag.Name))
+		if err != nil {
+			utils.Fatalf("Could not create upstream RPC client: %v", err)
+		}
+		client.SetProxyUpstream(upstream)
+	}
 	client.Start()
 
+	// start the light client API and the verified proxy if requested
+	var servers []*http.Server
+	if lightServer := client.LightServer(); lightServer != nil {
+		servers = append(servers, startHTTPServer(ctx.String(utils.BlsyncLightApiAddrFlag.Name), lightServer, "Light client API"))
+	}
+	if proxy := client.Proxy(); proxy != nil {
+		servers = append(servers, startProxyServer(ctx.String(utils.BlsyncProxyAddrFlag.Name), proxy))
+	}
 	// run until stopped
 	<-ctx.Done()
+	for _, server := range servers {
+		server.Close()
+	}
 	client.Stop()
 	return nil
 }
 
+// startHTTPServer serves the given handler on the given address.
+func startHTTPServer(addr string, handler http.Handler, name string) *http.Server {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		utils.Fatalf("Could not start %s listener: %v", name, err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	log.Info(name+" started", "url", "http://"+listener.Addr().String())
+	return server
+}
+
+// startProxyServer serves the verified execution RPC proxy over HTTP.
+func startProxyServer(addr string, proxy *blsync.ProxyAPI) *http.Server {
+	handler := rpc.NewServer()
+	if err := handler.RegisterName("eth", proxy); err != nil {
+		utils.Fatalf("Could not register proxy API: %v", err)
+	}
+	return startHTTPServer(addr, handler, "Verified RPC proxy")
+}
+
 func makeRPCClient(ctx *cli.Context) *rpc.Client {
 	if !ctx.IsSet(utils.BlsyncApiFlag.Name) {
 		log.Warn("No engine API target specified, performing a dry run")
@@ -52,6 +52,7 @@ import (
 	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/ethereum/go-ethereum/signer/fourbyte"
+	"github.com/ethereum/go-ethereum/signer/policy"
 	"github.com/ethereum/go-ethereum/signer/rules"
 	"github.com/ethereum/go-ethereum/signer/storage"
 	"github.com/mattn/go-colorable"
@@ -281,6 +282,12 @@ func init() {
 		customDBFlag,
 		auditLogFlag,
 		ruleFlag,
+		policyFlag,
+		quorumApproversFlag,
+		quorumThresholdFlag,
+		quorumTimeoutFlag,
+		quorumListenAddrFlag,
+		quorumPortFlag,
 		stdiouiFlag,
 		testFlag,
 		advancedMode,
@@ -296,6 +303,7 @@ func init() {
 		gendocCommand,
 		listAccountsCommand,
 		listWalletsCommand,
+		policyCommand,
 	}
 }
 
@@ -640,6 +648,15 @@ func signer(c *cli.Context) error {
 		log.Info("Using CLI as UI-channel")
 		ui = core.NewCommandlineUI()
 	}
+	// Quorum approval replaces the manual approval of requests. The rules and the
+	// policy below are evaluated before requests are submitted to the quorum.
+	quorum, err := newQuorumUI(c, ui)
+	if err != nil {
+		utils.Fatalf("Invalid quorum configuration: %v", err)
+	}
+	if quorum != nil {
+		ui = quorum
+	}
 	// 4bytedb data
 	fourByteLocal := c.String(customDBFlag.Name)
 	db, err := fourbyte.NewWithFile(fourByteLocal)
@@ -692,6 +709,20 @@ func signer(c *cli.Context) error {
 				}
 			}
 		}
+		// Do we have a policy? It sits in front of the rule engine, which only
+		// sees the requests that the policy leaves for manual processing.
+		if policyFile := c.String(policyFlag.Name); policyFile != "" {
+			p, err := loadAttestedPolicy(policyFile, configStorage)
+			if err != nil {
+				log.Warn("Could not load policy, disabling", "file", policyFile, "err", err)
+			} else {
+				policykey := crypto.Keccak256([]byte("policystorage"), stretchedKey)
+				policyStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "policystorage.json"), policykey)
+				eval := policy.NewEvaluator(p, big.NewInt(c.Int64(chainIdFlag.Name)), policyStorage, db)
+				ui = policy.NewUI(ui, eval)
+				log.Info("Policy configured", "file", policyFile, "default", p.Default)
+			}
+		}
 	}
 	var (
 		chainId  = c.Int64(chainIdFlag.Name)
@@ -714,12 +745,27 @@ func signer(c *cli.Context) error {
 
 	// Audit logging
 	if logfile := c.String(auditLogFlag.Name); logfile != "" {
-		api, err = core.NewAuditLogger(logfile, api)
+		auditLogger, err := core.NewAuditLogger(logfile, api)
 		if err != nil {
 			utils.Fatalf(err.Error())
 		}
+		if quorum != nil {
+			quorum.SetAuditor(auditLogger)
+		}
+		api = auditLogger
 		log.Info("Audit logs configured", "file", logfile)
 	}
+	if quorum != nil {
+		httpServer, url, err := startQuorumService(c, quorum)
+		if err != nil {
+			utils.Fatalf("Could not start quorum approval service: %v", err)
+		}
+		log.Info("Quorum approval service opened", "url", url)
+		defer func() {
+			httpServer.Shutdown(context.Background())
+			log.Info("Quorum approval service closed", "url", url)
+		}()
+	}
 	// register signer API with server
 	var (
 		extapiURL = "n/a"
@@ -0,0 +1,200 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/fourbyte"
+	"github.com/ethereum/go-ethereum/signer/policy"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	policyFlag = &cli.StringFlag{
+		Name:  "policy",
+		Usage: "Path to a declarative policy file (YAML or JSON) to auto-authorize requests with",
+	}
+	policyCommand = &cli.Command{
+		Name:  "policy",
+		Usage: "Manage declarative approval policies",
+		Subcommands: []*cli.Command{
+			{
+				Action:    testPolicy,
+				Name:      "test",
+				Usage:     "Dry-run requests against a policy",
+				ArgsUsage: "<policyfile> <requestfile>",
+				Flags: []cli.Flag{
+					logLevelFlag,
+					chainIdFlag,
+					customDBFlag,
+				},
+				Description: `
+The policy test command evaluates a list of requests against a policy file and prints
+the outcome of each request. The request file contains a JSON array of objects like
+
+    {"method": "ApproveTx", "request": {...}, "expect": "approve"}
+
+where method is ApproveTx, ApproveSignData or ApproveListing, and request has the
+format of the corresponding UI API request. The optional expect field is checked
+against the outcome, and the command fails if any expectation is not met.
+
+Requests are evaluated in order, and daily limits apply across the requests in
+the file. The persistent limit counters of Clef are not touched.`,
+			},
+			{
+				Action:    attestPolicy,
+				Name:      "attest",
+				Usage:     "Attest that a policy file is to be used",
+				ArgsUsage: "<sha256sum>",
+				Flags: []cli.Flag{
+					logLevelFlag,
+					configdirFlag,
+					signerSecretFlag,
+				},
+				Description: `
+The policy attest command stores the sha256 of the policy file that you want to use
+for automatic processing of incoming requests. Whenever you make an edit to the policy
+file, you need to attest it again.`,
+			},
+		},
+	}
+)
+
+// policyRequest is an entry of the request file of 'clef policy test'.
+type policyRequest struct {
+	Method  string          `json:"method"`
+	Request json.RawMessage `json:"request"`
+	Expect  policy.Action   `json:"expect"`
+}
+
+func testPolicy(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		utils.Fatalf("This command requires a policy file and a request file.")
+	}
+	p, err := policy.Load(ctx.Args().Get(0))
+	if err != nil {
+		utils.Fatalf("Could not load policy: %v", err)
+	}
+	data, err := os.ReadFile(ctx.Args().Get(1))
+	if err != nil {
+		utils.Fatalf("Could not read requests: %v", err)
+	}
+	var requests []policyRequest
+	if err := json.Unmarshal(data, &requests); err != nil {
+		utils.Fatalf("Invalid request file: %v", err)
+	}
+	db, err := fourbyte.NewWithFile(ctx.String(customDBFlag.Name))
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	chainID := big.NewInt(ctx.Int64(chainIdFlag.Name))
+	eval := policy.NewEvaluator(p, chainID, storage.NewEphemeralStorage(), db)
+
+	var failed int
+	for i, req := range requests {
+		res, err := evalPolicyRequest(eval, req)
+		if err != nil {
+			utils.Fatalf("Request #%d: %v", i, err)
+		}
+		status := ""
+		if req.Expect != "" && req.Expect != res.Action {
+			status = fmt.Sprintf(" FAIL: expected %s", req.Expect)
+			failed++
+		}
+		fmt.Printf("#%d %s: %v%s\n", i, req.Method, res, status)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d requests did not meet expectations", failed, len(requests))
+	}
+	return nil
+}
+
+func evalPolicyRequest(eval *policy.Evaluator, req policyRequest) (policy.Result, error) {
+	switch req.Method {
+	case "ApproveTx":
+		var r core.SignTxRequest
+		if err := json.Unmarshal(req.Request, &r); err != nil {
+			return policy.Result{}, err
+		}
+		return eval.EvalTx(&r), nil
+	case "ApproveSignData":
+		var r core.SignDataRequest
+		if err := json.Unmarshal(req.Request, &r); err != nil {
+			return policy.Result{}, err
+		}
+		return eval.EvalSignData(&r), nil
+	case "ApproveListing":
+		var r core.ListRequest
+		if err := json.Unmarshal(req.Request, &r); err != nil {
+			return policy.Result{}, err
+		}
+		return eval.EvalListing(&r), nil
+	default:
+		return policy.Result{}, fmt.Errorf("unsupported method %q", req.Method)
+	}
+}
+
+func attestPolicy(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	if err := initialize(ctx); err != nil {
+		return err
+	}
+	stretchedKey, err := readMasterKey(ctx, nil)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	configDir := ctx.String(configdirFlag.Name)
+	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
+	confKey := crypto.Keccak256([]byte("config"), stretchedKey)
+
+	configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confKey)
+	val := ctx.Args().First()
+	configStorage.Put("policy_sha256", val)
+	log.Info("Policy attestation updated", "sha256", val)
+	return nil
+}
+
+// loadAttestedPolicy loads a policy file, if its hash matches the attestation
+// in the config storage.
+func loadAttestedPolicy(file string, configStorage storage.Storage) (*policy.Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	shasum := sha256.Sum256(data)
+	foundShaSum := hex.EncodeToString(shasum[:])
+	storedShasum, _ := configStorage.Get("policy_sha256")
+	if storedShasum != foundShaSum {
+		return nil, fmt.Errorf("policy hash %s not attested (attested: %q)", foundShaSum, storedShasum)
+	}
+	return policy.Parse(data)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	quorumApproversFlag = &cli.StringFlag{
+		Name:  "quorum.approvers",
+		Usage: "Comma separated list of approver addresses. If set, requests are released only after quorum approval",
+	}
+	quorumThresholdFlag = &cli.IntFlag{
+		Name:  "quorum.threshold",
+		Usage: "Number of approvals required to release a request (default: all approvers)",
+	}
+	quorumTimeoutFlag = &cli.DurationFlag{
+		Name:  "quorum.timeout",
+		Usage: "Time after which requests without quorum are rejected",
+		Value: 10 * time.Minute,
+	}
+	quorumListenAddrFlag = &cli.StringFlag{
+		Name:  "quorum.addr",
+		Usage: "Listening interface of the HTTP/WebSocket approval service",
+		Value: "localhost",
+	}
+	quorumPortFlag = &cli.IntFlag{
+		Name:  "quorum.port",
+		Usage: "Listening port of the HTTP/WebSocket approval service",
+		Value: 8555,
+	}
+)
+
+// newQuorumUI creates the quorum UI in front of ui, if approvers are configured.
+func newQuorumUI(c *cli.Context, ui core.UIClientAPI) (*core.QuorumUI, error) {
+	list := utils.SplitAndTrim(c.String(quorumApproversFlag.Name))
+	if len(list) == 0 {
+		return nil, nil
+	}
+	cfg := core.QuorumConfig{
+		Threshold: c.Int(quorumThresholdFlag.Name),
+		Timeout:   c.Duration(quorumTimeoutFlag.Name),
+	}
+	for _, a := range list {
+		if !common.IsHexAddress(a) {
+			return nil, fmt.Errorf("invalid approver address %q", a)
+		}
+		cfg.Approvers = append(cfg.Approvers, common.HexToAddress(a))
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = len(cfg.Approvers)
+	}
+	return core.NewQuorumUI(ui, cfg)
+}
+
+// startQuorumService serves the quorum API over HTTP and WebSocket on the same port.
+func startQuorumService(c *cli.Context, quorum *core.QuorumUI) (*http.Server, string, error) {
+	srv := rpc.NewServer()
+	srv.SetBatchLimits(node.DefaultConfig.BatchRequestLimit, node.DefaultConfig.BatchResponseMaxSize)
+	if err := node.RegisterApis(quorum.APIs(), []string{"quorum"}, srv); err != nil {
+		return nil, "", err
+	}
+	var (
+		vhosts  = utils.SplitAndTrim(c.String(utils.HTTPVirtualHostsFlag.Name))
+		httpH   = node.NewHTTPHandlerStack(srv, nil, vhosts, nil)
+		wsH     = node.NewWSHandlerStack(srv.WebsocketHandler(nil), nil)
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				wsH.ServeHTTP(w, r)
+			} else {
+				httpH.ServeHTTP(w, r)
+			}
+		})
+	)
+	endpoint := net.JoinHostPort(c.String(quorumListenAddrFlag.Name), fmt.Sprintf("%d", c.Int(quorumPortFlag.Name)))
+	httpServer, addr, err := node.StartHTTPEndpoint(endpoint, rpc.DefaultHTTPTimeouts, handler)
+	if err != nil {
+		return nil, "", err
+	}
+	return httpServer, fmt.Sprintf("http://%v/", addr), nil
+}
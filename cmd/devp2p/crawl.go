@@ -17,13 +17,17 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/urfave/cli/v2"
 )
 
 type crawler struct {
@@ -37,6 +41,9 @@ type crawler struct {
 
 	// settings
 	revalidateInterval time.Duration
+	handshakeInterval  time.Duration
+	handshakeKey       *ecdsa.PrivateKey
+	db                 *crawlDB
 	mu                 sync.RWMutex
 }
 
@@ -207,6 +214,10 @@ func (c *crawler) updateNode(n *enode.Node) int {
 			status = nodeAdded
 		}
 		node.LastResponse = node.LastCheck
+		if c.needsHandshake(node) {
+			node.Handshake = crawlHandshake(nn, c.handshakeKey, node.Handshake)
+			log.Debug("Handshake with node", "id", n.ID(), "client", node.Handshake.Client, "err", node.Handshake.Error)
+		}
 	}
 	// Store/update node in output set.
 	c.mu.Lock()
@@ -214,13 +225,74 @@ func (c *crawler) updateNode(n *enode.Node) int {
 	if node.Score <= 0 {
 		log.Debug("Removing node", "id", n.ID())
 		delete(c.output, n.ID())
+		if c.db != nil {
+			if err := c.db.delete(n.ID()); err != nil {
+				log.Error("Failed to delete node from crawl database", "id", n.ID(), "err", err)
+			}
+		}
 		return nodeRemoved
 	}
 	log.Debug("Updating node", "id", n.ID(), "seq", n.Seq(), "score", node.Score)
 	c.output[n.ID()] = node
+	if c.db != nil {
+		if err := c.db.put(node); err != nil {
+			log.Error("Failed to store node in crawl database", "id", n.ID(), "err", err)
+		}
+	}
 	return status
 }
 
+// needsHandshake reports whether the node is due for an RLPx handshake.
+func (c *crawler) needsHandshake(node nodeJSON) bool {
+	if c.handshakeInterval <= 0 || node.N.TCP() == 0 {
+		return false
+	}
+	return node.Handshake == nil || time.Since(node.Handshake.LastAttempt) >= c.handshakeInterval
+}
+
 func truncNow() time.Time {
 	return time.Now().UTC().Truncate(1 * time.Second)
 }
+
+// openCrawlInput loads the input set of a crawl from the nodes file argument
+// and the crawl database. It returns the nodes file name, which is empty if
+// none was given.
+func openCrawlInput(ctx *cli.Context) (string, nodeSet, *crawlDB, error) {
+	var (
+		nodesFile = ctx.Args().First()
+		inputSet  = make(nodeSet)
+		db        *crawlDB
+	)
+	if nodesFile == "" && !ctx.IsSet(crawlDBFlag.Name) {
+		return "", nil, nil, errors.New("need nodes file as argument")
+	}
+	if nodesFile != "" && common.FileExist(nodesFile) {
+		inputSet = loadNodesJSON(nodesFile)
+	}
+	if ctx.IsSet(crawlDBFlag.Name) {
+		var err error
+		if db, err = openCrawlDB(ctx.String(crawlDBFlag.Name)); err != nil {
+			return "", nil, nil, err
+		}
+		stored, err := db.nodes()
+		if err != nil {
+			db.close()
+			return "", nil, nil, err
+		}
+		for id, n := range stored {
+			inputSet[id] = n
+		}
+	}
+	return nodesFile, inputSet, db, nil
+}
+
+// configureCrawler applies the persistence and handshake settings of the
+// command line to c.
+func configureCrawler(ctx *cli.Context, c *crawler, db *crawlDB) {
+	c.revalidateInterval = 10 * time.Minute
+	c.db = db
+	if db != nil {
+		c.handshakeInterval = ctx.Duration(crawlHandshakeIntervalFlag.Name)
+		c.handshakeKey, _ = crypto.GenerateKey()
+	}
+}
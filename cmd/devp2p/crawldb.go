@@ -0,0 +1,83 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// crawlDBNodePrefix is the key prefix of node entries in the crawl database.
+const crawlDBNodePrefix = "n:"
+
+// crawlDB persists the results of a long-running crawl. It stores the nodeJSON
+// of every node, including the result of the last RLPx handshake.
+type crawlDB struct {
+	lvl *leveldb.DB
+}
+
+// openCrawlDB opens or creates the crawl database in the given directory.
+func openCrawlDB(path string) (*crawlDB, error) {
+	lvl, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't open crawl database: %v", err)
+	}
+	return &crawlDB{lvl: lvl}, nil
+}
+
+func crawlDBKey(id enode.ID) []byte {
+	return append([]byte(crawlDBNodePrefix), id[:]...)
+}
+
+// put stores a node.
+func (db *crawlDB) put(n nodeJSON) error {
+	enc, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return db.lvl.Put(crawlDBKey(n.N.ID()), enc, nil)
+}
+
+// delete removes a node.
+func (db *crawlDB) delete(id enode.ID) error {
+	return db.lvl.Delete(crawlDBKey(id), nil)
+}
+
+// nodes returns all nodes in the database.
+func (db *crawlDB) nodes() (nodeSet, error) {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(crawlDBNodePrefix)), nil)
+	defer it.Release()
+
+	ns := make(nodeSet)
+	for it.Next() {
+		var n nodeJSON
+		if err := json.Unmarshal(it.Value(), &n); err != nil {
+			return nil, fmt.Errorf("invalid node entry %x: %v", it.Key(), err)
+		}
+		ns[n.N.ID()] = n
+	}
+	return ns, it.Error()
+}
+
+// close closes the database.
+func (db *crawlDB) close() error {
+	return db.lvl.Close()
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// startStatusServer runs a p2p server with an eth protocol which only sends the
+// status message.
+func startStatusServer(t *testing.T, status *eth.StatusPacket) *p2p.Server {
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		NoDiscovery: true,
+		Name:        "Geth/v1.14.0-test/linux-amd64/go1.22",
+		Protocols: []p2p.Protocol{{
+			Name:    "eth",
+			Version: 68,
+			Length:  17,
+			Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+				if err := p2p.Send(rw, eth.StatusMsg, status); err != nil {
+					return err
+				}
+				_, err := rw.ReadMsg()
+				return err
+			},
+		}},
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestCrawlHandshake(t *testing.T) {
+	var (
+		genesis = core.DefaultGenesisBlock().ToBlock()
+		now     = uint64(time.Now().Unix())
+		ready   = newForkReadiness(params.MainnetChainConfig, genesis, now)
+		status  = &eth.StatusPacket{
+			ProtocolVersion: 68,
+			NetworkID:       1,
+			TD:              big.NewInt(1),
+			Genesis:         genesis.Hash(),
+			ForkID:          ready.current,
+		}
+	)
+	srv := startStatusServer(t, status)
+	defer srv.Stop()
+
+	key, _ := crypto.GenerateKey()
+	h := crawlHandshake(srv.Self(), key, nil)
+	if h.Error != "" {
+		t.Fatal("handshake failed:", h.Error)
+	}
+	if !h.reachable() {
+		t.Fatal("node not reachable")
+	}
+	if h.clientName() != "geth" {
+		t.Errorf("wrong client name %q", h.clientName())
+	}
+	if h.EthVersion != 68 || h.NetworkID != 1 || *h.Genesis != genesis.Hash() {
+		t.Errorf("wrong status: %+v", h)
+	}
+	if s := ready.status(h); s != forkReady {
+		t.Errorf("wrong fork status %q", forkStatusNames[s])
+	}
+
+	// Check that the handshake results are retained when the node goes offline.
+	srv.Stop()
+	h = crawlHandshake(srv.Self(), key, h)
+	if h.Error == "" || h.reachable() {
+		t.Fatal("offline node reachable")
+	}
+	if h.Client == "" || h.ForkHash == nil {
+		t.Fatal("handshake results not retained")
+	}
+}
+
+func TestCrawlReport(t *testing.T) {
+	var (
+		genesis = core.DefaultGenesisBlock().ToBlock()
+		ready   = newForkReadiness(params.MainnetChainConfig, genesis, uint64(time.Now().Unix()))
+		hash    = genesis.Hash()
+		now     = time.Now()
+		ns      = make(nodeSet)
+	)
+	add := func(h *handshakeJSON) {
+		key, _ := crypto.GenerateKey()
+		n := enode.NewV4(&key.PublicKey, nil, 30303, 30303)
+		ns[n.ID()] = nodeJSON{N: n, Seq: n.Seq(), Handshake: h}
+	}
+	add(&handshakeJSON{Client: "Geth/v1", EthVersion: 68, Genesis: &hash, ForkHash: ready.current.Hash[:], ForkNext: ready.current.Next, LastAttempt: now, LastSuccess: now, Reachable: true})
+	add(&handshakeJSON{Client: "Nethermind/v1", EthVersion: 68, Genesis: &hash, ForkHash: []byte{1, 2, 3, 4}, LastAttempt: now, LastSuccess: now, Reachable: true})
+	add(&handshakeJSON{Client: "Geth/v1", Error: "i/o timeout", LastAttempt: now, LastSuccess: now.Add(-time.Hour)})
+	add(&handshakeJSON{Error: "connection refused", LastAttempt: now})
+	add(nil)
+
+	var buf bytes.Buffer
+	writeCrawlReport(&buf, ns, "mainnet", ready)
+	report := strings.Join(strings.Fields(buf.String()), " ")
+	for _, want := range []string{
+		"Database contains 5 nodes, 4 have been checked",
+		"Reachable: 2 (50.0%), unreachable: 2 (50.0%)",
+		"geth 1 50.0%",
+		"nethermind 1 50.0%",
+		"eth/68 2 100.0%",
+		"ready 1 50.0%",
+		"other fork 1 50.0%",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+
+	// Check the filters using handshake results.
+	filtered, err := filterNodeSet(ns, []string{"-reachable", "-client", "geth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 1 {
+		t.Fatalf("wrong number of filtered nodes %d", len(filtered))
+	}
+	filtered, _ = filterNodeSet(ns, []string{"-fork-ready", "mainnet"})
+	if len(filtered) != 1 {
+		t.Fatalf("wrong number of fork-ready nodes %d", len(filtered))
+	}
+}
+
+func TestCrawlDB(t *testing.T) {
+	db, err := openCrawlDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	key, _ := crypto.GenerateKey()
+	n := enode.NewV4(&key.PublicKey, nil, 30303, 30303)
+	stored := nodeJSON{N: n, Seq: n.Seq(), Score: 3, Handshake: &handshakeJSON{Client: "Geth/v1", EthVersion: 68}}
+	if err := db.put(stored); err != nil {
+		t.Fatal(err)
+	}
+	ns, err := db.nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 1 || ns[n.ID()].Score != 3 || ns[n.ID()].Handshake.Client != "Geth/v1" {
+		t.Fatalf("wrong nodes loaded: %+v", ns)
+	}
+	db.delete(n.ID())
+	if ns, _ := db.nodes(); len(ns) != 0 {
+		t.Fatal("node not deleted")
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	crawldbCommand = &cli.Command{
+		Name:  "crawldb",
+		Usage: "Crawl database tools",
+		Subcommands: []*cli.Command{
+			crawldbReportCommand,
+			crawldbExportCommand,
+		},
+	}
+	crawldbReportCommand = &cli.Command{
+		Name:      "report",
+		Usage:     "Shows client distribution, fork readiness and reachability of crawled nodes",
+		ArgsUsage: "<crawl-db>",
+		Action:    crawldbReport,
+		Flags:     []cli.Flag{crawlNetworkFlag},
+	}
+	crawldbExportCommand = &cli.Command{
+		Name:      "export",
+		Usage:     "Writes the crawled nodes matching the filters as nodes.json",
+		ArgsUsage: "<crawl-db> filters..",
+		Action:    crawldbExport,
+
+		SkipFlagParsing: true,
+	}
+)
+
+var crawlNetworkFlag = &cli.StringFlag{
+	Name:  "network",
+	Usage: "Network for the fork readiness report (mainnet, goerli, sepolia, holesky)",
+	Value: "mainnet",
+}
+
+func crawldbReport(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need crawl database as argument")
+	}
+	network := ctx.String(crawlNetworkFlag.Name)
+	config, genesis, err := networkConfig(network)
+	if err != nil {
+		return err
+	}
+	ns, err := loadCrawlDB(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	readiness := newForkReadiness(config, genesis, uint64(time.Now().Unix()))
+	writeCrawlReport(os.Stdout, ns, network, readiness)
+	return nil
+}
+
+func crawldbExport(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need crawl database as argument")
+	}
+	ns, err := loadCrawlDB(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	result, err := filterNodeSet(ns, ctx.Args().Tail())
+	if err != nil {
+		return err
+	}
+	writeNodesJSON("-", result)
+	return nil
+}
+
+// loadCrawlDB reads all nodes from a crawl database.
+func loadCrawlDB(path string) (nodeSet, error) {
+	if !common.FileExist(path) {
+		return nil, fmt.Errorf("crawl database %s does not exist", path)
+	}
+	db, err := openCrawlDB(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.close()
+	return db.nodes()
+}
+
+// forkStatus is the fork readiness of a node.
+type forkStatus int
+
+const (
+	forkNoStatus     forkStatus = iota // no eth status known
+	forkReady                          // fork ID matches the local fork schedule
+	forkNotReady                       // current fork matches, but the next fork doesn't
+	forkOther                          // on a different fork
+	forkOtherNetwork                   // genesis doesn't match
+)
+
+var forkStatusNames = map[forkStatus]string{
+	forkNoStatus:     "no status",
+	forkReady:        "ready",
+	forkNotReady:     "not ready",
+	forkOther:        "other fork",
+	forkOtherNetwork: "other network",
+}
+
+// forkReadiness classifies nodes by comparing their fork ID against the fork
+// schedule of a network.
+type forkReadiness struct {
+	genesis common.Hash
+	current forkid.ID
+}
+
+// newForkReadiness creates a readiness check for the given time. All block
+// number based forks are assumed to have passed.
+func newForkReadiness(config *params.ChainConfig, genesis *types.Block, now uint64) *forkReadiness {
+	return &forkReadiness{
+		genesis: genesis.Hash(),
+		current: forkid.NewID(config, genesis, math.MaxUint64, now),
+	}
+}
+
+// status returns the fork readiness of a node.
+func (r *forkReadiness) status(h *handshakeJSON) forkStatus {
+	id, ok := h.forkID()
+	switch {
+	case !ok || h.Genesis == nil:
+		return forkNoStatus
+	case *h.Genesis != r.genesis:
+		return forkOtherNetwork
+	case id == r.current:
+		return forkReady
+	case id.Hash == r.current.Hash:
+		return forkNotReady
+	default:
+		return forkOther
+	}
+}
+
+// writeCrawlReport prints statistics about the handshake results of a node set.
+func writeCrawlReport(w io.Writer, ns nodeSet, network string, readiness *forkReadiness) {
+	var (
+		checked, reachable int
+		clients            = make(map[string]int)
+		ethVersions        = make(map[string]int)
+		forks              = make(map[string]int)
+		forkIDs            = make(map[string]int)
+	)
+	for _, n := range ns {
+		h := n.Handshake
+		if h == nil {
+			continue
+		}
+		checked++
+		if !h.reachable() {
+			continue
+		}
+		reachable++
+		client := h.clientName()
+		if client == "" {
+			client = "unknown"
+		}
+		clients[client]++
+		if h.EthVersion != 0 {
+			ethVersions[fmt.Sprintf("eth/%d", h.EthVersion)]++
+		}
+		status := readiness.status(h)
+		forks[forkStatusNames[status]]++
+		if id, ok := h.forkID(); ok && status != forkOtherNetwork {
+			forkIDs[fmt.Sprintf("%x next=%d", id.Hash, id.Next)]++
+		}
+	}
+
+	fmt.Fprintf(w, "Database contains %d nodes, %d have been checked with RLPx handshakes.\n", len(ns), checked)
+	if checked == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Reachable: %d (%s), unreachable: %d (%s)\n",
+		reachable, percent(reachable, checked), checked-reachable, percent(checked-reachable, checked))
+	fmt.Fprintln(w)
+	printCounts(w, "Clients of reachable nodes:", clients, reachable)
+	printCounts(w, "Negotiated eth protocol versions:", ethVersions, reachable)
+	fmt.Fprintf(w, "Fork readiness on %s (current fork ID %x, next fork %d):\n", network, readiness.current.Hash, readiness.current.Next)
+	printCounts(w, "", forks, reachable)
+	printCounts(w, fmt.Sprintf("Fork IDs of %s nodes:", network), forkIDs, reachable)
+}
+
+// printCounts prints a table of counts, largest first.
+func printCounts(w io.Writer, title string, counts map[string]int, total int) {
+	if title != "" {
+		fmt.Fprintln(w, title)
+	}
+	keys := make([]string, 0, len(counts))
+	width := 0
+	for k := range counts {
+		keys = append(keys, k)
+		width = max(width, len(k))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %-*s %6d %7s\n", width, k, counts[k], percent(counts[k], total))
+	}
+	fmt.Fprintln(w)
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)*100/float64(total))
+}
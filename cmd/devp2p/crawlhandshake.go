@@ -0,0 +1,203 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// handshakeTimeout is the time limit of a crawler RLPx handshake, including
+// the eth status exchange.
+const handshakeTimeout = 10 * time.Second
+
+// devp2p message codes and the offset of eth protocol messages.
+const (
+	helloMsg  = 0x00
+	discMsg   = 0x01
+	pingMsg   = 0x02
+	pongMsg   = 0x03
+	ethOffset = 0x10
+)
+
+// crawlCaps are the capabilities announced in crawler handshakes.
+var crawlCaps = []p2p.Cap{{Name: "eth", Version: 67}, {Name: "eth", Version: 68}}
+
+var errNoEth = errors.New("node does not support eth")
+
+// handshakeJSON is the result of the last RLPx handshake with a node.
+type handshakeJSON struct {
+	Client     string        `json:"client,omitempty"`
+	Caps       []string      `json:"caps,omitempty"`
+	EthVersion uint32        `json:"ethVersion,omitempty"`
+	NetworkID  uint64        `json:"networkId,omitempty"`
+	Genesis    *common.Hash  `json:"genesis,omitempty"`
+	ForkHash   hexutil.Bytes `json:"forkHash,omitempty"`
+	ForkNext   uint64        `json:"forkNext,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Reachable  bool          `json:"reachable"`
+
+	// These track the time of the last handshake attempt, and of the last
+	// time the node responded with its protocol handshake.
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess,omitempty"`
+}
+
+// reachable reports whether the node responded to the last handshake attempt.
+func (h *handshakeJSON) reachable() bool {
+	return h != nil && h.Reachable
+}
+
+// clientName returns the name of the client implementation, e.g. "geth".
+func (h *handshakeJSON) clientName() string {
+	if h == nil || h.Client == "" {
+		return ""
+	}
+	name, _, _ := strings.Cut(h.Client, "/")
+	return strings.ToLower(name)
+}
+
+// forkID returns the fork ID announced in the eth status message.
+func (h *handshakeJSON) forkID() (forkid.ID, bool) {
+	if h == nil || len(h.ForkHash) != 4 {
+		return forkid.ID{}, false
+	}
+	var id forkid.ID
+	copy(id.Hash[:], h.ForkHash)
+	id.Next = h.ForkNext
+	return id, true
+}
+
+// crawlHandshake connects to a node to record its client name, capabilities and
+// eth status. The result of the previous handshake may be given as prev, its
+// fields are retained if the node can't be reached.
+func crawlHandshake(n *enode.Node, key *ecdsa.PrivateKey, prev *handshakeJSON) *handshakeJSON {
+	h := new(handshakeJSON)
+	if prev != nil {
+		*h = *prev
+	}
+	h.LastAttempt = truncNow()
+	h.Error, h.Reachable = "", false
+	if err := queryNode(n, key, h); err != nil {
+		h.Error = err.Error()
+	}
+	return h
+}
+
+// queryNode performs the RLPx handshake and eth status exchange with a node.
+func queryNode(n *enode.Node, key *ecdsa.PrivateKey, h *handshakeJSON) error {
+	endpoint, ok := n.TCPEndpoint()
+	if !ok {
+		return errors.New("node has no TCP endpoint")
+	}
+	fd, err := net.DialTimeout("tcp", endpoint.String(), handshakeTimeout)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	fd.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	conn := rlpx.NewConn(fd, n.Pubkey())
+	if _, err := conn.Handshake(key); err != nil {
+		return err
+	}
+	hello := &ethtest.Hello{Version: 5, Caps: crawlCaps, ID: crypto.FromECDSAPub(&key.PublicKey)[1:]}
+	if err := writeMsg(conn, helloMsg, hello); err != nil {
+		return err
+	}
+	for {
+		code, data, _, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		switch code {
+		case helloMsg:
+			var remote ethtest.Hello
+			if err := rlp.DecodeBytes(data, &remote); err != nil {
+				return fmt.Errorf("invalid hello: %v", err)
+			}
+			h.Client, h.Caps, h.EthVersion = remote.Name, nil, 0
+			h.LastSuccess, h.Reachable = h.LastAttempt, true
+			for _, c := range remote.Caps {
+				h.Caps = append(h.Caps, c.String())
+				if c.Name == "eth" && c.Version > uint(h.EthVersion) && hasCap(crawlCaps, c) {
+					h.EthVersion = uint32(c.Version)
+				}
+			}
+			if h.EthVersion == 0 {
+				writeMsg(conn, discMsg, []p2p.DiscReason{p2p.DiscUselessPeer})
+				return errNoEth
+			}
+			if remote.Version >= 5 {
+				conn.SetSnappy(true)
+			}
+		case discMsg:
+			var reason []p2p.DiscReason
+			rlp.DecodeBytes(data, &reason)
+			if len(reason) == 0 {
+				return errors.New("disconnected")
+			}
+			return fmt.Errorf("disconnected: %v", reason[0])
+		case pingMsg:
+			writeMsg(conn, pongMsg, []interface{}{})
+		case ethOffset + eth.StatusMsg:
+			var status eth.StatusPacket
+			if err := rlp.DecodeBytes(data, &status); err != nil {
+				return fmt.Errorf("invalid status: %v", err)
+			}
+			h.NetworkID = status.NetworkID
+			h.Genesis = &status.Genesis
+			h.ForkHash = status.ForkID.Hash[:]
+			h.ForkNext = status.ForkID.Next
+			writeMsg(conn, discMsg, []p2p.DiscReason{p2p.DiscRequested})
+			return nil
+		}
+	}
+}
+
+func writeMsg(conn *rlpx.Conn, code uint64, msg interface{}) error {
+	enc, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(code, enc)
+	return err
+}
+
+func hasCap(caps []p2p.Cap, c p2p.Cap) bool {
+	for _, have := range caps {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
@@ -88,10 +88,11 @@ var (
 		}),
 	}
 	discv4CrawlCommand = &cli.Command{
-		Name:   "crawl",
-		Usage:  "Updates a nodes.json file with random nodes found in the DHT",
-		Action: discv4Crawl,
-		Flags:  flags.Merge(discoveryNodeFlags, []cli.Flag{crawlTimeoutFlag, crawlParallelismFlag}),
+		Name:      "crawl",
+		Usage:     "Updates a nodes.json file with random nodes found in the DHT",
+		ArgsUsage: "[ <nodes.json> ]",
+		Action:    discv4Crawl,
+		Flags:     flags.Merge(discoveryNodeFlags, crawlFlags),
 	}
 	discv4TestCommand = &cli.Command{
 		Name:   "test",
@@ -130,9 +131,18 @@ var (
 	}
 	crawlTimeoutFlag = &cli.DurationFlag{
 		Name:  "timeout",
-		Usage: "Time limit for the crawl.",
+		Usage: "Time limit for the crawl (zero means no limit).",
 		Value: 30 * time.Minute,
 	}
+	crawlDBFlag = &cli.StringFlag{
+		Name:  "db",
+		Usage: "Crawl database directory, which is used as input and keeps the crawl results",
+	}
+	crawlHandshakeIntervalFlag = &cli.DurationFlag{
+		Name:  "handshake-interval",
+		Usage: "Interval of RLPx handshakes with crawled nodes, requires --db (zero disables handshakes)",
+		Value: time.Hour,
+	}
 	crawlParallelismFlag = &cli.IntFlag{
 		Name:  "parallel",
 		Usage: "How many parallel discoveries to attempt.",
@@ -157,6 +167,13 @@ var discoveryNodeFlags = []cli.Flag{
 	extAddrFlag,
 }
 
+var crawlFlags = []cli.Flag{
+	crawlTimeoutFlag,
+	crawlParallelismFlag,
+	crawlDBFlag,
+	crawlHandshakeIntervalFlag,
+}
+
 func discv4Ping(ctx *cli.Context) error {
 	n := getNodeArg(ctx)
 	disc, _ := startV4(ctx)
@@ -247,13 +264,12 @@ func discv4ResolveJSON(ctx *cli.Context) error {
 }
 
 func discv4Crawl(ctx *cli.Context) error {
-	if ctx.NArg() < 1 {
-		return errors.New("need nodes file as argument")
+	nodesFile, inputSet, db, err := openCrawlInput(ctx)
+	if err != nil {
+		return err
 	}
-	nodesFile := ctx.Args().First()
-	inputSet := make(nodeSet)
-	if common.FileExist(nodesFile) {
-		inputSet = loadNodesJSON(nodesFile)
+	if db != nil {
+		defer db.close()
 	}
 
 	disc, config := startV4(ctx)
@@ -263,9 +279,11 @@ func discv4Crawl(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	c.revalidateInterval = 10 * time.Minute
+	configureCrawler(ctx, c, db)
 	output := c.run(ctx.Duration(crawlTimeoutFlag.Name), ctx.Int(crawlParallelismFlag.Name))
-	writeNodesJSON(nodesFile, output)
+	if nodesFile != "" {
+		writeNodesJSON(nodesFile, output)
+	}
 	return nil
 }
 
@@ -17,12 +17,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/v5test"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/urfave/cli/v2"
@@ -38,6 +38,8 @@ var (
 			discv5CrawlCommand,
 			discv5TestCommand,
 			discv5ListenCommand,
+			discv5TopicRegisterCommand,
+			discv5TopicSearchCommand,
 		},
 	}
 	discv5PingCommand = &cli.Command{
@@ -53,12 +55,11 @@ var (
 		Flags:  discoveryNodeFlags,
 	}
 	discv5CrawlCommand = &cli.Command{
-		Name:   "crawl",
-		Usage:  "Updates a nodes.json file with random nodes found in the DHT",
-		Action: discv5Crawl,
-		Flags: flags.Merge(discoveryNodeFlags, []cli.Flag{
-			crawlTimeoutFlag,
-		}),
+		Name:      "crawl",
+		Usage:     "Updates a nodes.json file with random nodes found in the DHT",
+		ArgsUsage: "[ <nodes.json> ]",
+		Action:    discv5Crawl,
+		Flags:     flags.Merge(discoveryNodeFlags, crawlFlags),
 	}
 	discv5TestCommand = &cli.Command{
 		Name:   "test",
@@ -77,6 +78,36 @@ var (
 		Action: discv5Listen,
 		Flags:  discoveryNodeFlags,
 	}
+	discv5TopicRegisterCommand = &cli.Command{
+		Name:      "topic-register",
+		Usage:     "Runs a node which advertises itself under a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5TopicRegister,
+		Flags:     discoveryNodeFlags,
+	}
+	discv5TopicSearchCommand = &cli.Command{
+		Name:      "topic-search",
+		Usage:     "Finds nodes registered under a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5TopicSearch,
+		Flags: flags.Merge(discoveryNodeFlags, []cli.Flag{
+			topicSearchLimitFlag,
+			topicSearchTimeoutFlag,
+		}),
+	}
+)
+
+var (
+	topicSearchLimitFlag = &cli.IntFlag{
+		Name:  "limit",
+		Usage: "Stop after this many nodes have been found",
+		Value: 16,
+	}
+	topicSearchTimeoutFlag = &cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time limit for the search",
+		Value: 2 * time.Minute,
+	}
 )
 
 func discv5Ping(ctx *cli.Context) error {
@@ -98,13 +129,12 @@ func discv5Resolve(ctx *cli.Context) error {
 }
 
 func discv5Crawl(ctx *cli.Context) error {
-	if ctx.NArg() < 1 {
-		return errors.New("need nodes file as argument")
+	nodesFile, inputSet, db, err := openCrawlInput(ctx)
+	if err != nil {
+		return err
 	}
-	nodesFile := ctx.Args().First()
-	inputSet := make(nodeSet)
-	if common.FileExist(nodesFile) {
-		inputSet = loadNodesJSON(nodesFile)
+	if db != nil {
+		defer db.close()
 	}
 
 	disc, config := startV5(ctx)
@@ -114,9 +144,11 @@ func discv5Crawl(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	c.revalidateInterval = 10 * time.Minute
+	configureCrawler(ctx, c, db)
 	output := c.run(ctx.Duration(crawlTimeoutFlag.Name), ctx.Int(crawlParallelismFlag.Name))
-	writeNodesJSON(nodesFile, output)
+	if nodesFile != "" {
+		writeNodesJSON(nodesFile, output)
+	}
 	return nil
 }
 
@@ -138,6 +170,46 @@ func discv5Listen(ctx *cli.Context) error {
 	select {}
 }
 
+func discv5TopicRegister(ctx *cli.Context) error {
+	topic, err := getTopicArg(ctx)
+	if err != nil {
+		return err
+	}
+	disc, _ := startV5(ctx)
+	defer disc.Close()
+
+	fmt.Println(disc.Self())
+	fmt.Println("Advertising topic", topic)
+	disc.RegisterTopic(context.Background(), topic)
+	return nil
+}
+
+func discv5TopicSearch(ctx *cli.Context) error {
+	topic, err := getTopicArg(ctx)
+	if err != nil {
+		return err
+	}
+	disc, _ := startV5(ctx)
+	defer disc.Close()
+
+	it := disc.TopicNodes(topic)
+	timeout := time.AfterFunc(ctx.Duration(topicSearchTimeoutFlag.Name), it.Close)
+	defer timeout.Stop()
+	for found := 0; found < ctx.Int(topicSearchLimitFlag.Name) && it.Next(); found++ {
+		fmt.Println(it.Node())
+	}
+	it.Close()
+	return nil
+}
+
+// getTopicArg parses the topic name argument.
+func getTopicArg(ctx *cli.Context) (discover.TopicID, error) {
+	if ctx.NArg() < 1 {
+		return discover.TopicID{}, errors.New("missing topic as first argument")
+	}
+	return discover.NewTopic(ctx.Args().First()), nil
+}
+
 // startV5 starts an ephemeral discovery v5 node.
 func startV5(ctx *cli.Context) (*discover.UDPv5, discover.Config) {
 	ln, config := makeDiscoveryConfig(ctx)
@@ -18,11 +18,13 @@ package v5test
 
 import (
 	"bytes"
+	crand "crypto/rand"
 	"net"
 	"slices"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/utesting"
 	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -56,6 +58,9 @@ func (s *Suite) AllTests() []utesting.Test {
 		{Name: "TalkRequest", Fn: s.TestTalkRequest},
 		{Name: "FindnodeZeroDistance", Fn: s.TestFindnodeZeroDistance},
 		{Name: "FindnodeResults", Fn: s.TestFindnodeResults},
+		{Name: "TopicRegistration", Fn: s.TestTopicRegistration},
+		{Name: "TopicRegistrationInvalidRecord", Fn: s.TestTopicRegistrationInvalidRecord},
+		{Name: "TopicQueryEmpty", Fn: s.TestTopicQueryEmpty},
 	}
 }
 
@@ -292,6 +297,111 @@ func (s *Suite) TestFindnodeResults(t *utesting.T) {
 	}
 }
 
+// maxTopicWait is the longest waiting time accepted by the topic registration test.
+const maxTopicWait = 10 * time.Second
+
+// randomTopic creates a topic ID which is unknown to the remote node.
+func randomTopic() [32]byte {
+	var name [16]byte
+	crand.Read(name[:])
+	return crypto.Keccak256Hash([]byte("v5test"), name[:])
+}
+
+// TestTopicRegistration registers a node under a new topic and checks that it is returned
+// by TOPICQUERY to another node.
+func (s *Suite) TestTopicRegistration(t *utesting.T) {
+	conn, l1 := s.listen1(t)
+	defer conn.close()
+	conn.setEndpoint(l1)
+
+	topic := randomTopic()
+	reg := &v5wire.Regtopic{ReqID: conn.nextReqID(), Topic: topic, ENR: conn.localNode.Node().Record()}
+	for registered := false; !registered; {
+		switch resp := conn.reqresp(l1, reg).(type) {
+		case *v5wire.Ticket:
+			if !bytes.Equal(resp.ReqID, reg.ReqID) {
+				t.Fatalf("wrong request ID %x in TICKET, want %x", resp.ReqID, reg.ReqID)
+			}
+			if resp.WaitTime == 0 {
+				registered = true
+				break
+			}
+			// The registration has to be retried with the ticket.
+			wait := time.Duration(resp.WaitTime) * time.Millisecond
+			if len(resp.Ticket) == 0 {
+				t.Fatal("empty ticket in TICKET with non-zero waiting time")
+			}
+			if wait > maxTopicWait {
+				t.Fatalf("waiting time %v for new topic exceeds %v", wait, maxTopicWait)
+			}
+			time.Sleep(wait)
+			reg.ReqID, reg.Ticket = conn.nextReqID(), resp.Ticket
+		default:
+			t.Fatal("expected TICKET, got", resp.Name())
+		}
+	}
+
+	// Check that the registration can be found.
+	searcher, l2 := s.listen1(t)
+	defer searcher.close()
+	nodes, err := searcher.topicQuery(l2, topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsNode(nodes, conn.localNode.ID()) {
+		t.Fatalf("registered node %v not returned by TOPICQUERY", conn.localNode.ID())
+	}
+}
+
+// TestTopicRegistrationInvalidRecord sends REGTOPIC with a record that doesn't contain
+// the endpoint of the sender. The remote node should not accept the registration.
+func (s *Suite) TestTopicRegistrationInvalidRecord(t *utesting.T) {
+	conn, l1 := s.listen1(t)
+	defer conn.close()
+
+	topic := randomTopic()
+	reg := &v5wire.Regtopic{ReqID: conn.nextReqID(), Topic: topic, ENR: conn.localNode.Node().Record()}
+	switch resp := conn.reqresp(l1, reg).(type) {
+	case *v5wire.Ticket:
+		if resp.WaitTime == 0 {
+			t.Fatal("registration with invalid record accepted")
+		}
+	case *readError:
+		if !netutil.IsTimeout(resp.err) {
+			t.Fatal(resp)
+		}
+	default:
+		t.Fatal("expected TICKET or no response, got", resp.Name())
+	}
+
+	nodes, err := conn.topicQuery(l1, topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) > 0 {
+		t.Fatalf("TOPICQUERY returned %d nodes for topic with invalid registration", len(nodes))
+	}
+}
+
+// TestTopicQueryEmpty checks that TOPICQUERY for an unknown topic is answered with
+// an empty NODES response.
+func (s *Suite) TestTopicQueryEmpty(t *utesting.T) {
+	conn, l1 := s.listen1(t)
+	defer conn.close()
+
+	nodes, err := conn.topicQuery(l1, randomTopic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) > 0 {
+		t.Fatalf("TOPICQUERY returned %d nodes for unknown topic", len(nodes))
+	}
+}
+
+func containsNode(nodes []*enode.Node, id enode.ID) bool {
+	return slices.ContainsFunc(nodes, func(n *enode.Node) bool { return n.ID() == id })
+}
+
 // A bystander is a node whose only purpose is filling a spot in the remote table.
 type bystander struct {
 	dest *enode.Node
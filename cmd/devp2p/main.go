@@ -51,6 +51,7 @@ func init() {
 		discv5Command,
 		dnsCommand,
 		nodesetCommand,
+		crawldbCommand,
 		rlpxCommand,
 	}
 }
@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -101,19 +102,29 @@ func nodesetFilter(ctx *cli.Context) error {
 	if ctx.NArg() < 1 {
 		return errors.New("need nodes file as argument")
 	}
-	// Parse -limit.
-	limit, err := parseFilterLimit(ctx.Args().Tail())
+	ns := loadNodesJSON(ctx.Args().First())
+	result, err := filterNodeSet(ns, ctx.Args().Tail())
 	if err != nil {
 		return err
 	}
+	writeNodesJSON("-", result)
+	return nil
+}
+
+// filterNodeSet applies the filters and -limit option in args to a node set.
+func filterNodeSet(ns nodeSet, args []string) (nodeSet, error) {
+	// Parse -limit.
+	limit, err := parseFilterLimit(args)
+	if err != nil {
+		return nil, err
+	}
 	// Parse the filters.
-	filter, err := andFilter(ctx.Args().Tail())
+	filter, err := andFilter(args)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Load nodes and apply filters.
-	ns := loadNodesJSON(ctx.Args().First())
+	// Apply filters.
 	result := make(nodeSet)
 	for id, n := range ns {
 		if filter(n) {
@@ -123,8 +134,7 @@ func nodesetFilter(ctx *cli.Context) error {
 	if limit >= 0 {
 		result = result.topN(limit)
 	}
-	writeNodesJSON("-", result)
-	return nil
+	return result, nil
 }
 
 type nodeFilter func(nodeJSON) bool
@@ -141,6 +151,10 @@ var filterFlags = map[string]nodeFilterC{
 	"-eth-network": {1, ethFilter},
 	"-les-server":  {0, lesFilter},
 	"-snap":        {0, snapFilter},
+	"-client":      {1, clientFilter},
+	"-eth-version": {1, ethVersionFilter},
+	"-reachable":   {0, reachableFilter},
+	"-fork-ready":  {1, forkReadyFilter},
 }
 
 // parseFilters parses nodeFilters from args.
@@ -225,20 +239,28 @@ func minAgeFilter(args []string) (nodeFilter, error) {
 	return f, nil
 }
 
-func ethFilter(args []string) (nodeFilter, error) {
-	var filter forkid.Filter
-	switch args[0] {
+// networkConfig returns the chain configuration and genesis block of a network.
+func networkConfig(name string) (*params.ChainConfig, *types.Block, error) {
+	switch name {
 	case "mainnet":
-		filter = forkid.NewStaticFilter(params.MainnetChainConfig, core.DefaultGenesisBlock().ToBlock())
+		return params.MainnetChainConfig, core.DefaultGenesisBlock().ToBlock(), nil
 	case "goerli":
-		filter = forkid.NewStaticFilter(params.GoerliChainConfig, core.DefaultGoerliGenesisBlock().ToBlock())
+		return params.GoerliChainConfig, core.DefaultGoerliGenesisBlock().ToBlock(), nil
 	case "sepolia":
-		filter = forkid.NewStaticFilter(params.SepoliaChainConfig, core.DefaultSepoliaGenesisBlock().ToBlock())
+		return params.SepoliaChainConfig, core.DefaultSepoliaGenesisBlock().ToBlock(), nil
 	case "holesky":
-		filter = forkid.NewStaticFilter(params.HoleskyChainConfig, core.DefaultHoleskyGenesisBlock().ToBlock())
+		return params.HoleskyChainConfig, core.DefaultHoleskyGenesisBlock().ToBlock(), nil
 	default:
-		return nil, fmt.Errorf("unknown network %q", args[0])
+		return nil, nil, fmt.Errorf("unknown network %q", name)
+	}
+}
+
+func ethFilter(args []string) (nodeFilter, error) {
+	config, genesis, err := networkConfig(args[0])
+	if err != nil {
+		return nil, err
 	}
+	filter := forkid.NewStaticFilter(config, genesis)
 
 	f := func(n nodeJSON) bool {
 		var eth struct {
@@ -272,3 +294,43 @@ func snapFilter(args []string) (nodeFilter, error) {
 	}
 	return f, nil
 }
+
+// The filters below use the results of crawler RLPx handshakes.
+
+func clientFilter(args []string) (nodeFilter, error) {
+	name := strings.ToLower(args[0])
+	f := func(n nodeJSON) bool {
+		return n.Handshake.clientName() == name
+	}
+	return f, nil
+}
+
+func ethVersionFilter(args []string) (nodeFilter, error) {
+	version, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	f := func(n nodeJSON) bool {
+		return n.Handshake != nil && n.Handshake.EthVersion >= uint32(version)
+	}
+	return f, nil
+}
+
+func reachableFilter(args []string) (nodeFilter, error) {
+	f := func(n nodeJSON) bool {
+		return n.Handshake.reachable()
+	}
+	return f, nil
+}
+
+func forkReadyFilter(args []string) (nodeFilter, error) {
+	config, genesis, err := networkConfig(args[0])
+	if err != nil {
+		return nil, err
+	}
+	check := newForkReadiness(config, genesis, uint64(time.Now().Unix()))
+	f := func(n nodeJSON) bool {
+		return check.status(n.Handshake) == forkReady
+	}
+	return f, nil
+}
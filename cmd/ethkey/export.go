@@ -0,0 +1,345 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	formatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "export format (eip2335 or hex)",
+		Value: "eip2335",
+	}
+	kdfFlag = &cli.StringFlag{
+		Name:  "kdf",
+		Usage: "key derivation function of the exported keystore (scrypt or pbkdf2)",
+		Value: "scrypt",
+	}
+	keyPathFlag = &cli.StringFlag{
+		Name:  "path",
+		Usage: "BIP-32 derivation path of the key to record in the exported keystore",
+	}
+	descriptionFlag = &cli.StringFlag{
+		Name:  "description",
+		Usage: "description to record in the exported keystore",
+	}
+)
+
+var commandExport = &cli.Command{
+	Name:      "export",
+	Usage:     "export a keyfile into another format",
+	ArgsUsage: "<keyfile> [ <outfile> ]",
+	Description: `
+Export the key of a keyfile into another format, writing it to the given output
+file or to stdout.
+
+The supported formats are:
+  eip2335  an EIP-2335 style keystore, encrypted with the password given by
+           --newpasswordfile (or prompted for) using scrypt or pbkdf2 (--kdf)
+  hex      the raw hex encoded private key, as accepted by generate --privatekey
+
+Make sure to use the hex format with great caution!`,
+	Flags: []cli.Flag{
+		passphraseFlag,
+		newPassphraseFlag,
+		lightKDFFlag,
+		formatFlag,
+		kdfFlag,
+		keyPathFlag,
+		descriptionFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		keyfilepath := ctx.Args().First()
+		outfile := ctx.Args().Get(1)
+
+		// Read and decrypt the key.
+		keyjson, err := os.ReadFile(keyfilepath)
+		if err != nil {
+			utils.Fatalf("Failed to read the keyfile at '%s': %v", keyfilepath, err)
+		}
+		passphrase := getPassphrase(ctx, false)
+		privateKey, err := decryptKeyfile(keyjson, passphrase)
+		if err != nil {
+			utils.Fatalf("Error decrypting key: %v", err)
+		}
+
+		// Convert it into the requested format.
+		var output []byte
+		switch format := ctx.String(formatFlag.Name); format {
+		case "hex":
+			output = []byte(hex.EncodeToString(crypto.FromECDSA(privateKey)))
+
+		case "eip2335":
+			path := ctx.String(keyPathFlag.Name)
+			if path != "" {
+				parsed, err := accounts.ParseDerivationPath(path)
+				if err != nil {
+					utils.Fatalf("Invalid derivation path: %v", err)
+				}
+				path = parsed.String()
+			}
+			var newPhrase string
+			if passFile := ctx.String(newPassphraseFlag.Name); passFile != "" {
+				content, err := os.ReadFile(passFile)
+				if err != nil {
+					utils.Fatalf("Failed to read new password file '%s': %v", passFile, err)
+				}
+				newPhrase = strings.TrimRight(string(content), "\r\n")
+			} else {
+				fmt.Fprintln(os.Stderr, "Please provide a password for the exported keystore")
+				newPhrase = utils.GetPassPhrase("", true)
+			}
+			ks, err := encryptEIP2335(privateKey, newPhrase, ctx.String(kdfFlag.Name), ctx.Bool(lightKDFFlag.Name))
+			if err != nil {
+				utils.Fatalf("Error encrypting key: %v", err)
+			}
+			ks.Path = path
+			ks.Description = ctx.String(descriptionFlag.Name)
+			if output, err = json.MarshalIndent(ks, "", "  "); err != nil {
+				utils.Fatalf("Failed to marshal keystore: %v", err)
+			}
+
+		default:
+			utils.Fatalf("Unknown export format %q", format)
+		}
+
+		// Write out the result.
+		if outfile == "" {
+			fmt.Println(string(output))
+			return nil
+		}
+		if _, err := os.Stat(outfile); err == nil {
+			utils.Fatalf("Output file already exists at %s.", outfile)
+		}
+		if err := os.WriteFile(outfile, output, 0600); err != nil {
+			utils.Fatalf("Failed to write output file %s: %v", outfile, err)
+		}
+		return nil
+	},
+}
+
+// eip2335Module is a cryptographic module of an EIP-2335 keystore.
+type eip2335Module struct {
+	Function string                 `json:"function"`
+	Params   map[string]interface{} `json:"params"`
+	Message  string                 `json:"message"`
+}
+
+// eip2335Keystore is a keystore in the EIP-2335 format. As the format is made
+// for BLS12-381 keys, the public key of the secp256k1 key is stored in its
+// compressed form instead.
+type eip2335Keystore struct {
+	Crypto struct {
+		KDF      eip2335Module `json:"kdf"`
+		Checksum eip2335Module `json:"checksum"`
+		Cipher   eip2335Module `json:"cipher"`
+	} `json:"crypto"`
+	Description string `json:"description"`
+	Pubkey      string `json:"pubkey"`
+	Path        string `json:"path"`
+	UUID        string `json:"uuid"`
+	Version     int    `json:"version"`
+}
+
+const (
+	eip2335Version = 4
+	eip2335Rounds  = 262144 // Default scrypt N and pbkdf2 c of EIP-2335
+)
+
+// eip2335Password processes a password as mandated by EIP-2335: it is NFKD
+// normalized and stripped of all control codes.
+func eip2335Password(password string) []byte {
+	return []byte(strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, norm.NFKD.String(password)))
+}
+
+// eip2335DeriveKey derives the decryption key of a keystore from the password.
+func eip2335DeriveKey(kdf eip2335Module, password string) ([]byte, error) {
+	param := func(name string) int {
+		switch v := kdf.Params[name].(type) {
+		case int:
+			return v
+		case float64:
+			return int(v)
+		}
+		return 0
+	}
+	saltHex, _ := kdf.Params["salt"].(string)
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	switch kdf.Function {
+	case "scrypt":
+		return scrypt.Key(eip2335Password(password), salt, param("n"), param("r"), param("p"), param("dklen"))
+	case "pbkdf2":
+		if prf := kdf.Params["prf"]; prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %v", prf)
+		}
+		return pbkdf2.Key(eip2335Password(password), salt, param("c"), param("dklen"), sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", kdf.Function)
+	}
+}
+
+// encryptEIP2335 encrypts a private key into an EIP-2335 keystore using either
+// scrypt or pbkdf2 as the key derivation function.
+func encryptEIP2335(key *ecdsa.PrivateKey, password string, kdf string, light bool) (*eip2335Keystore, error) {
+	rounds := eip2335Rounds
+	if light {
+		rounds = keystore.LightScryptN
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	ks := new(eip2335Keystore)
+	ks.Crypto.KDF = eip2335Module{Function: kdf, Params: map[string]interface{}{"dklen": 32, "salt": hex.EncodeToString(salt)}}
+	switch kdf {
+	case "scrypt":
+		ks.Crypto.KDF.Params["n"] = rounds
+		ks.Crypto.KDF.Params["r"] = 8
+		ks.Crypto.KDF.Params["p"] = 1
+	case "pbkdf2":
+		ks.Crypto.KDF.Params["c"] = rounds
+		ks.Crypto.KDF.Params["prf"] = "hmac-sha256"
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", kdf)
+	}
+	derivedKey, err := eip2335DeriveKey(ks.Crypto.KDF, password)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	ciphertext, err := aesCTR(derivedKey[:16], iv, crypto.FromECDSA(key))
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(append(derivedKey[16:32:32], ciphertext...))
+
+	ks.Crypto.Checksum = eip2335Module{Function: "sha256", Params: map[string]interface{}{}, Message: hex.EncodeToString(checksum[:])}
+	ks.Crypto.Cipher = eip2335Module{Function: "aes-128-ctr", Params: map[string]interface{}{"iv": hex.EncodeToString(iv)}, Message: hex.EncodeToString(ciphertext)}
+	ks.Pubkey = hex.EncodeToString(crypto.CompressPubkey(&key.PublicKey))
+	ks.UUID = uuid.NewString()
+	ks.Version = eip2335Version
+	return ks, nil
+}
+
+// decryptEIP2335 decrypts the private key of an EIP-2335 keystore.
+func decryptEIP2335(ks *eip2335Keystore, password string) (*ecdsa.PrivateKey, error) {
+	if ks.Version != eip2335Version {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if ks.Crypto.Checksum.Function != "sha256" || ks.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, errors.New("unsupported checksum or cipher function")
+	}
+	derivedKey, err := eip2335DeriveKey(ks.Crypto.KDF, password)
+	if err != nil {
+		return nil, err
+	}
+	if len(derivedKey) < 32 {
+		return nil, errors.New("derived key too short")
+	}
+	ciphertext, err := hex.DecodeString(ks.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := hex.DecodeString(ks.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, err
+	}
+	if want := sha256.Sum256(append(derivedKey[16:32:32], ciphertext...)); !bytes.Equal(checksum, want[:]) {
+		return nil, keystore.ErrDecrypt
+	}
+	ivHex, _ := ks.Crypto.Cipher.Params["iv"].(string)
+	iv, err := hex.DecodeString(ivHex)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aesCTR(derivedKey[:16], iv, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(plaintext)
+}
+
+// decryptKeyfile decrypts the private key of either a standard (version 3) or
+// an EIP-2335 (version 4) keyfile.
+func decryptKeyfile(keyjson []byte, password string) (*ecdsa.PrivateKey, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(keyjson, &header); err != nil {
+		return nil, err
+	}
+	if header.Version != eip2335Version {
+		key, err := keystore.DecryptKey(keyjson, password)
+		if err != nil {
+			return nil, err
+		}
+		return key.PrivateKey, nil
+	}
+	ks := new(eip2335Keystore)
+	if err := json.Unmarshal(keyjson, ks); err != nil {
+		return nil, err
+	}
+	return decryptEIP2335(ks, password)
+}
+
+// aesCTR encrypts or decrypts data using AES in counter mode.
+func aesCTR(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid iv length")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(out, data)
+	return out, nil
+}
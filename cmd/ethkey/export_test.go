@@ -0,0 +1,127 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Test vector from EIP-2335, using pbkdf2 as the key derivation function.
+const eip2335TestVector = `{
+	"crypto": {
+		"kdf": {
+			"function": "pbkdf2",
+			"params": {
+				"dklen": 32,
+				"c": 262144,
+				"prf": "hmac-sha256",
+				"salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"
+			},
+			"message": ""
+		},
+		"checksum": {
+			"function": "sha256",
+			"params": {},
+			"message": "8a9f5d9912ed7e75ea794bc5a89bca5f193721d30868ade6f73043c6ea6febf1"
+		},
+		"cipher": {
+			"function": "aes-128-ctr",
+			"params": {
+				"iv": "264daa3f303d7259501c93d997d84fe6"
+			},
+			"message": "cee03fde2af33149775b7223e7845e4fb2c8ae1792e5f99fe9ecf474cc8c16ad"
+		}
+	},
+	"description": "This is a test keystore that uses PBKDF2 to secure the secret.",
+	"pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+	"path": "m/12381/60/0/0",
+	"uuid": "64625def-3331-4eea-ab6f-782f3ed16a83",
+	"version": 4
+}`
+
+func TestEIP2335(t *testing.T) {
+	t.Parallel()
+
+	// Decrypt the test vector, checking the password processing
+	password := "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511"
+	key, err := decryptKeyfile([]byte(eip2335TestVector), password)
+	if err != nil {
+		t.Fatalf("failed to decrypt test vector: %v", err)
+	}
+	want := "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	if have := hex.EncodeToString(crypto.FromECDSA(key)); have != want {
+		t.Fatalf("secret mismatch: have %s, want %s", have, want)
+	}
+	if _, err := decryptKeyfile([]byte(eip2335TestVector), "wrong"); err == nil {
+		t.Fatal("decryption with wrong password succeeded")
+	}
+	// Round trip the key with both key derivation functions
+	for _, kdf := range []string{"scrypt", "pbkdf2"} {
+		ks, err := encryptEIP2335(key, "foobar", kdf, true)
+		if err != nil {
+			t.Fatalf("%s: failed to encrypt key: %v", kdf, err)
+		}
+		blob, _ := json.Marshal(ks)
+		decrypted, err := decryptKeyfile(blob, "foo\x00bar")
+		if err != nil {
+			t.Fatalf("%s: failed to decrypt key: %v", kdf, err)
+		}
+		if !decrypted.Equal(key) {
+			t.Errorf("%s: key mismatch after round trip", kdf)
+		}
+	}
+}
+
+// Tests exporting a keyfile into an EIP-2335 keystore, which can be inspected.
+func TestExportInspect(t *testing.T) {
+	t.Parallel()
+	var (
+		tmpdir   = t.TempDir()
+		keyfile  = filepath.Join(tmpdir, "keyfile")
+		exported = filepath.Join(tmpdir, "exported")
+		password = filepath.Join(tmpdir, "password")
+		newpass  = filepath.Join(tmpdir, "newpassword")
+	)
+	os.WriteFile(password, []byte("foobar\n"), 0600)
+	os.WriteFile(newpass, []byte("barfoo\n"), 0600)
+
+	generate := runEthkey(t, "generate", "--lightkdf", "--passwordfile", password, keyfile)
+	_, matches := generate.ExpectRegexp(`Address: (0x[0-9a-fA-F]{40})\n`)
+	address := matches[1]
+	generate.ExpectExit()
+
+	export := runEthkey(t, "export", "--lightkdf", "--path", "m/44'/60'/0'/0/0", "--passwordfile", password, "--newpasswordfile", newpass, keyfile, exported)
+	export.ExpectExit()
+
+	var ks eip2335Keystore
+	blob, _ := os.ReadFile(exported)
+	if err := json.Unmarshal(blob, &ks); err != nil {
+		t.Fatalf("invalid exported keystore: %v", err)
+	}
+	if ks.Version != 4 || ks.Path != "m/44'/60'/0'/0/0" || ks.Crypto.KDF.Function != "scrypt" {
+		t.Fatalf("unexpected exported keystore: %s", blob)
+	}
+	inspect := runEthkey(t, "inspect", "--passwordfile", newpass, exported)
+	inspect.ExpectRegexp(`Address:\s+` + address + `\nPublic key:\s+[0-9a-f]{130}\n`)
+	inspect.ExpectExit()
+}
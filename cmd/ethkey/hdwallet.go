@@ -0,0 +1,296 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/tyler-smith/go-bip39"
+	"github.com/urfave/cli/v2"
+)
+
+type outputMnemonic struct {
+	Mnemonic string
+}
+
+type outputDerive struct {
+	Address string
+	Path    string
+	Keyfile string
+}
+
+var (
+	wordsFlag = &cli.IntFlag{
+		Name:  "words",
+		Usage: "number of words in the mnemonic (12, 15, 18, 21 or 24)",
+		Value: 24,
+	}
+	mnemonicFileFlag = &cli.StringFlag{
+		Name:  "mnemonicfile",
+		Usage: "the file that contains the BIP-39 mnemonic",
+	}
+	seedPassphraseFlag = &cli.StringFlag{
+		Name:  "seedpasswordfile",
+		Usage: "the file that contains the optional BIP-39 passphrase extending the mnemonic",
+	}
+	derivationPathFlag = &cli.StringFlag{
+		Name:  "path",
+		Usage: "BIP-32 derivation path of the first key",
+		Value: accounts.DefaultBaseDerivationPath.String(),
+	}
+	countFlag = &cli.IntFlag{
+		Name:  "count",
+		Usage: "number of keys to derive, incrementing the last path component",
+		Value: 1,
+	}
+)
+
+var commandMnemonic = &cli.Command{
+	Name:  "mnemonic",
+	Usage: "generate a new BIP-39 mnemonic",
+	Description: `
+Generate a new random BIP-39 mnemonic, from which keys can be derived with the
+derive command.
+
+Anyone knowing the mnemonic has access to all keys derived from it, so make sure
+to store it safely!`,
+	Flags: []cli.Flag{
+		jsonFlag,
+		wordsFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		words := ctx.Int(wordsFlag.Name)
+		if words < 12 || words > 24 || words%3 != 0 {
+			utils.Fatalf("Invalid mnemonic length %d, must be one of 12, 15, 18, 21 or 24", words)
+		}
+		entropy, err := bip39.NewEntropy(words / 3 * 32)
+		if err != nil {
+			utils.Fatalf("Failed to generate entropy: %v", err)
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			utils.Fatalf("Failed to generate mnemonic: %v", err)
+		}
+		out := outputMnemonic{Mnemonic: mnemonic}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			fmt.Println("Mnemonic:", out.Mnemonic)
+		}
+		return nil
+	},
+}
+
+var commandDerive = &cli.Command{
+	Name:      "derive",
+	Usage:     "derive keyfiles from a BIP-39 mnemonic",
+	ArgsUsage: "<keydir>",
+	Description: `
+Derive keys from a BIP-39 mnemonic along a BIP-32 derivation path and store them
+as encrypted keyfiles in the given directory.
+
+The mnemonic is read from the file given by --mnemonicfile, otherwise the user is
+prompted for it. By default the first key of the standard Ethereum derivation
+path (m/44'/60'/0'/0/0) is derived, use --path to change the derivation path and
+--count to derive multiple consecutive keys.`,
+	Flags: []cli.Flag{
+		passphraseFlag,
+		jsonFlag,
+		lightKDFFlag,
+		mnemonicFileFlag,
+		seedPassphraseFlag,
+		derivationPathFlag,
+		countFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		keydir := ctx.Args().First()
+		if keydir == "" {
+			utils.Fatalf("No key directory specified")
+		}
+		path, err := accounts.ParseDerivationPath(ctx.String(derivationPathFlag.Name))
+		if err != nil {
+			utils.Fatalf("Invalid derivation path: %v", err)
+		}
+		count := ctx.Int(countFlag.Name)
+		if count < 1 {
+			utils.Fatalf("Invalid key count %d", count)
+		}
+
+		// Recreate the seed from the mnemonic.
+		mnemonic := getMnemonic(ctx)
+		var seedPassphrase string
+		if file := ctx.String(seedPassphraseFlag.Name); file != "" {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				utils.Fatalf("Failed to read seed password file '%s': %v", file, err)
+			}
+			seedPassphrase = strings.TrimRight(string(content), "\r\n")
+		}
+		seed, err := bip39.NewSeedWithErrorChecking(mnemonic, seedPassphrase)
+		if err != nil {
+			utils.Fatalf("Invalid mnemonic: %v", err)
+		}
+
+		// Derive and store the keys.
+		passphrase := getPassphrase(ctx, true)
+		scryptN, scryptP := keystore.StandardScryptN, keystore.StandardScryptP
+		if ctx.Bool(lightKDFFlag.Name) {
+			scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+		}
+		if err := os.MkdirAll(keydir, 0700); err != nil {
+			utils.Fatalf("Could not create directory %s: %v", keydir, err)
+		}
+		var (
+			out  []outputDerive
+			next = accounts.DefaultIterator(path)
+		)
+		for i := 0; i < count; i++ {
+			path := next()
+			privateKey, err := deriveKey(seed, path)
+			if err != nil {
+				utils.Fatalf("Failed to derive key %v: %v", path, err)
+			}
+			keyfile, err := storeKey(keydir, privateKey, passphrase, scryptN, scryptP)
+			if err != nil {
+				utils.Fatalf("Failed to store key %v: %v", path, err)
+			}
+			out = append(out, outputDerive{
+				Address: crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+				Path:    path.String(),
+				Keyfile: keyfile,
+			})
+		}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			for _, key := range out {
+				fmt.Printf("Address: %s Path: %s Keyfile: %s\n", key.Address, key.Path, key.Keyfile)
+			}
+		}
+		return nil
+	},
+}
+
+// getMnemonic obtains the mnemonic from the --mnemonicfile flag or otherwise
+// prompts the user for it.
+func getMnemonic(ctx *cli.Context) string {
+	var mnemonic string
+	if file := ctx.String(mnemonicFileFlag.Name); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read mnemonic file '%s': %v", file, err)
+		}
+		mnemonic = string(content)
+	} else {
+		input, err := prompt.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			utils.Fatalf("Failed to read mnemonic: %v", err)
+		}
+		mnemonic = input
+	}
+	return strings.Join(strings.Fields(mnemonic), " ")
+}
+
+// errInvalidChildKey is returned if a derivation step yields an invalid key,
+// which happens with a probability lower than 1 in 2^127.
+var errInvalidChildKey = errors.New("invalid child key")
+
+// deriveKey derives the private key at the given path from a BIP-32 seed.
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	n := crypto.S256().Params().N
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, chainCode := new(big.Int).SetBytes(sum[:32]), sum[32:]
+	if key.Sign() == 0 || key.Cmp(n) >= 0 {
+		return nil, errInvalidChildKey
+	}
+	for _, index := range path {
+		mac := hmac.New(sha512.New, chainCode)
+		if index >= 0x80000000 {
+			// Hardened child, derived from the parent private key
+			mac.Write([]byte{0})
+			mac.Write(math.PaddedBigBytes(key, 32))
+		} else {
+			// Normal child, derived from the parent public key
+			parent, err := crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+			if err != nil {
+				return nil, err
+			}
+			mac.Write(crypto.CompressPubkey(&parent.PublicKey))
+		}
+		mac.Write(binary.BigEndian.AppendUint32(nil, index))
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(n) >= 0 {
+			return nil, errInvalidChildKey
+		}
+		key = tweak.Add(tweak, key).Mod(tweak, n)
+		if key.Sign() == 0 {
+			return nil, errInvalidChildKey
+		}
+		chainCode = sum[32:]
+	}
+	return crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+}
+
+// storeKey encrypts a private key and stores it in the key directory, using the
+// keystore's file naming scheme. It returns the path of the new keyfile.
+func storeKey(keydir string, privateKey *ecdsa.PrivateKey, passphrase string, scryptN, scryptP int) (string, error) {
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+	if matches, _ := filepath.Glob(filepath.Join(keydir, "*--"+hex.EncodeToString(address[:]))); len(matches) > 0 {
+		return "", fmt.Errorf("keyfile for %v already exists at %s", address, matches[0])
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	key := &keystore.Key{
+		Id:         id,
+		Address:    address,
+		PrivateKey: privateKey,
+	}
+	keyjson, err := keystore.EncryptKey(key, passphrase, scryptN, scryptP)
+	if err != nil {
+		return "", err
+	}
+	keyfile := filepath.Join(keydir, keystore.KeyFileName(key.Address))
+	if err := keystore.WriteKeyFile(keyfile, keyjson); err != nil {
+		return "", err
+	}
+	return keyfile, nil
+}
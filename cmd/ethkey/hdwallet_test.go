@@ -0,0 +1,129 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// Tests key derivation against the BIP-32 test vectors and the well known
+// addresses of the standard test mnemonic.
+func TestDeriveKey(t *testing.T) {
+	t.Parallel()
+
+	vectorSeed := common.FromHex("000102030405060708090a0b0c0d0e0f")
+	mnemonicSeed := bip39.NewSeed(testMnemonic, "")
+
+	tests := []struct {
+		seed []byte
+		path string
+		want string
+	}{
+		// BIP-32 test vector 1
+		{vectorSeed, "m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{vectorSeed, "m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{vectorSeed, "m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for _, tt := range tests {
+		path, err := accounts.ParseDerivationPath(tt.path)
+		if err != nil {
+			t.Fatalf("%s: invalid path: %v", tt.path, err)
+		}
+		key, err := deriveKey(tt.seed, path)
+		if err != nil {
+			t.Fatalf("%s: derivation failed: %v", tt.path, err)
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key)); have != tt.want {
+			t.Errorf("%s: key mismatch: have %s, want %s", tt.path, have, tt.want)
+		}
+	}
+	addresses := []struct {
+		path string
+		want common.Address
+	}{
+		{"m/44'/60'/0'/0/0", common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")},
+		{"m/44'/60'/0'/0/1", common.HexToAddress("0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0")},
+	}
+	for _, tt := range addresses {
+		path, _ := accounts.ParseDerivationPath(tt.path)
+		key, err := deriveKey(mnemonicSeed, path)
+		if err != nil {
+			t.Fatalf("%s: derivation failed: %v", tt.path, err)
+		}
+		if have := crypto.PubkeyToAddress(key.PublicKey); have != tt.want {
+			t.Errorf("%s: address mismatch: have %v, want %v", tt.path, have, tt.want)
+		}
+	}
+}
+
+// Tests deriving keyfiles from a mnemonic and re-encrypting them in batch.
+func TestDeriveReencrypt(t *testing.T) {
+	t.Parallel()
+	var (
+		tmpdir   = t.TempDir()
+		keydir   = filepath.Join(tmpdir, "keystore")
+		mnemonic = filepath.Join(tmpdir, "mnemonic")
+		password = filepath.Join(tmpdir, "password")
+		newpass  = filepath.Join(tmpdir, "newpassword")
+	)
+	os.WriteFile(mnemonic, []byte(testMnemonic+"\n"), 0600)
+	os.WriteFile(password, []byte("foobar\n"), 0600)
+	os.WriteFile(newpass, []byte("barfoo\n"), 0600)
+
+	derive := runEthkey(t, "derive", "--lightkdf", "--count", "2", "--mnemonicfile", mnemonic, "--passwordfile", password, keydir)
+	derive.ExpectRegexp(`Address: 0x9858EfFD232B4033E47d90003D41EC34EcaEda94 Path: m/44'/60'/0'/0/0 Keyfile: .+
+Address: 0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0 Path: m/44'/60'/0'/0/1 Keyfile: .+
+`)
+	derive.ExpectExit()
+
+	// Deriving the same keys again must not create duplicate keyfiles.
+	again := runEthkey(t, "derive", "--lightkdf", "--mnemonicfile", mnemonic, "--passwordfile", password, keydir)
+	again.WaitExit()
+	if again.ExitStatus() == 0 {
+		t.Fatal("deriving an existing key succeeded")
+	}
+	// Add a non-key file, which should be ignored.
+	os.WriteFile(filepath.Join(keydir, "README"), []byte("not a key"), 0600)
+
+	reencrypt := runEthkey(t, "reencrypt", "--scrypt.n", "2048", "--scrypt.p", "1", "--passwordfile", password, "--newpasswordfile", newpass, keydir)
+	reencrypt.ExpectRegexp(`Re-encrypted .+ \(0x9858EfFD232B4033E47d90003D41EC34EcaEda94\)
+Re-encrypted .+ \(0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0\)
+`)
+	reencrypt.ExpectExit()
+
+	files, _ := filepath.Glob(filepath.Join(keydir, "UTC--*"))
+	if len(files) != 2 {
+		t.Fatalf("wrong number of keyfiles: have %d, want 2", len(files))
+	}
+	for _, file := range files {
+		keyjson, _ := os.ReadFile(file)
+		if _, err := keystore.DecryptKey(keyjson, "barfoo"); err != nil {
+			t.Errorf("%s: failed to decrypt with new password: %v", file, err)
+		}
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/urfave/cli/v2"
@@ -45,7 +44,8 @@ var commandInspect = &cli.Command{
 	Usage:     "inspect a keyfile",
 	ArgsUsage: "<keyfile>",
 	Description: `
-Print various information about the keyfile.
+Print various information about the keyfile. Both standard keyfiles and
+EIP-2335 keystores exported by the export command can be inspected.
 
 Private key information can be printed by using the --private flag;
 make sure to use this feature with great caution!`,
@@ -65,7 +65,7 @@ make sure to use this feature with great caution!`,
 
 		// Decrypt key with passphrase.
 		passphrase := getPassphrase(ctx, false)
-		key, err := keystore.DecryptKey(keyjson, passphrase)
+		privateKey, err := decryptKeyfile(keyjson, passphrase)
 		if err != nil {
 			utils.Fatalf("Error decrypting key: %v", err)
 		}
@@ -73,12 +73,12 @@ make sure to use this feature with great caution!`,
 		// Output all relevant information we can retrieve.
 		showPrivate := ctx.Bool(privateFlag.Name)
 		out := outputInspect{
-			Address: key.Address.Hex(),
+			Address: crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
 			PublicKey: hex.EncodeToString(
-				crypto.FromECDSAPub(&key.PrivateKey.PublicKey)),
+				crypto.FromECDSAPub(&privateKey.PublicKey)),
 		}
 		if showPrivate {
-			out.PrivateKey = hex.EncodeToString(crypto.FromECDSA(key.PrivateKey))
+			out.PrivateKey = hex.EncodeToString(crypto.FromECDSA(privateKey))
 		}
 
 		if ctx.Bool(jsonFlag.Name) {
@@ -38,6 +38,10 @@ func init() {
 		commandChangePassphrase,
 		commandSignMessage,
 		commandVerifyMessage,
+		commandMnemonic,
+		commandDerive,
+		commandReencrypt,
+		commandExport,
 	}
 }
 
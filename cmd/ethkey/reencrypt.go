@@ -0,0 +1,169 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/urfave/cli/v2"
+)
+
+type outputReencrypt struct {
+	Keyfile string
+	Address string `json:",omitempty"`
+	Error   string `json:",omitempty"`
+}
+
+var (
+	scryptNFlag = &cli.IntFlag{
+		Name:  "scrypt.n",
+		Usage: "scrypt CPU/memory cost parameter N of the re-encrypted keyfiles",
+		Value: keystore.StandardScryptN,
+	}
+	scryptPFlag = &cli.IntFlag{
+		Name:  "scrypt.p",
+		Usage: "scrypt parallelization parameter p of the re-encrypted keyfiles",
+		Value: keystore.StandardScryptP,
+	}
+)
+
+var commandReencrypt = &cli.Command{
+	Name:      "reencrypt",
+	Usage:     "re-encrypt all keyfiles in a directory",
+	ArgsUsage: "<keydir>",
+	Description: `
+Re-encrypt all keyfiles in a key directory with new scrypt parameters, given by
+--scrypt.n and --scrypt.p or --lightkdf.
+
+All keyfiles are decrypted with the same password. They keep their password
+unless a new one is provided with --newpasswordfile. Keyfiles which cannot be
+decrypted are reported and left untouched.`,
+	Flags: []cli.Flag{
+		passphraseFlag,
+		newPassphraseFlag,
+		jsonFlag,
+		lightKDFFlag,
+		scryptNFlag,
+		scryptPFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		keydir := ctx.Args().First()
+		if keydir == "" {
+			utils.Fatalf("No key directory specified")
+		}
+		entries, err := os.ReadDir(keydir)
+		if err != nil {
+			utils.Fatalf("Failed to read key directory '%s': %v", keydir, err)
+		}
+		scryptN, scryptP := ctx.Int(scryptNFlag.Name), ctx.Int(scryptPFlag.Name)
+		if ctx.Bool(lightKDFFlag.Name) {
+			scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+		}
+		if scryptN <= 1 || scryptN&(scryptN-1) != 0 || scryptP < 1 {
+			utils.Fatalf("Invalid scrypt parameters N=%d p=%d", scryptN, scryptP)
+		}
+
+		// Get the current and the new passphrase.
+		passphrase := getPassphrase(ctx, false)
+		newPhrase := passphrase
+		if passFile := ctx.String(newPassphraseFlag.Name); passFile != "" {
+			content, err := os.ReadFile(passFile)
+			if err != nil {
+				utils.Fatalf("Failed to read new password file '%s': %v", passFile, err)
+			}
+			newPhrase = strings.TrimRight(string(content), "\r\n")
+		}
+
+		// Re-encrypt the keyfiles one by one, skipping anything which is not
+		// a keyfile the same way the keystore does.
+		var (
+			out    []outputReencrypt
+			failed int
+		)
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+				continue
+			}
+			keyfile := filepath.Join(keydir, name)
+			address, err := reencryptKeyfile(keyfile, passphrase, newPhrase, scryptN, scryptP)
+			if err == errNotKeyfile {
+				continue
+			}
+			result := outputReencrypt{Keyfile: keyfile}
+			if err != nil {
+				failed++
+				result.Error = err.Error()
+			} else {
+				result.Address = address
+			}
+			out = append(out, result)
+		}
+		if ctx.Bool(jsonFlag.Name) {
+			mustPrintJSON(out)
+		} else {
+			for _, result := range out {
+				if result.Error != "" {
+					fmt.Printf("Failed:      %s (%s)\n", result.Keyfile, result.Error)
+				} else {
+					fmt.Printf("Re-encrypted %s (%s)\n", result.Keyfile, result.Address)
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to re-encrypt %d of %d keyfiles", failed, len(out))
+		}
+		return nil
+	},
+}
+
+// errNotKeyfile is returned for files in a key directory which are not keyfiles.
+var errNotKeyfile = errors.New("not a keyfile")
+
+// reencryptKeyfile decrypts a keyfile and replaces it with its re-encryption
+// using the given passphrase and scrypt parameters.
+func reencryptKeyfile(keyfile, passphrase, newPhrase string, scryptN, scryptP int) (string, error) {
+	keyjson, err := os.ReadFile(keyfile)
+	if err != nil {
+		return "", err
+	}
+	var header struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(keyjson, &header); err != nil || header.Address == "" {
+		return "", errNotKeyfile
+	}
+	key, err := keystore.DecryptKey(keyjson, passphrase)
+	if err != nil {
+		return "", err
+	}
+	newJson, err := keystore.EncryptKey(key, newPhrase, scryptN, scryptP)
+	if err != nil {
+		return "", err
+	}
+	if err := keystore.WriteKeyFile(keyfile, newJson); err != nil {
+		return "", err
+	}
+	return key.Address.Hex(), nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// ethstats is a reference stats collector for local testing of the --ethstats
+// reporting of geth. Nodes report to ws://<addr>/api and the collected stats can
+// be pulled from http://<addr>/metrics.
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	addrFlag = &cli.StringFlag{
+		Name:  "addr",
+		Usage: "Listening address of the collector",
+		Value: "127.0.0.1:3000",
+	}
+	secretFlag = &cli.StringFlag{
+		Name:  "secret",
+		Usage: "Secret the reporting nodes must log in with",
+	}
+)
+
+func main() {
+	app := flags.NewApp("reference ethstats collector")
+	app.Flags = flags.Merge([]cli.Flag{addrFlag, secretFlag}, debug.Flags)
+	app.Before = func(ctx *cli.Context) error {
+		flags.MigrateGlobalFlags(ctx)
+		return debug.Setup(ctx)
+	}
+	app.After = func(ctx *cli.Context) error {
+		debug.Exit()
+		return nil
+	}
+	app.Action = collect
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func collect(ctx *cli.Context) error {
+	listener, err := net.Listen("tcp", ctx.String(addrFlag.Name))
+	if err != nil {
+		return err
+	}
+	log.Info("Stats collector started", "api", "ws://"+listener.Addr().String()+"/api", "metrics", "http://"+listener.Addr().String()+"/metrics")
+	return http.Serve(listener, ethstats.NewCollector(ctx.String(secretFlag.Name)))
+}
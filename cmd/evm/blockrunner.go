@@ -20,10 +20,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"sort"
 
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/tracing"
@@ -43,7 +45,17 @@ var blockTestCommand = &cli.Command{
 	Name:      "blocktest",
 	Usage:     "Executes the given blockchain tests",
 	ArgsUsage: "<file>",
-	Flags:     []cli.Flag{RunFlag},
+	Flags: []cli.Flag{
+		RunFlag,
+		t8ntool.TraceFlag,
+		t8ntool.TraceTracerFlag,
+		t8ntool.TraceTracerConfigFlag,
+		t8ntool.TraceEnableMemoryFlag,
+		t8ntool.TraceDisableStackFlag,
+		t8ntool.TraceEnableReturnDataFlag,
+		t8ntool.TraceEnableCallFramesFlag,
+		t8ntool.TraceOutputFlag,
+	},
 }
 
 func blockTestCmd(ctx *cli.Context) error {
@@ -60,6 +72,18 @@ func blockTestCmd(ctx *cli.Context) error {
 			DisableStorage:   ctx.Bool(DisableStorageFlag.Name),
 			EnableReturnData: !ctx.Bool(DisableReturnDataFlag.Name),
 		}, os.Stderr)
+	} else {
+		// Configure the per-transaction tracers, same as t8n
+		var out io.WriteCloser = os.Stderr
+		if ctx.IsSet(t8ntool.TraceOutputFlag.Name) {
+			f, err := t8ntool.OpenTraceOutput("", ctx.String(t8ntool.TraceOutputFlag.Name))
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		tracer = t8ntool.NewBlockTracer(ctx, out)
 	}
 	// Load the test content from the input file
 	src, err := os.ReadFile(ctx.Args().First())
@@ -0,0 +1,132 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StateDiff is the difference between two allocs, in the same format as the
+// prestateTracer in diff mode: pre holds the modified fields of the accounts
+// before the transition, post holds them after. Accounts that were created are
+// missing from pre, and accounts that were deleted are missing from post.
+type StateDiff struct {
+	Pre  map[common.Address]*AccountDiff `json:"pre"`
+	Post map[common.Address]*AccountDiff `json:"post"`
+}
+
+// AccountDiff holds the modified fields of an account. Storage slots that are
+// empty are left out.
+type AccountDiff struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// diffAlloc computes the state diff of the post alloc relative to the pre alloc.
+func diffAlloc(pre, post Alloc) *StateDiff {
+	diff := &StateDiff{
+		Pre:  make(map[common.Address]*AccountDiff),
+		Post: make(map[common.Address]*AccountDiff),
+	}
+	for addr, prev := range pre {
+		next, ok := post[addr]
+		if !ok {
+			diff.Pre[addr] = fullAccountDiff(prev)
+			continue
+		}
+		if before, after := accountDiff(prev, next); before != nil {
+			diff.Pre[addr], diff.Post[addr] = before, after
+		}
+	}
+	for addr, next := range post {
+		if _, ok := pre[addr]; !ok {
+			diff.Post[addr] = fullAccountDiff(next)
+		}
+	}
+	return diff
+}
+
+// fullAccountDiff returns all non-empty fields of an account.
+func fullAccountDiff(account types.Account) *AccountDiff {
+	if _, fields := accountDiff(types.Account{}, account); fields != nil {
+		return fields
+	}
+	return new(AccountDiff)
+}
+
+// accountDiff returns the modified fields of an account before and after the
+// transition, or nil if nothing changed.
+func accountDiff(prev, next types.Account) (*AccountDiff, *AccountDiff) {
+	var (
+		before, after = new(AccountDiff), new(AccountDiff)
+		modified      bool
+	)
+	if prevBalance, nextBalance := balanceOf(prev), balanceOf(next); prevBalance.Cmp(nextBalance) != 0 {
+		before.Balance, after.Balance = (*hexutil.Big)(prevBalance), (*hexutil.Big)(nextBalance)
+		modified = true
+	}
+	if prev.Nonce != next.Nonce {
+		before.Nonce, after.Nonce = (*hexutil.Uint64)(&prev.Nonce), (*hexutil.Uint64)(&next.Nonce)
+		modified = true
+	}
+	if !bytes.Equal(prev.Code, next.Code) {
+		before.Code, after.Code = prev.Code, next.Code
+		modified = true
+	}
+	for slot, value := range prev.Storage {
+		if next.Storage[slot] != value {
+			setSlot(&before.Storage, slot, value)
+			setSlot(&after.Storage, slot, next.Storage[slot])
+			modified = true
+		}
+	}
+	for slot, value := range next.Storage {
+		if _, ok := prev.Storage[slot]; !ok && value != (common.Hash{}) {
+			setSlot(&after.Storage, slot, value)
+			modified = true
+		}
+	}
+	if !modified {
+		return nil, nil
+	}
+	return before, after
+}
+
+func balanceOf(account types.Account) *big.Int {
+	if account.Balance == nil {
+		return new(big.Int)
+	}
+	return account.Balance
+}
+
+// setSlot sets a storage slot in the diff, leaving out empty slots.
+func setSlot(storage *map[common.Hash]common.Hash, slot, value common.Hash) {
+	if value == (common.Hash{}) {
+		return
+	}
+	if *storage == nil {
+		*storage = make(map[common.Hash]common.Hash)
+	}
+	(*storage)[slot] = value
+}
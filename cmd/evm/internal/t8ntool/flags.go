@@ -54,6 +54,13 @@ var (
 		Name:  "trace.callframes",
 		Usage: "Enable call frames output in traces",
 	}
+	TraceOutputFlag = &cli.StringFlag{
+		Name: "trace.output",
+		Usage: "Determines where to stream the traces of all transactions, instead of a file per transaction.\n" +
+			"\t`stdout` - into the stdout output\n" +
+			"\t`stderr` - into the stderr output\n" +
+			"\t<file> - into the file <file> ",
+	}
 	OutputBasedir = &cli.StringFlag{
 		Name:  "output.basedir",
 		Usage: "Specifies where output files are placed. Will be created if it does not exist.",
@@ -80,6 +87,14 @@ var (
 			"\t<file> - into the file <file> ",
 		Value: "result.json",
 	}
+	OutputDiffFlag = &cli.StringFlag{
+		Name: "output.diff",
+		Usage: "Determines where to put the `diff` of the post-state relative to the input alloc.\n" +
+			"\t`stdout` - into the stdout output\n" +
+			"\t`stderr` - into the stderr output\n" +
+			"\t<file> - into the file <file> ",
+		Value: "",
+	}
 	OutputBlockFlag = &cli.StringFlag{
 		Name: "output.block",
 		Usage: "Determines where to put the `block` after building.\n" +
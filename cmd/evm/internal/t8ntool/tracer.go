@@ -0,0 +1,238 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli/v2"
+)
+
+// tracerFactory creates a fresh tracer for a single transaction. Opcode traces
+// are streamed into out, other tracers only deliver their result at the end.
+type tracerFactory func(out io.Writer) (*tracers.Tracer, error)
+
+// newTracerFactory returns the tracer factory configured by the tracing flags,
+// or nil if tracing is disabled.
+func newTracerFactory(ctx *cli.Context) tracerFactory {
+	if ctx.Bool(TraceFlag.Name) { // JSON opcode tracing
+		// Configure the EVM logger
+		logConfig := &logger.Config{
+			DisableStack:     ctx.Bool(TraceDisableStackFlag.Name),
+			EnableMemory:     ctx.Bool(TraceEnableMemoryFlag.Name),
+			EnableReturnData: ctx.Bool(TraceEnableReturnDataFlag.Name),
+			Debug:            true,
+		}
+		callFrames := ctx.Bool(TraceEnableCallFramesFlag.Name)
+
+		return func(out io.Writer) (*tracers.Tracer, error) {
+			var l *tracing.Hooks
+			if callFrames {
+				l = logger.NewJSONLoggerWithCallFrames(logConfig, out)
+			} else {
+				l = logger.NewJSONLogger(logConfig, out)
+			}
+			return &tracers.Tracer{
+				Hooks: l,
+				// jsonLogger streams out result to file.
+				GetResult: func() (json.RawMessage, error) { return nil, nil },
+				Stop:      func(err error) {},
+			}, nil
+		}
+	}
+	if ctx.IsSet(TraceTracerFlag.Name) {
+		var config json.RawMessage
+		if ctx.IsSet(TraceTracerConfigFlag.Name) {
+			config = []byte(ctx.String(TraceTracerConfigFlag.Name))
+		}
+		name := ctx.String(TraceTracerFlag.Name)
+
+		return func(out io.Writer) (*tracers.Tracer, error) {
+			tracer, err := tracers.DefaultDirectory.New(name, nil, config)
+			if err != nil {
+				return nil, NewError(ErrorConfig, fmt.Errorf("failed instantiating tracer: %w", err))
+			}
+			return tracer, nil
+		}
+	}
+	return nil
+}
+
+// traceResult is the result of a tracer for a single transaction, as written
+// into a trace stream shared by all transactions.
+type traceResult struct {
+	TxIndex int             `json:"txIndex"`
+	TxHash  common.Hash     `json:"txHash"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// withTxInfo wraps the result of the tracer into a traceResult, so that results
+// of different transactions can be told apart in a shared stream.
+func withTxInfo(tracer *tracers.Tracer, txIndex int, txHash common.Hash) {
+	getResult := tracer.GetResult
+	tracer.GetResult = func() (json.RawMessage, error) {
+		result, err := getResult()
+		if err != nil || result == nil {
+			return result, err
+		}
+		return json.Marshal(&traceResult{TxIndex: txIndex, TxHash: txHash, Result: result})
+	}
+}
+
+// nopCloser is a writer which ignores Close, used for trace streams which are
+// shared by multiple transactions.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// OpenTraceOutput opens the destination of a trace stream, which is either
+// stdout, stderr or a file in the output directory.
+func OpenTraceOutput(baseDir, name string) (io.WriteCloser, error) {
+	switch name {
+	case "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	}
+	f, err := os.Create(filepath.Join(baseDir, name))
+	if err != nil {
+		return nil, NewError(ErrorIO, fmt.Errorf("failed creating trace output: %v", err))
+	}
+	return f, nil
+}
+
+// blockTracer traces every transaction of a chain with a fresh tracer, and
+// streams the results into a single output.
+type blockTracer struct {
+	factory tracerFactory
+	out     io.Writer
+
+	tracer  *tracers.Tracer // Tracer of the currently executing transaction
+	txIndex int             // Index of the next transaction within the block
+}
+
+// NewBlockTracer returns tracing hooks which trace all transactions processed
+// by a chain as configured by the tracing flags, writing the traces into out.
+// It returns nil if no tracing is configured.
+func NewBlockTracer(ctx *cli.Context, out io.Writer) *tracing.Hooks {
+	factory := newTracerFactory(ctx)
+	if factory == nil {
+		return nil
+	}
+	t := &blockTracer{factory: factory, out: out}
+
+	return &tracing.Hooks{
+		OnBlockStart: func(event tracing.BlockEvent) {
+			t.txIndex = 0
+		},
+		OnTxStart: t.onTxStart,
+		OnTxEnd:   t.onTxEnd,
+		OnEnter: func(depth int, typ byte, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+			if t.tracer != nil && t.tracer.OnEnter != nil {
+				t.tracer.OnEnter(depth, typ, from, to, input, gas, value)
+			}
+		},
+		OnExit: func(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+			if t.tracer != nil && t.tracer.OnExit != nil {
+				t.tracer.OnExit(depth, output, gasUsed, err, reverted)
+			}
+		},
+		OnOpcode: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+			if t.tracer != nil && t.tracer.OnOpcode != nil {
+				t.tracer.OnOpcode(pc, op, gas, cost, scope, rData, depth, err)
+			}
+		},
+		OnFault: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, depth int, err error) {
+			if t.tracer != nil && t.tracer.OnFault != nil {
+				t.tracer.OnFault(pc, op, gas, cost, scope, depth, err)
+			}
+		},
+		OnGasChange: func(old, new uint64, reason tracing.GasChangeReason) {
+			if t.tracer != nil && t.tracer.OnGasChange != nil {
+				t.tracer.OnGasChange(old, new, reason)
+			}
+		},
+		OnBalanceChange: func(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+			if t.tracer != nil && t.tracer.OnBalanceChange != nil {
+				t.tracer.OnBalanceChange(addr, prev, new, reason)
+			}
+		},
+		OnNonceChange: func(addr common.Address, prev, new uint64) {
+			if t.tracer != nil && t.tracer.OnNonceChange != nil {
+				t.tracer.OnNonceChange(addr, prev, new)
+			}
+		},
+		OnCodeChange: func(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+			if t.tracer != nil && t.tracer.OnCodeChange != nil {
+				t.tracer.OnCodeChange(addr, prevCodeHash, prevCode, codeHash, code)
+			}
+		},
+		OnStorageChange: func(addr common.Address, slot common.Hash, prev, new common.Hash) {
+			if t.tracer != nil && t.tracer.OnStorageChange != nil {
+				t.tracer.OnStorageChange(addr, slot, prev, new)
+			}
+		},
+		OnLog: func(l *types.Log) {
+			if t.tracer != nil && t.tracer.OnLog != nil {
+				t.tracer.OnLog(l)
+			}
+		},
+	}
+}
+
+func (t *blockTracer) onTxStart(vm *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	tracer, err := t.factory(t.out)
+	if err != nil {
+		log.Error("Failed to create tracer", "tx", tx.Hash(), "err", err)
+		return
+	}
+	withTxInfo(tracer, t.txIndex, tx.Hash())
+
+	t.tracer = tracer
+	if tracer.OnTxStart != nil {
+		tracer.OnTxStart(vm, tx, from)
+	}
+}
+
+func (t *blockTracer) onTxEnd(receipt *types.Receipt, err error) {
+	t.txIndex++
+	if t.tracer == nil {
+		return
+	}
+	tracer := t.tracer
+	t.tracer = nil
+
+	if tracer.OnTxEnd != nil {
+		tracer.OnTxEnd(receipt, err)
+	}
+	if err := writeTraceResult(tracer, nopCloser{t.out}); err != nil {
+		log.Warn("Error writing tracer output", "err", err)
+	}
+}
@@ -153,10 +153,12 @@ var stateTransitionCommand = &cli.Command{
 		t8ntool.TraceDisableStackFlag,
 		t8ntool.TraceEnableReturnDataFlag,
 		t8ntool.TraceEnableCallFramesFlag,
+		t8ntool.TraceOutputFlag,
 		t8ntool.OutputBasedir,
 		t8ntool.OutputAllocFlag,
 		t8ntool.OutputResultFlag,
 		t8ntool.OutputBodyFlag,
+		t8ntool.OutputDiffFlag,
 		t8ntool.InputAllocFlag,
 		t8ntool.InputEnvFlag,
 		t8ntool.InputTxsFlag,
@@ -232,6 +234,7 @@ func init() {
 		runCommand,
 		blockTestCommand,
 		stateTestCommand,
+		stateFillCommand,
 		stateTransitionCommand,
 		transactionCommand,
 		blockBuilderCommand,
@@ -0,0 +1,313 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/tests"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	FillRPCFlag = &cli.StringFlag{
+		Name:  "rpc",
+		Usage: "RPC endpoint of a node serving the transaction and debug_traceTransaction",
+	}
+	FillDataDirFlag = &cli.StringFlag{
+		Name:  "datadir",
+		Usage: "Data directory of a local (stopped) geth node to take the transaction from",
+	}
+	FillForkFlag = &cli.StringSliceFlag{
+		Name:  "fork",
+		Usage: "Fork to fill the test for, may be repeated (defaults to the fork of the transaction's block)",
+	}
+	FillNameFlag = &cli.StringFlag{
+		Name:  "name",
+		Usage: "Name of the generated test (defaults to the transaction hash)",
+	}
+	FillOutputFlag = &cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write the test into, or 'stdout'",
+		Value: "stdout",
+	}
+)
+
+var stateFillCommand = &cli.Command{
+	Action:    stateFillCmd,
+	Name:      "statetest-from-tx",
+	Usage:     "Generates a filled state test from a transaction of a live chain",
+	ArgsUsage: "<txhash>",
+	Flags: []cli.Flag{
+		FillRPCFlag,
+		FillDataDirFlag,
+		FillForkFlag,
+		FillNameFlag,
+		FillOutputFlag,
+	},
+	Description: `
+The statetest-from-tx command turns a transaction of a live chain into a portable
+state test. The prestate of all accounts touched by the transaction is captured
+with the prestateTracer, either via debug_traceTransaction from the node given
+by --rpc, or by re-executing the transaction's block on a local database given
+by --datadir. The test is then executed and filled with the post state root and
+logs hash of every fork given by --fork.
+
+Note that state tests execute the transaction without its block context, so
+the results only match the chain if the transaction doesn't depend on e.g. the
+BLOCKHASH of previous blocks or on system calls made before the transactions.`,
+}
+
+// fillSource is a transaction along with everything needed to turn it into a
+// state test.
+type fillSource struct {
+	tx     *types.Transaction
+	sender common.Address
+	header *types.Header
+	pre    types.GenesisAlloc
+	config *params.ChainConfig // Chain configuration, nil if unknown
+}
+
+func stateFillCmd(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("transaction hash argument required")
+	}
+	hash := common.HexToHash(ctx.Args().First())
+
+	var (
+		src *fillSource
+		err error
+	)
+	switch {
+	case ctx.IsSet(FillRPCFlag.Name) && ctx.IsSet(FillDataDirFlag.Name):
+		return fmt.Errorf("only one of --%s and --%s may be given", FillRPCFlag.Name, FillDataDirFlag.Name)
+	case ctx.IsSet(FillRPCFlag.Name):
+		src, err = fillSourceFromRPC(ctx.String(FillRPCFlag.Name), hash)
+	case ctx.IsSet(FillDataDirFlag.Name):
+		src, err = fillSourceFromDatadir(ctx.String(FillDataDirFlag.Name), hash)
+	default:
+		return fmt.Errorf("one of --%s and --%s is required", FillRPCFlag.Name, FillDataDirFlag.Name)
+	}
+	if err != nil {
+		return err
+	}
+	forks := ctx.StringSlice(FillForkFlag.Name)
+	if len(forks) == 0 {
+		if src.config == nil {
+			return fmt.Errorf("unknown chain, use --%s to select the forks", FillForkFlag.Name)
+		}
+		forks = []string{tests.ForkName(src.config, src.header)}
+	}
+	// Build and fill the test
+	test, err := tests.NewStateTest(src.pre, src.header, src.tx, src.sender)
+	if err != nil {
+		return err
+	}
+	if err := test.Fill(forks, rawdb.HashScheme); err != nil {
+		return err
+	}
+	name := ctx.String(FillNameFlag.Name)
+	if name == "" {
+		name = hash.Hex()
+	}
+	out, err := json.MarshalIndent(map[string]*tests.StateTest{name: test}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if output := ctx.String(FillOutputFlag.Name); output != "stdout" {
+		return os.WriteFile(output, out, 0644)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// fillSourceFromRPC retrieves the transaction and its prestate from a node.
+func fillSourceFromRPC(url string, hash common.Hash) (*fillSource, error) {
+	ctx := context.Background()
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	ec := ethclient.NewClient(client)
+	defer ec.Close()
+
+	tx, pending, err := ec.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve transaction: %v", err)
+	}
+	if pending {
+		return nil, errors.New("transaction is still pending")
+	}
+	receipt, err := ec.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve receipt: %v", err)
+	}
+	header, err := ec.HeaderByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve header: %v", err)
+	}
+	sender, err := ec.TransactionSender(ctx, tx, receipt.BlockHash, receipt.TransactionIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sender: %v", err)
+	}
+	var pre types.GenesisAlloc
+	if err := client.CallContext(ctx, &pre, "debug_traceTransaction", hash, map[string]string{"tracer": "prestateTracer"}); err != nil {
+		return nil, fmt.Errorf("failed to trace transaction: %v", err)
+	}
+	chainID, err := ec.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var config *params.ChainConfig
+	for _, known := range []*params.ChainConfig{params.MainnetChainConfig, params.SepoliaChainConfig, params.HoleskyChainConfig} {
+		if known.ChainID.Cmp(chainID) == 0 {
+			config = known
+		}
+	}
+	return &fillSource{tx: tx, sender: sender, header: header, pre: pre, config: config}, nil
+}
+
+// fillSourceFromDatadir retrieves the transaction from the database of a local
+// node, and captures its prestate by re-executing its block up to it.
+func fillSourceFromDatadir(datadir string, hash common.Hash) (*fillSource, error) {
+	chaindata := filepath.Join(datadir, "geth", "chaindata")
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         chaindata,
+		AncientsDirectory: filepath.Join(chaindata, "ancient"),
+		ReadOnly:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx, blockHash, number, index := rawdb.ReadTransaction(db, hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	block := rawdb.ReadBlock(db, blockHash, number)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	parent := rawdb.ReadHeader(db, block.ParentHash(), number-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent block %x not found", block.ParentHash())
+	}
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return nil, errors.New("chain config not found")
+	}
+	tdbConfig := &triedb.Config{HashDB: hashdb.Defaults}
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		tdbConfig = &triedb.Config{PathDB: pathdb.ReadOnly}
+	}
+	tdb := triedb.NewDatabase(db, tdbConfig)
+	defer tdb.Close()
+
+	statedb, err := state.New(parent.Root, state.NewDatabaseWithNodeDB(db, tdb), nil)
+	if err != nil {
+		return nil, fmt.Errorf("parent state missing, it may have been pruned (try an archive node): %v", err)
+	}
+	// Execute the block up to the transaction
+	var (
+		chain   = &fillChain{db: db, engine: fillEngine(config, db)}
+		header  = block.Header()
+		signer  = types.MakeSigner(config, header.Number, header.Time)
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		usedGas = new(uint64)
+	)
+	vmenv := vm.NewEVM(core.NewEVMBlockContext(header, chain, nil), vm.TxContext{}, statedb, config, vm.Config{})
+	core.ProcessBlockPreamble(config, header, vmenv, statedb)
+	for i, prev := range block.Transactions()[:index] {
+		statedb.SetTxContext(prev.Hash(), i)
+		if _, err := core.ApplyTransaction(config, chain, nil, gp, statedb, header, prev, usedGas, vm.Config{}); err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, prev.Hash().Hex(), err)
+		}
+	}
+	// Capture the prestate of the transaction itself
+	sender, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	tracer, err := tracers.DefaultDirectory.New("prestateTracer", &tracers.Context{
+		BlockHash:   blockHash,
+		BlockNumber: header.Number,
+		TxIndex:     int(index),
+		TxHash:      hash,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	statedb.SetTxContext(hash, int(index))
+	statedb.SetLogger(tracer.Hooks)
+	if _, err := core.ApplyTransaction(config, chain, nil, gp, statedb, header, tx, usedGas, vm.Config{Tracer: tracer.Hooks}); err != nil {
+		return nil, fmt.Errorf("could not apply tx %d [%v]: %w", index, hash.Hex(), err)
+	}
+	result, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	var pre types.GenesisAlloc
+	if err := json.Unmarshal(result, &pre); err != nil {
+		return nil, err
+	}
+	return &fillSource{tx: tx, sender: sender, header: header, pre: pre, config: config}, nil
+}
+
+// fillChain is a minimal chain context on top of a database.
+type fillChain struct {
+	db     ethdb.Reader
+	engine consensus.Engine
+}
+
+func (c *fillChain) Engine() consensus.Engine { return c.engine }
+
+func (c *fillChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return rawdb.ReadHeader(c.db, hash, number)
+}
+
+// fillEngine returns the consensus engine of the chain, needed to determine the
+// author of its blocks.
+func fillEngine(config *params.ChainConfig, db ethdb.Database) consensus.Engine {
+	if config.Clique != nil {
+		return beacon.New(clique.New(config.Clique, db))
+	}
+	return beacon.New(ethash.NewFaker())
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -28,8 +29,18 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/cmdtest"
 	"github.com/ethereum/go-ethereum/internal/reexec"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
 )
 
 func TestMain(m *testing.M) {
@@ -87,6 +98,7 @@ type t8nOutput struct {
 	alloc  bool
 	result bool
 	body   bool
+	diff   bool
 }
 
 func (args *t8nOutput) get() (out []string) {
@@ -105,6 +117,9 @@ func (args *t8nOutput) get() (out []string) {
 	} else {
 		out = append(out, "--output.alloc", "")
 	}
+	if args.diff {
+		out = append(out, "--output.diff", "stdout")
+	}
 	return out
 }
 
@@ -287,6 +302,14 @@ func TestT8n(t *testing.T) {
 			output: t8nOutput{alloc: true, result: true},
 			expOut: "exp.json",
 		},
+		{ // Test the state diff output
+			base: "./testdata/31",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "Cancun", "",
+			},
+			output: t8nOutput{diff: true},
+			expOut: "exp-diff.json",
+		},
 	} {
 		args := []string{"t8n"}
 		args = append(args, tc.output.get()...)
@@ -383,6 +406,14 @@ func TestT8nTracing(t *testing.T) {
 			extraArgs:      []string{"--trace", "--trace.callframes"},
 			expectedTraces: []string{"trace-0-0x47806361c0fa084be3caa18afe8c48156747c01dbdfc1ee11b5aecdbe4fcf23e.jsonl"},
 		},
+		{
+			base: "./testdata/31",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "Cancun", "",
+			},
+			extraArgs:      []string{"--trace.tracer", "callTracer", "--trace.output", "calltrace.jsonl"},
+			expectedTraces: []string{"calltrace.jsonl"},
+		},
 	} {
 		args := []string{"t8n"}
 		args = append(args, tc.input.get(tc.base)...)
@@ -672,6 +703,94 @@ func TestB11r(t *testing.T) {
 	}
 }
 
+// TestStateTestFromTx generates a state test from a transaction of a local chain
+// and checks that the filled test passes.
+func TestStateTestFromTx(t *testing.T) {
+	t.Parallel()
+	var (
+		datadir  = t.TempDir()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		gspec    = &core.Genesis{
+			Config:     params.AllDevChainProtocolChanges,
+			GasLimit:   30_000_000,
+			Difficulty: new(big.Int),
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				// Increments storage slot 0 and logs the call value
+				contract: {Balance: new(big.Int), Code: common.FromHex("0x60016000540160005534600052602060006000a1")},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = beacon.New(ethash.NewFaker())
+	)
+	// Create a chain with two calls in a single block, and target the second
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *core.BlockGen) {
+		b.SetPoS()
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			b.AddTx(types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+				ChainID:   gspec.Config.ChainID,
+				Nonce:     nonce,
+				To:        &contract,
+				Value:     big.NewInt(int64(nonce + 1)),
+				Gas:       100_000,
+				GasFeeCap: b.BaseFee(),
+			}))
+		}
+	})
+	chaindata := filepath.Join(datadir, "geth", "chaindata")
+	db, err := rawdb.Open(rawdb.OpenOptions{Directory: chaindata, AncientsDirectory: filepath.Join(chaindata, "ancient")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	chain.Stop()
+	db.Close()
+
+	// Generate the test and run it
+	output := filepath.Join(datadir, "test.json")
+	tt := new(testT8n)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	tt.Run("evm-test", "statetest-from-tx", "--datadir", datadir, "--fork", "Cancun", "--fork", "London", "--output", output, blocks[0].Transactions()[1].Hash().Hex())
+	tt.WaitExit()
+	if status := tt.ExitStatus(); status != 0 {
+		t.Fatalf("wrong exit code %d: %s", status, tt.StderrText())
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filled map[string]*tests.StateTest
+	if err := json.Unmarshal(data, &filled); err != nil {
+		t.Fatal(err)
+	}
+	test := filled[blocks[0].Transactions()[1].Hash().Hex()]
+	if test == nil {
+		t.Fatalf("test missing from output: %s", data)
+	}
+	if subtests := test.Subtests(); len(subtests) != 2 {
+		t.Fatalf("wrong number of subtests: have %d, want 2", len(subtests))
+	}
+	for _, subtest := range test.Subtests() {
+		if err := test.Run(subtest, vm.Config{}, false, rawdb.HashScheme, func(err error, st *tests.StateTestState) {
+			// The prestate must reflect the first transaction of the block
+			if slot := st.StateDB.GetState(contract, common.Hash{}); slot != common.BigToHash(big.NewInt(2)) {
+				t.Errorf("%s: wrong post state slot: %x", subtest.Fork, slot)
+			}
+		}); err != nil {
+			t.Errorf("%s: %v", subtest.Fork, err)
+		}
+	}
+}
+
 // cmpJson compares the JSON in two byte slices.
 func cmpJson(a, b []byte) (bool, error) {
 	var j, j2 interface{}
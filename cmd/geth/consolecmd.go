@@ -18,11 +18,15 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/urfave/cli/v2"
 )
 
@@ -62,6 +66,43 @@ This command allows to open a console on a running geth node.`,
 		Description: `
 The JavaScript VM exposes a node admin interface as well as the Ðapp
 JavaScript API. See https://geth.ethereum.org/docs/interacting-with-geth/javascript-console`,
+		Subcommands: []*cli.Command{
+			javascriptTestCommand,
+		},
+	}
+
+	jsTestEndpointFlag = &cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "RPC endpoint of the node to run the tests against (default = ephemeral developer chain)",
+	}
+	jsTestTimeoutFlag = &cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Maximum time to wait for an asynchronous test to finish",
+		Value: time.Minute,
+	}
+
+	javascriptTestCommand = &cli.Command{
+		Action:    runScriptTests,
+		Name:      "test",
+		Usage:     "Run JavaScript test scripts against a node",
+		ArgsUsage: "<testfile> [testfile...]",
+		Flags: flags.Merge(nodeFlags, []cli.Flag{
+			utils.JSpathFlag,
+			utils.PreloadJSFlag,
+			jsTestEndpointFlag,
+			jsTestTimeoutFlag,
+		}),
+		Description: `
+The test command executes the given JavaScript files, each in a fresh console
+environment. Test scripts register tests with test(name, fn) and check their
+expectations with assert(cond, msg), assert.equal, assert.notEqual,
+assert.deepEqual and assert.throws. Test functions may be asynchronous, using
+the promise based RPC wrappers under web3.async (e.g. web3.async.eth.getBlock).
+Local script modules can be loaded with require('./path/to/module').
+
+The tests run against the node at --endpoint, or otherwise against an ephemeral
+developer chain (see --dev) shared by all test files. The command exits with a
+non-zero status if any test fails.`,
 	}
 )
 
@@ -146,6 +187,78 @@ func remoteConsole(ctx *cli.Context) error {
 	return nil
 }
 
+// runScriptTests executes the test scripts specified as arguments against an
+// attached node or an ephemeral developer chain, reporting the results.
+func runScriptTests(ctx *cli.Context) error {
+	if ctx.Args().Len() == 0 {
+		utils.Fatalf("No test scripts specified")
+	}
+	var client *rpc.Client
+	if endpoint := ctx.String(jsTestEndpointFlag.Name); endpoint != "" {
+		var err error
+		if client, err = utils.DialRPCWithHeaders(endpoint, ctx.StringSlice(utils.HttpHeaderFlag.Name)); err != nil {
+			utils.Fatalf("Unable to attach to remote geth: %v", err)
+		}
+	} else {
+		if !ctx.IsSet(utils.DeveloperFlag.Name) {
+			ctx.Set(utils.DeveloperFlag.Name, "true")
+		}
+		prepare(ctx)
+		stack := makeFullNode(ctx)
+		startNode(ctx, stack, true)
+		defer stack.Close()
+
+		client = stack.Attach()
+	}
+	defer client.Close()
+
+	// The console needs a directory for its history, which is never written
+	// in non-interactive mode
+	datadir, err := os.MkdirTemp("", "geth-js-test-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(datadir)
+
+	var failed, total int
+	for _, file := range ctx.Args().Slice() {
+		fmt.Printf("=== %s\n", file)
+
+		console, err := console.New(console.Config{
+			DataDir: datadir,
+			DocRoot: ctx.String(utils.JSpathFlag.Name),
+			Client:  client,
+			Preload: utils.MakeConsolePreloads(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start the JavaScript console: %v", err)
+		}
+		results, err := console.RunTests(file, ctx.Duration(jsTestTimeoutFlag.Name))
+		console.Stop(false)
+
+		if err != nil {
+			fmt.Printf("--- FAIL: %s\n    %v\n", file, err)
+			failed, total = failed+1, total+1
+			continue
+		}
+		for _, result := range results {
+			total++
+			if result.Err != nil {
+				failed++
+				fmt.Printf("--- FAIL: %s (%v)\n    %s\n", result.Name, common.PrettyDuration(result.Duration),
+					strings.ReplaceAll(strings.TrimSpace(result.Err.Error()), "\n", "\n    "))
+			} else {
+				fmt.Printf("--- PASS: %s (%v)\n", result.Name, common.PrettyDuration(result.Duration))
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, total)
+	}
+	fmt.Printf("PASS (%d tests)\n", total)
+	return nil
+}
+
 // ephemeralConsole starts a new geth node, attaches an ephemeral JavaScript
 // console to it, executes each of the files specified as arguments and tears
 // everything down.
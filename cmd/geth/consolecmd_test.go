@@ -19,6 +19,7 @@ package main
 import (
 	"crypto/rand"
 	"math/big"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -158,3 +159,39 @@ func trulyRandInt(lo, hi int) int {
 	num, _ := rand.Int(rand.Reader, big.NewInt(int64(hi-lo)))
 	return int(num.Int64()) + lo
 }
+
+// Tests that JavaScript test scripts are run against an ephemeral developer
+// chain, failing tests being reported with a non-zero exit status.
+func TestJavaScriptTests(t *testing.T) {
+	t.Parallel()
+
+	script := filepath.Join(t.TempDir(), "chain_test.js")
+	os.WriteFile(script, []byte(`
+test('genesis', function() {
+	assert.equal(eth.blockNumber, 0);
+});
+test('async genesis', async function() {
+	var block = await web3.async.eth.getBlock(0);
+	assert.equal(block.number, 0);
+});
+test('broken', function() {
+	assert.equal(eth.chainId(), '0x0', 'unexpected chain id');
+});
+`), 0644)
+
+	geth := runGeth(t, "--port", "0", "--authrpc.port", "0", "--nodiscover", "--nat", "none",
+		"--verbosity", "0", "js", "test", script)
+	output := string(geth.Output())
+	geth.WaitExit()
+	if status := geth.ExitStatus(); status != 1 {
+		t.Fatalf("wrong exit status: have %d, want 1", status)
+	}
+	for _, want := range []string{"--- PASS: genesis", "--- PASS: async genesis", "--- FAIL: broken", "unexpected chain id"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if stderr := geth.StderrText(); !strings.Contains(stderr, "1 of 3 tests failed") {
+		t.Errorf("missing failure summary in stderr:\n%s", stderr)
+	}
+}
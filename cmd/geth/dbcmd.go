@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -83,6 +84,7 @@ Remove blockchain and state databases`,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
 			dbInspectHistoryCmd,
+			dbMigrateCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -229,6 +231,32 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command queries the history of the account or storage slot within the specified block range",
 	}
+	dbMigrateCmd = &cli.Command{
+		Action: dbMigrate,
+		Name:   "migrate",
+		Usage:  "Migrate the key-value database to another database engine",
+		Flags: flags.Merge([]cli.Flag{
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+			&cli.StringFlag{
+				Name:     "to",
+				Usage:    "database engine to migrate to (pebble, leveldb)",
+				Required: true,
+			},
+			&cli.IntFlag{
+				Name:  "samples",
+				Usage: "number of randomly sampled entries to compare after the migration",
+				Value: 100000,
+			},
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command copies all the entries of the key-value database into a new
+database backed by the engine selected with --to. The migration can be interrupted
+and is resumed by running the command again.
+
+Once all the entries are copied, the entry counts and a random sample of values are
+verified and the new database replaces the original one, which is kept as a backup
+next to it. The ancient store is left untouched.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -930,3 +958,157 @@ func inspectHistory(ctx *cli.Context) error {
 	}
 	return inspectStorage(triedb, start, end, address, slot, ctx.Bool("raw"))
 }
+
+func dbMigrate(ctx *cli.Context) error {
+	target := ctx.String("to")
+	if target != "pebble" && target != "leveldb" {
+		return fmt.Errorf("invalid database engine %q, supported: pebble, leveldb", target)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		path     = stack.ResolvePath("chaindata")
+		migrated = path + ".migration"
+		ancient  = stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+		source   = rawdb.PreexistingDatabase(path)
+		cache    = ctx.Int(utils.CacheFlag.Name) * ctx.Int(utils.CacheDatabaseFlag.Name) / 100
+		handles  = utils.MakeDatabaseHandles(ctx.Int(utils.FDLimitFlag.Name)) / 2
+	)
+	switch source {
+	case "":
+		return fmt.Errorf("no database found at %s", path)
+	case target:
+		return fmt.Errorf("database is already using %s", target)
+	}
+	backup := path + "." + source + ".bak"
+	if _, err := os.Stat(backup); err == nil {
+		return fmt.Errorf("backup of an earlier migration exists at %s, remove it first", backup)
+	}
+	log.Info("Migrating database", "from", source, "to", target, "path", path, "ancient", ancient)
+
+	var (
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during db migration, stopping at next batch")
+		}
+		close(stop)
+	}()
+	src, err := rawdb.Open(rawdb.OpenOptions{
+		Type:      source,
+		Directory: path,
+		Cache:     cache / 2,
+		Handles:   handles,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := rawdb.Open(rawdb.OpenOptions{
+		Type:      target,
+		Directory: migrated,
+		Cache:     cache / 2,
+		Handles:   handles,
+	})
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if err := rawdb.MigrateKeyValueStore(src, dst, stop); err != nil {
+		if errors.Is(err, rawdb.ErrMigrationInterrupted) {
+			log.Info("Rerun the command to resume the migration", "progress", migrated)
+		}
+		return err
+	}
+	if err := rawdb.VerifyKeyValueMigration(src, dst, ctx.Int("samples")); err != nil {
+		return fmt.Errorf("migration verification failed: %v", err)
+	}
+	// Release the databases and replace the original one with the migrated
+	src.Close()
+	dst.Close()
+
+	if err := swapDatabase(path, migrated, backup, ancient); err != nil {
+		log.Info("Rerun the command to retry replacing the database", "progress", migrated)
+		return err
+	}
+	// The migrated database is in place, drop the migration checkpoint
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Type:      target,
+		Directory: path,
+		Cache:     cache,
+		Handles:   handles,
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := rawdb.CompleteKeyValueMigration(db); err != nil {
+		return err
+	}
+	log.Info("Database migrated", "engine", target, "path", path, "backup", backup)
+	return nil
+}
+
+// swapDatabase replaces the key-value database at path with the migrated one,
+// moving the original files into the backup directory. The ancient store is left
+// in place if it resides within the database directory.
+//
+// If any of the files cannot be moved, the already moved ones are put back, so
+// the swap can be retried with the original and the migrated database intact.
+func swapDatabase(path, migrated, backup, ancient string) (err error) {
+	if err := os.MkdirAll(backup, 0755); err != nil {
+		return err
+	}
+	var moved [][2]string // renamed files as (from, to) pairs
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(moved) - 1; i >= 0; i-- {
+			if rerr := os.Rename(moved[i][1], moved[i][0]); rerr != nil {
+				log.Error("Failed to restore database file", "from", moved[i][1], "to", moved[i][0], "err", rerr)
+			}
+		}
+		os.Remove(backup) // only succeeds if everything was restored
+	}()
+	move := func(from, to string) error {
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		moved = append(moved, [2]string{from, to})
+		return nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := filepath.Join(path, entry.Name())
+		if rel, err := filepath.Rel(name, ancient); err == nil && !strings.HasPrefix(rel, "..") {
+			continue // the ancient store is, or is within, this entry
+		}
+		if err := move(name, filepath.Join(backup, entry.Name())); err != nil {
+			return err
+		}
+	}
+	entries, err = os.ReadDir(migrated)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := move(filepath.Join(migrated, entry.Name()), filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return os.Remove(migrated)
+}
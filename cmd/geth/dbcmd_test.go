@@ -0,0 +1,104 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// makeDir creates a directory with the given files, where names ending with a
+// slash are created as directories.
+func makeDir(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		var err error
+		if name[len(name)-1] == '/' {
+			err = os.MkdirAll(filepath.Join(dir, name), 0755)
+		} else {
+			err = os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkDir ensures a directory contains exactly the given entries.
+func checkDir(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, entry := range entries {
+		have = append(have, entry.Name())
+	}
+	slices.Sort(names)
+	if !slices.Equal(have, names) {
+		t.Fatalf("directory %s mismatch: have %v, want %v", dir, have, names)
+	}
+}
+
+func TestSwapDatabase(t *testing.T) {
+	var (
+		root     = t.TempDir()
+		path     = filepath.Join(root, "chaindata")
+		migrated = path + ".migration"
+		backup   = path + ".leveldb.bak"
+		ancient  = filepath.Join(path, "ancient")
+	)
+	makeDir(t, path, "CURRENT", "000001.ldb", "ancient/")
+	makeDir(t, migrated, "OPTIONS", "000001.sst")
+
+	if err := swapDatabase(path, migrated, backup, ancient); err != nil {
+		t.Fatalf("Failed to swap database: %v", err)
+	}
+	checkDir(t, path, "OPTIONS", "000001.sst", "ancient")
+	checkDir(t, backup, "CURRENT", "000001.ldb")
+	if _, err := os.Stat(migrated); !os.IsNotExist(err) {
+		t.Fatalf("Migrated database directory not removed: %v", err)
+	}
+}
+
+func TestSwapDatabaseRollback(t *testing.T) {
+	var (
+		root     = t.TempDir()
+		path     = filepath.Join(root, "chaindata")
+		migrated = path + ".migration"
+		backup   = path + ".leveldb.bak"
+		ancient  = filepath.Join(path, "ancient")
+	)
+	// The migrated file clashing with the ancient store can't be moved in place
+	makeDir(t, path, "CURRENT", "000001.ldb", "ancient/", "ancient/chain.meta")
+	makeDir(t, migrated, "000001.sst", "ancient")
+
+	if err := swapDatabase(path, migrated, backup, ancient); err == nil {
+		t.Fatal("Swapped database with clashing files")
+	}
+	checkDir(t, path, "CURRENT", "000001.ldb", "ancient")
+	checkDir(t, migrated, "000001.sst", "ancient")
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Fatalf("Backup directory not removed: %v", err)
+	}
+}
@@ -0,0 +1,697 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	replayOutputFlag = &cli.StringFlag{
+		Name:  "output",
+		Usage: "Directory to write the fixture, traces and state diffs into",
+		Value: "replay",
+	}
+	replayBlockFlag = &cli.StringFlag{
+		Name:  "block",
+		Usage: "File containing the RLP of the block to replay (binary or hex, e.g. from debug_getBadBlocks)",
+	}
+	replayExpectedFlag = &cli.StringFlag{
+		Name:  "expected",
+		Usage: "File containing the expected per-transaction state diffs to bisect against",
+	}
+	replayExpectedTraceFlag = &cli.StringFlag{
+		Name:  "expected.trace",
+		Usage: "File containing the expected EIP-3155 trace of the diverging transaction",
+	}
+
+	debugCommand = &cli.Command{
+		Name:  "debug",
+		Usage: "Tools for debugging consensus failures",
+		Subcommands: []*cli.Command{
+			replayBadBlockCommand,
+		},
+	}
+	replayBadBlockCommand = &cli.Command{
+		Action:    replayBadBlock,
+		Name:      "replay-bad-block",
+		Usage:     "Replay a block which failed import as a self-contained evm t8n fixture",
+		ArgsUsage: "[<hash>]",
+		Flags: flags.Merge([]cli.Flag{
+			replayOutputFlag,
+			replayBlockFlag,
+			replayExpectedFlag,
+			replayExpectedTraceFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+The replay-bad-block command loads a block which failed import, either from the
+bad blocks stored in the database (the latest one if no hash is given) or from
+the file given by --block, and re-executes it on top of the state of its parent.
+
+It writes a fixture for 'evm t8n' into the output directory, consisting of the
+prestate of all accounts touched by the block (alloc.json), the block environment
+(env.json) and the transactions (txs.rlp). The fixture is then replayed without
+access to the database, writing the EIP-3155 trace of every transaction into
+trace-<index>-<hash>.jsonl and the state diff of every transaction into
+diffs.jsonl, in the same format as 'evm t8n --trace.tracer prestateTracer
+--trace.output'.
+
+If --expected is given, it must contain the expected state diffs, either as the
+result of debug_traceBlockByHash with the prestateTracer in diff mode, or as a
+diffs.jsonl stream. The replay is bisected to the first transaction whose diff
+does not match. If --expected.trace is given too, the trace of that transaction
+is compared against it to find the first diverging opcode.`,
+	}
+)
+
+// beaconRootsHistory is the length of the ring buffers of the EIP-4788 beacon
+// roots contract.
+const beaconRootsHistory = 8191
+
+// replayEnv is the block environment of a replay fixture, in the format of the
+// env file of evm t8n.
+type replayEnv struct {
+	Coinbase              common.Address                      `json:"currentCoinbase"`
+	Difficulty            *math.HexOrDecimal256               `json:"currentDifficulty"`
+	Random                *common.Hash                        `json:"currentRandom,omitempty"`
+	GasLimit              math.HexOrDecimal64                 `json:"currentGasLimit"`
+	Number                math.HexOrDecimal64                 `json:"currentNumber"`
+	Timestamp             math.HexOrDecimal64                 `json:"currentTimestamp"`
+	BaseFee               *math.HexOrDecimal256               `json:"currentBaseFee,omitempty"`
+	ParentTimestamp       math.HexOrDecimal64                 `json:"parentTimestamp"`
+	ParentUncleHash       common.Hash                         `json:"parentUncleHash"`
+	BlockHashes           map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
+	Ommers                []replayOmmer                       `json:"ommers,omitempty"`
+	Withdrawals           []*types.Withdrawal                 `json:"withdrawals,omitempty"`
+	ExcessBlobGas         *math.HexOrDecimal64                `json:"currentExcessBlobGas,omitempty"`
+	ParentBeaconBlockRoot *common.Hash                        `json:"parentBeaconBlockRoot,omitempty"`
+}
+
+// replayOmmer is an uncle of the replayed block, as expected by evm t8n.
+type replayOmmer struct {
+	Delta   uint64         `json:"delta"`
+	Address common.Address `json:"address"`
+}
+
+// replayDiff is the state diff of a single transaction, in the format of the
+// trace output of evm t8n.
+type replayDiff struct {
+	TxIndex int             `json:"txIndex"`
+	TxHash  common.Hash     `json:"txHash"`
+	Result  json.RawMessage `json:"result"`
+}
+
+func replayBadBlock(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, true)
+	defer db.Close()
+
+	block, err := loadReplayBlock(ctx, db)
+	if err != nil {
+		return err
+	}
+	parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return fmt.Errorf("parent %x of block %d not found", block.ParentHash(), block.NumberU64())
+	}
+	log.Info("Replaying block", "number", block.Number(), "hash", block.Hash(), "txs", len(block.Transactions()))
+
+	// Extract the prestate of the block and assemble the fixture
+	alloc, err := replayPrestate(chain, block, parent)
+	if err != nil {
+		return err
+	}
+	env := replayEnvironment(chain, block, parent)
+	txs, err := rlp.EncodeToBytes(block.Transactions())
+	if err != nil {
+		return err
+	}
+	dir := ctx.String(replayOutputFlag.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, obj := range map[string]interface{}{
+		"alloc.json": alloc,
+		"env.json":   env,
+		"txs.rlp":    hexutil.Bytes(txs),
+	} {
+		if err := writeReplayFile(filepath.Join(dir, name), obj); err != nil {
+			return err
+		}
+	}
+	log.Info("Wrote evm t8n fixture", "dir", dir, "accounts", len(alloc))
+	log.Info("Rerun the fixture with", "cmd", fmt.Sprintf("evm t8n --input.alloc=%s --input.env=%s --input.txs=%s --state.fork=%s --state.chainid=%v --state.reward=%v --trace",
+		filepath.Join(dir, "alloc.json"), filepath.Join(dir, "env.json"), filepath.Join(dir, "txs.rlp"),
+		tests.ForkName(chain.Config(), block.Header()), chain.Config().ChainID, replayReward(chain.Config(), block)))
+
+	// Replay the fixture with tracing, collecting the opcode traces and diffs
+	diffs, err := replayFixture(chain, block, alloc, env, dir)
+	if err != nil {
+		log.Warn("Block replay failed", "err", err)
+	}
+	log.Info("Wrote transaction traces", "dir", dir, "txs", len(diffs))
+
+	// Bisect against the expected state diffs if requested
+	if !ctx.IsSet(replayExpectedFlag.Name) {
+		return nil
+	}
+	expected, err := readExpectedDiffs(ctx.String(replayExpectedFlag.Name))
+	if err != nil {
+		return err
+	}
+	index, path, have, want := bisectDiffs(diffs, expected, len(block.Transactions()))
+	if index < 0 {
+		fmt.Println("No diverging transaction found")
+		return nil
+	}
+	if index >= len(block.Transactions()) {
+		fmt.Printf("Diverging transaction count: have %v, want %v\n", have, want)
+		return nil
+	}
+	tx := block.Transactions()[index]
+	fmt.Printf("First diverging transaction %d (%x)\n", index, tx.Hash())
+	fmt.Printf("  at %s: have %v, want %v\n", path, have, want)
+
+	if ctx.IsSet(replayExpectedTraceFlag.Name) {
+		trace := filepath.Join(dir, fmt.Sprintf("trace-%d-%v.jsonl", index, tx.Hash().String()))
+		step, field, have, want, err := bisectTraces(trace, ctx.String(replayExpectedTraceFlag.Name))
+		if err != nil {
+			return err
+		}
+		if step < 0 {
+			fmt.Println("No diverging opcode found")
+		} else {
+			fmt.Printf("First diverging opcode at step %d\n", step)
+			fmt.Printf("  at %s: have %v, want %v\n", field, have, want)
+		}
+	}
+	return nil
+}
+
+// loadReplayBlock retrieves the block to replay, either from the file given on
+// the command line or from the bad blocks stored in the database.
+func loadReplayBlock(ctx *cli.Context, db ethdb.Reader) (*types.Block, error) {
+	if ctx.IsSet(replayBlockFlag.Name) {
+		data, err := os.ReadFile(ctx.String(replayBlockFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		if text := strings.TrimSpace(string(data)); strings.HasPrefix(text, "0x") {
+			if data, err = hexutil.Decode(text); err != nil {
+				return nil, fmt.Errorf("invalid block hex: %v", err)
+			}
+		}
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(data, block); err != nil {
+			return nil, fmt.Errorf("invalid block rlp: %v", err)
+		}
+		return block, nil
+	}
+	if ctx.NArg() == 1 {
+		hash := common.HexToHash(ctx.Args().First())
+		if block := rawdb.ReadBadBlock(db, hash); block != nil {
+			return block, nil
+		}
+		number := rawdb.ReadHeaderNumber(db, hash)
+		if number == nil {
+			return nil, fmt.Errorf("block %x not found", hash)
+		}
+		block := rawdb.ReadBlock(db, hash, *number)
+		if block == nil {
+			return nil, fmt.Errorf("block %x (#%d) body not found", hash, *number)
+		}
+		return block, nil
+	}
+	blocks := rawdb.ReadAllBadBlocks(db)
+	if len(blocks) == 0 {
+		return nil, errors.New("no bad blocks in the database")
+	}
+	return blocks[0], nil
+}
+
+// replayPrestate collects the accounts and storage slots accessed by the block
+// and returns their values in the state of the parent block.
+func replayPrestate(chain *core.BlockChain, block *types.Block, parent *types.Header) (types.GenesisAlloc, error) {
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, fmt.Errorf("parent state missing, it may have been pruned (try an archive node): %v", err)
+	}
+	// Execute the block with the prestate tracer to find all touched slots
+	var (
+		config  = chain.Config()
+		signer  = types.MakeSigner(config, block.Number(), block.Time())
+		touched = make(map[common.Address]map[common.Hash]struct{})
+	)
+	touch := func(addr common.Address, slots ...common.Hash) {
+		if touched[addr] == nil {
+			touched[addr] = make(map[common.Hash]struct{})
+		}
+		for _, slot := range slots {
+			touched[addr][slot] = struct{}{}
+		}
+	}
+	results, err := replayBlock(config, block, core.NewEVMBlockContext(block.Header(), chain, nil), statedb, func(i int, tx *types.Transaction) (*tracers.Tracer, error) {
+		return tracers.DefaultDirectory.New("prestateTracer", &tracers.Context{BlockHash: block.Hash(), BlockNumber: block.Number(), TxIndex: i, TxHash: tx.Hash()}, nil)
+	})
+	if err != nil {
+		log.Warn("Block execution failed", "err", err)
+	}
+	for _, result := range results {
+		var prestate map[common.Address]struct {
+			Storage map[common.Hash]common.Hash `json:"storage"`
+		}
+		if err := json.Unmarshal(result, &prestate); err != nil {
+			return nil, fmt.Errorf("invalid prestate: %v", err)
+		}
+		for addr, account := range prestate {
+			touch(addr)
+			for slot := range account.Storage {
+				touch(addr, slot)
+			}
+		}
+	}
+	// Add the accounts touched outside of transactions
+	for _, tx := range block.Transactions() {
+		if from, err := types.Sender(signer, tx); err == nil {
+			touch(from)
+		}
+		if to := tx.To(); to != nil {
+			touch(*to)
+		}
+	}
+	touch(block.Coinbase())
+	for _, uncle := range block.Uncles() {
+		touch(uncle.Coinbase)
+	}
+	for _, w := range block.Withdrawals() {
+		touch(w.Address)
+	}
+	if block.BeaconRoot() != nil {
+		var timestamp, root common.Hash
+		new(big.Int).SetUint64(block.Time() % beaconRootsHistory).FillBytes(timestamp[:])
+		new(big.Int).SetUint64(block.Time()%beaconRootsHistory + beaconRootsHistory).FillBytes(root[:])
+		touch(params.BeaconRootsAddress, timestamp, root)
+	}
+	// Read the values of all touched slots from the untouched parent state
+	statedb, err = chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	alloc := make(types.GenesisAlloc)
+	for addr, slots := range touched {
+		if !statedb.Exist(addr) {
+			continue
+		}
+		account := types.Account{
+			Balance: statedb.GetBalance(addr).ToBig(),
+			Nonce:   statedb.GetNonce(addr),
+			Code:    statedb.GetCode(addr),
+		}
+		for slot := range slots {
+			if value := statedb.GetState(addr, slot); value != (common.Hash{}) {
+				if account.Storage == nil {
+					account.Storage = make(map[common.Hash]common.Hash)
+				}
+				account.Storage[slot] = value
+			}
+		}
+		alloc[addr] = account
+	}
+	return alloc, nil
+}
+
+// replayEnvironment assembles the block environment of the fixture.
+func replayEnvironment(chain *core.BlockChain, block *types.Block, parent *types.Header) *replayEnv {
+	env := &replayEnv{
+		Coinbase:              block.Coinbase(),
+		Difficulty:            (*math.HexOrDecimal256)(block.Difficulty()),
+		GasLimit:              math.HexOrDecimal64(block.GasLimit()),
+		Number:                math.HexOrDecimal64(block.NumberU64()),
+		Timestamp:             math.HexOrDecimal64(block.Time()),
+		BaseFee:               (*math.HexOrDecimal256)(block.BaseFee()),
+		ParentTimestamp:       math.HexOrDecimal64(parent.Time),
+		ParentUncleHash:       parent.UncleHash,
+		BlockHashes:           make(map[math.HexOrDecimal64]common.Hash),
+		ExcessBlobGas:         (*math.HexOrDecimal64)(block.ExcessBlobGas()),
+		ParentBeaconBlockRoot: block.BeaconRoot(),
+	}
+	if block.Difficulty().Sign() == 0 {
+		random := block.MixDigest()
+		env.Random = &random
+	}
+	for _, uncle := range block.Uncles() {
+		env.Ommers = append(env.Ommers, replayOmmer{
+			Delta:   block.NumberU64() - uncle.Number.Uint64(),
+			Address: uncle.Coinbase,
+		})
+	}
+	if chain.Config().IsShanghai(block.Number(), block.Time()) {
+		env.Withdrawals = append([]*types.Withdrawal{}, block.Withdrawals()...)
+	}
+	// Include the hashes of all ancestors accessible via BLOCKHASH
+	for header := parent; header != nil && block.NumberU64()-header.Number.Uint64() <= 256; {
+		env.BlockHashes[math.HexOrDecimal64(header.Number.Uint64())] = header.Hash()
+		if header.Number.Uint64() == 0 {
+			break
+		}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return env
+}
+
+// replayFixture executes the block on top of the fixture prestate, using only
+// the data contained in the fixture. The EIP-3155 trace of every transaction is
+// written into the output directory, the state diffs are written to diffs.jsonl
+// and returned.
+func replayFixture(chain *core.BlockChain, block *types.Block, alloc types.GenesisAlloc, env *replayEnv, dir string) ([]json.RawMessage, error) {
+	blockCtx := core.NewEVMBlockContext(block.Header(), chain, nil)
+	blockCtx.GetHash = func(n uint64) common.Hash {
+		return env.BlockHashes[math.HexOrDecimal64(n)]
+	}
+	// Trace all opcodes into separate files
+	var traces []*os.File
+	defer func() {
+		for _, f := range traces {
+			f.Close()
+		}
+	}()
+	_, err := replayBlock(chain.Config(), block, blockCtx, replayState(alloc), func(i int, tx *types.Transaction) (*tracers.Tracer, error) {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("trace-%d-%v.jsonl", i, tx.Hash().String())))
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, f)
+		return &tracers.Tracer{
+			Hooks:     logger.NewJSONLogger(&logger.Config{EnableReturnData: true}, f),
+			GetResult: func() (json.RawMessage, error) { return nil, nil },
+			Stop:      func(err error) {},
+		}, nil
+	})
+	if err != nil {
+		log.Warn("Traced block execution failed", "err", err)
+	}
+	// Collect the state diffs of all transactions in a second pass
+	config := json.RawMessage(`{"diffMode": true}`)
+	diffs, err := replayBlock(chain.Config(), block, blockCtx, replayState(alloc), func(i int, tx *types.Transaction) (*tracers.Tracer, error) {
+		return tracers.DefaultDirectory.New("prestateTracer", &tracers.Context{BlockHash: block.Hash(), BlockNumber: block.Number(), TxIndex: i, TxHash: tx.Hash()}, config)
+	})
+	f, ferr := os.Create(filepath.Join(dir, "diffs.jsonl"))
+	if ferr != nil {
+		return nil, ferr
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for i, diff := range diffs {
+		if ferr := enc.Encode(&replayDiff{TxIndex: i, TxHash: block.Transactions()[i].Hash(), Result: diff}); ferr != nil {
+			return nil, ferr
+		}
+	}
+	return diffs, err
+}
+
+// replayState creates an in-memory state database holding the given accounts.
+func replayState(alloc types.GenesisAlloc) *state.StateDB {
+	sdb := state.NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), triedb.HashDefaults)
+	statedb, _ := state.New(types.EmptyRootHash, sdb, nil)
+	for addr, account := range alloc {
+		statedb.SetCode(addr, account.Code)
+		statedb.SetNonce(addr, account.Nonce)
+		statedb.SetBalance(addr, uint256.MustFromBig(account.Balance), tracing.BalanceIncreaseGenesisBalance)
+		for slot, value := range account.Storage {
+			statedb.SetState(addr, slot, value)
+		}
+	}
+	// Commit and re-open to start with a clean state
+	root, _ := statedb.Commit(0, false)
+	statedb, _ = state.New(root, sdb, nil)
+	return statedb
+}
+
+// replayBlock executes the transactions of a block on top of the given state,
+// tracing each of them with a fresh tracer. It returns the results of the
+// tracers up until the first transaction failing to apply.
+func replayBlock(config *params.ChainConfig, block *types.Block, blockCtx vm.BlockContext, statedb *state.StateDB, newTracer func(int, *types.Transaction) (*tracers.Tracer, error)) ([]json.RawMessage, error) {
+	var (
+		header  = block.Header()
+		signer  = types.MakeSigner(config, block.Number(), block.Time())
+		gp      = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+		results []json.RawMessage
+	)
+	core.ProcessBlockPreamble(config, header, vm.NewEVM(blockCtx, vm.TxContext{}, statedb, config, vm.Config{}), statedb)
+	for i, tx := range block.Transactions() {
+		msg, err := core.TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return results, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		tracer, err := newTracer(i, tx)
+		if err != nil {
+			return results, err
+		}
+		statedb.SetTxContext(tx.Hash(), i)
+		statedb.SetLogger(tracer.Hooks)
+
+		vmenv := vm.NewEVM(blockCtx, vm.TxContext{}, statedb, config, vm.Config{Tracer: tracer.Hooks})
+		_, err = core.ApplyTransactionWithEVM(msg, config, gp, statedb, block.Number(), block.Hash(), tx, usedGas, vmenv)
+		statedb.SetLogger(nil)
+
+		result, rerr := tracer.GetResult()
+		if rerr != nil {
+			return results, rerr
+		}
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+	}
+	return results, nil
+}
+
+// replayReward returns the mining reward of the block as expected by evm t8n,
+// or -1 if there is none.
+func replayReward(config *params.ChainConfig, block *types.Block) *big.Int {
+	switch {
+	case block.Difficulty().Sign() == 0 || config.Clique != nil:
+		return big.NewInt(-1)
+	case config.IsConstantinople(block.Number()):
+		return ethash.ConstantinopleBlockReward.ToBig()
+	case config.IsByzantium(block.Number()):
+		return ethash.ByzantiumBlockReward.ToBig()
+	default:
+		return ethash.FrontierBlockReward.ToBig()
+	}
+}
+
+// writeReplayFile writes an object as indented JSON into a file.
+func writeReplayFile(path string, obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// readExpectedDiffs reads the expected state diffs of all transactions, either
+// from a JSON array of trace results or from a stream of them.
+func readExpectedDiffs(path string) ([]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []replayDiff
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("invalid expected diffs: %v", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for dec.More() {
+			var result replayDiff
+			if err := dec.Decode(&result); err != nil {
+				return nil, fmt.Errorf("invalid expected diffs: %v", err)
+			}
+			results = append(results, result)
+		}
+	}
+	diffs := make([]json.RawMessage, len(results))
+	for i, result := range results {
+		diffs[i] = result.Result
+	}
+	return diffs, nil
+}
+
+// bisectDiffs returns the index of the first transaction whose state diff does
+// not match the expected one, along with the first mismatching field. The index
+// is -1 if all diffs match. If more diffs are expected than the block has
+// transactions, the index is the transaction count and the mismatching field
+// is the number of transactions.
+func bisectDiffs(have, want []json.RawMessage, txs int) (int, string, interface{}, interface{}) {
+	for i := 0; i < txs && (i < len(have) || i < len(want)); i++ {
+		var h, w interface{}
+		if i < len(have) {
+			json.Unmarshal(have[i], &h)
+		}
+		if i < len(want) {
+			json.Unmarshal(want[i], &w)
+		}
+		if path, hv, wv, ok := diffJSON(normalizeJSON(h), normalizeJSON(w), "diff"); !ok {
+			return i, path, hv, wv
+		}
+	}
+	if len(want) > txs {
+		return txs, "transactions", txs, len(want)
+	}
+	return -1, "", nil, nil
+}
+
+// bisectTraces compares two EIP-3155 traces and returns the first step where
+// they diverge, along with the mismatching field. The step is -1 if the traces
+// are equal.
+func bisectTraces(have, want string) (int, string, interface{}, interface{}, error) {
+	hf, err := os.Open(have)
+	if err != nil {
+		return 0, "", nil, nil, err
+	}
+	defer hf.Close()
+	wf, err := os.Open(want)
+	if err != nil {
+		return 0, "", nil, nil, err
+	}
+	defer wf.Close()
+
+	var (
+		hs     = bufio.NewScanner(hf)
+		ws     = bufio.NewScanner(wf)
+		fields = []string{"pc", "op", "gas", "gasCost", "depth", "stack", "error", "output", "gasUsed"}
+	)
+	hs.Buffer(nil, 64*1024*1024)
+	ws.Buffer(nil, 64*1024*1024)
+	for step := 0; ; step++ {
+		hok, wok := hs.Scan(), ws.Scan()
+		if !hok && !wok {
+			return -1, "", nil, nil, nil
+		}
+		var h, w map[string]interface{}
+		if hok {
+			json.Unmarshal(hs.Bytes(), &h)
+		}
+		if wok {
+			json.Unmarshal(ws.Bytes(), &w)
+		}
+		if h == nil || w == nil {
+			return step, "step", h, w, nil
+		}
+		for _, field := range fields {
+			if hv, wv := normalizeJSON(h[field]), normalizeJSON(w[field]); !reflect.DeepEqual(hv, wv) {
+				return step, field, hv, wv, nil
+			}
+		}
+	}
+}
+
+// normalizeJSON lowercases all keys and strings of a decoded JSON value, so that
+// checksummed and plain hex values compare equal.
+func normalizeJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[strings.ToLower(key)] = normalizeJSON(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = normalizeJSON(value)
+		}
+		return s
+	case string:
+		return strings.ToLower(v)
+	default:
+		return v
+	}
+}
+
+// diffJSON compares two decoded JSON values and returns the path of the first
+// difference, along with the differing values.
+func diffJSON(have, want interface{}, path string) (string, interface{}, interface{}, bool) {
+	hm, hok := have.(map[string]interface{})
+	wm, wok := want.(map[string]interface{})
+	if !hok || !wok {
+		if !reflect.DeepEqual(have, want) {
+			return path, have, want, false
+		}
+		return "", nil, nil, true
+	}
+	keys := make(map[string]struct{})
+	for key := range hm {
+		keys[key] = struct{}{}
+	}
+	for key := range wm {
+		keys[key] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		if p, h, w, ok := diffJSON(hm[key], wm[key], path+"/"+key); !ok {
+			return p, h, w, false
+		}
+	}
+	return "", nil, nil, true
+}
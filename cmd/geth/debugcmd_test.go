@@ -0,0 +1,223 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// TestReplayBadBlock replays a block on top of an initialized datadir, and checks
+// that the fixture and traces are written and that divergences are bisected.
+func TestReplayBadBlock(t *testing.T) {
+	t.Parallel()
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		gspec    = &core.Genesis{
+			Config:     params.AllDevChainProtocolChanges,
+			GasLimit:   30_000_000,
+			Difficulty: big.NewInt(0),
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				// Increments storage slot 0 on every call
+				contract: {
+					Balance: new(big.Int),
+					Code:    common.FromHex("0x600160005401600055"),
+					Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(5))},
+				},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, beacon.New(ethash.NewFaker()), 1, func(i int, b *core.BlockGen) {
+		b.SetPoS()
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			tx := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+				ChainID:   gspec.Config.ChainID,
+				Nonce:     nonce,
+				To:        &contract,
+				Gas:       100_000,
+				GasFeeCap: b.BaseFee(),
+				GasTipCap: big.NewInt(0),
+			})
+			b.AddTx(tx)
+		}
+		b.AddWithdrawal(&types.Withdrawal{Validator: 42, Address: common.Address{0xee}, Amount: 1})
+	})
+	// Initialize the datadir and write the block to replay
+	var (
+		dir       = t.TempDir()
+		datadir   = filepath.Join(dir, "geth")
+		genesis   = filepath.Join(dir, "genesis.json")
+		blockfile = filepath.Join(dir, "block.rlp")
+		output    = filepath.Join(dir, "replay")
+	)
+	writeJSON(t, genesis, gspec)
+	enc, err := rlp.EncodeToBytes(blocks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blockfile, []byte(hexutil.Encode(enc)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGeth(t, "--datadir", datadir, "init", genesis).WaitExit()
+
+	geth := runGeth(t, "--datadir", datadir, "debug", "replay-bad-block", "--block", blockfile, "--output", output)
+	geth.WaitExit()
+	if status := geth.ExitStatus(); status != 0 {
+		t.Fatalf("replay failed with status %d: %s", status, geth.StderrText())
+	}
+	// Check the fixture and traces
+	var alloc types.GenesisAlloc
+	readJSON(t, filepath.Join(output, "alloc.json"), &alloc)
+	if have, want := alloc[contract].Storage[common.Hash{}], common.BigToHash(big.NewInt(5)); have != want {
+		t.Errorf("wrong prestate slot: have %x, want %x", have, want)
+	}
+	if _, ok := alloc[sender]; !ok {
+		t.Errorf("sender missing from prestate")
+	}
+	for _, name := range []string{"env.json", "txs.rlp"} {
+		if _, err := os.Stat(filepath.Join(output, name)); err != nil {
+			t.Errorf("fixture file %s missing: %v", name, err)
+		}
+	}
+	txs := blocks[0].Transactions()
+	for i, tx := range txs {
+		if _, err := os.Stat(filepath.Join(output, fmt.Sprintf("trace-%d-%v.jsonl", i, tx.Hash().String()))); err != nil {
+			t.Errorf("trace of tx %d missing: %v", i, err)
+		}
+	}
+	diffs, err := os.ReadFile(filepath.Join(output, "diffs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(diffs), "\n"); lines != len(txs) {
+		t.Fatalf("wrong number of diffs: have %d, want %d", lines, len(txs))
+	}
+	// Tamper with the diff and trace of the second transaction and bisect
+	var (
+		expected      = filepath.Join(dir, "expected.jsonl")
+		expectedTrace = filepath.Join(dir, "expected-trace.jsonl")
+		parts         = bytes.SplitAfter(diffs, []byte("\n"))
+	)
+	parts[1] = []byte(strings.Replace(string(parts[1]), common.BigToHash(big.NewInt(7)).Hex(), common.BigToHash(big.NewInt(8)).Hex(), 1))
+	if err := os.WriteFile(expected, bytes.Join(parts, nil), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tamperTrace(t, filepath.Join(output, fmt.Sprintf("trace-1-%v.jsonl", txs[1].Hash().String())), expectedTrace, 3)
+
+	geth = runGeth(t, "--datadir", datadir, "debug", "replay-bad-block", "--block", blockfile, "--output", output,
+		"--expected", expected, "--expected.trace", expectedTrace)
+	geth.ExpectRegexp(fmt.Sprintf(`First diverging transaction 1 \(%x\)`, txs[1].Hash()))
+	geth.ExpectRegexp(`First diverging opcode at step 3`)
+	geth.ExpectRegexp(`at gasCost`)
+	geth.WaitExit()
+}
+
+// Tests that expected diffs beyond the transactions of the block are reported
+// as a mismatch instead of being indexed into the block.
+func TestBisectDiffsExtraExpected(t *testing.T) {
+	t.Parallel()
+	var (
+		diff = json.RawMessage(`{"post":{},"pre":{}}`)
+		have = []json.RawMessage{diff}
+		want = []json.RawMessage{diff, diff}
+	)
+	if index, _, _, _ := bisectDiffs(have, have, 1); index != -1 {
+		t.Fatalf("unexpected divergence at %d", index)
+	}
+	index, path, hv, wv := bisectDiffs(have, want, 1)
+	if index != 1 || path != "transactions" || hv != 1 || wv != 2 {
+		t.Fatalf("wrong divergence: index %d, path %s, have %v, want %v", index, path, hv, wv)
+	}
+}
+
+// tamperTrace copies an EIP-3155 trace, modifying the gas cost of a single step.
+func tamperTrace(t *testing.T, src, dst string, step int) {
+	t.Helper()
+
+	f, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		line := scanner.Bytes()
+		if i == step {
+			var entry map[string]interface{}
+			if err := json.Unmarshal(line, &entry); err != nil {
+				t.Fatal(err)
+			}
+			entry["gasCost"] = "0x0"
+			if line, err = json.Marshal(entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	if err := os.WriteFile(dst, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeJSON(t *testing.T, path string, obj interface{}) {
+	t.Helper()
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readJSON(t *testing.T, path string, obj interface{}) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		t.Fatal(err)
+	}
+}
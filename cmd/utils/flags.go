@@ -30,6 +30,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
 	godebug "runtime/debug"
 	"strconv"
 	"strings"
@@ -277,6 +278,29 @@ var (
 		Value:    ethconfig.Defaults.StateHistory,
 		Category: flags.StateCategory,
 	}
+	StatePruneOnlineFlag = &cli.BoolFlag{
+		Name:     "state.prune.online",
+		Usage:    "Enable background pruning of stale state, only relevant in state.scheme=hash",
+		Category: flags.StateCategory,
+	}
+	StatePruneRetainFlag = &cli.Uint64Flag{
+		Name:     "state.prune.retain",
+		Usage:    "Number of recent blocks whose state is retained by the online pruning (minimum 128)",
+		Value:    ethconfig.Defaults.OnlinePruningRetain,
+		Category: flags.StateCategory,
+	}
+	StatePruneBloomSizeFlag = &cli.Uint64Flag{
+		Name:     "state.prune.bloomsize",
+		Usage:    "Megabytes of memory allocated to the bloom-filter of the online pruning",
+		Value:    ethconfig.Defaults.OnlinePruningBloomSize,
+		Category: flags.StateCategory,
+	}
+	StatePruneIntervalFlag = &cli.DurationFlag{
+		Name:     "state.prune.interval",
+		Usage:    "Time to wait between two consecutive online pruning cycles",
+		Value:    ethconfig.Defaults.OnlinePruningInterval,
+		Category: flags.StateCategory,
+	}
 	TransactionHistoryFlag = &cli.Uint64Flag{
 		Name:     "history.transactions",
 		Usage:    "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
@@ -335,6 +359,22 @@ var (
 		Usage:    "Path to a JWT secret to use for target engine API endpoint",
 		Category: flags.BeaconCategory,
 	}
+	BlsyncLightApiAddrFlag = &cli.StringFlag{
+		Name:     "blsync.lightapi.addr",
+		Usage:    "Listening address of the beacon light client REST API serving the locally verified data",
+		Category: flags.BeaconCategory,
+	}
+	BlsyncProxyUpstreamFlag = &cli.StringFlag{
+		Name:     "blsync.proxy.upstream",
+		Usage:    "Untrusted execution RPC endpoint to serve verified requests from",
+		Category: flags.BeaconCategory,
+	}
+	BlsyncProxyAddrFlag = &cli.StringFlag{
+		Name:     "blsync.proxy.addr",
+		Usage:    "Listening address of the verified execution RPC proxy",
+		Value:    "127.0.0.1:8548",
+		Category: flags.BeaconCategory,
+	}
 	// Transaction pool settings
 	TxPoolLocalsFlag = &cli.StringFlag{
 		Name:     "txpool.locals",
@@ -539,6 +579,11 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	VMParallelFlag = &cli.BoolFlag{
+		Name:     "vmparallel",
+		Usage:    "Execute block transactions optimistically in parallel (experimental)",
+		Category: flags.VMCategory,
+	}
 	VMTraceFlag = &cli.StringFlag{
 		Name:     "vmtrace",
 		Usage:    "Name of tracer which should record internal VM operations (costly)",
@@ -687,6 +732,16 @@ var (
 		Value:    strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 		Category: flags.APICategory,
 	}
+	GraphQLTracingFlag = &cli.BoolFlag{
+		Name:     "graphql.tracing",
+		Usage:    "Enable the transaction tracing fields of GraphQL (native tracers only)",
+		Category: flags.APICategory,
+	}
+	GraphQLTracingJSFlag = &cli.BoolFlag{
+		Name:     "graphql.tracing.js",
+		Usage:    "Allow JavaScript tracers in GraphQL trace queries (requires --graphql.tracing)",
+		Category: flags.APICategory,
+	}
 	WSEnabledFlag = &cli.BoolFlag{
 		Name:     "ws",
 		Usage:    "Enable the WS-RPC server",
@@ -792,7 +847,7 @@ var (
 	}
 	NATFlag = &cli.StringFlag{
 		Name:     "nat",
-		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|pmp:<IP>|extip:<IP>)",
+		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|pmp:<IP>|pcp|pcp:<IP>|extip:<IP>)",
 		Value:    "any",
 		Category: flags.NetworkingCategory,
 	}
@@ -830,6 +885,11 @@ var (
 		Value:    30303,
 		Category: flags.NetworkingCategory,
 	}
+	QUICPortFlag = &cli.IntFlag{
+		Name:     "quic.port",
+		Usage:    "Accept and dial P2P connections over QUIC on this UDP port (disabled by default)",
+		Category: flags.NetworkingCategory,
+	}
 
 	// Console
 	JSpathFlag = &flags.DirectoryFlag{
@@ -1111,6 +1171,9 @@ func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.IsSet(DiscoveryPortFlag.Name) {
 		cfg.DiscAddr = fmt.Sprintf(":%d", ctx.Int(DiscoveryPortFlag.Name))
 	}
+	if ctx.IsSet(QUICPortFlag.Name) {
+		cfg.QUICListenAddr = fmt.Sprintf(":%d", ctx.Int(QUICPortFlag.Name))
+	}
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -1201,6 +1264,15 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.String(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.IsSet(GraphQLTracingFlag.Name) {
+		cfg.GraphQLTracing = ctx.Bool(GraphQLTracingFlag.Name)
+	}
+	if ctx.IsSet(GraphQLTracingJSFlag.Name) {
+		cfg.GraphQLTracingJS = ctx.Bool(GraphQLTracingJSFlag.Name)
+	}
+	if cfg.GraphQLTracingJS && !cfg.GraphQLTracing {
+		log.Warn("Allowing GraphQL JavaScript tracers has no effect without tracing enabled", "flag", GraphQLTracingFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1712,6 +1784,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(StateSchemeFlag.Name) {
 		cfg.StateScheme = ctx.String(StateSchemeFlag.Name)
 	}
+	if ctx.IsSet(StatePruneOnlineFlag.Name) {
+		cfg.OnlinePruning = ctx.Bool(StatePruneOnlineFlag.Name)
+	}
+	if ctx.IsSet(StatePruneRetainFlag.Name) {
+		cfg.OnlinePruningRetain = ctx.Uint64(StatePruneRetainFlag.Name)
+	}
+	if ctx.IsSet(StatePruneBloomSizeFlag.Name) {
+		cfg.OnlinePruningBloomSize = ctx.Uint64(StatePruneBloomSizeFlag.Name)
+	}
+	if ctx.IsSet(StatePruneIntervalFlag.Name) {
+		cfg.OnlinePruningInterval = ctx.Duration(StatePruneIntervalFlag.Name)
+	}
 	// Parse transaction history flag, if user is still using legacy config
 	// file with 'TxLookupLimit' configured, copy the value to 'TransactionHistory'.
 	if cfg.TransactionHistory == ethconfig.Defaults.TransactionHistory && cfg.TxLookupLimit != ethconfig.Defaults.TxLookupLimit {
@@ -1768,6 +1852,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CollectWitnessFlag.Name) {
 		cfg.EnableWitnessCollection = ctx.Bool(CollectWitnessFlag.Name)
 	}
+	if ctx.IsSet(VMParallelFlag.Name) {
+		cfg.ParallelExecution = ctx.Bool(VMParallelFlag.Name)
+	}
 
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
@@ -1956,7 +2043,8 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 
 // RegisterGraphQLService adds the GraphQL API to the node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cfg *node.Config) {
-	err := graphql.New(stack, backend, filterSystem, cfg.GraphQLCors, cfg.GraphQLVirtualHosts)
+	tracing := graphql.TracingConfig{Enabled: cfg.GraphQLTracing, AllowJS: cfg.GraphQLTracingJS}
+	err := graphql.New(stack, backend, filterSystem, cfg.GraphQLCors, cfg.GraphQLVirtualHosts, tracing)
 	if err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
@@ -2187,6 +2275,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	if !ctx.Bool(SnapshotFlag.Name) {
 		cache.SnapshotLimit = 0 // Disabled
 	}
+	if ctx.Bool(VMParallelFlag.Name) {
+		cache.ParallelExecution = runtime.NumCPU()
+	}
 	// If we're in readonly, do not bother generating snapshot data.
 	if readonly {
 		cache.SnapshotNoBuild = true
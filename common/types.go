@@ -463,6 +463,11 @@ func isString(input []byte) bool {
 	return len(input) >= 2 && input[0] == '"' && input[len(input)-1] == '"'
 }
 
+// MarshalText encodes the number in decimal syntax, resulting in a JSON string.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(d), 10)), nil
+}
+
 // UnmarshalJSON parses a hash in hex syntax.
 func (d *Decimal) UnmarshalJSON(input []byte) error {
 	if !isString(input) {
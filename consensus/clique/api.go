@@ -99,19 +99,34 @@ func (api *API) Proposals() map[common.Address]bool {
 	defer api.clique.lock.RUnlock()
 
 	proposals := make(map[common.Address]bool)
-	for address, auth := range api.clique.proposals {
-		proposals[address] = auth
+	for address, proposal := range api.clique.proposals {
+		proposals[address] = proposal.authorize
 	}
 	return proposals
 }
 
 // Propose injects a new authorization proposal that the signer will attempt to
-// push through.
-func (api *API) Propose(address common.Address, auth bool) {
+// push through. The proposal is dropped after the given number of blocks, which
+// defaults to the configured vote expiry if activated (zero meaning never).
+func (api *API) Propose(address common.Address, auth bool, expiry *uint64) {
+	var (
+		head   = api.chain.CurrentHeader().Number.Uint64()
+		blocks uint64
+	)
+	if api.clique.config.IsVoteExpiry(head) {
+		blocks = api.clique.config.VoteExpiry
+	}
+	if expiry != nil {
+		blocks = *expiry
+	}
+	p := &proposal{authorize: auth}
+	if blocks != 0 {
+		p.expiry = head + blocks
+	}
 	api.clique.lock.Lock()
 	defer api.clique.lock.Unlock()
 
-	api.clique.proposals[address] = auth
+	api.clique.proposals[address] = p
 }
 
 // Discard drops a currently running proposal, stopping the signer from casting
@@ -123,6 +138,103 @@ func (api *API) Discard(address common.Address) {
 	delete(api.clique.proposals, address)
 }
 
+// ScheduleRotation appends signer changes to the scheduled rotation. The steps
+// are voted on one after the other, each starting no earlier than its block and
+// only after the previous step passed. A due step takes priority over other
+// proposals until the local signer voted on it, other proposals are voted on
+// while the step is waiting for the votes of the remaining signers.
+func (api *API) ScheduleRotation(steps []RotationStep) error {
+	for i := 1; i < len(steps); i++ {
+		if steps[i].Block < steps[i-1].Block {
+			return fmt.Errorf("rotation step %d scheduled before step %d", i, i-1)
+		}
+	}
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	for i := range steps {
+		step := steps[i]
+		api.clique.rotation = append(api.clique.rotation, &step)
+	}
+	return nil
+}
+
+// GetRotation returns the signer changes of the scheduled rotation which are
+// not yet in effect.
+func (api *API) GetRotation() []RotationStep {
+	api.clique.lock.RLock()
+	defer api.clique.lock.RUnlock()
+
+	steps := make([]RotationStep, 0, len(api.clique.rotation))
+	for _, step := range api.clique.rotation {
+		steps = append(steps, *step)
+	}
+	return steps
+}
+
+// ClearRotation drops all remaining steps of the scheduled rotation.
+func (api *API) ClearRotation() {
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.rotation = nil
+}
+
+// GetProposalHistory retrieves every vote and tally change between the given
+// blocks (defaulting to the genesis and the current block respectively), by
+// walking the checkpoint snapshots backwards.
+func (api *API) GetProposalHistory(from, to *rpc.BlockNumber) ([]*ProposalEvent, error) {
+	// Retrieve the requested block range
+	var header *types.Header
+	if to == nil || *to == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(to.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	first, last := uint64(0), header.Number.Uint64()
+	if from != nil && *from > 0 {
+		first = uint64(from.Int64())
+	}
+	if first > last {
+		return nil, fmt.Errorf("invalid block range %d..%d", first, last)
+	}
+	// Collect the history of each checkpoint window, newest first
+	var (
+		windows [][]*ProposalEvent
+		number  = last
+		hash    = header.Hash()
+	)
+	for number > 0 {
+		snap, err := api.clique.history(api.chain, number, hash)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, snap.History)
+
+		start := (number - 1) / checkpointInterval * checkpointInterval
+		if start < first {
+			break
+		}
+		if header = api.chain.GetHeaderByNumber(start); header == nil {
+			return nil, errUnknownBlock
+		}
+		number, hash = start, header.Hash()
+	}
+	// Flatten the windows in chronological order and filter the requested range
+	events := make([]*ProposalEvent, 0)
+	for i := len(windows) - 1; i >= 0; i-- {
+		for _, event := range windows[i] {
+			if event.Block >= first && event.Block <= last {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
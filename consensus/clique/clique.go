@@ -168,6 +168,19 @@ func ecrecover(header *types.Header, sigcache *sigLRU) (common.Address, error) {
 	return signer, nil
 }
 
+// proposal is a local authorization change the signer is voting for.
+type proposal struct {
+	authorize bool   // Whether to authorize or deauthorize the account
+	expiry    uint64 // Last block number to vote in (0 = never expires)
+}
+
+// RotationStep is a single signer change within a scheduled rotation.
+type RotationStep struct {
+	Address   common.Address `json:"address"`   // Account whose authorization to change
+	Authorize bool           `json:"authorize"` // Whether to authorize or deauthorize the account
+	Block     uint64         `json:"block"`     // Block number from which to start voting on the change
+}
+
 // Clique is the proof-of-authority consensus engine proposed to support the
 // Ethereum testnet following the Ropsten attacks.
 type Clique struct {
@@ -177,11 +190,12 @@ type Clique struct {
 	recents    *lru.Cache[common.Hash, *Snapshot] // Snapshots for recent block to speed up reorgs
 	signatures *sigLRU                            // Signatures of recent blocks to speed up mining
 
-	proposals map[common.Address]bool // Current list of proposals we are pushing
+	proposals map[common.Address]*proposal // Current list of proposals we are pushing
+	rotation  []*RotationStep              // Scheduled signer changes to push through in order
 
 	signer common.Address // Ethereum address of the signing key
 	signFn SignerFn       // Signer function to authorize hashes with
-	lock   sync.RWMutex   // Protects the signer and proposals fields
+	lock   sync.RWMutex   // Protects the signer, proposals and rotation fields
 
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
@@ -204,7 +218,7 @@ func New(config *params.CliqueConfig, db ethdb.Database) *Clique {
 		db:         db,
 		recents:    recents,
 		signatures: signatures,
-		proposals:  make(map[common.Address]bool),
+		proposals:  make(map[common.Address]*proposal),
 	}
 }
 
@@ -446,20 +460,70 @@ func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 	for i := 0; i < len(headers)/2; i++ {
 		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
 	}
-	snap, err := snap.apply(headers)
+	// Apply the headers one checkpoint window at a time, saving every generated
+	// checkpoint snapshot to disk to retain the voting history of each window
+	for len(headers) > 0 {
+		n := uint64(len(headers))
+		if next := checkpointInterval - snap.Number%checkpointInterval; next < n {
+			n = next
+		}
+		var err error
+		if snap, err = snap.apply(headers[:n]); err != nil {
+			return nil, err
+		}
+		headers = headers[n:]
+
+		if snap.Number%checkpointInterval == 0 {
+			if err = snap.store(c.db); err != nil {
+				return nil, err
+			}
+			log.Trace("Stored voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+		}
+	}
+	c.recents.Add(snap.Hash, snap)
+	return snap, nil
+}
+
+// history retrieves the authorization snapshot at a given point in time along
+// with the voting history since the previous checkpoint. If the snapshot was
+// created before history tracking, the window is re-executed from the previous
+// checkpoint snapshot.
+func (c *Clique) history(chain consensus.ChainHeaderReader, number uint64, hash common.Hash) (*Snapshot, error) {
+	snap, err := c.snapshot(chain, number, hash, nil)
 	if err != nil {
 		return nil, err
 	}
+	if snap.History != nil || number == 0 {
+		return snap, nil
+	}
+	// History unknown, gather the headers of the window and re-apply them
+	start := (number - 1) / checkpointInterval * checkpointInterval
+
+	headers := make([]*types.Header, number-start)
+	for i := len(headers) - 1; i >= 0; i-- {
+		header := chain.GetHeader(hash, number)
+		if header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		headers[i] = header
+		number, hash = number-1, header.ParentHash
+	}
+	base, err := c.snapshot(chain, number, hash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if snap, err = base.apply(headers); err != nil {
+		return nil, err
+	}
 	c.recents.Add(snap.Hash, snap)
 
-	// If we've generated a new checkpoint snapshot, save to disk
-	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
+	if snap.Number%checkpointInterval == 0 {
 		if err = snap.store(c.db); err != nil {
 			return nil, err
 		}
-		log.Trace("Stored voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+		log.Debug("Stored voting history to disk", "number", snap.Number, "hash", snap.Hash)
 	}
-	return snap, err
+	return snap, nil
 }
 
 // VerifyUncles implements consensus.Engine, always returning an error for any
@@ -523,29 +587,53 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	if err != nil {
 		return err
 	}
-	c.lock.RLock()
+	c.lock.Lock()
+	// Copy signer protected by mutex to avoid race condition
+	signer := c.signer
+
 	if number%c.config.Epoch != 0 {
-		// Gather all the proposals that make sense voting on
-		addresses := make([]common.Address, 0, len(c.proposals))
-		for address, authorize := range c.proposals {
-			if snap.validVote(address, authorize) {
-				addresses = append(addresses, address)
+		// Drop any proposals which expired
+		for address, proposal := range c.proposals {
+			if proposal.expiry != 0 && number > proposal.expiry {
+				log.Info("Clique proposal expired", "address", address, "authorize", proposal.authorize, "expiry", proposal.expiry)
+				delete(c.proposals, address)
 			}
 		}
-		// If there's pending proposals, cast a vote on them
-		if len(addresses) > 0 {
-			header.Coinbase = addresses[rand.Intn(len(addresses))]
-			if c.proposals[header.Coinbase] {
+		// Drop any rotation steps which are already in effect
+		for len(c.rotation) > 0 && !snap.validVote(c.rotation[0].Address, c.rotation[0].Authorize) {
+			log.Info("Clique rotation step completed", "address", c.rotation[0].Address, "authorize", c.rotation[0].Authorize)
+			c.rotation = c.rotation[1:]
+		}
+		// If a scheduled rotation step is due and we haven't voted on it yet,
+		// vote on it. Otherwise (also while a step we voted on is waiting for
+		// the other signers) gather all the proposals that make sense voting on
+		// and cast a random vote.
+		if len(c.rotation) > 0 && number >= c.rotation[0].Block && !snap.hasVote(signer, c.rotation[0].Address, c.rotation[0].Authorize) {
+			header.Coinbase = c.rotation[0].Address
+			if c.rotation[0].Authorize {
 				copy(header.Nonce[:], nonceAuthVote)
 			} else {
 				copy(header.Nonce[:], nonceDropVote)
 			}
+		} else {
+			addresses := make([]common.Address, 0, len(c.proposals))
+			for address, proposal := range c.proposals {
+				if snap.validVote(address, proposal.authorize) {
+					addresses = append(addresses, address)
+				}
+			}
+			// If there's pending proposals, cast a vote on them
+			if len(addresses) > 0 {
+				header.Coinbase = addresses[rand.Intn(len(addresses))]
+				if c.proposals[header.Coinbase].authorize {
+					copy(header.Nonce[:], nonceAuthVote)
+				} else {
+					copy(header.Nonce[:], nonceDropVote)
+				}
+			}
 		}
 	}
-
-	// Copy signer protected by mutex to avoid race condition
-	signer := c.signer
-	c.lock.RUnlock()
+	c.lock.Unlock()
 
 	// Set the correct difficulty
 	header.Difficulty = calcDifficulty(snap, signer)
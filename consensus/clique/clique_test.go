@@ -17,7 +17,9 @@
 package clique
 
 import (
+	"bytes"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -26,7 +28,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // This test case is a repro of an annoying bug that took us forever to catch.
@@ -123,3 +127,166 @@ func TestSealHash(t *testing.T) {
 		t.Errorf("have %x, want %x", have, want)
 	}
 }
+
+// newVotingChain creates a chain signed in turns by the given accounts, with only
+// the first one authorized in the genesis and the votes cast specified by number.
+func newVotingChain(t *testing.T, db ethdb.Database, accounts *testerAccountPool, signers []string, votes map[uint64]testerVote, length int) (*Clique, *core.BlockChain) {
+	genesis := &core.Genesis{
+		Config:    params.AllCliqueProtocolChanges,
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	accounts.checkpoint(&types.Header{Extra: genesis.ExtraData}, signers[:1])
+	engine := New(genesis.Config.Clique, db)
+	engine.fakeDiff = true
+
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, engine, length, func(i int, gen *core.BlockGen) {
+		vote := votes[uint64(i+1)]
+		gen.SetCoinbase(accounts.address(vote.voted))
+		if vote.auth {
+			var nonce types.BlockNonce
+			copy(nonce[:], nonceAuthVote)
+			gen.SetNonce(nonce)
+		}
+	})
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn
+
+		accounts.sign(header, signers[i%len(signers)])
+		blocks[i] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	return engine, chain
+}
+
+// Tests that the voting history is tracked across checkpoint windows, and that
+// it can be reconstructed for snapshots which were stored without history.
+func TestProposalHistory(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		accounts = newTesterAccountPool()
+		votes    = map[uint64]testerVote{
+			1:    {voted: "B", auth: true},
+			1030: {voted: "C", auth: true},
+		}
+	)
+	engine, chain := newVotingChain(t, db, accounts, []string{"A", "B"}, votes, 1030)
+	defer chain.Stop()
+
+	block := chain.GetBlockByNumber(1)
+	want := []*ProposalEvent{
+		{Block: 1, Hash: block.Hash(), Kind: ProposalVoted, Signer: accounts.address("A"), Address: accounts.address("B"), Authorize: true, Tally: 1},
+		{Block: 1, Hash: block.Hash(), Kind: ProposalPassed, Signer: accounts.address("A"), Address: accounts.address("B"), Authorize: true, Tally: 1},
+		{Block: 1030, Hash: chain.CurrentBlock().Hash(), Kind: ProposalVoted, Signer: accounts.address("B"), Address: accounts.address("C"), Authorize: true, Tally: 1},
+	}
+	api := &API{chain: chain, clique: engine}
+	if have, err := api.GetProposalHistory(nil, nil); err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	} else if !reflect.DeepEqual(have, want) {
+		t.Fatalf("history mismatch: have %v, want %v", have, want)
+	}
+	from := rpc.BlockNumber(2)
+	if have, err := api.GetProposalHistory(&from, nil); err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	} else if !reflect.DeepEqual(have, want[2:]) {
+		t.Fatalf("ranged history mismatch: have %v, want %v", have, want[2:])
+	}
+	// Drop the history from the stored checkpoint and ensure it's reconstructed
+	checkpoint := chain.GetHeaderByNumber(checkpointInterval).Hash()
+	snap, err := loadSnapshot(engine.config, engine.signatures, db, checkpoint)
+	if err != nil {
+		t.Fatalf("failed to load checkpoint snapshot: %v", err)
+	}
+	if len(snap.History) != 2 {
+		t.Fatalf("checkpoint history mismatch: have %d events, want 2", len(snap.History))
+	}
+	snap.History = nil
+	if err := snap.store(db); err != nil {
+		t.Fatalf("failed to store checkpoint snapshot: %v", err)
+	}
+	api = &API{chain: chain, clique: New(engine.config, db)}
+	if have, err := api.GetProposalHistory(nil, nil); err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	} else if !reflect.DeepEqual(have, want) {
+		t.Fatalf("reconstructed history mismatch: have %v, want %v", have, want)
+	}
+	if snap, _ = loadSnapshot(engine.config, engine.signatures, db, checkpoint); len(snap.History) != 2 {
+		t.Fatalf("reconstructed history not persisted: have %d events, want 2", len(snap.History))
+	}
+}
+
+// Tests that expired proposals are dropped and scheduled rotations are voted on
+// in order, taking precedence over other proposals.
+func TestProposalRotation(t *testing.T) {
+	accounts := newTesterAccountPool()
+	engine, chain := newVotingChain(t, rawdb.NewMemoryDatabase(), accounts, []string{"A", "B"}, map[uint64]testerVote{1: {voted: "B", auth: true}, 3: {voted: "A"}}, 3)
+	defer chain.Stop()
+
+	var (
+		api     = &API{chain: chain, clique: engine}
+		parent  = chain.CurrentHeader()
+		prepare = func() *types.Header {
+			header := &types.Header{Number: new(big.Int).Add(parent.Number, common.Big1), ParentHash: parent.Hash()}
+			if err := engine.Prepare(chain, header); err != nil {
+				t.Fatalf("failed to prepare header: %v", err)
+			}
+			return header
+		}
+	)
+	// Proposals expire after the requested number of blocks
+	expiry := uint64(0)
+	api.Propose(accounts.address("C"), true, &expiry)
+	if header := prepare(); header.Coinbase != accounts.address("C") {
+		t.Fatalf("proposal not voted on: have %x, want %x", header.Coinbase, accounts.address("C"))
+	}
+	engine.proposals[accounts.address("C")].expiry = 2
+	if header := prepare(); header.Coinbase != (common.Address{}) {
+		t.Fatalf("expired proposal voted on: have %x", header.Coinbase)
+	}
+	if len(api.Proposals()) != 0 {
+		t.Fatalf("expired proposal not dropped: %v", api.Proposals())
+	}
+	// Rotation steps already in effect are skipped, due ones take precedence
+	api.Propose(accounts.address("C"), true, nil)
+	err := api.ScheduleRotation([]RotationStep{
+		{Address: accounts.address("B"), Authorize: true},
+		{Address: accounts.address("A"), Authorize: false, Block: 3},
+		{Address: accounts.address("D"), Authorize: true, Block: 10},
+	})
+	if err != nil {
+		t.Fatalf("failed to schedule rotation: %v", err)
+	}
+	header := prepare()
+	if header.Coinbase != accounts.address("A") || !bytes.Equal(header.Nonce[:], nonceDropVote) {
+		t.Fatalf("rotation step not voted on: have %x/%x, want %x", header.Coinbase, header.Nonce, accounts.address("A"))
+	}
+	if rotation := api.GetRotation(); len(rotation) != 2 {
+		t.Fatalf("rotation length mismatch: have %d, want 2", len(rotation))
+	}
+	if err := api.ScheduleRotation([]RotationStep{{Block: 2}, {Block: 1}}); err == nil {
+		t.Fatalf("unordered rotation accepted")
+	}
+	// Once the local signer voted on the due step, other proposals are let through
+	engine.signer = accounts.address("A")
+	if header := prepare(); header.Coinbase != accounts.address("C") {
+		t.Fatalf("proposal not voted on while waiting for rotation: have %x, want %x", header.Coinbase, accounts.address("C"))
+	}
+	if rotation := api.GetRotation(); len(rotation) != 2 {
+		t.Fatalf("rotation length mismatch: have %d, want 2", len(rotation))
+	}
+	api.ClearRotation()
+	if header := prepare(); header.Coinbase != accounts.address("C") {
+		t.Fatalf("proposal not voted on after rotation: have %x, want %x", header.Coinbase, accounts.address("C"))
+	}
+}
@@ -48,6 +48,26 @@ type Tally struct {
 	Votes     int  `json:"votes"`     // Number of votes until now wanting to pass the proposal
 }
 
+// Kinds of events recorded in the proposal history.
+const (
+	ProposalVoted   = "vote"    // A signer cast a vote on a proposal
+	ProposalPassed  = "passed"  // A proposal reached majority and was applied
+	ProposalExpired = "expired" // A vote was discarded after the configured expiry
+	ProposalReset   = "reset"   // A vote was discarded at an epoch transition
+)
+
+// ProposalEvent is a single entry of the voting history, recording how a vote
+// changed the tally of a proposal.
+type ProposalEvent struct {
+	Block     uint64         `json:"block"`     // Block number the event happened in
+	Hash      common.Hash    `json:"hash"`      // Block hash the event happened in
+	Kind      string         `json:"kind"`      // Type of the event (vote, passed, expired, reset)
+	Signer    common.Address `json:"signer"`    // Authorized signer that cast the vote
+	Address   common.Address `json:"address"`   // Account being voted on to change its authorization
+	Authorize bool           `json:"authorize"` // Whether to authorize or deauthorize the voted account
+	Tally     int            `json:"tally"`     // Number of votes on the proposal after the event
+}
+
 type sigLRU = lru.Cache[common.Hash, common.Address]
 
 // Snapshot is the state of the authorization voting at a given point in time.
@@ -61,6 +81,7 @@ type Snapshot struct {
 	Recents map[uint64]common.Address   `json:"recents"` // Set of recent signers for spam protections
 	Votes   []*Vote                     `json:"votes"`   // List of votes cast in chronological order
 	Tally   map[common.Address]Tally    `json:"tally"`   // Current vote tally to avoid recalculating
+	History []*ProposalEvent            `json:"history"` // Voting events since the last checkpoint (nil if unknown)
 }
 
 // newSnapshot creates a new snapshot with the specified startup parameters. This
@@ -75,6 +96,7 @@ func newSnapshot(config *params.CliqueConfig, sigcache *sigLRU, number uint64, h
 		Signers:  make(map[common.Address]struct{}),
 		Recents:  make(map[uint64]common.Address),
 		Tally:    make(map[common.Address]Tally),
+		History:  []*ProposalEvent{},
 	}
 	for _, signer := range signers {
 		snap.Signers[signer] = struct{}{}
@@ -107,7 +129,8 @@ func (s *Snapshot) store(db ethdb.Database) error {
 	return db.Put(append(rawdb.CliqueSnapshotPrefix, s.Hash[:]...), blob)
 }
 
-// copy creates a deep copy of the snapshot, though not the individual votes.
+// copy creates a deep copy of the snapshot, though not the individual votes
+// and history events.
 func (s *Snapshot) copy() *Snapshot {
 	return &Snapshot{
 		config:   s.config,
@@ -118,6 +141,7 @@ func (s *Snapshot) copy() *Snapshot {
 		Recents:  maps.Clone(s.Recents),
 		Votes:    slices.Clone(s.Votes),
 		Tally:    maps.Clone(s.Tally),
+		History:  slices.Clone(s.History),
 	}
 }
 
@@ -165,6 +189,33 @@ func (s *Snapshot) uncast(address common.Address, authorize bool) bool {
 	return true
 }
 
+// record appends an event to the voting history, unless the history of the
+// current checkpoint window is unknown (snapshot predating history tracking).
+func (s *Snapshot) record(header *types.Header, kind string, signer common.Address, address common.Address, authorize bool) {
+	if s.History == nil {
+		return
+	}
+	s.History = append(s.History, &ProposalEvent{
+		Block:     header.Number.Uint64(),
+		Hash:      header.Hash(),
+		Kind:      kind,
+		Signer:    signer,
+		Address:   address,
+		Authorize: authorize,
+		Tally:     s.Tally[address].Votes,
+	})
+}
+
+// hasVote returns whether the signer has a pending vote on the given proposal.
+func (s *Snapshot) hasVote(signer, address common.Address, authorize bool) bool {
+	for _, vote := range s.Votes {
+		if vote.Signer == signer && vote.Address == address && vote.Authorize == authorize {
+			return true
+		}
+	}
+	return false
+}
+
 // apply creates a new authorization snapshot by applying the given headers to
 // the original one.
 func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
@@ -189,12 +240,36 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		logged = time.Now()
 	)
 	for i, header := range headers {
-		// Remove any votes on checkpoint blocks
+		// Start a new voting history at checkpoint window boundaries
 		number := header.Number.Uint64()
+		if (number-1)%checkpointInterval == 0 {
+			snap.History = []*ProposalEvent{}
+		}
+		// Remove any votes on checkpoint blocks
 		if number%s.config.Epoch == 0 {
+			for _, vote := range snap.Votes {
+				snap.uncast(vote.Address, vote.Authorize)
+				snap.record(header, ProposalReset, vote.Signer, vote.Address, vote.Authorize)
+			}
 			snap.Votes = nil
 			snap.Tally = make(map[common.Address]Tally)
 		}
+		// Remove any votes that have been pending for too long, once vote
+		// expiry is activated
+		if s.config.IsVoteExpiry(number) {
+			expiry := s.config.VoteExpiry
+			for i := 0; i < len(snap.Votes); i++ {
+				if vote := snap.Votes[i]; number-vote.Block >= expiry {
+					// Uncast the vote from the cached tally
+					snap.uncast(vote.Address, vote.Authorize)
+					snap.record(header, ProposalExpired, vote.Signer, vote.Address, vote.Authorize)
+
+					// Uncast the vote from the chronological list
+					snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+					i--
+				}
+			}
+		}
 		// Delete the oldest signer from the recent list to allow it signing again
 		if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
 			delete(snap.Recents, number-limit)
@@ -242,9 +317,12 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 				Address:   header.Coinbase,
 				Authorize: authorize,
 			})
+			snap.record(header, ProposalVoted, signer, header.Coinbase, authorize)
 		}
 		// If the vote passed, update the list of signers
 		if tally := snap.Tally[header.Coinbase]; tally.Votes > len(snap.Signers)/2 {
+			snap.record(header, ProposalPassed, signer, header.Coinbase, tally.Authorize)
+
 			if tally.Authorize {
 				snap.Signers[header.Coinbase] = struct{}{}
 			} else {
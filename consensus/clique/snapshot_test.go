@@ -98,6 +98,8 @@ type testerVote struct {
 
 type cliqueTest struct {
 	epoch   uint64
+	expiry  uint64
+	active  uint64 // Block from which votes expire
 	signers []string
 	votes   []testerVote
 	results []string
@@ -378,6 +380,39 @@ func TestClique(t *testing.T) {
 				{signer: "A", newbatch: true},
 			},
 			failure: errRecentlySigned,
+		}, {
+			// Three signers, the votes of two of them being enough to add a fourth
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "C"},
+				{signer: "B", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C", "D"},
+		}, {
+			// Three signers, the same votes not being enough if the first one expires
+			expiry:  2,
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "C"},
+				{signer: "B", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C"},
+		}, {
+			// Three signers, vote expiry not affecting votes before its activation
+			expiry:  2,
+			active:  5,
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "C"},
+				{signer: "B", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C", "D"},
 		},
 	}
 
@@ -414,8 +449,12 @@ func (tt *cliqueTest) run(t *testing.T) {
 	// Assemble a chain of headers from the cast votes
 	config := *params.TestChainConfig
 	config.Clique = &params.CliqueConfig{
-		Period: 1,
-		Epoch:  tt.epoch,
+		Period:     1,
+		Epoch:      tt.epoch,
+		VoteExpiry: tt.expiry,
+	}
+	if tt.expiry != 0 {
+		config.Clique.VoteExpiryBlock = new(big.Int).SetUint64(tt.active)
 	}
 	genesis.Config = &config
 
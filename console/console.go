@@ -28,6 +28,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/ethereum/go-ethereum/console/prompt"
@@ -228,14 +229,21 @@ func (c *Console) initExtensions() error {
 		}
 	}
 
-	// Apply aliases.
+	// Apply aliases, and expose promise based variants of the namespaces under
+	// web3.async for use with async/await.
 	c.jsre.Do(func(vm *goja.Runtime) {
 		web3 := getObject(vm, "web3")
+		asyncify, _ := goja.AssertFunction(vm.Get("asyncify"))
+		async := vm.NewObject()
 		for name := range aliases {
 			if v := web3.Get(name); v != nil {
 				vm.Set(name, v)
+				if wrapped, err := asyncify(goja.Undefined(), v); err == nil {
+					async.Set(name, wrapped)
+				}
 			}
 		}
+		web3.Set("async", async)
 	})
 	return nil
 }
@@ -544,6 +552,12 @@ func countIndents(input string) int {
 	return indents
 }
 
+// RunTests executes a test script, returning the results of the tests it
+// registered. See jsre.RunTests for details.
+func (c *Console) RunTests(path string, timeout time.Duration) ([]jsre.TestResult, error) {
+	return c.jsre.RunTests(path, timeout)
+}
+
 // Stop cleans up the console and terminates the runtime environment.
 func (c *Console) Stop(graceful bool) error {
 	c.stopOnce.Do(func() {
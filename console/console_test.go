@@ -230,6 +230,29 @@ func TestPreload(t *testing.T) {
 	}
 }
 
+// Tests that test scripts can require modules, use the asynchronous RPC wrappers
+// and report failures.
+func TestRunTests(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	results, err := tester.console.RunTests("rpctest.js", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to run test script: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("wrong number of results: have %d, want 3", len(results))
+	}
+	for i, result := range results[:2] {
+		if result.Err != nil {
+			t.Errorf("test %d (%s) failed: %v", i, result.Name, result.Err)
+		}
+	}
+	if results[2].Err == nil {
+		t.Errorf("test %s succeeded, want failure", results[2].Name)
+	}
+}
+
 // Tests that the JavaScript objects returned by statement executions are properly
 // pretty printed instead of just displaying "[object]".
 func TestPrettyPrint(t *testing.T) {
@@ -0,0 +1,17 @@
+var lib = require('./testlib');
+
+test("sync rpc", function() {
+	assert(lib.isZero(eth.blockNumber), "block number not zero");
+});
+
+test("async rpc", async function() {
+	var number = await web3.async.eth.getBlockNumber();
+	assert.equal(number, 0);
+
+	var block = await web3.async.eth.getBlock(number);
+	assert.equal(block.number, 0);
+});
+
+test("failing rpc", async function() {
+	await web3.async.eth.getBlock("0xnonsense");
+});
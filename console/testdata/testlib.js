@@ -0,0 +1,6 @@
+// Helpers shared by the console test scripts.
+var BigNumber = require('bignumber.js');
+
+exports.isZero = function(number) {
+	return new BigNumber(number).isZero();
+};
@@ -141,6 +141,8 @@ type CacheConfig struct {
 	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
 	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
 
+	ParallelExecution int // Number of threads for optimistic parallel transaction execution (0 = disabled)
+
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -313,7 +315,14 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
 	bc.validator = NewBlockValidator(chainConfig, bc)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc.hc)
-	bc.processor = NewStateProcessor(chainConfig, bc.hc)
+	if cacheConfig.ParallelExecution > 1 {
+		bc.processor = NewParallelStateProcessor(chainConfig, bc.hc, cacheConfig.ParallelExecution)
+	} else {
+		if cacheConfig.ParallelExecution == 1 {
+			log.Warn("Parallel execution needs at least two threads, processing transactions serially")
+		}
+		bc.processor = NewStateProcessor(chainConfig, bc.hc)
+	}
 
 	bc.genesisBlock = bc.GetBlockByNumber(0)
 	if bc.genesisBlock == nil {
@@ -0,0 +1,279 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	parallelSpeculativeMeter = metrics.NewRegisteredMeter("chain/parallel/speculative", nil)
+	parallelReexecuteMeter   = metrics.NewRegisteredMeter("chain/parallel/reexecuted", nil)
+)
+
+// speculation is the outcome of executing a transaction optimistically on top
+// of the block's pre-state, in isolation from the other transactions.
+type speculation struct {
+	msg    *Message
+	msgErr error // Error converting the transaction into a message
+
+	state  *state.StateDB   // Private state the transaction was executed on
+	access *txAccess        // State items read and written by the transaction
+	result *ExecutionResult // Execution result, nil if the transaction failed
+	err    error            // Consensus error of the speculative execution
+
+	done chan struct{} // Closed when the speculative execution finishes
+}
+
+// ParallelStateProcessor is a Processor which executes the transactions of a
+// block optimistically in parallel.
+//
+// Every transaction is first executed speculatively on its own copy of the
+// block's pre-state, recording the accounts and storage slots it reads and
+// writes. The speculative results are then validated and committed strictly in
+// block order: a result is only accepted if none of the state it read has been
+// modified by the transactions committed before it, otherwise the transaction
+// is re-executed on top of the canonical state. The receipts and state produced
+// are thus identical to the ones of the sequential StateProcessor.
+//
+// ParallelStateProcessor implements Processor.
+type ParallelStateProcessor struct {
+	config  *params.ChainConfig // Chain configuration options
+	chain   *HeaderChain        // Canonical header chain
+	workers int                 // Number of speculative execution threads
+
+	sequential *StateProcessor // Fallback processor for unsupported setups
+}
+
+// NewParallelStateProcessor initialises a new ParallelStateProcessor, executing
+// transactions speculatively on the given number of threads.
+func NewParallelStateProcessor(config *params.ChainConfig, chain *HeaderChain, workers int) *ParallelStateProcessor {
+	return &ParallelStateProcessor{
+		config:     config,
+		chain:      chain,
+		workers:    workers,
+		sequential: NewStateProcessor(config, chain),
+	}
+}
+
+// Process processes the state changes according to the Ethereum rules by running
+// the transaction messages using the statedb and applying any rewards to both
+// the processor (coinbase) and any included uncles.
+//
+// Process returns the receipts and logs accumulated during the process and
+// returns the amount of gas that was used in the process. If any of the
+// transactions failed to execute due to insufficient gas it will return an error.
+func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	// Tracing requires the hooks to be invoked in execution order and witness
+	// collection requires all state accesses to go through the canonical state,
+	// fall back to sequential processing for both.
+	if p.workers < 2 || len(block.Transactions()) < 2 || cfg.Tracer != nil || statedb.Witness() != nil {
+		return p.sequential.Process(block, statedb, cfg)
+	}
+	var (
+		receipts    types.Receipts
+		usedGas     = new(uint64)
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		allLogs     []*types.Log
+		gp          = new(GasPool).AddGas(block.GasLimit())
+		byzantium   = p.config.IsByzantium(blockNumber)
+		eip158      = p.config.IsEIP158(blockNumber)
+	)
+	context := NewEVMBlockContext(header, p.chain, nil)
+	vmenv := vm.NewEVM(context, vm.TxContext{}, statedb, p.config, cfg)
+
+	// Mutate the block and state according to any hard-fork specs
+	ProcessBlockPreamble(p.config, header, vmenv, statedb)
+
+	// Start executing all the transactions speculatively in the background
+	var (
+		interrupt atomic.Bool
+		specs     = p.speculate(block, statedb.Copy(), cfg, &interrupt)
+		written   = newBlockWrites()
+	)
+	defer interrupt.Store(true)
+
+	// Validate and commit the transactions in order, re-executing any whose
+	// speculative execution has been invalidated
+	for i, tx := range block.Transactions() {
+		spec := specs[i]
+		<-spec.done
+		if spec.msgErr != nil {
+			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), spec.msgErr)
+		}
+		statedb.SetTxContext(tx.Hash(), i)
+
+		var (
+			result *ExecutionResult
+			access *txAccess
+		)
+		if spec.err == nil && gp.Gas() >= spec.msg.GasLimit && !written.conflicts(spec.access) {
+			applySpeculation(statedb, tx, spec)
+			gp.SubGas(spec.msg.GasLimit)
+			gp.AddGas(spec.msg.GasLimit - spec.result.UsedGas)
+			result, access = spec.result, spec.access
+			parallelSpeculativeMeter.Mark(1)
+		} else {
+			recorder := newAccessRecorder(statedb)
+			vmenv.Reset(NewEVMTxContext(spec.msg), recorder)
+
+			var err error
+			result, err = ApplyMessage(vmenv, spec.msg, gp)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			access = recorder.access
+			parallelReexecuteMeter.Mark(1)
+		}
+		// Update the state with pending changes and assemble the receipt
+		var root []byte
+		if byzantium {
+			statedb.Finalise(true)
+		} else {
+			root = statedb.IntermediateRoot(eip158).Bytes()
+		}
+		*usedGas += result.UsedGas
+		written.add(access, statedb)
+
+		vmenv.Reset(NewEVMTxContext(spec.msg), statedb)
+		receipt := MakeReceipt(vmenv, result, statedb, blockNumber, blockHash, tx, *usedGas, root)
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	// Fail if Shanghai not enabled and len(withdrawals) is non-zero.
+	withdrawals := block.Withdrawals()
+	if len(withdrawals) > 0 && !p.config.IsShanghai(block.Number(), block.Time()) {
+		return nil, nil, 0, errors.New("withdrawals before shanghai")
+	}
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	p.chain.engine.Finalize(p.chain, header, statedb, block.Body())
+
+	return receipts, allLogs, *usedGas, nil
+}
+
+// speculate starts executing all the transactions of the block on top of the
+// given pre-state, each in isolation of the others. Transactions are scheduled
+// in block order, until the interrupt flag is raised.
+func (p *ParallelStateProcessor) speculate(block *types.Block, base *state.StateDB, cfg vm.Config, interrupt *atomic.Bool) []*speculation {
+	var (
+		header      = block.Header()
+		txs         = block.Transactions()
+		signer      = types.MakeSigner(p.config, header.Number, header.Time)
+		deleteEmpty = p.config.IsByzantium(header.Number) || p.config.IsEIP158(header.Number)
+		specs       = make([]*speculation, len(txs))
+		next        = make(chan int)
+	)
+	for i := range specs {
+		specs[i] = &speculation{done: make(chan struct{})}
+	}
+	go func() {
+		defer close(next)
+		for i := range specs {
+			if interrupt.Load() {
+				return
+			}
+			next <- i
+		}
+	}()
+	workers := min(p.workers, len(txs))
+	for w := 0; w < workers; w++ {
+		go func() {
+			// The block context caches block hashes internally, so every
+			// worker needs its own one.
+			evm := vm.NewEVM(NewEVMBlockContext(header, p.chain, nil), vm.TxContext{}, base, p.config, cfg)
+			for i := range next {
+				spec := specs[i]
+				spec.msg, spec.msgErr = TransactionToMessage(txs[i], signer, header.BaseFee)
+				if spec.msgErr == nil {
+					spec.state = base.Copy()
+					spec.state.SetTxContext(txs[i].Hash(), i)
+
+					recorder := newAccessRecorder(spec.state)
+					evm.Reset(NewEVMTxContext(spec.msg), recorder)
+
+					spec.access = recorder.access
+					spec.result, spec.err = ApplyMessage(evm, spec.msg, new(GasPool).AddGas(block.GasLimit()))
+					if spec.err == nil {
+						spec.err = spec.state.Error()
+					}
+					if spec.err == nil {
+						spec.state.Finalise(deleteEmpty)
+					}
+				}
+				close(spec.done)
+			}
+		}()
+	}
+	return specs
+}
+
+// applySpeculation transfers the state changes of a validated speculative
+// execution into the canonical state. As the transaction didn't read anything
+// modified since the pre-state, the values it wrote are final.
+func applySpeculation(statedb *state.StateDB, tx *types.Transaction, spec *speculation) {
+	for addr := range spec.access.writes {
+		if spec.access.feeOnly(addr) {
+			statedb.AddBalance(addr, spec.access.fees[addr], tracing.BalanceIncreaseRewardTransactionFee)
+			continue
+		}
+		if !spec.state.Exist(addr) {
+			// The account was destructed or deleted as an empty one
+			if statedb.Exist(addr) {
+				statedb.SelfDestruct(addr)
+			}
+			continue
+		}
+		if !statedb.Exist(addr) {
+			statedb.CreateAccount(addr)
+		}
+		statedb.SetBalance(addr, spec.state.GetBalance(addr), tracing.BalanceChangeUnspecified)
+		if nonce := spec.state.GetNonce(addr); nonce != statedb.GetNonce(addr) {
+			statedb.SetNonce(addr, nonce)
+		}
+		if hash := spec.state.GetCodeHash(addr); hash != statedb.GetCodeHash(addr) {
+			statedb.SetCode(addr, spec.state.GetCode(addr))
+		}
+	}
+	for addr, slots := range spec.access.writeSlots {
+		if !spec.state.Exist(addr) {
+			continue
+		}
+		for key := range slots {
+			if value := spec.state.GetState(addr, key); value != statedb.GetState(addr, key) {
+				statedb.SetState(addr, key, value)
+			}
+		}
+	}
+	for _, log := range spec.state.GetLogs(tx.Hash(), 0, common.Hash{}) {
+		statedb.AddLog(log)
+	}
+	for hash, preimage := range spec.state.Preimages() {
+		statedb.AddPreimage(hash, preimage)
+	}
+}
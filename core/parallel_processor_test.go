@@ -0,0 +1,229 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// counterCode increments the value of storage slot 0 (conflicting)
+	counterCode = common.FromHex("600054600101600055")
+
+	// callerCounterCode increments the storage slot of the caller (independent)
+	callerCounterCode = common.FromHex("33546001013355")
+
+	// coinbaseReaderCode stores the coinbase balance into the slot of the caller
+	coinbaseReaderCode = common.FromHex("41313355")
+
+	// loggerCode emits a log with the caller as its topic
+	loggerCode = common.FromHex("3360006000a1")
+
+	// destructCode self-destructs, sending its funds to the caller
+	destructCode = common.FromHex("33ff")
+
+	// deployCode deploys a contract with the code 0x00
+	deployCode = common.FromHex("6000600053600160006000f0600055")
+)
+
+func TestParallelProcessingFrontier(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainID:        big.NewInt(1),
+		HomesteadBlock: big.NewInt(0),
+		Ethash:         new(params.EthashConfig),
+	}
+	testParallelProcessing(t, config, ethash.NewFaker())
+}
+
+func TestParallelProcessingSpuriousDragon(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainID:        big.NewInt(1),
+		HomesteadBlock: big.NewInt(0),
+		EIP150Block:    big.NewInt(0),
+		EIP155Block:    big.NewInt(0),
+		EIP158Block:    big.NewInt(0),
+		Ethash:         new(params.EthashConfig),
+	}
+	testParallelProcessing(t, config, ethash.NewFaker())
+}
+
+func TestParallelProcessingLondon(t *testing.T) {
+	testParallelProcessing(t, params.TestChainConfig, ethash.NewFaker())
+}
+
+func TestParallelProcessingCancun(t *testing.T) {
+	testParallelProcessing(t, params.MergedTestChainConfig, beacon.New(ethash.NewFaker()))
+}
+
+// testParallelProcessing generates a chain with a mix of independent and
+// conflicting transactions and checks that the parallel processor produces
+// exactly the same results as the sequential one.
+func testParallelProcessing(t *testing.T, config *params.ChainConfig, engine consensus.Engine) {
+	var (
+		keys     []*ecdsa.PrivateKey
+		senders  []common.Address
+		coinbase = common.HexToAddress("0xc0ffee")
+		empty    = common.HexToAddress("0xe3e3e3")
+		targets  = []common.Address{
+			common.HexToAddress("0x1001"), // counter
+			common.HexToAddress("0x1002"), // caller counter
+			common.HexToAddress("0x1003"), // coinbase reader
+			common.HexToAddress("0x1004"), // logger
+			common.HexToAddress("0x1005"), // self-destructing
+		}
+		gspec = &Genesis{
+			Config: config,
+			Alloc: types.GenesisAlloc{
+				targets[0]: {Code: counterCode, Balance: big.NewInt(0)},
+				targets[1]: {Code: callerCounterCode, Balance: big.NewInt(0)},
+				targets[2]: {Code: coinbaseReaderCode, Balance: big.NewInt(0)},
+				targets[3]: {Code: loggerCode, Balance: big.NewInt(0)},
+				targets[4]: {Code: destructCode, Balance: big.NewInt(params.Ether)},
+				empty:      {Balance: big.NewInt(0)},
+			},
+		}
+		signer   = types.LatestSigner(config)
+		gasPrice = big.NewInt(1000 * params.GWei)
+	)
+	for i := 0; i < 16; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		senders = append(senders, crypto.PubkeyToAddress(key.PublicKey))
+		gspec.Alloc[senders[i]] = types.Account{Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000))}
+	}
+	if config.TerminalTotalDifficulty != nil {
+		gspec.Difficulty = common.Big0
+	}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 8, func(i int, b *BlockGen) {
+		b.SetCoinbase(coinbase)
+		if config.TerminalTotalDifficulty != nil {
+			b.SetPoS()
+		}
+		for j := 0; j < 40; j++ {
+			var (
+				sender = (i + j) % len(keys)
+				to     *common.Address
+				value  = new(big.Int)
+				data   []byte
+				gas    = uint64(100000)
+			)
+			switch (i*7 + j) % 9 {
+			case 0, 1, 2, 3, 4:
+				to = &targets[(i*7+j)%9]
+			case 5:
+				// Plain transfer to a fresh account
+				addr := common.BigToAddress(big.NewInt(int64(0x2000 + i*40 + j)))
+				to, value = &addr, big.NewInt(1)
+			case 6:
+				// Zero value transfer touching an empty account
+				to = &empty
+			case 7:
+				// Contract creation, which deploys another contract
+				data, gas = deployCode, 200000
+			case 8:
+				// Transfer to the coinbase
+				to, value = &coinbase, big.NewInt(params.GWei)
+			}
+			var tx *types.Transaction
+			if to == nil {
+				tx = types.NewContractCreation(b.TxNonce(senders[sender]), value, gas, gasPrice, data)
+			} else {
+				tx = types.NewTransaction(b.TxNonce(senders[sender]), *to, value, gas, gasPrice, data)
+			}
+			tx, err := types.SignTx(tx, signer, keys[sender])
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			b.AddTx(tx)
+		}
+	})
+	// Import the chain sequentially, using it as the reference for executing
+	// each block with both processors.
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var (
+		sequential = NewStateProcessor(config, chain.HeaderChain())
+		parallel   = NewParallelStateProcessor(config, chain.HeaderChain(), 4)
+	)
+	for _, block := range blocks {
+		parent := chain.GetHeaderByHash(block.ParentHash())
+
+		seqState, _ := chain.StateAt(parent.Root)
+		seqReceipts, seqLogs, seqGas, err := sequential.Process(block, seqState, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: sequential processing failed: %v", block.NumberU64(), err)
+		}
+		parState, _ := chain.StateAt(parent.Root)
+		parReceipts, parLogs, parGas, err := parallel.Process(block, parState, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: parallel processing failed: %v", block.NumberU64(), err)
+		}
+		if seqGas != parGas {
+			t.Fatalf("block %d: gas mismatch: have %d, want %d", block.NumberU64(), parGas, seqGas)
+		}
+		have, _ := json.Marshal(parReceipts)
+		want, _ := json.Marshal(seqReceipts)
+		if string(have) != string(want) {
+			t.Fatalf("block %d: receipt mismatch:\nhave %s\nwant %s", block.NumberU64(), have, want)
+		}
+		have, _ = json.Marshal(parLogs)
+		want, _ = json.Marshal(seqLogs)
+		if string(have) != string(want) {
+			t.Fatalf("block %d: log mismatch:\nhave %s\nwant %s", block.NumberU64(), have, want)
+		}
+		deleteEmpty := config.IsEIP158(block.Number())
+		if have, want := parState.IntermediateRoot(deleteEmpty), seqState.IntermediateRoot(deleteEmpty); have != want {
+			t.Fatalf("block %d: state root mismatch: have %x, want %x", block.NumberU64(), have, want)
+		}
+		if root := seqState.IntermediateRoot(deleteEmpty); root != block.Root() {
+			t.Fatalf("block %d: reference state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+	}
+	// Ensure the processor can also be used for importing the chain directly
+	cache := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	cache.ParallelExecution = 4
+
+	parChain, err := NewBlockChain(rawdb.NewMemoryDatabase(), cache, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer parChain.Stop()
+
+	if _, err := parChain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain with parallel processing: %v", err)
+	}
+}
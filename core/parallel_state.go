@@ -0,0 +1,314 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/holiman/uint256"
+)
+
+// txAccess is the set of state items read and written by a single transaction.
+// Account metadata (existence, balance, nonce and code) is tracked per account,
+// storage is tracked per slot. The sets are conservative: an item accessed in a
+// reverted call frame is still reported.
+type txAccess struct {
+	reads     map[common.Address]struct{}                 // Accounts whose metadata was read
+	readSlots map[common.Address]map[common.Hash]struct{} // Storage slots read
+	readRoots map[common.Address]struct{}                 // Accounts whose whole storage was inspected
+
+	writes     map[common.Address]struct{}                 // Accounts whose metadata was modified
+	writeSlots map[common.Address]map[common.Hash]struct{} // Storage slots modified
+	wipes      map[common.Address]struct{}                 // Accounts whose storage was (possibly) cleared
+
+	fees map[common.Address]*uint256.Int // Transaction fees credited, tracked as deltas
+}
+
+func newTxAccess() *txAccess {
+	return &txAccess{
+		reads:      make(map[common.Address]struct{}),
+		readSlots:  make(map[common.Address]map[common.Hash]struct{}),
+		readRoots:  make(map[common.Address]struct{}),
+		writes:     make(map[common.Address]struct{}),
+		writeSlots: make(map[common.Address]map[common.Hash]struct{}),
+		wipes:      make(map[common.Address]struct{}),
+		fees:       make(map[common.Address]*uint256.Int),
+	}
+}
+
+func (a *txAccess) read(addr common.Address) {
+	a.reads[addr] = struct{}{}
+}
+
+func (a *txAccess) write(addr common.Address) {
+	a.writes[addr] = struct{}{}
+}
+
+func (a *txAccess) wipe(addr common.Address) {
+	a.writes[addr] = struct{}{}
+	a.wipes[addr] = struct{}{}
+}
+
+func (a *txAccess) readSlot(addr common.Address, key common.Hash) {
+	slots, ok := a.readSlots[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		a.readSlots[addr] = slots
+	}
+	slots[key] = struct{}{}
+}
+
+func (a *txAccess) writeSlot(addr common.Address, key common.Hash) {
+	slots, ok := a.writeSlots[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		a.writeSlots[addr] = slots
+	}
+	slots[key] = struct{}{}
+}
+
+// feeOnly reports whether the only interaction of the transaction with the given
+// account was the crediting of transaction fees, in which case the credit can be
+// applied as a delta on top of whatever the account holds at commit time.
+func (a *txAccess) feeOnly(addr common.Address) bool {
+	if _, ok := a.fees[addr]; !ok {
+		return false
+	}
+	_, ok := a.reads[addr]
+	return !ok
+}
+
+// blockWrites is the accumulated set of state items modified by the transactions
+// committed so far in a block, used to validate the speculative executions of
+// the transactions that follow.
+type blockWrites struct {
+	accounts map[common.Address]struct{}
+	slots    map[common.Address]map[common.Hash]struct{}
+	wipes    map[common.Address]struct{}
+}
+
+func newBlockWrites() *blockWrites {
+	return &blockWrites{
+		accounts: make(map[common.Address]struct{}),
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+		wipes:    make(map[common.Address]struct{}),
+	}
+}
+
+// add merges the write set of a committed transaction. Any modified account
+// that no longer exists after the transaction is treated as wiped, covering the
+// deletion of touched empty accounts which is only decided upon finalisation.
+func (w *blockWrites) add(access *txAccess, statedb *state.StateDB) {
+	for addr := range access.writes {
+		w.accounts[addr] = struct{}{}
+		if !statedb.Exist(addr) {
+			w.wipes[addr] = struct{}{}
+		}
+	}
+	for addr := range access.wipes {
+		w.wipes[addr] = struct{}{}
+	}
+	for addr, keys := range access.writeSlots {
+		slots, ok := w.slots[addr]
+		if !ok {
+			slots = make(map[common.Hash]struct{})
+			w.slots[addr] = slots
+		}
+		for key := range keys {
+			slots[key] = struct{}{}
+		}
+	}
+}
+
+// conflicts reports whether the transaction read any state item which has been
+// modified by a previously committed transaction, invalidating its speculative
+// execution.
+func (w *blockWrites) conflicts(access *txAccess) bool {
+	for addr := range access.reads {
+		if _, ok := w.accounts[addr]; ok {
+			return true
+		}
+	}
+	for addr, keys := range access.readSlots {
+		if _, ok := w.wipes[addr]; ok {
+			return true
+		}
+		slots := w.slots[addr]
+		for key := range keys {
+			if _, ok := slots[key]; ok {
+				return true
+			}
+		}
+	}
+	for addr := range access.readRoots {
+		if _, ok := w.wipes[addr]; ok {
+			return true
+		}
+		if len(w.slots[addr]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// accessRecorder is a vm.StateDB wrapping a state database, which records all
+// the state items accessed by the EVM into a txAccess set.
+type accessRecorder struct {
+	*state.StateDB
+	access *txAccess
+}
+
+// newAccessRecorder wraps the given state database to record state accesses.
+func newAccessRecorder(statedb *state.StateDB) *accessRecorder {
+	return &accessRecorder{
+		StateDB: statedb,
+		access:  newTxAccess(),
+	}
+}
+
+func (r *accessRecorder) CreateAccount(addr common.Address) {
+	r.access.read(addr)
+	r.access.wipe(addr)
+	r.StateDB.CreateAccount(addr)
+}
+
+func (r *accessRecorder) CreateContract(addr common.Address) {
+	r.access.read(addr)
+	r.access.write(addr)
+	r.StateDB.CreateContract(addr)
+}
+
+// touch records an account modification by a balance change. Zero value changes
+// are only relevant for empty accounts, which get created or deleted by them.
+func (r *accessRecorder) touch(addr common.Address, amount *uint256.Int) {
+	r.access.read(addr)
+	if !amount.IsZero() || r.StateDB.Empty(addr) {
+		r.access.write(addr)
+	}
+}
+
+func (r *accessRecorder) SubBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
+	r.touch(addr, amount)
+	r.StateDB.SubBalance(addr, amount, reason)
+}
+
+func (r *accessRecorder) AddBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
+	// Transaction fees are credited to the coinbase by every transaction. Track
+	// them as deltas to avoid all transactions conflicting with each other.
+	if reason == tracing.BalanceIncreaseRewardTransactionFee {
+		fee, ok := r.access.fees[addr]
+		if !ok {
+			fee = new(uint256.Int)
+			r.access.fees[addr] = fee
+		}
+		fee.Add(fee, amount)
+		r.access.write(addr)
+	} else {
+		r.touch(addr, amount)
+	}
+	r.StateDB.AddBalance(addr, amount, reason)
+}
+
+func (r *accessRecorder) GetBalance(addr common.Address) *uint256.Int {
+	r.access.read(addr)
+	return r.StateDB.GetBalance(addr)
+}
+
+func (r *accessRecorder) GetNonce(addr common.Address) uint64 {
+	r.access.read(addr)
+	return r.StateDB.GetNonce(addr)
+}
+
+func (r *accessRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.access.read(addr)
+	r.access.write(addr)
+	r.StateDB.SetNonce(addr, nonce)
+}
+
+func (r *accessRecorder) GetCodeHash(addr common.Address) common.Hash {
+	r.access.read(addr)
+	return r.StateDB.GetCodeHash(addr)
+}
+
+func (r *accessRecorder) GetCode(addr common.Address) []byte {
+	r.access.read(addr)
+	return r.StateDB.GetCode(addr)
+}
+
+func (r *accessRecorder) SetCode(addr common.Address, code []byte) {
+	r.access.read(addr)
+	r.access.write(addr)
+	r.StateDB.SetCode(addr, code)
+}
+
+func (r *accessRecorder) GetCodeSize(addr common.Address) int {
+	r.access.read(addr)
+	return r.StateDB.GetCodeSize(addr)
+}
+
+func (r *accessRecorder) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	r.access.readSlot(addr, key)
+	return r.StateDB.GetCommittedState(addr, key)
+}
+
+func (r *accessRecorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	r.access.readSlot(addr, key)
+	return r.StateDB.GetState(addr, key)
+}
+
+func (r *accessRecorder) SetState(addr common.Address, key, value common.Hash) {
+	// Storage writes into a non-existent account implicitly create it.
+	if !r.StateDB.Exist(addr) {
+		r.access.wipe(addr)
+	}
+	r.access.readSlot(addr, key)
+	r.access.writeSlot(addr, key)
+	r.StateDB.SetState(addr, key, value)
+}
+
+func (r *accessRecorder) GetStorageRoot(addr common.Address) common.Hash {
+	r.access.readRoots[addr] = struct{}{}
+	return r.StateDB.GetStorageRoot(addr)
+}
+
+func (r *accessRecorder) SelfDestruct(addr common.Address) {
+	r.access.read(addr)
+	r.access.wipe(addr)
+	r.StateDB.SelfDestruct(addr)
+}
+
+func (r *accessRecorder) HasSelfDestructed(addr common.Address) bool {
+	r.access.read(addr)
+	return r.StateDB.HasSelfDestructed(addr)
+}
+
+func (r *accessRecorder) Selfdestruct6780(addr common.Address) {
+	r.access.read(addr)
+	r.access.wipe(addr)
+	r.StateDB.Selfdestruct6780(addr)
+}
+
+func (r *accessRecorder) Exist(addr common.Address) bool {
+	r.access.read(addr)
+	return r.StateDB.Exist(addr)
+}
+
+func (r *accessRecorder) Empty(addr common.Address) bool {
+	r.access.read(addr)
+	return r.StateDB.Empty(addr)
+}
@@ -160,6 +160,28 @@ func DeleteTrieJournal(db ethdb.KeyValueWriter) {
 	}
 }
 
+// ReadOnlinePruningProgress retrieves the serialized online state pruning
+// progress saved at the last checkpoint.
+func ReadOnlinePruningProgress(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(onlinePruningProgressKey)
+	return data
+}
+
+// WriteOnlinePruningProgress stores the serialized online state pruning progress.
+func WriteOnlinePruningProgress(db ethdb.KeyValueWriter, progress []byte) {
+	if err := db.Put(onlinePruningProgressKey, progress); err != nil {
+		log.Crit("Failed to store online pruning progress", "err", err)
+	}
+}
+
+// DeleteOnlinePruningProgress deletes the serialized online state pruning
+// progress, marking the current pruning cycle as finished.
+func DeleteOnlinePruningProgress(db ethdb.KeyValueWriter) {
+	if err := db.Delete(onlinePruningProgressKey); err != nil {
+		log.Crit("Failed to remove online pruning progress", "err", err)
+	}
+}
+
 // ReadStateHistoryMeta retrieves the metadata corresponding to the specified
 // state history. Compute the position of state history in freezer by minus
 // one since the id of first state history starts from one(zero for initial
@@ -0,0 +1,262 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ErrMigrationInterrupted is returned if a key-value store migration is aborted
+// before completion. The progress is retained and the migration can be resumed.
+var ErrMigrationInterrupted = errors.New("migration interrupted")
+
+// migrationProgress is the checkpoint of a key-value store migration, stored in
+// the target store along with every batch of migrated entries.
+type migrationProgress struct {
+	Marker   []byte // Last key migrated into the target store
+	Count    uint64 // Number of entries migrated
+	Size     uint64 // Total size of the entries migrated
+	Done     bool   // Flag whether all the entries have been migrated
+	Verified bool   `rlp:"optional"` // Flag whether the migrated entries have been verified
+}
+
+// readMigrationProgress retrieves the migration checkpoint from the target store,
+// or nil if there is no migration in progress.
+func readMigrationProgress(db ethdb.KeyValueReader) (*migrationProgress, error) {
+	blob, _ := db.Get(databaseMigrationKey)
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	var progress migrationProgress
+	if err := rlp.DecodeBytes(blob, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// writeMigrationProgress stores the migration checkpoint into the target store.
+func writeMigrationProgress(db ethdb.KeyValueWriter, progress *migrationProgress) error {
+	blob, err := rlp.EncodeToBytes(progress)
+	if err != nil {
+		return err
+	}
+	return db.Put(databaseMigrationKey, blob)
+}
+
+// MigrateKeyValueStore copies all the entries of the source key-value store into
+// the target one in batches, e.g. for switching to a different database engine.
+//
+// The progress is checkpointed into the target store atomically with each batch,
+// so an interrupted migration is resumed from the last written batch by calling
+// the function again with the same stores. The checkpoint is only removed once
+// the migrated store replaced the source, see CompleteKeyValueMigration.
+func MigrateKeyValueStore(src ethdb.KeyValueStore, dst ethdb.KeyValueStore, interrupt chan struct{}) error {
+	progress, err := readMigrationProgress(dst)
+	if err != nil {
+		return err
+	}
+	var start []byte
+	switch {
+	case progress == nil:
+		// Refuse to migrate into a store with unrelated content
+		it := dst.NewIterator(nil, nil)
+		nonEmpty := it.Next()
+		it.Release()
+		if nonEmpty {
+			return errors.New("target database is not empty")
+		}
+		progress = new(migrationProgress)
+
+	case progress.Done:
+		log.Info("Database already migrated", "count", progress.Count, "size", common.StorageSize(progress.Size))
+		return nil
+
+	default:
+		// Resume right after the last migrated key
+		start = append(common.CopyBytes(progress.Marker), 0x00)
+		log.Info("Resuming database migration", "count", progress.Count, "size", common.StorageSize(progress.Size), "marker", common.Bytes2Hex(progress.Marker))
+	}
+	var (
+		it     = src.NewIterator(nil, start)
+		batch  = dst.NewBatch()
+		begin  = time.Now()
+		logged = time.Now()
+	)
+	defer it.Release()
+
+	// flush writes out the pending batch along with the checkpoint
+	flush := func() error {
+		if err := writeMigrationProgress(batch, progress); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	for it.Next() {
+		key, val := it.Key(), it.Value()
+		if bytes.Equal(key, databaseMigrationKey) {
+			continue // leftover of an earlier migration of the source
+		}
+		if err := batch.Put(key, val); err != nil {
+			return err
+		}
+		progress.Marker = common.CopyBytes(key)
+		progress.Count++
+		progress.Size += uint64(len(key) + len(val))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			// Check interruption emitted by ctrl+c
+			select {
+			case <-interrupt:
+				log.Info("Database migration interrupted", "count", progress.Count, "size", common.StorageSize(progress.Size), "elapsed", common.PrettyDuration(time.Since(begin)))
+				return ErrMigrationInterrupted
+			default:
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Migrating database", "count", progress.Count, "size", common.StorageSize(progress.Size), "elapsed", common.PrettyDuration(time.Since(begin)))
+				logged = time.Now()
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	progress.Done = true
+	if err := flush(); err != nil {
+		return err
+	}
+	log.Info("Migrated database", "count", progress.Count, "size", common.StorageSize(progress.Size), "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
+
+// VerifyKeyValueMigration checks a completed migration by comparing the number
+// of entries in the source and target stores, as well as the values of a random
+// sample of entries. On success the checkpoint is marked as verified, so that a
+// rerun after a failed database swap doesn't need to verify again.
+func VerifyKeyValueMigration(src ethdb.KeyValueStore, dst ethdb.KeyValueStore, samples int) error {
+	progress, err := readMigrationProgress(dst)
+	if err != nil {
+		return err
+	}
+	if progress == nil || !progress.Done {
+		return errors.New("migration is not completed")
+	}
+	if progress.Verified {
+		log.Info("Database migration already verified", "count", progress.Count, "size", common.StorageSize(progress.Size))
+		return nil
+	}
+	var (
+		begin  = time.Now()
+		logged = time.Now()
+		picked [][]byte
+		count  uint64
+	)
+	// Count the source entries, reservoir sampling the keys to compare
+	it := src.NewIterator(nil, nil)
+	for it.Next() {
+		if bytes.Equal(it.Key(), databaseMigrationKey) {
+			continue
+		}
+		count++
+		if len(picked) < samples {
+			picked = append(picked, common.CopyBytes(it.Key()))
+		} else if n := rand.Uint64() % count; n < uint64(samples) {
+			picked[n] = common.CopyBytes(it.Key())
+		}
+		if count%100000 == 0 && time.Since(logged) > 8*time.Second {
+			log.Info("Verifying source database", "count", count, "elapsed", common.PrettyDuration(time.Since(begin)))
+			logged = time.Now()
+		}
+	}
+	err = it.Error()
+	it.Release()
+	if err != nil {
+		return err
+	}
+	if count != progress.Count {
+		return fmt.Errorf("migrated entry count mismatch, source: %d, migrated: %d", count, progress.Count)
+	}
+	// Count the target entries, ignoring the migration checkpoint
+	var migrated uint64
+	it = dst.NewIterator(nil, nil)
+	for it.Next() {
+		if bytes.Equal(it.Key(), databaseMigrationKey) {
+			continue
+		}
+		migrated++
+		if migrated%100000 == 0 && time.Since(logged) > 8*time.Second {
+			log.Info("Verifying target database", "count", migrated, "total", count, "elapsed", common.PrettyDuration(time.Since(begin)))
+			logged = time.Now()
+		}
+	}
+	err = it.Error()
+	it.Release()
+	if err != nil {
+		return err
+	}
+	if migrated != count {
+		return fmt.Errorf("entry count mismatch, source: %d, target: %d", count, migrated)
+	}
+	// Compare the sampled values
+	for _, key := range picked {
+		want, err := src.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read source entry %x: %v", key, err)
+		}
+		have, err := dst.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read target entry %x: %v", key, err)
+		}
+		if !bytes.Equal(have, want) {
+			return fmt.Errorf("entry %x mismatch, source: %x, target: %x", key, want, have)
+		}
+	}
+	progress.Verified = true
+	if err := writeMigrationProgress(dst, progress); err != nil {
+		return err
+	}
+	log.Info("Verified database migration", "count", count, "samples", len(picked), "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
+
+// CompleteKeyValueMigration removes the checkpoint of a verified migration from
+// the migrated store, once it replaced the source store.
+func CompleteKeyValueMigration(db ethdb.KeyValueStore) error {
+	progress, err := readMigrationProgress(db)
+	if err != nil {
+		return err
+	}
+	if progress == nil || !progress.Verified {
+		return errors.New("migration is not verified")
+	}
+	return db.Delete(databaseMigrationKey)
+}
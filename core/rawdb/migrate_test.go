@@ -0,0 +1,162 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// newMigrationSource creates a key-value store with enough content to require
+// multiple batches to migrate.
+func newMigrationSource(t *testing.T) ethdb.KeyValueStore {
+	db := memorydb.New()
+	for i := 0; i < 5000; i++ {
+		key, val := make([]byte, 32), make([]byte, 100)
+		rand.Read(key)
+		rand.Read(val)
+		if err := db.Put(key, val); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	return db
+}
+
+// checkMigrated ensures the target store contains exactly the source entries.
+func checkMigrated(t *testing.T, src, dst ethdb.KeyValueStore) {
+	srcIt, dstIt := src.NewIterator(nil, nil), dst.NewIterator(nil, nil)
+	defer srcIt.Release()
+	defer dstIt.Release()
+
+	for srcIt.Next() {
+		if !dstIt.Next() {
+			t.Fatalf("Entry %x missing from target", srcIt.Key())
+		}
+		if !bytes.Equal(srcIt.Key(), dstIt.Key()) || !bytes.Equal(srcIt.Value(), dstIt.Value()) {
+			t.Fatalf("Entry mismatch, source: %x, target: %x", srcIt.Key(), dstIt.Key())
+		}
+	}
+	if dstIt.Next() {
+		t.Fatalf("Unexpected entry %x in target", dstIt.Key())
+	}
+}
+
+func TestMigrateKeyValueStore(t *testing.T) {
+	var (
+		src = newMigrationSource(t)
+		dst = memorydb.New()
+	)
+	if err := MigrateKeyValueStore(src, dst, nil); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	if err := CompleteKeyValueMigration(dst); err == nil {
+		t.Fatal("Completed migration before verifying it")
+	}
+	if err := VerifyKeyValueMigration(src, dst, 128); err != nil {
+		t.Fatalf("Failed to verify migration: %v", err)
+	}
+	// Rerunning a verified migration, e.g. after a failed database swap, should
+	// keep the checkpoint and succeed right away
+	if err := MigrateKeyValueStore(src, dst, nil); err != nil {
+		t.Fatalf("Failed to rerun migration: %v", err)
+	}
+	if err := VerifyKeyValueMigration(src, dst, 128); err != nil {
+		t.Fatalf("Failed to rerun verification: %v", err)
+	}
+	if err := CompleteKeyValueMigration(dst); err != nil {
+		t.Fatalf("Failed to complete migration: %v", err)
+	}
+	checkMigrated(t, src, dst)
+
+	// Migrating into a non-empty database should be rejected
+	if err := MigrateKeyValueStore(src, dst, nil); err == nil {
+		t.Fatal("Migration into non-empty database succeeded")
+	}
+}
+
+func TestMigrateKeyValueStoreResume(t *testing.T) {
+	var (
+		src       = newMigrationSource(t)
+		dst       = memorydb.New()
+		interrupt = make(chan struct{})
+	)
+	close(interrupt)
+
+	// Interrupt the migration after every batch, until done
+	var runs int
+	for {
+		runs++
+		err := MigrateKeyValueStore(src, dst, interrupt)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrMigrationInterrupted) {
+			t.Fatalf("Failed to migrate database: %v", err)
+		}
+		progress, err := readMigrationProgress(dst)
+		if err != nil || progress == nil || progress.Done {
+			t.Fatalf("Invalid progress after interruption: %v, %v", progress, err)
+		}
+	}
+	if runs < 2 {
+		t.Fatalf("Migration was not interrupted")
+	}
+	if err := VerifyKeyValueMigration(src, dst, 128); err != nil {
+		t.Fatalf("Failed to verify migration: %v", err)
+	}
+	if err := CompleteKeyValueMigration(dst); err != nil {
+		t.Fatalf("Failed to complete migration: %v", err)
+	}
+	checkMigrated(t, src, dst)
+}
+
+func TestVerifyKeyValueMigration(t *testing.T) {
+	var (
+		src = newMigrationSource(t)
+		dst = memorydb.New()
+	)
+	if err := VerifyKeyValueMigration(src, dst, 128); err == nil {
+		t.Fatal("Verified migration before running it")
+	}
+	if err := MigrateKeyValueStore(src, dst, nil); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	// Drop an entry from the target, the verification must fail
+	it := dst.NewIterator(nil, nil)
+	it.Next()
+	key := it.Key()
+	it.Release()
+
+	if err := dst.Delete(key); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	if err := VerifyKeyValueMigration(src, dst, 128); err == nil {
+		t.Fatal("Verified incomplete migration")
+	}
+	// Corrupt an entry, the verification must fail if sampled
+	if err := dst.Put(key, []byte("corrupted")); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+	if err := VerifyKeyValueMigration(src, dst, 1<<20); err == nil {
+		t.Fatal("Verified corrupted migration")
+	}
+}
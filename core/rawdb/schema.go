@@ -76,6 +76,12 @@ var (
 	// trieJournalKey tracks the in-memory trie node layers across restarts.
 	trieJournalKey = []byte("TrieJournal")
 
+	// onlinePruningProgressKey tracks the online state pruning progress across restarts.
+	onlinePruningProgressKey = []byte("OnlinePruningProgress")
+
+	// databaseMigrationKey tracks the key-value store migration progress across restarts.
+	databaseMigrationKey = []byte("DatabaseMigration")
+
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
@@ -141,9 +147,11 @@ var (
 
 	CliqueSnapshotPrefix = []byte("clique-")
 
-	BestUpdateKey         = []byte("update-")    // bigEndian64(syncPeriod) -> RLP(types.LightClientUpdate)  (nextCommittee only referenced by root hash)
-	FixedCommitteeRootKey = []byte("fixedRoot-") // bigEndian64(syncPeriod) -> committee root hash
-	SyncCommitteeKey      = []byte("committee-") // bigEndian64(syncPeriod) -> serialized committee
+	BestUpdateKey         = []byte("update-")           // bigEndian64(syncPeriod) -> RLP(types.LightClientUpdate)  (nextCommittee only referenced by root hash)
+	FixedCommitteeRootKey = []byte("fixedRoot-")        // bigEndian64(syncPeriod) -> committee root hash
+	SyncCommitteeKey      = []byte("committee-")        // bigEndian64(syncPeriod) -> serialized committee
+	BeaconCheckpointKey   = []byte("beacon-checkpoint") // -> RLP(types.BootstrapData) (committee only referenced by root hash)
+	BeaconFinalizedKey    = []byte("beacon-finalized")  // -> RLP(types.Header) of the latest verified finalized header
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -0,0 +1,669 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+var (
+	onlineMarkedMeter       = metrics.NewRegisteredMeter("state/prune/online/marked", nil)
+	onlineSweptMeter        = metrics.NewRegisteredMeter("state/prune/online/swept", nil)
+	onlineDeletedMeter      = metrics.NewRegisteredMeter("state/prune/online/deleted", nil)
+	onlineDeletedBytesMeter = metrics.NewRegisteredMeter("state/prune/online/deleted/bytes", nil)
+	onlineProgressGauge     = metrics.NewRegisteredGauge("state/prune/online/progress", nil)
+	onlineMarkTimer         = metrics.NewRegisteredResettingTimer("state/prune/online/mark/time", nil)
+	onlineCycleTimer        = metrics.NewRegisteredResettingTimer("state/prune/online/cycle/time", nil)
+)
+
+var (
+	// errPrunerStopped is returned if a pruning cycle is interrupted by
+	// the termination of the pruner.
+	errPrunerStopped = errors.New("pruner stopped")
+
+	// errNotSynced is returned if a pruning cycle is interrupted because
+	// the node is (again) in the middle of a state sync.
+	errNotSynced = errors.New("state not synced")
+)
+
+// Phases reported by the online pruner.
+const (
+	PhaseIdle     = "idle"
+	PhaseMarking  = "marking"
+	PhaseSweeping = "sweeping"
+)
+
+// OnlineConfig includes all the configurations for online pruning.
+type OnlineConfig struct {
+	Retain    uint64        // Number of recent blocks whose state must survive pruning
+	BloomSize uint64        // Megabytes of memory allocated to the bloom-filter
+	BatchSize int           // Maximum number of trie nodes deleted in a single batch
+	Throttle  time.Duration // Pause between two consecutive deletion batches
+	Interval  time.Duration // Pause between two consecutive pruning cycles
+}
+
+// DefaultOnlineConfig contains the default settings for online pruning.
+var DefaultOnlineConfig = OnlineConfig{
+	Retain:    state.TriesInMemory,
+	BloomSize: 1024,
+	BatchSize: 10000,
+	Throttle:  100 * time.Millisecond,
+	Interval:  6 * time.Hour,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (c OnlineConfig) sanitize() OnlineConfig {
+	conf := c
+	// The states held in memory by the chain might be partially flushed to
+	// disk already, they must always be retained.
+	if conf.Retain < state.TriesInMemory {
+		log.Warn("Sanitizing online pruning retention", "provided", conf.Retain, "updated", state.TriesInMemory)
+		conf.Retain = state.TriesInMemory
+	}
+	if conf.BloomSize < 256 {
+		log.Warn("Sanitizing online pruning bloomfilter size", "provided(MB)", conf.BloomSize, "updated(MB)", 256)
+		conf.BloomSize = 256
+	}
+	if conf.BatchSize <= 0 {
+		log.Warn("Sanitizing online pruning batch size", "provided", conf.BatchSize, "updated", DefaultOnlineConfig.BatchSize)
+		conf.BatchSize = DefaultOnlineConfig.BatchSize
+	}
+	return conf
+}
+
+// ChainReader defines the small collection of methods needed to access the
+// local chain during online pruning.
+type ChainReader interface {
+	// CurrentBlock retrieves the current head block of the canonical chain.
+	CurrentBlock() *types.Header
+
+	// GetHeaderByNumber retrieves a block header from the canonical chain.
+	GetHeaderByNumber(number uint64) *types.Header
+
+	// TrieDB retrieves the trie database used by the chain.
+	TrieDB() *triedb.Database
+
+	// Snapshots returns the snapshot tree, nil if it's not enabled.
+	Snapshots() *snapshot.Tree
+}
+
+// onlineProgress is the checkpoint of a running pruning cycle, persisted into
+// the database so that the sweeping can resume after a restart.
+type onlineProgress struct {
+	Marker  []byte // Last database key swept
+	Swept   uint64 // Number of trie nodes checked so far
+	Deleted uint64 // Number of trie nodes deleted so far
+	Size    uint64 // Approximate size of deleted trie nodes so far
+	Started uint64 // Unix timestamp the cycle was started at
+}
+
+// OnlineStatus is the progress report of the online pruner.
+type OnlineStatus struct {
+	Phase      string             `json:"phase"`
+	Cycles     uint64             `json:"cycles"`
+	BaseNumber uint64             `json:"baseNumber"`
+	BaseRoot   common.Hash        `json:"baseRoot"`
+	Marked     uint64             `json:"marked"`
+	Swept      uint64             `json:"swept"`
+	Deleted    uint64             `json:"deleted"`
+	Size       common.StorageSize `json:"size"`
+	Marker     hexutil.Bytes      `json:"marker"`
+	Progress   float64            `json:"progress"`
+	Started    time.Time          `json:"started"`
+	LastError  string             `json:"lastError,omitempty"`
+}
+
+// OnlinePruner is a background service to incrementally prune the stale trie
+// nodes of a live node running the hash-based state scheme. Every cycle runs
+// in two phases:
+//
+//   - marking: all the trie nodes reachable from the most recent persisted
+//     state, as well as from the states of the recent blocks still held in
+//     memory, are recorded in a bloom filter. Recent states are traversed
+//     incrementally, only visiting the nodes that differ from an already
+//     marked state, with the snapshot guiding the lookup of the previous
+//     storage roots.
+//   - sweeping: the database is iterated and all the trie nodes not contained
+//     in the bloom filter are deleted in small throttled batches.
+//
+// Trie nodes flushed to disk after the marking started are added to the bloom
+// filter as they are written, so the states created during the cycle are never
+// affected. The sweeping position is checkpointed after every batch, which
+// allows an interrupted cycle to be resumed after a restart.
+type OnlinePruner struct {
+	config OnlineConfig
+	db     ethdb.Database
+	chain  ChainReader
+	synced func() bool
+
+	status OnlineStatus
+	lock   sync.RWMutex // Protects the status
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewOnlinePruner creates the online pruner instance. The synced callback is
+// used to postpone pruning as long as the node is still syncing its state.
+func NewOnlinePruner(db ethdb.Database, chain ChainReader, config OnlineConfig, synced func() bool) (*OnlinePruner, error) {
+	if scheme := chain.TrieDB().Scheme(); scheme != rawdb.HashScheme {
+		return nil, fmt.Errorf("online pruning is not supported in %s scheme", scheme)
+	}
+	return &OnlinePruner{
+		config: config.sanitize(),
+		db:     db,
+		chain:  chain,
+		synced: synced,
+		status: OnlineStatus{Phase: PhaseIdle},
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// Start launches the background pruning loop. If a previous cycle was
+// interrupted, it's resumed immediately.
+func (p *OnlinePruner) Start() {
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Stop terminates the background pruning loop, waiting for the current batch
+// to finish. The progress is kept in the database for resumption.
+func (p *OnlinePruner) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+// Status returns the current progress report of the pruner.
+func (p *OnlinePruner) Status() OnlineStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	status := p.status
+	status.Marker = common.CopyBytes(p.status.Marker)
+	return status
+}
+
+// updateStatus applies the given modification to the status under lock.
+func (p *OnlinePruner) updateStatus(update func(status *OnlineStatus)) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	update(&p.status)
+}
+
+// loop runs the pruning cycles until the pruner is stopped.
+func (p *OnlinePruner) loop() {
+	defer p.wg.Done()
+
+	// Resume the interrupted cycle right away, otherwise wait a full interval
+	// before touching the database, giving the node time to settle.
+	wait := p.config.Interval
+	if blob := rawdb.ReadOnlinePruningProgress(p.db); len(blob) > 0 {
+		wait = 0
+	}
+	for {
+		select {
+		case <-time.After(wait):
+		case <-p.quit:
+			return
+		}
+		wait = p.config.Interval
+
+		err := p.prune()
+		switch {
+		case errors.Is(err, errPrunerStopped):
+			return
+		case errors.Is(err, errNotSynced):
+			log.Debug("Postponing online state pruning, state not synced")
+			wait = time.Minute
+		case err != nil:
+			log.Error("Online state pruning failed", "err", err)
+		}
+		p.updateStatus(func(status *OnlineStatus) {
+			status.Phase = PhaseIdle
+			if err != nil {
+				status.LastError = err.Error()
+			}
+		})
+	}
+}
+
+// stopped reports whether the pruner has been requested to terminate.
+func (p *OnlinePruner) stopped() bool {
+	select {
+	case <-p.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// ready reports whether the local state is complete and can be pruned.
+func (p *OnlinePruner) ready() bool {
+	if p.synced != nil && !p.synced() {
+		return false
+	}
+	return rawdb.ReadSnapSyncStatusFlag(p.db) != rawdb.StateSyncRunning
+}
+
+// prune runs a single pruning cycle, resuming the persisted one if available.
+func (p *OnlinePruner) prune() error {
+	if !p.ready() {
+		return errNotSynced
+	}
+	var progress onlineProgress
+	if blob := rawdb.ReadOnlinePruningProgress(p.db); len(blob) > 0 {
+		if err := rlp.DecodeBytes(blob, &progress); err != nil {
+			log.Warn("Discarding corrupted online pruning progress", "err", err)
+			progress = onlineProgress{}
+		}
+	}
+	if progress.Started == 0 {
+		progress.Started = uint64(time.Now().Unix())
+	}
+	start := time.Now()
+
+	stateBloom, err := newStateBloomWithSize(p.config.BloomSize)
+	if err != nil {
+		return err
+	}
+	// Install the flush hook before selecting anything to mark, ensuring all
+	// the trie nodes persisted from now on are retained, whatever state they
+	// belong to.
+	triedb := p.chain.TrieDB()
+	if err := triedb.SetFlushHook(func(hash common.Hash) {
+		stateBloom.Put(hash.Bytes(), nil)
+	}); err != nil {
+		return err
+	}
+	defer triedb.SetFlushHook(nil)
+
+	p.updateStatus(func(status *OnlineStatus) {
+		status.Phase = PhaseMarking
+		status.Marked = 0
+		status.Swept, status.Deleted, status.Size = progress.Swept, progress.Deleted, common.StorageSize(progress.Size)
+		status.Marker = progress.Marker
+		status.Started = time.Unix(int64(progress.Started), 0)
+		status.LastError = ""
+	})
+	if err := p.mark(stateBloom); err != nil {
+		return err
+	}
+	onlineMarkTimer.UpdateSince(start)
+
+	p.updateStatus(func(status *OnlineStatus) {
+		status.Phase = PhaseSweeping
+	})
+	if err := p.sweep(stateBloom, &progress); err != nil {
+		return err
+	}
+	rawdb.DeleteOnlinePruningProgress(p.db)
+	onlineCycleTimer.UpdateSince(start)
+	onlineProgressGauge.Update(0)
+
+	p.updateStatus(func(status *OnlineStatus) {
+		status.Cycles++
+	})
+	log.Info("Online state pruning finished", "nodes", progress.Deleted, "size", common.StorageSize(progress.Size),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// mark records all the trie nodes belonging to the retained states into the
+// given bloom filter.
+func (p *OnlinePruner) mark(stateBloom *stateBloom) error {
+	// Select the most recent state fully persisted on disk as the base of the
+	// marking. Its trie is immutable, so it's safe to traverse it slowly. All
+	// the in-memory states are descended from it and only differences need
+	// to be visited later.
+	head := p.chain.CurrentBlock()
+	if head == nil {
+		return errors.New("missing head block")
+	}
+	var base *types.Header
+	for number := head.Number.Uint64(); ; number-- {
+		header := p.chain.GetHeaderByNumber(number)
+		if header != nil && rawdb.HasLegacyTrieNode(p.db, header.Root) {
+			base = header
+			break
+		}
+		if number == 0 {
+			return errors.New("no persisted state available")
+		}
+	}
+	p.updateStatus(func(status *OnlineStatus) {
+		status.BaseNumber, status.BaseRoot = base.Number.Uint64(), base.Root
+	})
+	log.Info("Marking retained state", "base", base.Number, "root", base.Root, "head", head.Number)
+
+	if err := p.markState(stateBloom, common.Hash{}, base.Root); err != nil {
+		return err
+	}
+	// Always retain the genesis state, the chain might be rewound to it.
+	if err := extractGenesis(p.db, stateBloom); err != nil {
+		return err
+	}
+	// Retain the recent states on top of the base one, from the newest to the
+	// oldest one. The difference between the latest head and the base state
+	// is visited first, then the difference between each pair of consecutive
+	// states. The older states might have been garbage collected from memory
+	// since, which is fine as they are not referenced anymore.
+	head = p.chain.CurrentBlock()
+	if err := p.markState(stateBloom, base.Root, head.Root); err != nil {
+		return err
+	}
+	var (
+		number = head.Number.Uint64()
+		limit  uint64
+		prev   = head.Root
+	)
+	if number > p.config.Retain {
+		limit = number - p.config.Retain
+	}
+	for number > limit {
+		number--
+		header := p.chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		if header.Root != prev {
+			if err := p.markState(stateBloom, prev, header.Root); err != nil {
+				if errors.Is(err, errPrunerStopped) {
+					return err
+				}
+				var missing *trie.MissingNodeError
+				if !errors.As(err, &missing) {
+					return err
+				}
+				log.Debug("Stopped marking recent states", "number", number, "root", header.Root, "err", err)
+				break
+			}
+		}
+		prev = header.Root
+	}
+	return nil
+}
+
+// markState records the trie nodes of the given state root into the bloom
+// filter. If the parent root is specified, it's assumed to be marked already
+// and only the trie nodes which are not shared with it are visited.
+func (p *OnlinePruner) markState(stateBloom *stateBloom, parent common.Hash, root common.Hash) error {
+	if parent == root || root == types.EmptyRootHash {
+		return nil
+	}
+	triedb := p.chain.TrieDB()
+	t, err := trie.NewStateTrie(trie.StateTrieID(root), triedb)
+	if err != nil {
+		return err
+	}
+	it, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	// If the parent state is available, only iterate the difference and
+	// resolve the original accounts either via snapshot or the parent trie.
+	var (
+		parentSnap snapshot.Snapshot
+		parentTrie *trie.StateTrie
+	)
+	if parent != (common.Hash{}) {
+		if snaps := p.chain.Snapshots(); snaps != nil {
+			parentSnap = snaps.Snapshot(parent)
+		}
+		parentTrie, err = trie.NewStateTrie(trie.StateTrieID(parent), triedb)
+		if err != nil {
+			return err
+		}
+		parentIt, err := parentTrie.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		it, _ = trie.NewDifferenceIterator(parentIt, it)
+	}
+	return p.markIterator(stateBloom, it, func(hash common.Hash, blob []byte) error {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return err
+		}
+		if !bytes.Equal(acc.CodeHash, types.EmptyCodeHash.Bytes()) {
+			stateBloom.Put(acc.CodeHash, nil)
+		}
+		if acc.Root == types.EmptyRootHash {
+			return nil
+		}
+		origin, err := p.storageRoot(parentSnap, parentTrie, hash)
+		if err != nil {
+			return err
+		}
+		if origin == acc.Root {
+			return nil
+		}
+		id := trie.StorageTrieID(root, hash, acc.Root)
+		st, err := trie.NewStateTrie(id, triedb)
+		if err != nil {
+			return err
+		}
+		sit, err := st.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		if origin != types.EmptyRootHash {
+			ot, err := trie.NewStateTrie(trie.StorageTrieID(parent, hash, origin), triedb)
+			if err != nil {
+				return err
+			}
+			oit, err := ot.NodeIterator(nil)
+			if err != nil {
+				return err
+			}
+			sit, _ = trie.NewDifferenceIterator(oit, sit)
+		}
+		return p.markIterator(stateBloom, sit, nil)
+	})
+}
+
+// storageRoot resolves the storage root of the given account in the parent
+// state, preferring the snapshot over the trie. The empty root is returned
+// if there is no parent state or the account doesn't exist in it.
+func (p *OnlinePruner) storageRoot(parentSnap snapshot.Snapshot, parentTrie *trie.StateTrie, hash common.Hash) (common.Hash, error) {
+	if parentTrie == nil {
+		return types.EmptyRootHash, nil
+	}
+	if parentSnap != nil {
+		acc, err := parentSnap.Account(hash)
+		if err == nil {
+			if acc == nil || len(acc.Root) == 0 {
+				return types.EmptyRootHash, nil
+			}
+			return common.BytesToHash(acc.Root), nil
+		}
+		// The snapshot might be stale or still generating, fall back to the trie
+	}
+	acc, err := parentTrie.GetAccountByHash(hash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if acc == nil {
+		return types.EmptyRootHash, nil
+	}
+	return acc.Root, nil
+}
+
+// markIterator records all the trie nodes returned by the iterator into the
+// bloom filter, invoking the callback for every leaf encountered.
+func (p *OnlinePruner) markIterator(stateBloom *stateBloom, it trie.NodeIterator, onLeaf func(hash common.Hash, blob []byte) error) error {
+	var marked uint64
+
+	report := func() {
+		p.updateStatus(func(status *OnlineStatus) {
+			status.Marked += marked
+		})
+		onlineMarkedMeter.Mark(int64(marked))
+		marked = 0
+	}
+	defer report()
+
+	for it.Next(true) {
+		// Embedded nodes don't have hash.
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			stateBloom.Put(hash.Bytes(), nil)
+			marked++
+		}
+		if it.Leaf() && onLeaf != nil {
+			if err := onLeaf(common.BytesToHash(it.LeafKey()), it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+		if marked >= 10000 {
+			report()
+			if p.stopped() {
+				return errPrunerStopped
+			}
+		}
+	}
+	return it.Error()
+}
+
+// sweep iterates the database from the checkpointed position and deletes all
+// the legacy trie nodes which are not contained in the bloom filter.
+func (p *OnlinePruner) sweep(stateBloom *stateBloom, progress *onlineProgress) error {
+	var (
+		triedb = p.chain.TrieDB()
+		logged = time.Now()
+
+		swept, size uint64
+		hashes      []common.Hash
+		iter        = p.db.NewIterator(nil, nextKey(progress.Marker))
+	)
+
+	// flush deletes the accumulated candidates and checkpoints the progress.
+	flush := func(marker []byte) error {
+		deleted, err := triedb.DeleteNodes(hashes, func(hash common.Hash) bool {
+			return stateBloom.Contain(hash.Bytes())
+		})
+		if err != nil {
+			return err
+		}
+		progress.Marker = marker
+		progress.Swept += swept
+		progress.Deleted += uint64(deleted)
+		progress.Size += size
+
+		blob, err := rlp.EncodeToBytes(progress)
+		if err != nil {
+			return err
+		}
+		rawdb.WriteOnlinePruningProgress(p.db, blob)
+
+		onlineSweptMeter.Mark(int64(swept))
+		onlineDeletedMeter.Mark(int64(deleted))
+		onlineDeletedBytesMeter.Mark(int64(size))
+
+		ratio := sweepRatio(marker)
+		onlineProgressGauge.Update(int64(ratio * 100))
+		p.updateStatus(func(status *OnlineStatus) {
+			status.Swept, status.Deleted, status.Size = progress.Swept, progress.Deleted, common.StorageSize(progress.Size)
+			status.Marker = marker
+			status.Progress = ratio
+		})
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data online", "nodes", progress.Deleted, "swept", progress.Swept,
+				"size", common.StorageSize(progress.Size), "progress", fmt.Sprintf("%.2f%%", ratio*100))
+			logged = time.Now()
+		}
+		hashes, swept, size = hashes[:0], 0, 0
+		return nil
+	}
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		swept++
+		if !stateBloom.Contain(key) {
+			hashes = append(hashes, common.BytesToHash(key))
+			size += uint64(len(key) + len(iter.Value()))
+		}
+		// Checkpoint the progress once enough nodes are deleted, or enough
+		// are swept through in a live region of the database.
+		if len(hashes) < p.config.BatchSize && swept < uint64(p.config.BatchSize)*16 {
+			continue
+		}
+		// Recreate the iterator after every batch in order to allow the
+		// underlying compactor to delete the entries, and give the node
+		// some breathing room.
+		marker := common.CopyBytes(key)
+		iter.Release()
+
+		if err := flush(marker); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(p.config.Throttle):
+		case <-p.quit:
+			return errPrunerStopped
+		}
+		if !p.ready() {
+			return errNotSynced
+		}
+		iter = p.db.NewIterator(nil, nextKey(marker))
+	}
+	err := iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+	return flush(nil)
+}
+
+// nextKey returns the smallest key which is larger than the given one, or nil
+// if no key is given.
+func nextKey(key []byte) []byte {
+	if len(key) == 0 {
+		return nil
+	}
+	return append(common.CopyBytes(key), 0x00)
+}
+
+// sweepRatio estimates the completed fraction of the database sweep from the
+// position of the given key.
+func sweepRatio(key []byte) float64 {
+	if len(key) < 8 {
+		return 1
+	}
+	return float64(binary.BigEndian.Uint64(key[:8])) / math.MaxUint64
+}
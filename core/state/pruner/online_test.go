@@ -0,0 +1,215 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+)
+
+// testChain is a minimal chain implementation backed by a list of headers.
+type testChain struct {
+	headers []*types.Header
+	triedb  *triedb.Database
+}
+
+func (c *testChain) CurrentBlock() *types.Header { return c.headers[len(c.headers)-1] }
+func (c *testChain) TrieDB() *triedb.Database    { return c.triedb }
+func (c *testChain) Snapshots() *snapshot.Tree   { return nil }
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+// newTestChain creates a chain with the given number of blocks, each of them
+// modifying a few accounts and storage slots. All the states are persisted.
+func newTestChain(t *testing.T, db ethdb.Database, blocks int) *testChain {
+	var (
+		tdb     = triedb.NewDatabase(db, triedb.HashDefaults)
+		sdb     = state.NewDatabaseWithNodeDB(db, tdb)
+		root    = types.EmptyRootHash
+		headers []*types.Header
+	)
+	for i := 0; i < blocks; i++ {
+		statedb, err := state.New(root, sdb, nil)
+		if err != nil {
+			t.Fatalf("Failed to open state: %v", err)
+		}
+		for j := 0; j < 4; j++ {
+			addr := common.BigToAddress(big.NewInt(int64((i*4 + j) % 50)))
+			statedb.AddBalance(addr, uint256.NewInt(uint64(i+1)), tracing.BalanceChangeUnspecified)
+			statedb.SetState(addr, common.BigToHash(big.NewInt(int64(j))), common.BigToHash(big.NewInt(int64(i+1))))
+		}
+		root, err = statedb.Commit(uint64(i), false)
+		if err != nil {
+			t.Fatalf("Failed to commit state: %v", err)
+		}
+		if err := tdb.Commit(root, false); err != nil {
+			t.Fatalf("Failed to persist state: %v", err)
+		}
+		header := &types.Header{Number: big.NewInt(int64(i)), Root: root, Difficulty: common.Big0}
+		headers = append(headers, header)
+
+		block := types.NewBlockWithHeader(header)
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), uint64(i))
+	}
+	return &testChain{headers: headers, triedb: tdb}
+}
+
+// checkState iterates the whole state and reports any missing trie node.
+func checkState(tdb *triedb.Database, root common.Hash) error {
+	t, err := trie.NewStateTrie(trie.StateTrieID(root), tdb)
+	if err != nil {
+		return err
+	}
+	it, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		st, err := trie.NewStateTrie(trie.StorageTrieID(root, common.BytesToHash(it.LeafKey()), acc.Root), tdb)
+		if err != nil {
+			return err
+		}
+		sit, err := st.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		for sit.Next(true) {
+		}
+		if sit.Error() != nil {
+			return sit.Error()
+		}
+	}
+	return it.Error()
+}
+
+func TestOnlinePruning(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		blocks = 200
+		chain  = newTestChain(t, db, blocks)
+	)
+	pruner, err := NewOnlinePruner(db, chain, OnlineConfig{BloomSize: 256, BatchSize: 16}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create pruner: %v", err)
+	}
+	if err := pruner.prune(); err != nil {
+		t.Fatalf("Failed to prune state: %v", err)
+	}
+	status := pruner.Status()
+	if status.Deleted == 0 {
+		t.Fatal("No stale trie node deleted")
+	}
+	if len(rawdb.ReadOnlinePruningProgress(db)) != 0 {
+		t.Fatal("Pruning progress not cleaned up")
+	}
+	// All the retained states must be intact, while the stale ones should be
+	// (at least partially) gone.
+	for i := blocks - int(state.TriesInMemory); i < blocks; i++ {
+		if err := checkState(chain.triedb, chain.headers[i].Root); err != nil {
+			t.Fatalf("Retained state %d is corrupted: %v", i, err)
+		}
+	}
+	if err := checkState(chain.triedb, chain.headers[0].Root); err != nil {
+		t.Fatalf("Genesis state is corrupted: %v", err)
+	}
+	if rawdb.HasLegacyTrieNode(db, chain.headers[1].Root) {
+		t.Fatal("Stale state root is not pruned")
+	}
+}
+
+func TestOnlinePruningResume(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		blocks = 200
+		chain  = newTestChain(t, db, blocks)
+	)
+	// Simulate an interrupted cycle which swept half of the key space.
+	marker := common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000")
+	blob, _ := rlp.EncodeToBytes(&onlineProgress{Marker: marker.Bytes(), Started: 1})
+	rawdb.WriteOnlinePruningProgress(db, blob)
+
+	lowBefore, highBefore := countNodes(db, marker)
+
+	pruner, err := NewOnlinePruner(db, chain, OnlineConfig{BloomSize: 256, BatchSize: 16}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create pruner: %v", err)
+	}
+	if err := pruner.prune(); err != nil {
+		t.Fatalf("Failed to prune state: %v", err)
+	}
+	// Only the second half of the key space should have been swept.
+	lowAfter, highAfter := countNodes(db, marker)
+	if lowAfter != lowBefore {
+		t.Fatalf("Nodes before the resume marker were swept: have %d, want %d", lowAfter, lowBefore)
+	}
+	if highAfter >= highBefore {
+		t.Fatalf("Nodes after the resume marker were not swept: have %d, before %d", highAfter, highBefore)
+	}
+	for i := blocks - int(state.TriesInMemory); i < blocks; i++ {
+		if err := checkState(chain.triedb, chain.headers[i].Root); err != nil {
+			t.Fatalf("Retained state %d is corrupted: %v", i, err)
+		}
+	}
+}
+
+// countNodes returns the number of legacy trie nodes before and after the given
+// position in the database.
+func countNodes(db ethdb.Database, marker common.Hash) (int, int) {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	var low, high int
+	for it.Next() {
+		if len(it.Key()) != common.HashLength {
+			continue
+		}
+		if common.BytesToHash(it.Key()).Cmp(marker) <= 0 {
+			low++
+		} else {
+			high++
+		}
+	}
+	return low, high
+}
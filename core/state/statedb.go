@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"maps"
 	"math/big"
+	"runtime"
 	"slices"
 	"sort"
 	"sync"
@@ -48,6 +49,10 @@ import (
 // TriesInMemory represents the number of layers that are kept in RAM.
 const TriesInMemory = 128
 
+// commitConcurrency is the maximum number of tries hashed or committed in
+// parallel when computing the state root.
+var commitConcurrency = runtime.NumCPU()
+
 type revision struct {
 	id           int
 	journalIndex int
@@ -853,6 +858,8 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
 		// need concurrency support within the trie itself. That's a TODO for a
 		// later time.
 		workers.SetLimit(1)
+	} else {
+		workers.SetLimit(commitConcurrency)
 	}
 	for addr, op := range s.mutations {
 		if op.applied || op.isDelete() {
@@ -1183,7 +1190,7 @@ func (s *StateDB) commit(deleteEmptyObjects bool) (*stateUpdate, error) {
 		storageTrieNodesUpdated int
 		storageTrieNodesDeleted int
 
-		lock    sync.Mutex                                               // protect two maps below
+		lock    sync.Mutex                                               // protect the commit statistics
 		nodes   = trienode.NewMergedNodeSet()                            // aggregated trie nodes
 		updates = make(map[common.Hash]*accountUpdate, len(s.mutations)) // aggregated account updates
 
@@ -1195,14 +1202,11 @@ func (s *StateDB) commit(deleteEmptyObjects bool) (*stateUpdate, error) {
 		// the later one overwriting the previous one if any nodes are modified
 		// or deleted in both sets.
 		//
-		// merge run concurrently across  all the state objects and account trie.
+		// merge is invoked sequentially, in a deterministic order.
 		merge = func(set *trienode.NodeSet) error {
 			if set == nil {
 				return nil
 			}
-			lock.Lock()
-			defer lock.Unlock()
-
 			updates, deletes := set.Size()
 			if set.Owner == (common.Hash{}) {
 				accountTrieNodesUpdated += updates
@@ -1228,13 +1232,32 @@ func (s *StateDB) commit(deleteEmptyObjects bool) (*stateUpdate, error) {
 		}
 	}
 	// Handle all state updates afterwards, concurrently to one another to shave
-	// off some milliseconds from the commit operation. Also accumulate the code
-	// writes to run in parallel with the computations.
+	// off some milliseconds from the commit operation. The storage tries are
+	// independent of each other and are processed on a bounded worker pool,
+	// each producing its own nodeset. The sets are merged in a deterministic
+	// order once all the workers finish.
 	var (
 		start   = time.Now()
 		root    common.Hash
+		rootSet *trienode.NodeSet
 		workers errgroup.Group
+		objects = make([]*stateObject, 0, len(s.mutations))
 	)
+	for addr, op := range s.mutations {
+		if op.isDelete() {
+			continue
+		}
+		obj := s.stateObjects[addr]
+		if obj == nil {
+			return nil, errors.New("missing state object")
+		}
+		objects = append(objects, obj)
+	}
+	slices.SortFunc(objects, func(a, b *stateObject) int {
+		return a.addrHash.Cmp(b.addrHash)
+	})
+	workers.SetLimit(commitConcurrency)
+
 	// Schedule the account trie first since that will be the biggest, so give
 	// it the most time to crunch.
 	//
@@ -1246,52 +1269,45 @@ func (s *StateDB) commit(deleteEmptyObjects bool) (*stateUpdate, error) {
 	// code didn't anticipate for.
 	workers.Go(func() error {
 		// Write the account trie changes, measuring the amount of wasted time
-		newroot, set := s.trie.Commit(true)
-		root = newroot
-
-		if err := merge(set); err != nil {
-			return err
-		}
+		root, rootSet = s.trie.Commit(true)
 		s.AccountCommits = time.Since(start)
 		return nil
 	})
 	// Schedule each of the storage tries that need to be updated, so they can
 	// run concurrently to one another.
-	//
-	// TODO(karalabe): Experimentally, the account commit takes approximately the
-	// same time as all the storage commits combined, so we could maybe only have
-	// 2 threads in total. But that kind of depends on the account commit being
-	// more expensive than it should be, so let's fix that and revisit this todo.
-	for addr, op := range s.mutations {
-		if op.isDelete() {
-			continue
-		}
-		// Write any contract code associated with the state object
-		obj := s.stateObjects[addr]
-		if obj == nil {
-			return nil, errors.New("missing state object")
-		}
-		// Run the storage updates concurrently to one another
+	var (
+		storageUpdates = make([]*accountUpdate, len(objects))
+		storageSets    = make([]*trienode.NodeSet, len(objects))
+	)
+	for i, obj := range objects {
+		i, obj := i, obj // closure for the task runner below
 		workers.Go(func() error {
 			// Write any storage changes in the state object to its storage trie
 			update, set, err := obj.commit()
 			if err != nil {
 				return err
 			}
-			if err := merge(set); err != nil {
-				return err
-			}
+			storageUpdates[i], storageSets[i] = update, set
+
 			lock.Lock()
-			updates[obj.addrHash] = update
 			s.StorageCommits = time.Since(start) // overwrite with the longest storage commit runtime
 			lock.Unlock()
 			return nil
 		})
 	}
-	// Wait for everything to finish and update the metrics
+	// Wait for everything to finish, then merge the results in order
 	if err := workers.Wait(); err != nil {
 		return nil, err
 	}
+	if err := merge(rootSet); err != nil {
+		return nil, err
+	}
+	for i, obj := range objects {
+		if err := merge(storageSets[i]); err != nil {
+			return nil, err
+		}
+		updates[obj.addrHash] = storageUpdates[i]
+	}
 	accountUpdatedMeter.Mark(int64(s.AccountUpdated))
 	storageUpdatedMeter.Mark(s.StorageUpdated.Load())
 	accountDeletedMeter.Mark(int64(s.AccountDeleted))
@@ -23,8 +23,10 @@ import (
 	"fmt"
 	"maps"
 	"math"
+	"math/big"
 	"math/rand"
 	"reflect"
+	"runtime"
 	"slices"
 	"strings"
 	"sync"
@@ -1373,3 +1375,86 @@ func TestStorageDirtiness(t *testing.T) {
 	state.RevertToSnapshot(snap)
 	checkDirty(common.Hash{0x1}, common.Hash{0x1}, true)
 }
+
+// fillStorageHeavy populates the state with a number of contracts, each of
+// them modifying a bunch of storage slots.
+func fillStorageHeavy(state *StateDB, contracts, slots int) {
+	for i := 0; i < contracts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		state.SetNonce(addr, 1)
+		for j := 0; j < slots; j++ {
+			key := crypto.Keccak256Hash(addr[:], binary.BigEndian.AppendUint64(nil, uint64(j)))
+			state.SetState(addr, key, common.BigToHash(big.NewInt(int64(i*slots+j+1))))
+		}
+	}
+}
+
+// Tests that the state commit produces the same root and dirty node set no
+// matter how many storage tries are processed concurrently.
+func TestCommitConcurrency(t *testing.T) {
+	defer func(old int) { commitConcurrency = old }(commitConcurrency)
+
+	var (
+		roots []common.Hash
+		sets  []*trienode.MergedNodeSet
+	)
+	for _, concurrency := range []int{1, 4, 64} {
+		commitConcurrency = concurrency
+
+		state, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		fillStorageHeavy(state, 64, 32)
+		update, err := state.commit(false)
+		if err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		roots = append(roots, update.root)
+		sets = append(sets, update.nodes)
+	}
+	for i := 1; i < len(roots); i++ {
+		if roots[i] != roots[0] {
+			t.Fatalf("root mismatch: have %x, want %x", roots[i], roots[0])
+		}
+		if len(sets[i].Sets) != len(sets[0].Sets) {
+			t.Fatalf("nodeset count mismatch: have %d, want %d", len(sets[i].Sets), len(sets[0].Sets))
+		}
+		for owner, set := range sets[0].Sets {
+			other := sets[i].Sets[owner]
+			if other == nil || len(other.Nodes) != len(set.Nodes) || len(other.Leaves) != len(set.Leaves) {
+				t.Fatalf("nodeset mismatch for owner %x", owner)
+			}
+			for path, n := range set.Nodes {
+				if o := other.Nodes[path]; o == nil || o.Hash != n.Hash {
+					t.Fatalf("node mismatch for owner %x path %x", owner, path)
+				}
+			}
+		}
+	}
+}
+
+// Benchmarks the state commit of a storage heavy block with the storage tries
+// processed sequentially and concurrently.
+func BenchmarkCommitStorageHeavy(b *testing.B) {
+	b.Run("sequential", func(b *testing.B) {
+		benchmarkCommitStorageHeavy(b, 1)
+	})
+	b.Run("parallel", func(b *testing.B) {
+		benchmarkCommitStorageHeavy(b, runtime.NumCPU())
+	})
+}
+
+func benchmarkCommitStorageHeavy(b *testing.B, concurrency int) {
+	defer func(old int) { commitConcurrency = old }(commitConcurrency)
+	commitConcurrency = concurrency
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		state, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		fillStorageHeavy(state, 500, 100)
+		b.StartTimer()
+
+		if _, err := state.commit(false); err != nil {
+			b.Fatalf("failed to commit state: %v", err)
+		}
+	}
+}
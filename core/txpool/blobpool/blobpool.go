@@ -1608,6 +1608,11 @@ func (p *BlobPool) Nonce(addr common.Address) uint64 {
 	return p.state.GetNonce(addr)
 }
 
+// Name returns the short name of the subpool, used in stats reporting.
+func (p *BlobPool) Name() string {
+	return "blob"
+}
+
 // Stats retrieves the current pool stats, namely the number of pending and the
 // number of queued (non-executable) transactions.
 func (p *BlobPool) Stats() (int, int) {
@@ -461,6 +461,11 @@ func (pool *LegacyPool) Nonce(addr common.Address) uint64 {
 	return pool.pendingNonces.get(addr)
 }
 
+// Name returns the short name of the subpool, used in stats reporting.
+func (pool *LegacyPool) Name() string {
+	return "legacy"
+}
+
 // Stats retrieves the current pool stats, namely the number of pending and the
 // number of queued (non-executable) transactions.
 func (pool *LegacyPool) Stats() (int, int) {
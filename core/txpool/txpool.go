@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -404,6 +405,28 @@ func (p *TxPool) Stats() (int, int) {
 	return runnable, blocked
 }
 
+// SubpoolStats is the pending and queued transaction count of a single subpool.
+type SubpoolStats struct {
+	Name    string // Short name of the subpool, or its index if unnamed
+	Pending int    // Number of executable transactions
+	Queued  int    // Number of non-executable transactions
+}
+
+// SubpoolStats retrieves the current stats of each subpool individually, in the
+// order the subpools were registered.
+func (p *TxPool) SubpoolStats() []SubpoolStats {
+	stats := make([]SubpoolStats, len(p.subpools))
+	for i, subpool := range p.subpools {
+		if named, ok := subpool.(interface{ Name() string }); ok {
+			stats[i].Name = named.Name()
+		} else {
+			stats[i].Name = strconv.Itoa(i)
+		}
+		stats[i].Pending, stats[i].Queued = subpool.Stats()
+	}
+	return stats
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
 func (p *TxPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -443,3 +444,12 @@ func (api *DebugAPI) GetTrieFlushInterval() (string, error) {
 	}
 	return api.eth.blockchain.GetTrieFlushInterval().String(), nil
 }
+
+// PruningStatus returns the progress of the online state pruning.
+func (api *DebugAPI) PruningStatus() (*pruner.OnlineStatus, error) {
+	if api.eth.pruner == nil {
+		return nil, errors.New("online state pruning is not enabled")
+	}
+	status := api.eth.pruner.Status()
+	return &status, nil
+}
@@ -19,6 +19,7 @@ package eth
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"runtime"
@@ -70,6 +71,7 @@ type Ethereum struct {
 	txPool *txpool.TxPool
 
 	blockchain         *core.BlockChain
+	pruner             *pruner.OnlinePruner
 	handler            *handler
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
@@ -198,6 +200,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			StateScheme:         scheme,
 		}
 	)
+	if config.ParallelExecution {
+		cacheConfig.ParallelExecution = runtime.NumCPU()
+	}
 	if config.VMTrace != "" {
 		var traceConfig json.RawMessage
 		if config.VMTraceJsonConfig != "" {
@@ -227,6 +232,25 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if config.OnlinePruning && scheme == rawdb.PathScheme {
+		// The path-based database prunes the stale state by itself
+		log.Warn("Online state pruning is not needed for path-based database, ignoring it")
+	} else if config.OnlinePruning {
+		if config.NoPruning {
+			return nil, errors.New("online state pruning is not supported in archive mode")
+		}
+		eth.pruner, err = pruner.NewOnlinePruner(chainDb, eth.blockchain, pruner.OnlineConfig{
+			Retain:    config.OnlinePruningRetain,
+			BloomSize: config.OnlinePruningBloomSize,
+			BatchSize: pruner.DefaultOnlineConfig.BatchSize,
+			Throttle:  pruner.DefaultOnlineConfig.Throttle,
+			Interval:  config.OnlinePruningInterval,
+		}, eth.Synced)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if config.BlobPool.Datadir != "" {
 		config.BlobPool.Datadir = stack.ResolvePath(config.BlobPool.Datadir)
 	}
@@ -391,6 +415,11 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+
+	// Start the background state pruning if requested
+	if s.pruner != nil {
+		s.pruner.Start()
+	}
 	return nil
 }
 
@@ -403,6 +432,9 @@ func (s *Ethereum) Stop() error {
 	s.handler.Stop()
 
 	// Then stop everything else.
+	if s.pruner != nil {
+		s.pruner.Stop()
+	}
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
 	s.txPool.Close()
@@ -69,8 +69,9 @@ var (
 	errNoPivotHeader           = errors.New("pivot header is not found")
 )
 
-// peerDropFn is a callback type for dropping a peer detected as malicious.
-type peerDropFn func(id string)
+// peerDropFn is a callback type for dropping a peer detected as malicious, along
+// with the reputation penalty of the misbehaviour, see p2p.Peer.AdjustScore.
+type peerDropFn func(id string, penalty float64)
 
 // badBlockFn is a callback for the async beacon sync to notify the caller that
 // the origin header requested to sync to, produced a chain with a bad block.
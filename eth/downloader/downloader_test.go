@@ -110,7 +110,7 @@ func (dl *downloadTester) newPeer(id string, version uint, blocks []*types.Block
 }
 
 // dropPeer simulates a hard peer removal from the connection pool.
-func (dl *downloadTester) dropPeer(id string) {
+func (dl *downloadTester) dropPeer(id string, penalty float64) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 // timeoutGracePeriod is the amount of time to allow for a peer to deliver a
@@ -148,7 +149,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue) error {
 						// permitted it, consider the peer malicious attempting to
 						// stall the sync.
 						peer.log.Warn("Peer stalling, dropping", "waited", common.PrettyDuration(waited))
-						d.dropPeer(peer.id)
+						d.dropPeer(peer.id, p2p.ScoreTimeout)
 					}
 				}
 			}
@@ -298,7 +299,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue) error {
 			if fails > 2 {
 				queue.updateCapacity(peer, 0, 0)
 			} else {
-				d.dropPeer(peer.id)
+				d.dropPeer(peer.id, p2p.ScoreTimeout)
 			}
 
 		case res := <-responses:
@@ -336,6 +337,11 @@ func (d *Downloader) concurrentFetch(queue typedQueue) error {
 				if errors.Is(err, errInvalidChain) {
 					return err
 				}
+				// Bodies and receipts are validated against the requested headers,
+				// so a mismatching delivery is junk served by the peer itself.
+				if errors.Is(err, errInvalidBody) || errors.Is(err, errInvalidReceipt) {
+					d.dropPeer(peer.id, p2p.ScoreInvalidData)
+				}
 				// Unless a peer delivered something completely else than requested (usually
 				// caused by a timed out request which came through in the end), set it to
 				// idle. If the delivery's stale, the peer should have already been idled.
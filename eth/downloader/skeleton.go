@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 // scratchHeaders is the number of headers to store in a scratch space to allow
@@ -793,7 +794,7 @@ func (s *skeleton) executeTask(peer *peerConnection, req *headerRequest) {
 		// gone stale and monitor them. However, in that case too, we need a way
 		// to protect against malicious peers never responding, so it would need
 		// a second, hard-timeout mechanism.
-		s.drop(peer.id)
+		s.drop(peer.id, p2p.ScoreTimeout)
 
 	case res := <-resCh:
 		// Headers successfully retrieved, update the metrics
@@ -808,24 +809,28 @@ func (s *skeleton) executeTask(peer *peerConnection, req *headerRequest) {
 			// No headers were delivered, reject the response and reschedule
 			peer.log.Debug("No headers delivered")
 			res.Done <- errors.New("no headers delivered")
+			s.drop(peer.id, p2p.ScoreUselessResponse)
 			s.scheduleRevertRequest(req)
 
 		case headers[0].Number.Uint64() != req.head:
 			// Header batch anchored at non-requested number
 			peer.log.Debug("Invalid header response head", "have", headers[0].Number, "want", req.head)
 			res.Done <- errors.New("invalid header batch anchor")
+			s.drop(peer.id, p2p.ScoreInvalidData)
 			s.scheduleRevertRequest(req)
 
 		case req.head >= requestHeaders && len(headers) != requestHeaders:
 			// Invalid number of non-genesis headers delivered, reject the response and reschedule
 			peer.log.Debug("Invalid non-genesis header count", "have", len(headers), "want", requestHeaders)
 			res.Done <- errors.New("not enough non-genesis headers delivered")
+			s.drop(peer.id, p2p.ScoreInvalidData)
 			s.scheduleRevertRequest(req)
 
 		case req.head < requestHeaders && uint64(len(headers)) != req.head:
 			// Invalid number of genesis headers delivered, reject the response and reschedule
 			peer.log.Debug("Invalid genesis header count", "have", len(headers), "want", headers[0].Number.Uint64())
 			res.Done <- errors.New("not enough genesis headers delivered")
+			s.drop(peer.id, p2p.ScoreInvalidData)
 			s.scheduleRevertRequest(req)
 
 		default:
@@ -835,6 +840,7 @@ func (s *skeleton) executeTask(peer *peerConnection, req *headerRequest) {
 				if headers[i].ParentHash != headers[i+1].Hash() {
 					peer.log.Debug("Invalid hash progression", "index", i, "wantparenthash", headers[i].ParentHash, "haveparenthash", headers[i+1].Hash())
 					res.Done <- errors.New("invalid hash progression")
+					s.drop(peer.id, p2p.ScoreInvalidData)
 					s.scheduleRevertRequest(req)
 					return
 				}
@@ -955,7 +961,7 @@ func (s *skeleton) processResponse(res *headerResponse) (linked bool, merged boo
 			for i := 0; i < requestHeaders; i++ {
 				s.scratchSpace[i] = nil
 			}
-			s.drop(s.scratchOwners[0])
+			s.drop(s.scratchOwners[0], p2p.ScoreInvalidData)
 			s.scratchOwners[0] = ""
 			break
 		}
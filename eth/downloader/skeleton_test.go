@@ -183,7 +183,7 @@ func (p *skeletonTestPeer) RequestHeadersByNumber(origin uint64, amount int, ski
 		sink <- res
 		if err := <-res.Done; err != nil {
 			log.Warn("Skeleton test peer response rejected", "err", err)
-			p.dropped.Add(1)
+			p.dropped.CompareAndSwap(0, 1) // rejections are also penalized via drop
 		}
 	}()
 	return req, nil
@@ -842,9 +842,9 @@ func TestSkeletonSyncRetrievals(t *testing.T) {
 		}
 		// Create a peer dropper to track malicious peers
 		dropped := make(map[string]int)
-		drop := func(peer string) {
+		drop := func(peer string, penalty float64) {
 			if p := peerset.Peer(peer); p != nil {
-				p.peer.(*skeletonTestPeer).dropped.Add(1)
+				p.peer.(*skeletonTestPeer).dropped.CompareAndSwap(0, 1)
 			}
 			peerset.Unregister(peer)
 			dropped[peer]++
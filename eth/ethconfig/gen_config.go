@@ -28,6 +28,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TransactionHistory      uint64                 `toml:",omitempty"`
 		StateHistory            uint64                 `toml:",omitempty"`
 		StateScheme             string                 `toml:",omitempty"`
+		OnlinePruning           bool                   `toml:",omitempty"`
+		OnlinePruningRetain     uint64                 `toml:",omitempty"`
+		OnlinePruningBloomSize  uint64                 `toml:",omitempty"`
+		OnlinePruningInterval   time.Duration          `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -51,6 +55,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		EnableWitnessCollection bool `toml:"-"`
+		ParallelExecution       bool `toml:",omitempty"`
 		VMTrace                 string
 		VMTraceJsonConfig       string
 		DocRoot                 string `toml:"-"`
@@ -72,6 +77,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
 	enc.StateScheme = c.StateScheme
+	enc.OnlinePruning = c.OnlinePruning
+	enc.OnlinePruningRetain = c.OnlinePruningRetain
+	enc.OnlinePruningBloomSize = c.OnlinePruningBloomSize
+	enc.OnlinePruningInterval = c.OnlinePruningInterval
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -95,6 +104,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.EnableWitnessCollection = c.EnableWitnessCollection
+	enc.ParallelExecution = c.ParallelExecution
 	enc.VMTrace = c.VMTrace
 	enc.VMTraceJsonConfig = c.VMTraceJsonConfig
 	enc.DocRoot = c.DocRoot
@@ -120,6 +130,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TransactionHistory      *uint64                `toml:",omitempty"`
 		StateHistory            *uint64                `toml:",omitempty"`
 		StateScheme             *string                `toml:",omitempty"`
+		OnlinePruning           *bool                  `toml:",omitempty"`
+		OnlinePruningRetain     *uint64                `toml:",omitempty"`
+		OnlinePruningBloomSize  *uint64                `toml:",omitempty"`
+		OnlinePruningInterval   *time.Duration         `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -143,6 +157,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		EnableWitnessCollection *bool `toml:"-"`
+		ParallelExecution       *bool `toml:",omitempty"`
 		VMTrace                 *string
 		VMTraceJsonConfig       *string
 		DocRoot                 *string `toml:"-"`
@@ -189,6 +204,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
+	if dec.OnlinePruning != nil {
+		c.OnlinePruning = *dec.OnlinePruning
+	}
+	if dec.OnlinePruningRetain != nil {
+		c.OnlinePruningRetain = *dec.OnlinePruningRetain
+	}
+	if dec.OnlinePruningBloomSize != nil {
+		c.OnlinePruningBloomSize = *dec.OnlinePruningBloomSize
+	}
+	if dec.OnlinePruningInterval != nil {
+		c.OnlinePruningInterval = *dec.OnlinePruningInterval
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
@@ -258,6 +285,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnableWitnessCollection != nil {
 		c.EnableWitnessCollection = *dec.EnableWitnessCollection
 	}
+	if dec.ParallelExecution != nil {
+		c.ParallelExecution = *dec.ParallelExecution
+	}
 	if dec.VMTrace != nil {
 		c.VMTrace = *dec.VMTrace
 	}
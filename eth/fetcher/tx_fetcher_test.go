@@ -1438,7 +1438,7 @@ func TestInvalidAnnounceMetadata(t *testing.T) {
 					return make([]error, len(txs))
 				},
 				func(string, []common.Hash) error { return nil },
-				func(peer string) { drop <- peer },
+				func(peer string, penalty float64) { drop <- peer },
 			)
 		},
 		steps: []interface{}{
@@ -1997,7 +1997,7 @@ func TestTransactionForgotten(t *testing.T) {
 			return errs
 		},
 		func(string, []common.Hash) error { return nil },
-		func(string) {},
+		func(string, float64) {},
 	)
 	fetcher.Start()
 	defer fetcher.Stop()
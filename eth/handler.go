@@ -180,7 +180,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		return nil, errors.New("snap sync not supported with snapshots disabled")
 	}
 	// Construct the downloader (long sync)
-	h.downloader = downloader.New(config.Database, h.eventMux, h.chain, h.removePeer, h.enableSyncedFeatures)
+	h.downloader = downloader.New(config.Database, h.eventMux, h.chain, h.dropPeer, h.enableSyncedFeatures)
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := h.peers.peer(peer)
@@ -192,7 +192,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	addTxs := func(txs []*types.Transaction) []error {
 		return h.txpool.Add(txs, false, false)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, h.removePeer)
+	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, h.dropPeer)
 	return h, nil
 }
 
@@ -341,6 +341,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 				}
 				if headers[0].Number.Uint64() != number || headers[0].Hash() != hash {
 					peer.Log().Info("Required block mismatch, dropping peer", "number", number, "hash", headers[0].Hash(), "want", hash)
+					peer.Peer.AdjustScore(p2p.ScoreInvalidData)
 					res.Done <- errors.New("required block mismatch")
 					return
 				}
@@ -348,7 +349,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 				res.Done <- nil
 			case <-timeout.C:
 				peer.Log().Warn("Required block challenge timed out, dropping", "addr", peer.RemoteAddr(), "type", peer.Name())
-				h.removePeer(peer.ID())
+				h.dropPeer(peer.ID(), p2p.ScoreTimeout)
 			}
 		}(number, hash, req)
 	}
@@ -380,10 +381,11 @@ func (h *handler) runSnapExtension(peer *snap.Peer, handler snap.Handler) error
 	return handler(peer)
 }
 
-// removePeer requests disconnection of a peer.
-func (h *handler) removePeer(id string) {
+// dropPeer penalizes a peer for misbehaving and requests its disconnection.
+func (h *handler) dropPeer(id string, penalty float64) {
 	peer := h.peers.peer(id)
 	if peer != nil {
+		peer.Peer.AdjustScore(penalty)
 		peer.Peer.Disconnect(p2p.DiscUselessPeer)
 	}
 }
@@ -22,6 +22,11 @@ import (
 
 // enrEntry is the ENR entry which advertises `snap` protocol on the discovery.
 type enrEntry struct {
+	// Window is the number of recent states the node can serve ranges for,
+	// which might extend beyond the snapshot layers if state histories are
+	// available. It's missing from the records of older nodes.
+	Window uint64 `rlp:"optional"`
+
 	// Ignore additional fields (for forward compatibility).
 	Rest []rlp.RawValue `rlp:"tail"`
 }
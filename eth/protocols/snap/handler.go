@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -31,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
 )
 
 const (
@@ -89,6 +91,9 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 		return n.Load(&snap) == nil
 	})
 
+	// Advertise the range of states the local node can serve.
+	entry := &enrEntry{Window: stateWindow(backend.Chain())}
+
 	protocols := make([]p2p.Protocol, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		version := version // Closure
@@ -108,7 +113,7 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 			PeerInfo: func(id enode.ID) interface{} {
 				return backend.PeerInfo(id)
 			},
-			Attributes:     []enr.Entry{&enrEntry{}},
+			Attributes:     []enr.Entry{entry},
 			DialCandidates: dnsdisc,
 		}
 	}
@@ -161,7 +166,15 @@ func HandleMessage(backend Backend, peer *Peer) error {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
 		// Service the request, potentially returning nothing in case of errors
+		retry := req
 		accounts, proofs := ServiceGetAccountRangeQuery(backend.Chain(), &req)
+		if len(accounts) == 0 && len(proofs) == 0 {
+			// The state might be a historic one, reconstruct it and serve the
+			// request again if that's done in time
+			if historicBuilds.build(backend.Chain(), retry.Root, peer.id) {
+				accounts, proofs = ServiceGetAccountRangeQuery(backend.Chain(), &retry)
+			}
+		}
 
 		// Send back anything accumulated (or empty in case of errors)
 		return p2p.Send(peer.rw, AccountRangeMsg, &AccountRangePacket{
@@ -193,7 +206,15 @@ func HandleMessage(backend Backend, peer *Peer) error {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
 		// Service the request, potentially returning nothing in case of errors
+		retry := req
 		slots, proofs := ServiceGetStorageRangesQuery(backend.Chain(), &req)
+		if len(slots) == 0 && len(proofs) == 0 && len(retry.Accounts) > 0 {
+			// The state might be a historic one, reconstruct it and serve the
+			// request again if that's done in time
+			if historicBuilds.build(backend.Chain(), retry.Root, peer.id) {
+				slots, proofs = ServiceGetStorageRangesQuery(backend.Chain(), &retry)
+			}
+		}
 
 		// Send back anything accumulated (or empty in case of errors)
 		return p2p.Send(peer.rw, StorageRangesMsg, &StorageRangesPacket{
@@ -283,14 +304,26 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 	if req.Bytes > softResponseLimit {
 		req.Bytes = softResponseLimit
 	}
-	// Retrieve the requested state and bail out if non existent
-	tr, err := trie.New(trie.StateTrieID(req.Root), chain.TrieDB())
-	if err != nil {
-		return nil, nil
-	}
-	it, err := chain.Snapshots().AccountIterator(req.Root, req.Origin)
-	if err != nil {
-		return nil, nil
+	// Retrieve the requested state and bail out if non existent. States which
+	// already left the snapshot tree may still be served from state histories.
+	var (
+		tr proofTrie
+		it snapshot.AccountIterator
+	)
+	if live, err := trie.New(trie.StateTrieID(req.Root), chain.TrieDB()); err == nil {
+		if it, err = chain.Snapshots().AccountIterator(req.Root, req.Origin); err != nil {
+			return nil, nil
+		}
+		tr = live
+	} else {
+		hs := historicState(chain, req.Root)
+		if hs == nil {
+			return nil, nil
+		}
+		if it, err = newHistoricAccountIterator(hs, req.Origin); err != nil {
+			return nil, nil
+		}
+		tr = hs.AccountTrie()
 	}
 	// Iterate over the requested range and pile accounts up
 	var (
@@ -351,6 +384,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 		slots  [][]*StorageData
 		proofs [][]byte
 		size   uint64
+		hs     *pathdb.HistoricState // Historic state view, if not in the snapshot tree
 	)
 	for _, account := range req.Accounts {
 		// If we've exceeded the requested data limit, abort without opening
@@ -368,9 +402,23 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 			limit, req.Limit = common.BytesToHash(req.Limit), nil
 		}
 		// Retrieve the requested state and bail out if non existent
-		it, err := chain.Snapshots().StorageIterator(req.Root, account, origin)
-		if err != nil {
-			return nil, nil
+		var (
+			it  snapshot.StorageIterator
+			err error
+		)
+		if hs == nil {
+			if it, err = chain.Snapshots().StorageIterator(req.Root, account, origin); err != nil {
+				// The state already left the snapshot tree, try to serve
+				// it from the state histories instead.
+				if hs = historicState(chain, req.Root); hs == nil {
+					return nil, nil
+				}
+			}
+		}
+		if hs != nil {
+			if it, err = newHistoricStorageIterator(hs, account, origin); err != nil {
+				return nil, nil
+			}
 		}
 		// Iterate over the requested range and pile slots up
 		var (
@@ -410,16 +458,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 		if origin != (common.Hash{}) || (abort && len(storage) > 0) {
 			// Request started at a non-zero hash or was capped prematurely, add
 			// the endpoint Merkle proofs
-			accTrie, err := trie.NewStateTrie(trie.StateTrieID(req.Root), chain.TrieDB())
-			if err != nil {
-				return nil, nil
-			}
-			acc, err := accTrie.GetAccountByHash(account)
-			if err != nil || acc == nil {
-				return nil, nil
-			}
-			id := trie.StorageTrieID(req.Root, account, acc.Root)
-			stTrie, err := trie.NewStateTrie(id, chain.TrieDB())
+			stTrie, err := storageProofTrie(chain, hs, req.Root, account)
 			if err != nil {
 				return nil, nil
 			}
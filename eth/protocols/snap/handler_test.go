@@ -0,0 +1,250 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// newHistoricChain creates a path-based chain long enough for the states of its
+// first blocks to be only available via the state histories. Every block bumps
+// a storage slot of a contract and funds a new account.
+func newHistoricChain(t *testing.T) (*core.BlockChain, []*types.Block, common.Address) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		storage  = make(map[common.Hash]common.Hash)
+	)
+	for i := 0; i < 64; i++ {
+		storage[common.BigToHash(big.NewInt(int64(i+1)))] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			// Increments the slot 0 on every call
+			contract: {Code: common.FromHex("600054600101600055"), Storage: storage, Balance: common.Big0},
+		},
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), state.TriesInMemory+16, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, nil, 100000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(sender), common.BigToAddress(big.NewInt(int64(0x1000+i))), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(rawdb.PathScheme), gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("Failed to insert chain: %v", err)
+	}
+	return chain, blocks, contract
+}
+
+// proofSet converts a served list of proof nodes into a proof database.
+func proofSet(proof [][]byte) *trienode.ProofSet {
+	nodes := make(trienode.ProofList, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return nodes.Set()
+}
+
+// waitHistoricBuilds waits until no historic state reconstruction is running.
+func waitHistoricBuilds(t *testing.T) {
+	for start := time.Now(); !historicBuilds.idle(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Historic state reconstruction timed out")
+		}
+	}
+}
+
+// Tests that account and storage ranges of states which already left the live
+// snapshot tree are served from the state histories, along with valid proofs.
+func TestServeHistoricRanges(t *testing.T) {
+	chain, blocks, contract := newHistoricChain(t)
+	defer chain.Stop()
+
+	root := blocks[4].Root()
+	if _, err := chain.Snapshots().AccountIterator(root, common.Hash{}); err == nil {
+		t.Fatal("Historic state is unexpectedly available in the snapshot tree")
+	}
+	// Historic states are not reconstructed inline, only by the builder
+	req := &GetAccountRangePacket{
+		Root:  root,
+		Limit: common.MaxHash,
+		Bytes: softResponseLimit,
+	}
+	if accounts, proof := ServiceGetAccountRangeQuery(chain, req); len(accounts) != 0 || len(proof) != 0 {
+		t.Fatal("Historic state served before reconstruction")
+	}
+	waitHistoricBuilds(t)
+
+	// Reconstructions beyond the concurrency limit are refused
+	historicBuilds.lock.Lock()
+	for i := 0; i < maxHistoricBuilds; i++ {
+		historicBuilds.builds[common.Hash{byte(i)}] = make(chan struct{})
+	}
+	historicBuilds.lock.Unlock()
+	if historicBuilds.build(chain, root, "peer") {
+		t.Fatal("Historic state reconstructed beyond the concurrency limit")
+	}
+	historicBuilds.lock.Lock()
+	clear(historicBuilds.builds)
+	historicBuilds.lock.Unlock()
+
+	if !historicBuilds.build(chain, root, "peer") {
+		t.Fatal("Failed to reconstruct historic state")
+	}
+	if historicBuilds.build(chain, blocks[5].Root(), "peer") {
+		t.Fatal("Historic state reconstructed beyond the peer budget")
+	}
+	// Move the disk layer onto the chain head, the reconstructed state must
+	// still be served by rebasing it
+	if err := chain.TrieDB().Commit(chain.CurrentBlock().Root, false); err != nil {
+		t.Fatalf("Failed to flush state: %v", err)
+	}
+	// Retrieve the entire account range, ensuring it proves against the root
+	accounts, proof := ServiceGetAccountRangeQuery(chain, req)
+	if len(accounts) == 0 {
+		t.Fatal("No accounts served for historic state")
+	}
+	var (
+		keys   = make([][]byte, len(accounts))
+		values = make([][]byte, len(accounts))
+		stRoot common.Hash
+	)
+	for i, account := range accounts {
+		full, err := types.FullAccount(account.Body)
+		if err != nil {
+			t.Fatalf("Failed to decode account: %v", err)
+		}
+		keys[i] = account.Hash[:]
+		values[i], _ = rlp.EncodeToBytes(full)
+
+		if account.Hash == crypto.Keccak256Hash(contract[:]) {
+			stRoot = full.Root
+		}
+	}
+	if _, err := trie.VerifyRangeProof(root, common.Hash{}.Bytes(), keys, values, proofSet(proof)); err != nil {
+		t.Fatalf("Invalid account range proof: %v", err)
+	}
+	if stRoot == (common.Hash{}) {
+		t.Fatal("Contract missing from account range")
+	}
+	// Retrieve a partial storage range, ensuring it proves against the historic
+	// storage root of the contract
+	origin := common.HexToHash("0x4000000000000000000000000000000000000000000000000000000000000000")
+	slots, proof := ServiceGetStorageRangesQuery(chain, &GetStorageRangesPacket{
+		Root:     root,
+		Accounts: []common.Hash{crypto.Keccak256Hash(contract[:])},
+		Origin:   origin[:],
+		Bytes:    softResponseLimit,
+	})
+	if len(slots) != 1 || len(slots[0]) == 0 || len(proof) == 0 {
+		t.Fatalf("Unexpected storage response: %d ranges, %d proof nodes", len(slots), len(proof))
+	}
+	keys, values = keys[:0], values[:0]
+	for _, slot := range slots[0] {
+		keys = append(keys, slot.Hash[:])
+		values = append(values, slot.Body)
+	}
+	if _, err := trie.VerifyRangeProof(stRoot, origin[:], keys, values, proofSet(proof)); err != nil {
+		t.Fatalf("Invalid storage range proof: %v", err)
+	}
+	// Unknown states can't be served
+	accounts, proof = ServiceGetAccountRangeQuery(chain, &GetAccountRangePacket{
+		Root:  common.Hash{0x1},
+		Limit: common.MaxHash,
+		Bytes: softResponseLimit,
+	})
+	if len(accounts) != 0 || len(proof) != 0 {
+		t.Fatal("Unknown state served")
+	}
+}
+
+// Tests that the advertised state window is encoded in the ENR entry while the
+// records of older nodes still decode.
+func TestENRStateWindow(t *testing.T) {
+	blob, err := rlp.EncodeToBytes(&enrEntry{Window: 1152})
+	if err != nil {
+		t.Fatalf("Failed to encode entry: %v", err)
+	}
+	var entry enrEntry
+	if err := rlp.DecodeBytes(blob, &entry); err != nil || entry.Window != 1152 {
+		t.Fatalf("Failed to decode entry: %v, window %d", err, entry.Window)
+	}
+	// Legacy entries don't have any fields
+	entry = enrEntry{}
+	if err := rlp.DecodeBytes(common.FromHex("0xc0"), &entry); err != nil || entry.Window != 0 {
+		t.Fatalf("Failed to decode legacy entry: %v, window %d", err, entry.Window)
+	}
+}
+
+// Tests that idle peers are ordered by advertised state window first, and by
+// capacity within the same window.
+func TestCapacitySortWindow(t *testing.T) {
+	idlers := &capacitySort{
+		ids:     []string{"a", "b", "c", "d"},
+		caps:    []int{100, 10, 50, 1},
+		windows: []uint64{128, 1152, 1152, 0},
+	}
+	sort.Sort(sort.Reverse(idlers))
+
+	want := []string{"c", "b", "a", "d"}
+	for i, id := range want {
+		if idlers.ids[i] != id {
+			t.Fatalf("Unexpected order: have %v, want %v", idlers.ids, want)
+		}
+	}
+	// Without windows (non-range requests), only the capacity matters
+	idlers = &capacitySort{
+		ids:  []string{"a", "b", "c", "d"},
+		caps: []int{100, 10, 50, 1},
+	}
+	sort.Sort(sort.Reverse(idlers))
+
+	want = []string{"a", "c", "b", "d"}
+	for i, id := range want {
+		if idlers.ids[i] != id {
+			t.Fatalf("Unexpected order: have %v, want %v", idlers.ids, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// proofTrie is the trie functionality needed to prove the boundaries of a range.
+type proofTrie interface {
+	Prove(key []byte, proofDb ethdb.KeyValueWriter) error
+}
+
+// stateWindow returns the number of recent states the local node can serve
+// ranges for. Beyond the live snapshot layers, the path-based trie database
+// can also serve the states covered by its state histories.
+func stateWindow(chain *core.BlockChain) uint64 {
+	return uint64(state.TriesInMemory) + chain.TrieDB().HistoricStateWindow()
+}
+
+const (
+	// historicBuildInterval is the minimum time between two historic state
+	// reconstructions a single remote peer may start.
+	historicBuildInterval = time.Minute
+
+	// maxHistoricBuilds is the maximum number of historic states reconstructed
+	// at the same time.
+	maxHistoricBuilds = 2
+
+	// historicBuildWait is the maximum time a request waits for the requested
+	// historic state to be reconstructed, before being answered empty. The
+	// reconstruction carries on in the background for subsequent requests.
+	historicBuildWait = 2 * time.Second
+)
+
+// historicState returns a read-only view of a state which is not available in
+// the live snapshot tree anymore, or nil if the state is not reconstructed yet.
+// Requests never construct historic states inline, see historicBuilder.
+func historicState(chain *core.BlockChain, root common.Hash) *pathdb.HistoricState {
+	return chain.TrieDB().CachedHistoricState(root)
+}
+
+// historicBuilder reconstructs historic states requested by remote peers in the
+// background, at most maxHistoricBuilds at a time. Every peer may only start one
+// reconstruction per historicBuildInterval, so that it cannot keep the local node
+// busy reverting state histories for arbitrary roots. Requests for a state which
+// is already being reconstructed join the running reconstruction.
+type historicBuilder struct {
+	peers  map[string]time.Time          // Time of the last reconstruction started by each peer
+	builds map[common.Hash]chan struct{} // Reconstructions in progress, closed when done
+	lock   sync.Mutex
+}
+
+// historicBuilds is the builder of the historic states served over snap.
+var historicBuilds = &historicBuilder{
+	peers:  make(map[string]time.Time),
+	builds: make(map[common.Hash]chan struct{}),
+}
+
+// build reconstructs the given historic state on behalf of the given peer in the
+// background, or joins the reconstruction of it already in progress. It waits at
+// most historicBuildWait and reports whether the reconstruction finished, in which
+// case the request can be served again from the reconstructed state.
+func (b *historicBuilder) build(chain *core.BlockChain, root common.Hash, peer string) bool {
+	if chain.TrieDB().HistoricStateWindow() == 0 {
+		return false
+	}
+	b.lock.Lock()
+	done, ok := b.builds[root]
+	if !ok {
+		now := time.Now()
+		if len(b.builds) >= maxHistoricBuilds {
+			b.lock.Unlock()
+			return false
+		}
+		if last, ok := b.peers[peer]; ok && now.Sub(last) < historicBuildInterval {
+			b.lock.Unlock()
+			return false
+		}
+		for id, last := range b.peers {
+			if now.Sub(last) >= historicBuildInterval {
+				delete(b.peers, id)
+			}
+		}
+		b.peers[peer] = now
+
+		done = make(chan struct{})
+		b.builds[root] = done
+		go func() {
+			defer func() {
+				b.lock.Lock()
+				delete(b.builds, root)
+				b.lock.Unlock()
+				close(done)
+			}()
+			if _, err := chain.TrieDB().HistoricState(root); err != nil {
+				log.Trace("Historic state unavailable", "root", root, "peer", peer, "err", err)
+			}
+		}()
+	}
+	b.lock.Unlock()
+
+	timer := time.NewTimer(historicBuildWait)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// idle reports whether no reconstruction is in progress.
+func (b *historicBuilder) idle() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return len(b.builds) == 0
+}
+
+// storageProofTrie opens the storage trie of the given account for proving slot
+// ranges, either in the live state or in the historic state view if set.
+func storageProofTrie(chain *core.BlockChain, hs *pathdb.HistoricState, root common.Hash, account common.Hash) (proofTrie, error) {
+	if hs != nil {
+		return hs.StorageTrie(account)
+	}
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(root), chain.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	acc, err := accTrie.GetAccountByHash(account)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil {
+		return nil, fmt.Errorf("account %#x is not existent", account)
+	}
+	return trie.NewStateTrie(trie.StorageTrieID(root, account, acc.Root), chain.TrieDB())
+}
+
+// historicAccountIterator is an account iterator stepping over the leaves of
+// a historic account trie, converting them into the slim snapshot format.
+type historicAccountIterator struct {
+	it      *trie.Iterator
+	account []byte
+	err     error
+}
+
+// newHistoricAccountIterator creates an account iterator over the historic
+// state, starting at the given account hash.
+func newHistoricAccountIterator(hs *pathdb.HistoricState, origin common.Hash) (snapshot.AccountIterator, error) {
+	nodeIt, err := hs.AccountTrie().NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	return &historicAccountIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+func (it *historicAccountIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.it.Next() {
+		it.err = it.it.Err
+		return false
+	}
+	var acc types.StateAccount
+	if err := rlp.DecodeBytes(it.it.Value, &acc); err != nil {
+		it.err = err
+		return false
+	}
+	it.account = types.SlimAccountRLP(acc)
+	return true
+}
+
+func (it *historicAccountIterator) Error() error      { return it.err }
+func (it *historicAccountIterator) Hash() common.Hash { return common.BytesToHash(it.it.Key) }
+func (it *historicAccountIterator) Account() []byte   { return it.account }
+func (it *historicAccountIterator) Release()          {}
+
+// historicStorageIterator is a storage iterator stepping over the leaves of a
+// historic storage trie.
+type historicStorageIterator struct {
+	it *trie.Iterator
+}
+
+// newHistoricStorageIterator creates a storage iterator over the given account
+// in the historic state, starting at the given slot hash.
+func newHistoricStorageIterator(hs *pathdb.HistoricState, account common.Hash, origin common.Hash) (snapshot.StorageIterator, error) {
+	st, err := hs.StorageTrie(account)
+	if err != nil {
+		return nil, err
+	}
+	nodeIt, err := st.NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	return &historicStorageIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+func (it *historicStorageIterator) Next() bool        { return it.it.Next() }
+func (it *historicStorageIterator) Error() error      { return it.it.Err }
+func (it *historicStorageIterator) Hash() common.Hash { return common.BytesToHash(it.it.Key) }
+func (it *historicStorageIterator) Slot() []byte      { return it.it.Value }
+func (it *historicStorageIterator) Release()          {}
@@ -29,6 +29,7 @@ type Peer struct {
 	*p2p.Peer                   // The embedded P2P package peer
 	rw        p2p.MsgReadWriter // Input/output streams for snap
 	version   uint              // Protocol version negotiated
+	window    uint64            // Number of recent states advertised as servable
 
 	logger log.Logger // Contextual logger with the peer id injected
 }
@@ -37,11 +38,19 @@ type Peer struct {
 // version.
 func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
 	id := p.ID().String()
+
+	// Older nodes don't advertise their serving window, nor is the record of
+	// inbound peers known. Leave the window unknown (zero) in these cases.
+	var entry enrEntry
+	if p.Node().Load(&entry) != nil {
+		entry.Window = 0
+	}
 	return &Peer{
 		id:      id,
 		Peer:    p,
 		rw:      rw,
 		version: version,
+		window:  entry.Window,
 		logger:  log.New("peer", id[:8]),
 	}
 }
@@ -66,6 +75,12 @@ func (p *Peer) Version() uint {
 	return p.version
 }
 
+// StateWindow retrieves the number of recent states the peer advertised to be
+// able to serve, or 0 if unknown.
+func (p *Peer) StateWindow() uint64 {
+	return p.window
+}
+
 // Log overrides the P2P logger with the higher level one containing only the id.
 func (p *Peer) Log() log.Logger {
 	return p.logger
@@ -571,6 +571,21 @@ func (s *Syncer) Register(peer SyncPeer) error {
 	return nil
 }
 
+// windowedPeer is implemented by sync peers advertising the number of recent
+// states they are able to serve.
+type windowedPeer interface {
+	StateWindow() uint64
+}
+
+// peerWindow returns the state window advertised by the given peer, or 0 if
+// unknown. The caller must hold the syncer lock.
+func (s *Syncer) peerWindow(id string) uint64 {
+	if peer, ok := s.peers[id].(windowedPeer); ok {
+		return peer.StateWindow()
+	}
+	return 0
+}
+
 // Unregister injects a new data source into the syncer's peerset.
 func (s *Syncer) Unregister(id string) error {
 	// Remove all traces of the peer from the registry
@@ -1008,8 +1023,9 @@ func (s *Syncer) assignAccountTasks(success chan *accountResponse, fail chan *ac
 
 	// Sort the peers by download capacity to use faster ones if many available
 	idlers := &capacitySort{
-		ids:  make([]string, 0, len(s.accountIdlers)),
-		caps: make([]int, 0, len(s.accountIdlers)),
+		ids:     make([]string, 0, len(s.accountIdlers)),
+		caps:    make([]int, 0, len(s.accountIdlers)),
+		windows: make([]uint64, 0, len(s.accountIdlers)),
 	}
 	targetTTL := s.rates.TargetTimeout()
 	for id := range s.accountIdlers {
@@ -1018,6 +1034,7 @@ func (s *Syncer) assignAccountTasks(success chan *accountResponse, fail chan *ac
 		}
 		idlers.ids = append(idlers.ids, id)
 		idlers.caps = append(idlers.caps, s.rates.Capacity(id, AccountRangeMsg, targetTTL))
+		idlers.windows = append(idlers.windows, s.peerWindow(id))
 	}
 	if len(idlers.ids) == 0 {
 		return
@@ -1208,8 +1225,9 @@ func (s *Syncer) assignStorageTasks(success chan *storageResponse, fail chan *st
 
 	// Sort the peers by download capacity to use faster ones if many available
 	idlers := &capacitySort{
-		ids:  make([]string, 0, len(s.storageIdlers)),
-		caps: make([]int, 0, len(s.storageIdlers)),
+		ids:     make([]string, 0, len(s.storageIdlers)),
+		caps:    make([]int, 0, len(s.storageIdlers)),
+		windows: make([]uint64, 0, len(s.storageIdlers)),
 	}
 	targetTTL := s.rates.TargetTimeout()
 	for id := range s.storageIdlers {
@@ -1218,6 +1236,7 @@ func (s *Syncer) assignStorageTasks(success chan *storageResponse, fail chan *st
 		}
 		idlers.ids = append(idlers.ids, id)
 		idlers.caps = append(idlers.caps, s.rates.Capacity(id, StorageRangesMsg, targetTTL))
+		idlers.windows = append(idlers.windows, s.peerWindow(id))
 	}
 	if len(idlers.ids) == 0 {
 		return
@@ -3161,11 +3180,13 @@ func estimateRemainingSlots(hashes int, last common.Hash) (uint64, error) {
 }
 
 // capacitySort implements the Sort interface, allowing sorting by peer message
-// throughput. Note, callers should use sort.Reverse to get the desired effect
-// of highest capacity being at the front.
+// throughput and, for range requests, by advertised state window first. Note,
+// callers should use sort.Reverse to get the desired effect of the widest window
+// and highest capacity being at the front.
 type capacitySort struct {
-	ids  []string
-	caps []int
+	ids     []string
+	caps    []int
+	windows []uint64 // Advertised state windows, nil if irrelevant for the request type
 }
 
 func (s *capacitySort) Len() int {
@@ -3173,12 +3194,20 @@ func (s *capacitySort) Len() int {
 }
 
 func (s *capacitySort) Less(i, j int) bool {
+	// Peers serving older states are less likely to reject requests after the
+	// pivot moves on, so prefer them regardless of their throughput.
+	if s.windows != nil && s.windows[i] != s.windows[j] {
+		return s.windows[i] < s.windows[j]
+	}
 	return s.caps[i] < s.caps[j]
 }
 
 func (s *capacitySort) Swap(i, j int) {
 	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
 	s.caps[i], s.caps[j] = s.caps[j], s.caps[i]
+	if s.windows != nil {
+		s.windows[i], s.windows[j] = s.windows[j], s.windows[i]
+	}
 }
 
 // healRequestSort implements the Sort interface, allowing sorting trienode
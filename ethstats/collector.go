@@ -0,0 +1,328 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+)
+
+// Collector is a minimal stats server, speaking both the legacy and the extended
+// reporting protocol. It keeps the latest reports of every connected node and
+// exposes them for pulling in the Prometheus text format on /metrics. It's meant
+// as a reference implementation and for local testing, not as a dashboard.
+type Collector struct {
+	secret   string
+	upgrader websocket.Upgrader
+
+	nodes map[string]*nodeReport // Latest reports of connected nodes by id
+	lock  sync.RWMutex
+}
+
+// nodeReport is the latest data reported by a single node.
+type nodeReport struct {
+	info    nodeInfo
+	version int // Negotiated extended protocol version
+
+	latency int
+	block   *blockStats
+	pending *pendStats
+	stats   *nodeStats
+	ext     *extStats
+}
+
+// NewCollector creates a stats server accepting nodes logging in with the given
+// secret.
+func NewCollector(secret string) *Collector {
+	return &Collector{
+		secret: secret,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		nodes: make(map[string]*nodeReport),
+	}
+}
+
+// ServeHTTP implements http.Handler, accepting reporting nodes on /api and
+// serving the collected metrics on /metrics.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api":
+		conn, err := c.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Debug("Failed to upgrade stats connection", "err", err)
+			return
+		}
+		c.serve(conn)
+	case "/metrics":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		c.writeMetrics(w)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Nodes returns the ids of the nodes currently reporting to the collector.
+func (c *Collector) Nodes() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	ids := make([]string, 0, len(c.nodes))
+	for id := range c.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// collectorMsg is a message of the stats protocol, as seen by the server.
+type collectorMsg struct {
+	Emit []json.RawMessage `json:"emit"`
+}
+
+// serve runs the server side of the stats protocol on a single connection until
+// the node disconnects or misbehaves.
+func (c *Collector) serve(ws *websocket.Conn) {
+	conn := newConnectionWrapper(ws)
+	defer conn.Close()
+
+	// Authenticate the node and negotiate the protocol version
+	var (
+		hello   collectorMsg
+		command string
+		auth    authMsg
+	)
+	if err := conn.ReadJSON(&hello); err != nil || len(hello.Emit) != 2 {
+		log.Debug("Invalid stats login", "err", err)
+		return
+	}
+	if err := json.Unmarshal(hello.Emit[0], &command); err != nil || command != "hello" {
+		log.Debug("Unexpected stats login message", "command", command)
+		return
+	}
+	if err := json.Unmarshal(hello.Emit[1], &auth); err != nil || auth.Secret != c.secret {
+		conn.WriteJSON(map[string][]interface{}{"emit": {"unauthorized"}})
+		return
+	}
+	report := &nodeReport{info: auth.Info, version: min(auth.Info.ExtVersion, extVersion)}
+
+	ack := []interface{}{"ready"}
+	if report.version > 0 {
+		ack = append(ack, map[string]int{"extVersion": report.version})
+	}
+	if err := conn.WriteJSON(map[string][]interface{}{"emit": ack}); err != nil {
+		return
+	}
+	log.Info("Stats node logged in", "id", auth.ID, "version", report.version)
+
+	c.lock.Lock()
+	c.nodes[auth.ID] = report
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		if c.nodes[auth.ID] == report {
+			delete(c.nodes, auth.ID)
+		}
+		c.lock.Unlock()
+	}()
+	// Keep processing reports until the connection breaks
+	for {
+		var msg collectorMsg
+		if err := conn.ReadJSON(&msg); err != nil {
+			if err != io.EOF && !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				log.Debug("Stats node disconnected", "id", auth.ID, "err", err)
+			}
+			return
+		}
+		if len(msg.Emit) != 2 {
+			continue
+		}
+		if err := json.Unmarshal(msg.Emit[0], &command); err != nil {
+			return
+		}
+		if command == "node-ping" {
+			if err := conn.WriteJSON(map[string][]interface{}{"emit": {"node-pong", msg.Emit[1]}}); err != nil {
+				return
+			}
+			continue
+		}
+		if err := c.update(report, command, msg.Emit[1]); err != nil {
+			log.Debug("Invalid stats report", "id", auth.ID, "command", command, "err", err)
+			return
+		}
+	}
+}
+
+// update stores a single report of a node.
+func (c *Collector) update(report *nodeReport, command string, data json.RawMessage) error {
+	var err error
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch command {
+	case "latency":
+		var msg struct {
+			Latency string `json:"latency"`
+		}
+		if err = json.Unmarshal(data, &msg); err == nil {
+			report.latency, err = strconv.Atoi(msg.Latency)
+		}
+	case "block":
+		var msg struct {
+			Block *blockStats `json:"block"`
+		}
+		if err = json.Unmarshal(data, &msg); err == nil {
+			report.block = msg.Block
+		}
+	case "pending":
+		var msg struct {
+			Stats *pendStats `json:"stats"`
+		}
+		if err = json.Unmarshal(data, &msg); err == nil {
+			report.pending = msg.Stats
+		}
+	case "stats":
+		var msg struct {
+			Stats *nodeStats `json:"stats"`
+		}
+		if err = json.Unmarshal(data, &msg); err == nil {
+			report.stats = msg.Stats
+		}
+	case "ext-stats":
+		var msg struct {
+			Stats *extStats `json:"stats"`
+		}
+		if err = json.Unmarshal(data, &msg); err == nil {
+			report.ext = msg.Stats
+		}
+	}
+	return err
+}
+
+// writeMetrics writes the latest reports of all nodes in the Prometheus text
+// exposition format.
+func (c *Collector) writeMetrics(w io.Writer) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	ids := make([]string, 0, len(c.nodes))
+	for id := range c.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	gauge := func(name string, help string, values func(id string, report *nodeReport, emit func(labels string, value interface{}))) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, id := range ids {
+			values(id, c.nodes[id], func(labels string, value interface{}) {
+				fmt.Fprintf(w, "%s{node=%q%s} %v\n", name, id, labels, value)
+			})
+		}
+	}
+	gauge("ethstats_up", "Whether the node is connected, labelled with its client version", func(id string, r *nodeReport, emit func(string, interface{})) {
+		emit(fmt.Sprintf(",client=%q,net=%q,ext_version=\"%d\"", r.info.Node, r.info.Network, r.version), 1)
+	})
+	gauge("ethstats_latency_ms", "Latency between the node and the collector", func(id string, r *nodeReport, emit func(string, interface{})) {
+		emit("", r.latency)
+	})
+	gauge("ethstats_block_number", "Number of the latest reported block", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.block != nil && r.block.Number != nil {
+			emit("", r.block.Number)
+		}
+	})
+	gauge("ethstats_block_gas_used", "Gas used by the latest reported block", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.block != nil {
+			emit("", r.block.GasUsed)
+		}
+	})
+	gauge("ethstats_block_blob_gas_used", "Blob gas used by the latest reported block", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.block != nil && r.block.BlobGasUsed != nil {
+			emit("", *r.block.BlobGasUsed)
+		}
+	})
+	gauge("ethstats_block_blobs", "Number of blobs in the latest reported block", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.block != nil && r.block.Blobs != nil {
+			emit("", *r.block.Blobs)
+		}
+	})
+	gauge("ethstats_pending_transactions", "Number of pending transactions", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.pending != nil {
+			emit("", r.pending.Pending)
+		}
+	})
+	gauge("ethstats_peers", "Number of connected peers", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.stats != nil {
+			emit("", r.stats.Peers)
+		}
+	})
+	gauge("ethstats_syncing", "Whether the node is syncing", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.stats != nil {
+			emit("", boolToInt(r.stats.Syncing))
+		}
+	})
+	gauge("ethstats_gas_price", "Suggested gas price of the node", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.stats != nil {
+			emit("", r.stats.GasPrice)
+		}
+	})
+	gauge("ethstats_peer_clients", "Number of connected peers by client", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.ext == nil || r.ext.Peers == nil {
+			return
+		}
+		clients := make([]string, 0, len(r.ext.Peers.Clients))
+		for client := range r.ext.Peers.Clients {
+			clients = append(clients, client)
+		}
+		sort.Strings(clients)
+		for _, client := range clients {
+			emit(fmt.Sprintf(",client=%q", client), r.ext.Peers.Clients[client])
+		}
+	})
+	gauge("ethstats_txpool_transactions", "Number of transactions by subpool and status", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.ext == nil || r.ext.TxPool == nil {
+			return
+		}
+		for _, subpool := range r.ext.TxPool.Subpools {
+			emit(fmt.Sprintf(",subpool=%q,status=\"pending\"", subpool.Name), subpool.Pending)
+			emit(fmt.Sprintf(",subpool=%q,status=\"queued\"", subpool.Name), subpool.Queued)
+		}
+	})
+	gauge("ethstats_sync_block", "Sync progress in blocks, labelled with the sync stage", func(id string, r *nodeReport, emit func(string, interface{})) {
+		if r.ext == nil || r.ext.Sync == nil {
+			return
+		}
+		emit(fmt.Sprintf(",stage=%q,kind=\"current\"", r.ext.Sync.Stage), r.ext.Sync.CurrentBlock)
+		emit(fmt.Sprintf(",stage=%q,kind=\"highest\"", r.ext.Sync.Stage), r.ext.Sync.HighestBlock)
+	})
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
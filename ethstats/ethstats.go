@@ -57,6 +57,10 @@ const (
 	chainHeadChanSize = 10
 
 	messageSizeLimit = 15 * 1024 * 1024
+
+	// extVersion is the highest version of the extended stats protocol supported
+	// by this client. Version 0 is the legacy protocol, understood by all servers.
+	extVersion = 1
 )
 
 // backend encompasses the bare-minimum functionality needed for ethstats reporting
@@ -90,6 +94,8 @@ type Service struct {
 	pass string // Password to authorize access to the monitoring page
 	host string // Remote address of the monitoring service
 
+	version int // Extended protocol version negotiated with the current server
+
 	pongCh chan struct{} // Pong notifications are fed into this channel
 	histCh chan []uint64 // History request block numbers are fed into this channel
 
@@ -455,6 +461,8 @@ type nodeInfo struct {
 	OsVer    string `json:"os_v"`
 	Client   string `json:"client"`
 	History  bool   `json:"canUpdateHistory"`
+
+	ExtVersion int `json:"extVersion,omitempty"` // Highest supported extended protocol version
 }
 
 // authMsg is the authentication infos needed to login to a monitoring server.
@@ -492,6 +500,8 @@ func (s *Service) login(conn *connWrapper) error {
 			OsVer:    runtime.GOARCH,
 			Client:   "0.1.1",
 			History:  true,
+
+			ExtVersion: extVersion,
 		},
 		Secret: s.pass,
 	}
@@ -502,10 +512,23 @@ func (s *Service) login(conn *connWrapper) error {
 		return err
 	}
 	// Retrieve the remote ack or connection termination
-	var ack map[string][]string
-	if err := conn.ReadJSON(&ack); err != nil || len(ack["emit"]) != 1 || ack["emit"][0] != "ready" {
+	var ack map[string][]interface{}
+	if err := conn.ReadJSON(&ack); err != nil || len(ack["emit"]) == 0 || ack["emit"][0] != "ready" {
 		return errors.New("unauthorized")
 	}
+	// Legacy servers ack with a bare "ready", extended ones also tell which
+	// protocol version they accept.
+	s.version = 0
+	if len(ack["emit"]) > 1 {
+		if opts, ok := ack["emit"][1].(map[string]interface{}); ok {
+			if version, ok := opts["extVersion"].(float64); ok && version > 0 {
+				s.version = min(int(version), extVersion)
+			}
+		}
+	}
+	if s.version > 0 {
+		log.Debug("Negotiated extended stats protocol", "version", s.version)
+	}
 	return nil
 }
 
@@ -525,6 +548,9 @@ func (s *Service) report(conn *connWrapper) error {
 	if err := s.reportStats(conn); err != nil {
 		return err
 	}
+	if err := s.reportExtended(conn); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -583,6 +609,11 @@ type blockStats struct {
 	TxHash     common.Hash    `json:"transactionsRoot"`
 	Root       common.Hash    `json:"stateRoot"`
 	Uncles     uncleStats     `json:"uncles"`
+
+	// Blob usage, only reported over the extended protocol
+	BlobGasUsed   *uint64 `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas *uint64 `json:"excessBlobGas,omitempty"`
+	Blobs         *int    `json:"blobs,omitempty"`
 }
 
 // txStats is the information to report about individual transactions.
@@ -632,6 +663,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		td     *big.Int
 		txs    []txStats
 		uncles []*types.Header
+		blobs  int
 	)
 
 	// check if backend is a full node
@@ -653,6 +685,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		txs = make([]txStats, len(block.Transactions()))
 		for i, tx := range block.Transactions() {
 			txs[i].Hash = tx.Hash()
+			blobs += len(tx.BlobHashes())
 		}
 		uncles = block.Uncles()
 	} else {
@@ -669,7 +702,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 	// Assemble and return the block stats
 	author, _ := s.engine.Author(header)
 
-	return &blockStats{
+	stats := &blockStats{
 		Number:     header.Number,
 		Hash:       header.Hash(),
 		ParentHash: header.ParentHash,
@@ -684,6 +717,15 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		Root:       header.Root,
 		Uncles:     uncles,
 	}
+	if s.version >= 1 && header.BlobGasUsed != nil {
+		stats.BlobGasUsed = header.BlobGasUsed
+		stats.ExcessBlobGas = header.ExcessBlobGas
+		if ok {
+			// Light nodes don't have the transactions to count blobs
+			stats.Blobs = &blobs
+		}
+	}
+	return stats
 }
 
 // reportHistory retrieves the most recent batch of blocks and reports it to the
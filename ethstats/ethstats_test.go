@@ -17,8 +17,27 @@
 package ethstats
 
 import (
+	"context"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	ethproto "github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
 )
 
 func TestParseEthstatsURL(t *testing.T) {
@@ -80,3 +99,171 @@ func TestParseEthstatsURL(t *testing.T) {
 		}
 	}
 }
+
+// testBackend is a light node backend with a fixed chain head.
+type testBackend struct {
+	head *types.Header
+}
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error { <-quit; return nil })
+}
+func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error { <-quit; return nil })
+}
+func (b *testBackend) CurrentHeader() *types.Header { return b.head }
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.head, nil
+}
+func (b *testBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int { return big.NewInt(1) }
+func (b *testBackend) Stats() (int, int)                                    { return 3, 2 }
+func (b *testBackend) SyncProgress() ethereum.SyncProgress {
+	return ethereum.SyncProgress{CurrentBlock: 10, HighestBlock: 20, SyncedAccounts: 5}
+}
+
+// newTestService creates a stats service on top of a running p2p server.
+func newTestService(t *testing.T) *Service {
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		ListenAddr:  "127.0.0.1:0",
+		NoDiscovery: true,
+		Protocols: []p2p.Protocol{{
+			Name:     "eth",
+			Version:  68,
+			NodeInfo: func() interface{} { return &ethproto.NodeInfo{Network: 1337} },
+		}},
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+
+	blobGas, excessBlobGas := uint64(131072), uint64(0)
+	return &Service{
+		server: srv,
+		backend: &testBackend{head: &types.Header{
+			Number:        big.NewInt(10),
+			Difficulty:    big.NewInt(1),
+			BlobGasUsed:   &blobGas,
+			ExcessBlobGas: &excessBlobGas,
+		}},
+		engine: ethash.NewFaker(),
+		node:   "test",
+		pass:   "secret",
+		pongCh: make(chan struct{}),
+		histCh: make(chan []uint64, 1),
+	}
+}
+
+func dialTestServer(t *testing.T, url string) *connWrapper {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return newConnectionWrapper(conn)
+}
+
+// Tests that the service negotiates the extended protocol with the collector
+// and that the extended payloads end up in the metrics.
+func TestCollectorExtended(t *testing.T) {
+	collector := NewCollector("secret")
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	s := newTestService(t)
+	conn := dialTestServer(t, server.URL)
+	if err := s.login(conn); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if s.version != extVersion {
+		t.Fatalf("wrong negotiated version: have %d, want %d", s.version, extVersion)
+	}
+	go s.readLoop(conn)
+	if err := s.report(conn); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	want := []string{
+		`ethstats_up{node="test",client="",net="1337",ext_version="1"} 1`,
+		`ethstats_block_number{node="test"} 10`,
+		`ethstats_block_blob_gas_used{node="test"} 131072`,
+		`ethstats_pending_transactions{node="test"} 3`,
+		`ethstats_peers{node="test"} 0`,
+		`ethstats_sync_block{node="test",stage="state",kind="highest"} 20`,
+	}
+	// The reports are processed asynchronously, wait for the last one to arrive.
+	var metrics string
+	for i := 0; i < 100; i++ {
+		res, err := http.Get(server.URL + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if metrics = string(body); strings.Contains(metrics, "ethstats_sync_block{") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, line := range want {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("missing metric %q in:\n%s", line, metrics)
+		}
+	}
+	if nodes := collector.Nodes(); len(nodes) != 1 || nodes[0] != "test" {
+		t.Errorf("wrong reporting nodes: %v", nodes)
+	}
+}
+
+// Tests that the service falls back to the legacy protocol with servers which
+// don't know about the extensions.
+func TestLegacyServer(t *testing.T) {
+	var (
+		upgrader = websocket.Upgrader{}
+		commands = make(chan string, 10)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msg map[string][]interface{}
+		for conn.ReadJSON(&msg) == nil {
+			switch command := msg["emit"][0].(string); command {
+			case "hello":
+				conn.WriteJSON(map[string][]interface{}{"emit": {"ready"}})
+			case "node-ping":
+				conn.WriteJSON(map[string][]interface{}{"emit": {"node-pong", msg["emit"][1]}})
+			default:
+				commands <- command
+			}
+		}
+	}))
+	defer server.Close()
+
+	s := newTestService(t)
+	conn := dialTestServer(t, server.URL)
+	if err := s.login(conn); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if s.version != 0 {
+		t.Fatalf("wrong negotiated version: have %d, want 0", s.version)
+	}
+	go s.readLoop(conn)
+	if err := s.report(conn); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	for _, want := range []string{"latency", "block", "pending", "stats"} {
+		if have := <-commands; have != want {
+			t.Fatalf("wrong report: have %q, want %q", have, want)
+		}
+	}
+	select {
+	case command := <-commands:
+		t.Fatalf("unexpected report to legacy server: %q", command)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
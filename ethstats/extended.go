@@ -0,0 +1,185 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// extension is an optional payload of the extended stats protocol. Extensions
+// are only reported to servers that negotiated at least their version at login,
+// so new payloads can be added without confusing older servers.
+type extension struct {
+	name    string                     // Key of the payload in the ext-stats report
+	version int                        // Minimum protocol version carrying the payload
+	collect func(*Service) interface{} // Gathers the payload, nil if unavailable
+}
+
+// extensions is the list of payloads reported in the ext-stats message.
+var extensions = []extension{
+	{name: "peers", version: 1, collect: (*Service).peerStats},
+	{name: "txpool", version: 1, collect: (*Service).txPoolStats},
+	{name: "sync", version: 1, collect: (*Service).syncStats},
+}
+
+// txPoolBackend is implemented by backends with direct access to the transaction
+// pool, allowing the stats to be broken down by subpool.
+type txPoolBackend interface {
+	TxPool() *txpool.TxPool
+}
+
+// extStats is the ext-stats report, decoded by the collector. Payloads of
+// extensions the sender doesn't know about are left nil.
+type extStats struct {
+	Peers  *peerStats   `json:"peers,omitempty"`
+	TxPool *txPoolStats `json:"txpool,omitempty"`
+	Sync   *syncStats   `json:"sync,omitempty"`
+}
+
+// peerStats is the information to report about the connected peers.
+type peerStats struct {
+	Total   int            `json:"total"`
+	Inbound int            `json:"inbound"`
+	Clients map[string]int `json:"clients"` // Peer count by client name
+}
+
+// subpoolStats is the information to report about a single transaction subpool.
+type subpoolStats struct {
+	Name    string `json:"name"`
+	Pending int    `json:"pending"`
+	Queued  int    `json:"queued"`
+}
+
+// txPoolStats is the information to report about the transaction pool.
+type txPoolStats struct {
+	Pending  int            `json:"pending"`
+	Queued   int            `json:"queued"`
+	Subpools []subpoolStats `json:"subpools,omitempty"`
+}
+
+// syncStats is the information to report about the sync progress.
+type syncStats struct {
+	Stage string `json:"stage"`
+
+	StartingBlock uint64 `json:"startingBlock"`
+	CurrentBlock  uint64 `json:"currentBlock"`
+	HighestBlock  uint64 `json:"highestBlock"`
+
+	SyncedAccounts   uint64 `json:"syncedAccounts"`
+	SyncedStorage    uint64 `json:"syncedStorage"`
+	SyncedBytecodes  uint64 `json:"syncedBytecodes"`
+	HealedTrienodes  uint64 `json:"healedTrienodes"`
+	HealingTrienodes uint64 `json:"healingTrienodes"`
+	HealingBytecode  uint64 `json:"healingBytecode"`
+
+	TxIndexRemainingBlocks uint64 `json:"txIndexRemainingBlocks"`
+}
+
+// reportExtended gathers the payloads of all extensions supported by the server
+// and reports them in a single message. Nothing is sent to legacy servers.
+func (s *Service) reportExtended(conn *connWrapper) error {
+	stats := make(map[string]interface{})
+	for _, ext := range extensions {
+		if ext.version > s.version {
+			continue
+		}
+		if payload := ext.collect(s); payload != nil {
+			stats[ext.name] = payload
+		}
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+	log.Trace("Sending extended stats to ethstats", "version", s.version)
+
+	report := map[string][]interface{}{
+		"emit": {"ext-stats", map[string]interface{}{
+			"id":    s.node,
+			"stats": stats,
+		}},
+	}
+	return conn.WriteJSON(report)
+}
+
+// peerStats collects the peer count broken down by client name.
+func (s *Service) peerStats() interface{} {
+	stats := &peerStats{Clients: make(map[string]int)}
+	for _, peer := range s.server.Peers() {
+		stats.Total++
+		if peer.Inbound() {
+			stats.Inbound++
+		}
+		client, _, _ := strings.Cut(peer.Fullname(), "/")
+		if client == "" {
+			client = "unknown"
+		}
+		stats.Clients[client]++
+	}
+	return stats
+}
+
+// txPoolStats collects the transaction pool stats, broken down by subpool if
+// the backend has access to the pool.
+func (s *Service) txPoolStats() interface{} {
+	stats := new(txPoolStats)
+	stats.Pending, stats.Queued = s.backend.Stats()
+
+	if backend, ok := s.backend.(txPoolBackend); ok && backend.TxPool() != nil {
+		for _, subpool := range backend.TxPool().SubpoolStats() {
+			stats.Subpools = append(stats.Subpools, subpoolStats{
+				Name:    subpool.Name,
+				Pending: subpool.Pending,
+				Queued:  subpool.Queued,
+			})
+		}
+	}
+	return stats
+}
+
+// syncStats collects the sync progress and the sync stage it implies.
+func (s *Service) syncStats() interface{} {
+	progress := s.backend.SyncProgress()
+
+	stats := &syncStats{
+		StartingBlock:          progress.StartingBlock,
+		CurrentBlock:           progress.CurrentBlock,
+		HighestBlock:           progress.HighestBlock,
+		SyncedAccounts:         progress.SyncedAccounts,
+		SyncedStorage:          progress.SyncedStorage,
+		SyncedBytecodes:        progress.SyncedBytecodes,
+		HealedTrienodes:        progress.HealedTrienodes,
+		HealingTrienodes:       progress.HealingTrienodes,
+		HealingBytecode:        progress.HealingBytecode,
+		TxIndexRemainingBlocks: progress.TxIndexRemainingBlocks,
+	}
+	switch {
+	case progress.Done():
+		stats.Stage = "synced"
+	case progress.CurrentBlock >= progress.HighestBlock:
+		stats.Stage = "txindex"
+	case progress.HealingTrienodes > 0 || progress.HealingBytecode > 0:
+		stats.Stage = "heal"
+	case progress.SyncedAccounts > 0 || progress.SyncedStorage > 0 || progress.SyncedBytecodes > 0:
+		stats.Stage = "state"
+	default:
+		stats.Stage = "blocks"
+	}
+	return stats
+}
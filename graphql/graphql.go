@@ -170,6 +170,10 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return l.log.Data
 }
 
+func (l *Log) Removed(ctx context.Context) bool {
+	return l.log.Removed
+}
+
 // AccessTuple represents EIP-2930
 type AccessTuple struct {
 	address     common.Address
@@ -1284,6 +1288,10 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 type Resolver struct {
 	backend      ethapi.Backend
 	filterSystem *filters.FilterSystem
+	tracing      TracingConfig
+
+	eventsOnce sync.Once
+	events     *filters.EventSystem // Event system backing subscriptions, created on first use
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -1545,3 +1553,90 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+// eventSystem returns the event system backing the subscriptions.
+func (r *Resolver) eventSystem() *filters.EventSystem {
+	r.eventsOnce.Do(func() {
+		r.events = filters.NewEventSystem(r.filterSystem)
+	})
+	return r.events
+}
+
+// NewBlock delivers the blocks added to the canonical chain.
+func (r *Resolver) NewBlock(ctx context.Context) <-chan *Block {
+	headers := make(chan *types.Header)
+	sub := r.eventSystem().SubscribeNewHeads(headers)
+	return forwardEvents(ctx, sub, headers, func(header *types.Header) []*Block {
+		hash := header.Hash()
+		numberOrHash := rpc.BlockNumberOrHashWithHash(hash, false)
+		return []*Block{{r: r, numberOrHash: &numberOrHash, hash: hash, header: header}}
+	})
+}
+
+// NewLogs delivers the logs of new canonical blocks matching the filter.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	logs := make(chan []*types.Log)
+	sub, err := r.eventSystem().SubscribeLogs(crit, logs)
+	if err != nil {
+		return nil, err
+	}
+	return forwardEvents(ctx, sub, logs, func(logs []*types.Log) []*Log {
+		ret := make([]*Log, 0, len(logs))
+		for _, log := range logs {
+			ret = append(ret, &Log{
+				r:           r,
+				transaction: &Transaction{r: r, hash: log.TxHash},
+				log:         log,
+			})
+		}
+		return ret
+	}), nil
+}
+
+// PendingTransactions delivers the transactions entering the transaction pool.
+func (r *Resolver) PendingTransactions(ctx context.Context) <-chan *Transaction {
+	txs := make(chan []*types.Transaction)
+	sub := r.eventSystem().SubscribePendingTxs(txs)
+	return forwardEvents(ctx, sub, txs, func(txs []*types.Transaction) []*Transaction {
+		ret := make([]*Transaction, 0, len(txs))
+		for _, tx := range txs {
+			ret = append(ret, &Transaction{r: r, hash: tx.Hash(), tx: tx})
+		}
+		return ret
+	})
+}
+
+// forwardEvents converts the events of an event system subscription and delivers
+// them on the returned channel, until the subscription ends or ctx is cancelled.
+func forwardEvents[E any, T any](ctx context.Context, sub *filters.Subscription, events <-chan E, convert func(E) []T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				for _, item := range convert(ev) {
+					select {
+					case out <- item:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
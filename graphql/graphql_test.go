@@ -23,11 +23,13 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -41,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/gorilla/websocket"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -56,7 +59,7 @@ func TestBuildSchema(t *testing.T) {
 	}
 	defer stack.Close()
 	// Make sure the schema can be parsed and matched up to the object model.
-	if _, err := newHandler(stack, nil, nil, []string{}, []string{}); err != nil {
+	if _, err := newHandler(stack, nil, nil, []string{}, []string{}, TracingConfig{}); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -431,6 +434,108 @@ func TestWithdrawals(t *testing.T) {
 	}
 }
 
+func TestGraphQLTrace(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		dad    = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+
+		genesis = &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: common.Big1,
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	var tx *types.Transaction
+	handler, _ := newGQLService(t, stack, false, genesis, 1, func(i int, gen *core.BlockGen) {
+		tx, _ = types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Value: big.NewInt(1000), Gas: 21000, GasPrice: big.NewInt(params.InitialBaseFee)})
+		gen.AddTx(tx)
+	})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	for i, tt := range []struct {
+		body string
+		want string
+	}{
+		{
+			body: fmt.Sprintf(`{transaction(hash: "%s") { callTree } }`, tx.Hash()),
+			want: `{"transaction":{"callTree":{"from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasUsed":"0x5208","to":"0x0000000000000000000000000000000000000dad","input":"0x","value":"0x3e8","type":"CALL"}}}`,
+		},
+		{
+			body: fmt.Sprintf(`{transaction(hash: "%s") { trace(config: {disableStack: true}) } }`, tx.Hash()),
+			want: `{"transaction":{"trace":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}}}`,
+		},
+		{
+			body: `{block(number: 1) { trace(tracer: "callTracer", config: {onlyTopCall: true}) } }`,
+			want: fmt.Sprintf(`{"block":{"trace":[{"txHash":"%s","result":{"from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasUsed":"0x5208","to":"0x0000000000000000000000000000000000000dad","input":"0x","value":"0x3e8","type":"CALL"}}]}}`, tx.Hash()),
+		},
+		// Pending and unknown transactions can't be traced.
+		{
+			body: `{transaction(hash: "0x0000000000000000000000000000000000000000000000000000000000000001") { stateDiff } }`,
+			want: `{"transaction":null}`,
+		},
+	} {
+		res := handler.Schema.Exec(context.Background(), tt.body, "", map[string]interface{}{})
+		if res.Errors != nil {
+			t.Fatalf("failed to execute query for testcase #%d: %v", i, res.Errors)
+		}
+		have, err := json.Marshal(res.Data)
+		if err != nil {
+			t.Fatalf("failed to encode graphql response for testcase #%d: %s", i, err)
+		}
+		if string(have) != tt.want {
+			t.Errorf("response unmatch for testcase #%d.\nhave:\n%s\nwant:\n%s", i, have, tt.want)
+		}
+	}
+	// The state diff must report the balance change of the recipient.
+	res := handler.Schema.Exec(context.Background(), fmt.Sprintf(`{transaction(hash: "%s") { stateDiff } }`, tx.Hash()), "", map[string]interface{}{})
+	if res.Errors != nil {
+		t.Fatalf("failed to execute stateDiff query: %v", res.Errors)
+	}
+	var diff struct {
+		Transaction struct {
+			StateDiff struct {
+				Post map[common.Address]struct {
+					Balance *hexutil.Big `json:"balance"`
+				} `json:"post"`
+			} `json:"stateDiff"`
+		} `json:"transaction"`
+	}
+	if err := json.Unmarshal(res.Data, &diff); err != nil {
+		t.Fatalf("failed to decode stateDiff: %v", err)
+	}
+	if bal := diff.Transaction.StateDiff.Post[dad].Balance; bal == nil || bal.ToInt().Int64() != 1000 {
+		t.Errorf("wrong post balance for recipient: %v", bal)
+	}
+	// JavaScript tracers must be rejected unless explicitly allowed.
+	res = handler.Schema.Exec(context.Background(), fmt.Sprintf(`{transaction(hash: "%s") { trace(tracer: "{result: function() { return 1; }, fault: function() {}}") } }`, tx.Hash()), "", map[string]interface{}{})
+	if len(res.Errors) == 0 || res.Errors[0].Message != errJSTracerDisabled.Error() {
+		t.Errorf("JavaScript tracer not rejected: %v", res.Errors)
+	}
+	// Tracing must be refused if not enabled.
+	if _, err := new(Resolver).tracerAPI(); err != errTracingDisabled {
+		t.Errorf("wrong error with tracing disabled: have %v, want %v", err, errTracingDisabled)
+	}
+	// Client timeouts must be clamped to the server side maximum.
+	timeout := "1h"
+	config, err := TraceArgs{Timeout: &timeout}.traceConfig(TracingConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("failed to create trace config: %v", err)
+	}
+	if *config.Timeout != maxTraceTimeout.String() {
+		t.Errorf("timeout not clamped: have %s, want %s", *config.Timeout, maxTraceTimeout)
+	}
+}
+
 func createNode(t *testing.T) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost:     "127.0.0.1",
@@ -458,7 +563,9 @@ func newGQLService(t *testing.T, stack *node.Node, shanghai bool, gspec *core.Ge
 	var engine consensus.Engine = ethash.NewFaker()
 	if shanghai {
 		engine = beacon.NewFaker()
-		chainCfg := gspec.Config
+		// Copy the config to not affect other tests using it.
+		chainCfg := *gspec.Config
+		gspec.Config = &chainCfg
 		chainCfg.TerminalTotalDifficultyPassed = true
 		chainCfg.TerminalTotalDifficulty = common.Big0
 		// GenerateChain will increment timestamps by 10.
@@ -471,7 +578,7 @@ func newGQLService(t *testing.T, stack *node.Node, shanghai bool, gspec *core.Ge
 		t.Fatalf("could not create eth backend: %v", err)
 	}
 	// Create some blocks and import them
-	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, ethBackend.BlockChain().Genesis(),
+	chain, _ := core.GenerateChain(gspec.Config, ethBackend.BlockChain().Genesis(),
 		engine, ethBackend.ChainDb(), genBlocks, genfunc)
 	_, err = ethBackend.BlockChain().InsertChain(chain)
 	if err != nil {
@@ -479,9 +586,242 @@ func newGQLService(t *testing.T, stack *node.Node, shanghai bool, gspec *core.Ge
 	}
 	// Set up handler
 	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
-	handler, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{})
+	handler, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{}, TracingConfig{Enabled: true})
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
 	return handler, chain
 }
+
+func TestGraphQLSubscriptions(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dad     = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+		genesis = &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				dad: {
+					// LOG0(0, 0), LOG0(0, 0), RETURN(0, 0)
+					Code:    common.Hex2Bytes("60006000a060006000a060006000f3"),
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	ethBackend, err := eth.New(stack, &ethconfig.Config{
+		Genesis:        genesis,
+		NetworkId:      1337,
+		TrieCleanCache: 5,
+		TrieDirtyCache: 5,
+		TrieTimeout:    60 * time.Minute,
+		SnapshotCache:  5,
+		StateScheme:    rawdb.HashScheme,
+	})
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
+	if _, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{}, TracingConfig{}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{To: &dad, Gas: 100000, GasPrice: big.NewInt(params.InitialBaseFee)})
+	txEnc, _ := tx.MarshalBinary()
+
+	conn := dialGraphQLWebSocket(t, stack, wsProtocol)
+	conn.init(t)
+	conn.send(t, "1", "subscribe", `subscription { newBlock { number } }`)
+	conn.send(t, "2", "subscribe", fmt.Sprintf(`subscription { newLogs(filter: {addresses: ["%v"]}) { index transaction { hash } } }`, dad))
+	conn.send(t, "3", "subscribe", `subscription { pendingTransactions { hash } }`)
+	// Subscriptions are installed asynchronously.
+	time.Sleep(200 * time.Millisecond)
+
+	// Submit the transaction over the same connection.
+	conn.send(t, "4", "subscribe", fmt.Sprintf(`mutation { sendRawTransaction(data: "%#x") }`, txEnc))
+	conn.expect(t, map[string][]string{
+		"3": {fmt.Sprintf(`{"data":{"pendingTransactions":{"hash":"%v"}}}`, tx.Hash())},
+		"4": {fmt.Sprintf(`{"data":{"sendRawTransaction":"%v"}}`, tx.Hash()), "complete"},
+	})
+
+	// Import a block including the transaction.
+	chain, _ := core.GenerateChain(genesis.Config, ethBackend.BlockChain().Genesis(), ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(tx)
+	})
+	if _, err := ethBackend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	conn.expect(t, map[string][]string{
+		"1": {`{"data":{"newBlock":{"number":"0x1"}}}`},
+		"2": {
+			fmt.Sprintf(`{"data":{"newLogs":{"index":"0x0","transaction":{"hash":"%v"}}}}`, tx.Hash()),
+			fmt.Sprintf(`{"data":{"newLogs":{"index":"0x1","transaction":{"hash":"%v"}}}}`, tx.Hash()),
+		},
+	})
+
+	// Stopped subscriptions don't complete.
+	conn.send(t, "1", "complete", "")
+	conn.send(t, "5", "subscribe", `{ chainID }`)
+	conn.expect(t, map[string][]string{"5": {`{"data":{"chainID":"0x539"}}`, "complete"}})
+}
+
+func TestGraphQLWebSocketProtocol(t *testing.T) {
+	stack := createNode(t)
+	defer stack.Close()
+	newGQLService(t, stack, false, &core.Genesis{Config: params.AllEthashProtocolChanges}, 0, nil)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	// Invalid operations are reported as errors.
+	conn := dialGraphQLWebSocket(t, stack, wsProtocol)
+	conn.init(t)
+	conn.send(t, "1", "subscribe", `subscription { unknown }`)
+	msg := conn.read(t)
+	if msg.ID != "1" || msg.Type != "error" || !strings.Contains(string(msg.Payload), "unknown") {
+		t.Fatalf("wrong response to invalid operation: %+v", msg)
+	}
+
+	// Operations can't be started before initialisation.
+	conn = dialGraphQLWebSocket(t, stack, wsProtocol)
+	conn.send(t, "1", "subscribe", `{ chainID }`)
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, wsCloseUnauthorized) {
+		t.Fatalf("wrong error for operation before initialisation: %v", err)
+	}
+
+	// The legacy protocol uses different message types.
+	conn = dialGraphQLWebSocket(t, stack, wsProtocolLegacy)
+	conn.init(t)
+	conn.send(t, "1", "start", `{ chainID }`)
+	msg = conn.read(t)
+	if msg.ID != "1" || msg.Type != "data" || string(msg.Payload) != `{"data":{"chainID":"0x539"}}` {
+		t.Fatalf("wrong legacy response: %+v", msg)
+	}
+	conn.expectComplete(t, "1")
+}
+
+func TestGraphQLWebSocketLimits(t *testing.T) {
+	stack := createNode(t)
+	defer stack.Close()
+	h, _ := newGQLService(t, stack, false, &core.Genesis{Config: params.AllEthashProtocolChanges}, 0, nil)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	// Upgrades are subject to the virtual host check.
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	url := "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+	if _, res, err := dialer.Dial(url, http.Header{"Host": {"evil.example"}}); err == nil || res == nil || res.StatusCode != http.StatusForbidden {
+		t.Fatalf("upgrade not rejected for disallowed host: %v", err)
+	}
+
+	ws := newWSHandler(h.Schema, nil)
+	ws.maxOps = 1
+	ws.opTimeout = 200 * time.Millisecond
+	ws.idleTimeout = 500 * time.Millisecond
+	srv := httptest.NewServer(ws)
+	defer srv.Close()
+
+	conn := dialWebSocket(t, "ws"+strings.TrimPrefix(srv.URL, "http"), wsProtocol)
+	conn.init(t)
+	conn.send(t, "1", "subscribe", `subscription { newBlock { number } }`)
+	time.Sleep(50 * time.Millisecond)
+
+	// Operations beyond the limit are rejected.
+	conn.send(t, "2", "subscribe", `{ chainID }`)
+	if msg := conn.read(t); msg.ID != "2" || msg.Type != "error" || !strings.Contains(string(msg.Payload), errWSTooManyOperations.Error()) {
+		t.Fatalf("wrong response to operation beyond the limit: %+v", msg)
+	}
+	// Subscriptions are completed after the operation timeout.
+	conn.expectComplete(t, "1")
+
+	// Connections without operations are closed after the idle timeout.
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("wrong error for idle connection: %v", err)
+	}
+}
+
+type testWSConn struct {
+	*websocket.Conn
+}
+
+func dialGraphQLWebSocket(t *testing.T, stack *node.Node, protocol string) *testWSConn {
+	return dialWebSocket(t, "ws"+strings.TrimPrefix(stack.HTTPEndpoint(), "http")+"/graphql", protocol)
+}
+
+func dialWebSocket(t *testing.T, url string, protocol string) *testWSConn {
+	dialer := websocket.Dialer{Subprotocols: []string{protocol}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	return &testWSConn{conn}
+}
+
+func (c *testWSConn) init(t *testing.T) {
+	c.send(t, "", "connection_init", "")
+	if msg := c.read(t); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %+v", msg)
+	}
+}
+
+func (c *testWSConn) send(t *testing.T, id, typ, query string) {
+	msg := wsMessage{ID: id, Type: typ}
+	if query != "" {
+		msg.Payload, _ = json.Marshal(wsRequest{Query: query})
+	}
+	if err := c.WriteJSON(msg); err != nil {
+		t.Fatalf("could not send message: %v", err)
+	}
+}
+
+func (c *testWSConn) read(t *testing.T) wsMessage {
+	var msg wsMessage
+	if err := c.ReadJSON(&msg); err != nil {
+		t.Fatalf("could not read message: %v", err)
+	}
+	return msg
+}
+
+// expect reads messages until the given sequence of results has been received
+// for each operation, in any order across operations. A "complete" entry expects
+// the completion of the operation.
+func (c *testWSConn) expect(t *testing.T, want map[string][]string) {
+	for len(want) > 0 {
+		msg := c.read(t)
+		results, ok := want[msg.ID]
+		if !ok {
+			t.Fatalf("unexpected message %s %s %s", msg.ID, msg.Type, msg.Payload)
+		}
+		switch {
+		case results[0] == "complete":
+			if msg.Type != "complete" {
+				t.Fatalf("expected completion of operation %s, got %s %s", msg.ID, msg.Type, msg.Payload)
+			}
+		case msg.Type != "next" && msg.Type != "data":
+			t.Fatalf("unexpected message %s %s %s", msg.ID, msg.Type, msg.Payload)
+		case string(msg.Payload) != results[0]:
+			t.Fatalf("wrong result for operation %s:\nhave: %s\nwant: %s", msg.ID, msg.Payload, results[0])
+		}
+		if want[msg.ID] = results[1:]; len(want[msg.ID]) == 0 {
+			delete(want, msg.ID)
+		}
+	}
+}
+
+func (c *testWSConn) expectComplete(t *testing.T, id string) {
+	if msg := c.read(t); msg.ID != id || msg.Type != "complete" {
+		t.Fatalf("expected completion of operation %s, got %+v", id, msg)
+	}
+}
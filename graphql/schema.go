@@ -32,10 +32,13 @@ const schema string = `
     # Strings may be either decimal or 0x-prefixed hexadecimal. Output values are all
     # 0x-prefixed hexadecimal.
     scalar Long
+    # JSON is an arbitrary JSON value, used for tracer configuration and results.
+    scalar JSON
 
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -69,6 +72,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if the log was reverted due to a chain reorganisation.
+        # It can only be set for logs delivered by the newLogs subscription.
+        removed: Boolean!
     }
 
     # EIP-2718
@@ -170,6 +176,20 @@ const schema string = `
         rawReceipt: Bytes!
         # BlobVersionedHashes is a set of hash outputs from the blobs in the transaction.
         blobVersionedHashes: [Bytes32!]
+        # Trace re-executes the transaction with the given tracer and returns its
+        # result, like debug_traceTransaction. If no tracer is given, the struct
+        # logger is used. Config is the tracer specific configuration and timeout
+        # limits the execution time of the tracer (default 5s, at most 10s). Only
+        # mined transactions can be traced. The tracing fields are only available
+        # if enabled by the node operator, JavaScript tracers need to be allowed
+        # separately.
+        trace(tracer: String, config: JSON, timeout: String): JSON
+        # CallTree is the tree of calls made by the transaction, as returned by
+        # the callTracer.
+        callTree: JSON
+        # StateDiff is the state modified by the transaction, as returned by the
+        # prestateTracer in diff mode.
+        stateDiff: JSON
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -279,6 +299,16 @@ const schema string = `
         blobGasUsed: Long
         # ExcessBlobGas is a running total of blob gas consumed in excess of the target, prior to the block.
         excessBlobGas: Long
+        # Trace re-executes the transactions of this block with the given tracer,
+        # like debug_traceBlockByHash. The result is a list with an object for each
+        # transaction, holding the txHash and the tracer result or error.
+        trace(tracer: String, config: JSON, timeout: String): JSON
+        # CallTree is the call tree of each transaction in this block, as returned
+        # by the callTracer.
+        callTree: JSON
+        # StateDiff is the state modified by each transaction in this block, as
+        # returned by the prestateTracer in diff mode.
+        stateDiff: JSON
     }
 
     # CallData represents the data associated with a local contract call.
@@ -391,4 +421,17 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription streams chain events as they happen. Subscriptions are served
+    # over WebSocket using the graphql-ws protocol.
+    type Subscription {
+        # NewBlock delivers every block added to the canonical chain.
+        newBlock: Block!
+        # NewLogs delivers the log entries of new canonical blocks matching the
+        # provided filter. Logs of blocks dropped in a chain reorganisation are
+        # delivered again, with removed set to true.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions delivers every transaction entering the transaction pool.
+        pendingTransactions: Transaction!
+    }
 `
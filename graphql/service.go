@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 	gqlErrors "github.com/graph-gophers/graphql-go/errors"
 )
@@ -106,22 +107,33 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string) error {
-	_, err := newHandler(stack, backend, filterSystem, cors, vhosts)
+func New(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string, tracing TracingConfig) error {
+	_, err := newHandler(stack, backend, filterSystem, cors, vhosts, tracing)
 	return err
 }
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
 // It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string) (*handler, error) {
-	q := Resolver{backend, filterSystem}
+func newHandler(stack *node.Node, backend ethapi.Backend, filterSystem *filters.FilterSystem, cors, vhosts []string, tracing TracingConfig) (*handler, error) {
+	q := Resolver{backend: backend, filterSystem: filterSystem, tracing: tracing}
 
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return nil, err
 	}
 	h := handler{Schema: s}
-	handler := node.NewHTTPHandlerStack(h, cors, vhosts, nil)
+	var (
+		httpHandler = node.NewHTTPHandlerStack(h, cors, vhosts, nil)
+		wsHandler   = node.NewVHostHandler(vhosts, newWSHandler(s, cors))
+		handler     = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Subscriptions are served over WebSocket on the same endpoint.
+			if websocket.IsWebSocketUpgrade(r) {
+				wsHandler.ServeHTTP(w, r)
+			} else {
+				httpHandler.ServeHTTP(w, r)
+			}
+		})
+	)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui/", GraphiQL{})
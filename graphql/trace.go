@@ -0,0 +1,205 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+
+	// Force-load the native tracers backing the callTree and stateDiff fields.
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+var (
+	errTracingUnsupported = errors.New("tracing is not supported by the backend")
+	errTracingDisabled    = errors.New("tracing is not enabled on this endpoint")
+	errJSTracerDisabled   = errors.New("JavaScript tracers are not enabled on this endpoint")
+)
+
+const (
+	// maxTraceTimeout is the maximum execution time of a single transaction
+	// trace, regardless of the timeout requested by the client.
+	maxTraceTimeout = 10 * time.Second
+
+	// maxBlockTraceTimeout is the maximum execution time of tracing all the
+	// transactions of a block.
+	maxBlockTraceTimeout = time.Minute
+)
+
+// TracingConfig contains the options of the tracing fields. As tracing
+// re-executes transactions on request, it is disabled by default.
+type TracingConfig struct {
+	Enabled bool // Whether the trace, callTree and stateDiff fields are served
+	AllowJS bool // Whether JavaScript tracers may be used, instead of native ones only
+}
+
+var (
+	callTreeTracer  = "callTracer"
+	stateDiffTracer = "prestateTracer"
+	stateDiffConfig = json.RawMessage(`{"diffMode":true}`)
+)
+
+// JSON is an arbitrary JSON value.
+type JSON json.RawMessage
+
+// ImplementsGraphQLType returns true if JSON implements the provided GraphQL type.
+func (j JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	enc, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("unexpected value %v for JSON: %v", input, err)
+	}
+	*j = enc
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// TraceArgs are the arguments of the trace fields.
+type TraceArgs struct {
+	Tracer  *string
+	Config  *JSON
+	Timeout *string
+}
+
+// traceConfig converts the arguments into the configuration of the tracing API,
+// rejecting JavaScript tracers unless allowed and clamping the timeout. The
+// re-execution limit is left at the API default.
+func (args TraceArgs) traceConfig(tracing TracingConfig) (*tracers.TraceConfig, error) {
+	if args.Tracer != nil && !tracing.AllowJS && tracers.DefaultDirectory.IsJS(*args.Tracer) {
+		return nil, errJSTracerDisabled
+	}
+	config := &tracers.TraceConfig{Tracer: args.Tracer}
+	if args.Timeout != nil {
+		timeout, err := time.ParseDuration(*args.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %v", err)
+		}
+		if timeout > maxTraceTimeout {
+			timeout = maxTraceTimeout
+		}
+		limit := timeout.String()
+		config.Timeout = &limit
+	}
+	if args.Config == nil {
+		return config, nil
+	}
+	if args.Tracer == nil {
+		// The struct logger options are part of the main configuration.
+		config.Config = new(logger.Config)
+		if err := json.Unmarshal(*args.Config, config.Config); err != nil {
+			return nil, fmt.Errorf("invalid struct logger config: %v", err)
+		}
+	} else {
+		config.TracerConfig = json.RawMessage(*args.Config)
+	}
+	return config, nil
+}
+
+// tracerAPI returns the tracing API operating on the backend, if tracing is
+// enabled.
+func (r *Resolver) tracerAPI() (*tracers.API, error) {
+	if !r.tracing.Enabled {
+		return nil, errTracingDisabled
+	}
+	backend, ok := r.backend.(tracers.Backend)
+	if !ok {
+		return nil, errTracingUnsupported
+	}
+	return tracers.NewAPI(backend), nil
+}
+
+// encodeTrace encodes a tracer result as JSON.
+func encodeTrace(result interface{}, err error) (*JSON, error) {
+	if err != nil {
+		return nil, err
+	}
+	enc, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	ret := JSON(enc)
+	return &ret, nil
+}
+
+func (t *Transaction) trace(ctx context.Context, config *tracers.TraceConfig) (*JSON, error) {
+	api, err := t.r.tracerAPI()
+	if err != nil {
+		return nil, err
+	}
+	return encodeTrace(api.TraceTransaction(ctx, t.hash, config))
+}
+
+func (t *Transaction) Trace(ctx context.Context, args TraceArgs) (*JSON, error) {
+	config, err := args.traceConfig(t.r.tracing)
+	if err != nil {
+		return nil, err
+	}
+	return t.trace(ctx, config)
+}
+
+func (t *Transaction) CallTree(ctx context.Context) (*JSON, error) {
+	return t.trace(ctx, &tracers.TraceConfig{Tracer: &callTreeTracer})
+}
+
+func (t *Transaction) StateDiff(ctx context.Context) (*JSON, error) {
+	return t.trace(ctx, &tracers.TraceConfig{Tracer: &stateDiffTracer, TracerConfig: stateDiffConfig})
+}
+
+func (b *Block) trace(ctx context.Context, config *tracers.TraceConfig) (*JSON, error) {
+	api, err := b.r.tracerAPI()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxBlockTraceTimeout)
+	defer cancel()
+	return encodeTrace(api.TraceBlockByHash(ctx, hash, config))
+}
+
+func (b *Block) Trace(ctx context.Context, args TraceArgs) (*JSON, error) {
+	config, err := args.traceConfig(b.r.tracing)
+	if err != nil {
+		return nil, err
+	}
+	return b.trace(ctx, config)
+}
+
+func (b *Block) CallTree(ctx context.Context) (*JSON, error) {
+	return b.trace(ctx, &tracers.TraceConfig{Tracer: &callTreeTracer})
+}
+
+func (b *Block) StateDiff(ctx context.Context) (*JSON, error) {
+	return b.trace(ctx, &tracers.TraceConfig{Tracer: &stateDiffTracer, TracerConfig: stateDiffConfig})
+}
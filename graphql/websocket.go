@@ -0,0 +1,372 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlErrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+const (
+	// wsProtocol is the graphql-transport-ws protocol implemented by the graphql-ws
+	// library. wsProtocolLegacy is the older protocol of subscriptions-transport-ws.
+	wsProtocol       = "graphql-transport-ws"
+	wsProtocolLegacy = "graphql-ws"
+
+	wsInitTimeout  = 10 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	wsReadLimit    = 1024 * 1024
+
+	// wsMaxOperations is the maximum number of operations running concurrently
+	// on a single connection.
+	wsMaxOperations = 100
+
+	// wsOperationTimeout is the maximum lifetime of an operation. Subscriptions
+	// are completed once it elapses and need to be renewed by the client.
+	wsOperationTimeout = time.Hour
+
+	// wsIdleTimeout is the time after which a connection without any running
+	// operation is closed if the client doesn't send any message.
+	wsIdleTimeout = 5 * time.Minute
+)
+
+// Close codes defined by the graphql-transport-ws protocol.
+const (
+	wsCloseBadRequest        = 4400
+	wsCloseUnauthorized      = 4401
+	wsCloseBadProtocol       = 4406
+	wsCloseInitTimeout       = 4408
+	wsCloseDuplicateID       = 4409
+	wsCloseTooManyInitialize = 4429
+)
+
+var (
+	errWSDuplicateID       = errors.New("duplicate operation ID")
+	errWSTooManyOperations = errors.New("too many running operations")
+)
+
+// wsMessage is a message of the graphql-ws protocols.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsRequest is the payload of a subscribe message.
+type wsRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsHandler serves GraphQL operations, including subscriptions, over WebSocket.
+type wsHandler struct {
+	schema  *graphql.Schema
+	origins []string
+
+	maxOps      int           // maximum number of concurrent operations per connection
+	opTimeout   time.Duration // maximum lifetime of an operation
+	idleTimeout time.Duration // time after which connections without operations are closed
+}
+
+func newWSHandler(schema *graphql.Schema, origins []string) *wsHandler {
+	return &wsHandler{
+		schema:      schema,
+		origins:     origins,
+		maxOps:      wsMaxOperations,
+		opTimeout:   wsOperationTimeout,
+		idleTimeout: wsIdleTimeout,
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{wsProtocol, wsProtocolLegacy},
+		CheckOrigin:  h.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	c := &wsConn{
+		schema:  h.schema,
+		handler: h,
+		conn:    conn,
+		legacy:  conn.Subprotocol() == wsProtocolLegacy,
+		ops:     make(map[string]context.CancelFunc),
+		active:  time.Now(),
+	}
+	if conn.Subprotocol() == "" {
+		c.close(wsCloseBadProtocol, "Subprotocol not acceptable")
+		return
+	}
+	c.serve()
+}
+
+// checkOrigin accepts requests without origin and those from an allowed origin.
+func (h *wsHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	log.Debug("Rejected GraphQL WebSocket connection", "origin", origin)
+	return false
+}
+
+// wsConn is a GraphQL WebSocket connection.
+type wsConn struct {
+	schema  *graphql.Schema
+	handler *wsHandler
+	conn    *websocket.Conn
+	legacy  bool // whether the legacy graphql-ws protocol is used
+
+	writeMu sync.Mutex // serializes writes to conn
+
+	mu     sync.Mutex
+	ops    map[string]context.CancelFunc // running operations by ID
+	active time.Time                     // last time the connection was in use
+	idle   *time.Timer                   // timer checking for idleness
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// serve handles the messages of the client until the connection is closed.
+func (c *wsConn) serve() {
+	c.mu.Lock()
+	c.idle = time.AfterFunc(c.handler.idleTimeout, c.checkIdle)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.closed = true
+		c.idle.Stop()
+		for id, cancel := range c.ops {
+			cancel()
+			delete(c.ops, id)
+		}
+		c.mu.Unlock()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(wsReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+	initialized := false
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var netErr interface{ Timeout() bool }
+			if !initialized && errors.As(err, &netErr) && netErr.Timeout() {
+				c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+			} else if errors.As(err, new(*json.SyntaxError)) || errors.As(err, new(*json.UnmarshalTypeError)) {
+				c.close(wsCloseBadRequest, "Invalid message")
+			}
+			return
+		}
+		c.touch()
+		switch msg.Type {
+		case "connection_init":
+			if initialized {
+				c.close(wsCloseTooManyInitialize, "Too many initialisation requests")
+				return
+			}
+			initialized = true
+			c.conn.SetReadDeadline(time.Time{})
+			c.send(wsMessage{Type: "connection_ack"})
+
+		case "ping":
+			c.send(wsMessage{Type: "pong", Payload: msg.Payload})
+
+		case "pong":
+
+		case "subscribe", "start":
+			if !initialized {
+				c.close(wsCloseUnauthorized, "Unauthorized")
+				return
+			}
+			var req wsRequest
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				c.close(wsCloseBadRequest, "Invalid subscribe message")
+				return
+			}
+			switch err := c.start(msg.ID, req); {
+			case errors.Is(err, errWSDuplicateID):
+				c.close(wsCloseDuplicateID, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			case err != nil:
+				c.sendResult(msg.ID, "error", []*gqlErrors.QueryError{{Message: err.Error()}})
+			}
+
+		case "complete", "stop":
+			c.stop(msg.ID)
+
+		case "connection_terminate":
+			return
+
+		default:
+			c.close(wsCloseBadRequest, fmt.Sprintf("Unknown message type %q", msg.Type))
+			return
+		}
+	}
+}
+
+// touch marks the connection as being in use.
+func (c *wsConn) touch() {
+	c.mu.Lock()
+	c.active = time.Now()
+	c.mu.Unlock()
+}
+
+// checkIdle closes the connection if it had no running operations for the idle
+// timeout, or re-arms the check otherwise.
+func (c *wsConn) checkIdle() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	timeout, idle := c.handler.idleTimeout, time.Since(c.active)
+	if len(c.ops) > 0 || idle < timeout {
+		if len(c.ops) > 0 {
+			idle = 0
+		}
+		c.idle.Reset(timeout - idle)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	c.close(websocket.CloseGoingAway, "Idle timeout")
+	c.conn.Close() // unblocks the server loop
+}
+
+// start runs an operation in the background. It fails if an operation with the
+// same ID is already running, or if too many operations are running.
+func (c *wsConn) start(id string, req wsRequest) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.ops[id]; ok {
+		return errWSDuplicateID
+	}
+	if len(c.ops) >= c.handler.maxOps {
+		return errWSTooManyOperations
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.handler.opTimeout)
+	c.ops[id] = cancel
+	c.wg.Add(1)
+	go c.run(ctx, id, req)
+	return nil
+}
+
+// stop cancels a running operation.
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.ops[id]; ok {
+		cancel()
+		delete(c.ops, id)
+		c.active = time.Now()
+	}
+}
+
+// run executes an operation, forwarding its results to the client.
+func (c *wsConn) run(ctx context.Context, id string, req wsRequest) {
+	defer c.wg.Done()
+
+	responses, err := c.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.sendResult(id, "error", []*gqlErrors.QueryError{{Message: err.Error()}})
+		c.stop(id)
+		return
+	}
+	first := true
+	for r := range responses {
+		// The response channel must be drained until it is closed, even if the
+		// operation was stopped in the meantime.
+		if ctx.Err() != nil {
+			continue
+		}
+		resp := r.(*graphql.Response)
+		if first && resp.Data == nil && len(resp.Errors) > 0 && !c.legacy {
+			// Operation failed before producing results, e.g. validation.
+			c.sendResult(id, "error", resp.Errors)
+			c.stop(id)
+			continue
+		}
+		first = false
+		if c.legacy {
+			c.sendResult(id, "data", resp)
+		} else {
+			c.sendResult(id, "next", resp)
+		}
+	}
+	// Notify the client unless it stopped the operation itself.
+	c.mu.Lock()
+	cancel, running := c.ops[id]
+	delete(c.ops, id)
+	c.active = time.Now()
+	c.mu.Unlock()
+	if running {
+		cancel()
+		c.send(wsMessage{ID: id, Type: "complete"})
+	}
+}
+
+// sendResult sends a message with the given payload.
+func (c *wsConn) sendResult(id string, typ string, payload interface{}) {
+	enc, err := json.Marshal(payload)
+	if err != nil {
+		log.Warn("Failed to encode GraphQL result", "err", err)
+		return
+	}
+	c.send(wsMessage{ID: id, Type: typ, Payload: enc})
+}
+
+func (c *wsConn) send(msg wsMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(msg)
+}
+
+// close sends a close frame with the given code. The connection is closed by
+// the server loop.
+func (c *wsConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+}
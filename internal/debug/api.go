@@ -65,6 +65,15 @@ func (*HandlerT) Vmodule(pattern string) error {
 	return glogger.Vmodule(pattern)
 }
 
+// RotateLog closes the current log file and starts a new one. It fails if log
+// rotation is not enabled.
+func (*HandlerT) RotateLog() error {
+	if logRotator == nil {
+		return errors.New("log rotation not enabled")
+	}
+	return logRotator.Rotate()
+}
+
 // MemStats returns detailed runtime memory statistics.
 func (*HandlerT) MemStats() *runtime.MemStats {
 	s := new(runtime.MemStats)
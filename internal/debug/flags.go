@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
@@ -105,6 +106,27 @@ var (
 		Value:    false,
 		Category: flags.LoggingCategory,
 	}
+	logRotateIntervalFlag = &cli.DurationFlag{
+		Name:     "log.rotate.interval",
+		Usage:    "Rotate the log file periodically at this interval, in addition to size-based rotation (0 = disabled)",
+		Category: flags.LoggingCategory,
+	}
+	logSyslogFlag = &cli.StringFlag{
+		Name:     "log.syslog",
+		Usage:    "Send logs in RFC 5424 format to a syslog endpoint (udp://host:port, tcp://host:port or unix:///path)",
+		Category: flags.LoggingCategory,
+	}
+	logSyslogFacilityFlag = &cli.StringFlag{
+		Name:     "log.syslog.facility",
+		Usage:    "Syslog facility of the sent logs (e.g. daemon, user, local0)",
+		Value:    "daemon",
+		Category: flags.LoggingCategory,
+	}
+	logSyslogTagFlag = &cli.StringFlag{
+		Name:     "log.syslog.tag",
+		Usage:    "Application name of the sent logs (default = executable name)",
+		Category: flags.LoggingCategory,
+	}
 	pprofFlag = &cli.BoolFlag{
 		Name:     "pprof",
 		Usage:    "Enable the pprof HTTP server",
@@ -158,6 +180,10 @@ var Flags = []cli.Flag{
 	logMaxBackupsFlag,
 	logMaxAgeFlag,
 	logCompressFlag,
+	logRotateIntervalFlag,
+	logSyslogFlag,
+	logSyslogFacilityFlag,
+	logSyslogTagFlag,
 	pprofFlag,
 	pprofAddrFlag,
 	pprofPortFlag,
@@ -170,6 +196,9 @@ var Flags = []cli.Flag{
 var (
 	glogger       *log.GlogHandler
 	logOutputFile io.WriteCloser
+	logRotator    *lumberjack.Logger // Rotating log file, if rotation is enabled
+	logRotateStop chan struct{}      // Quit channel for periodic log rotation
+	logSyslog     io.WriteCloser     // Connection to the syslog endpoint, if enabled
 )
 
 func init() {
@@ -189,6 +218,9 @@ func Setup(ctx *cli.Context) error {
 		logFile  = ctx.String(logFileFlag.Name)
 		rotation = ctx.Bool(logRotateFlag.Name)
 	)
+	if ctx.IsSet(logRotateIntervalFlag.Name) && !rotation {
+		return fmt.Errorf("--%s requires --%s", logRotateIntervalFlag.Name, logRotateFlag.Name)
+	}
 	if len(logFile) > 0 {
 		if err := validateLogLocation(filepath.Dir(logFile)); err != nil {
 			return fmt.Errorf("failed to initiatilize file logger: %v", err)
@@ -208,14 +240,21 @@ func Setup(ctx *cli.Context) error {
 		} else {
 			context = append(context, "location", filepath.Join(os.TempDir(), "geth-lumberjack.log"))
 		}
-		logOutputFile = &lumberjack.Logger{
+		logRotator = &lumberjack.Logger{
 			Filename:   logFile,
 			MaxSize:    ctx.Int(logMaxSizeMBsFlag.Name),
 			MaxBackups: ctx.Int(logMaxBackupsFlag.Name),
 			MaxAge:     ctx.Int(logMaxAgeFlag.Name),
 			Compress:   ctx.Bool(logCompressFlag.Name),
 		}
+		logOutputFile = logRotator
 		output = io.MultiWriter(terminalOutput, logOutputFile)
+
+		if interval := ctx.Duration(logRotateIntervalFlag.Name); interval > 0 {
+			context = append(context, "interval", interval)
+			logRotateStop = make(chan struct{})
+			go rotateLoop(logRotator, interval, logRotateStop)
+		}
 	} else if logFile != "" {
 		var err error
 		if logOutputFile, err = os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
@@ -252,6 +291,23 @@ func Setup(ctx *cli.Context) error {
 		return fmt.Errorf("unknown log format: %v", ctx.String(logFormatFlag.Name))
 	}
 
+	if endpoint := ctx.String(logSyslogFlag.Name); endpoint != "" {
+		facility, ok := syslogFacilities[ctx.String(logSyslogFacilityFlag.Name)]
+		if !ok {
+			return fmt.Errorf("unknown syslog facility: %v", ctx.String(logSyslogFacilityFlag.Name))
+		}
+		tag := ctx.String(logSyslogTagFlag.Name)
+		if tag == "" {
+			tag = filepath.Base(os.Args[0])
+		}
+		writer, err := newSyslogWriter(endpoint)
+		if err != nil {
+			return err
+		}
+		logSyslog = writer
+		handler = teeHandler{handler, log.NewSyslogHandler(writer, facility, tag)}
+		context = append(context, "syslog", endpoint)
+	}
 	glogger = log.NewGlogHandler(handler)
 
 	// logging
@@ -301,12 +357,29 @@ func Setup(ctx *cli.Context) error {
 		// It cannot be imported because it will cause a cyclical dependency.
 		StartPProf(address, !ctx.IsSet("metrics.addr"))
 	}
-	if len(logFile) > 0 || rotation {
+	if len(logFile) > 0 || rotation || logSyslog != nil {
 		log.Info("Logging configured", context...)
 	}
 	return nil
 }
 
+// rotateLoop rotates the log file at every interval until stopped.
+func rotateLoop(logger *lumberjack.Logger, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := logger.Rotate(); err != nil {
+				log.Error("Failed to rotate log file", "err", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
 func StartPProf(address string, withMetrics bool) {
 	// Hook go-metrics into expvar on any /debug/metrics request, load all vars
 	// from the registry into expvar, and execute regular expvar handler.
@@ -327,9 +400,16 @@ func StartPProf(address string, withMetrics bool) {
 func Exit() {
 	Handler.StopCPUProfile()
 	Handler.StopGoTrace()
+	if logRotateStop != nil {
+		close(logRotateStop)
+		logRotateStop = nil
+	}
 	if logOutputFile != nil {
 		logOutputFile.Close()
 	}
+	if logSyslog != nil {
+		logSyslog.Close()
+	}
 }
 
 func validateLogLocation(path string) error {
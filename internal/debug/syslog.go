@@ -0,0 +1,264 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// syslogFacilities maps the syslog facility names to their RFC 5424 codes.
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+const (
+	// syslogDialTimeout is the maximum time to wait for a syslog connection or
+	// for a message to be written into it.
+	syslogDialTimeout = 5 * time.Second
+
+	// syslogFlushTimeout is the maximum time spent delivering the queued messages
+	// when the writer is closed. Messages not delivered by then are dropped.
+	syslogFlushTimeout = 5 * time.Second
+
+	// syslogQueueSize is the number of messages buffered for delivery. Messages
+	// are dropped if the endpoint can't keep up with them.
+	syslogQueueSize = 1024
+
+	// syslogMinRetryDelay and syslogMaxRetryDelay bound the exponential backoff
+	// between reconnection attempts to an unreachable endpoint.
+	syslogMinRetryDelay = time.Second
+	syslogMaxRetryDelay = time.Minute
+)
+
+// syslogWriter delivers syslog messages to a syslog daemon or journald over a
+// UDP, TCP or Unix socket, one message per write. Datagram transports preserve
+// message boundaries, stream transports use octet counting framing (RFC 6587).
+//
+// Messages are delivered by a background goroutine so that logging never blocks
+// on the endpoint. If the endpoint is unreachable or too slow, messages are
+// dropped and broken connections are re-established with exponential backoff.
+type syslogWriter struct {
+	network string // Network to dial (udp, tcp, unixgram or unix)
+	address string // Address of the syslog endpoint
+	stream  bool   // Whether the transport needs message framing
+
+	queue   chan []byte   // Messages waiting for delivery
+	closing chan struct{} // Channel closed to stop the delivery loop
+	closed  chan struct{} // Channel closed when the delivery loop terminated
+	once    sync.Once     // Ensures the writer is closed only once
+
+	// Fields below are owned by the delivery loop after construction
+	conn  net.Conn      // Connection to the endpoint, nil if broken
+	delay time.Duration // Current delay between reconnection attempts
+	retry time.Time     // Earliest time of the next reconnection attempt
+}
+
+// newSyslogWriter creates a syslog writer for an endpoint of the form
+// udp://host[:port], tcp://host[:port] or unix:///path/to/socket, and checks
+// that it can be connected to.
+func newSyslogWriter(endpoint string) (*syslogWriter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog endpoint %q: %v", endpoint, err)
+	}
+	w := &syslogWriter{
+		queue:   make(chan []byte, syslogQueueSize),
+		closing: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid syslog endpoint %q: missing host", endpoint)
+		}
+		w.network, w.address, w.stream = u.Scheme, u.Host, u.Scheme == "tcp"
+		if u.Port() == "" {
+			port := 514
+			if w.stream {
+				port = 601
+			}
+			w.address = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+		}
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid syslog endpoint %q: missing socket path", endpoint)
+		}
+		w.network, w.address = "unixgram", u.Path
+	default:
+		return nil, fmt.Errorf("invalid syslog endpoint %q: unsupported scheme %q", endpoint, u.Scheme)
+	}
+	if err := w.connect(time.Now().Add(syslogDialTimeout)); err != nil {
+		return nil, err
+	}
+	go w.loop()
+	return w, nil
+}
+
+// connect dials the syslog endpoint. Unix sockets are tried as datagram sockets
+// first, falling back to stream sockets.
+func (w *syslogWriter) connect(deadline time.Time) error {
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.Dial(w.network, w.address)
+	if err != nil && w.network == "unixgram" {
+		if conn, err = dialer.Dial("unix", w.address); err == nil {
+			w.network, w.stream = "unix", true
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %v", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write queues a single syslog message for delivery. The message is dropped if
+// the queue is full.
+func (w *syslogWriter) Write(msg []byte) (int, error) {
+	select {
+	case <-w.closing:
+		return 0, errors.New("syslog writer closed")
+	default:
+	}
+	select {
+	case w.queue <- bytes.Clone(msg):
+	default:
+	}
+	return len(msg), nil
+}
+
+// loop delivers the queued messages until the writer is closed, then flushes
+// the remaining ones.
+func (w *syslogWriter) loop() {
+	defer close(w.closed)
+
+	for {
+		select {
+		case msg := <-w.queue:
+			w.deliver(msg, time.Now().Add(syslogDialTimeout))
+		case <-w.closing:
+			// The flush is bounded as a whole, so that closing the writer doesn't
+			// hang on an unresponsive endpoint.
+			deadline := time.Now().Add(syslogFlushTimeout)
+			for len(w.queue) > 0 && time.Now().Before(deadline) {
+				w.deliver(<-w.queue, deadline)
+			}
+			if w.conn != nil {
+				w.conn.Close()
+				w.conn = nil
+			}
+			return
+		}
+	}
+}
+
+// deliver sends a message to the endpoint before the deadline, reconnecting once
+// if the connection was lost. While the endpoint is unreachable, messages are
+// dropped without any reconnection attempt until the backoff delay expires.
+func (w *syslogWriter) deliver(msg []byte, deadline time.Time) {
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if time.Now().Before(w.retry) {
+				return
+			}
+			if err := w.connect(deadline); err != nil {
+				w.delay = min(max(2*w.delay, syslogMinRetryDelay), syslogMaxRetryDelay)
+				w.retry = time.Now().Add(w.delay)
+				return
+			}
+			w.delay = 0
+		}
+		frame := msg
+		if w.stream {
+			frame = appendOctetCounted(nil, msg)
+		}
+		w.conn.SetWriteDeadline(deadline)
+		if _, err := w.conn.Write(frame); err == nil {
+			return
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// appendOctetCounted appends a message framed by octet counting (RFC 6587) to
+// the buffer, i.e. prefixed by its length and a space.
+func appendOctetCounted(buf []byte, msg []byte) []byte {
+	buf = strconv.AppendInt(buf, int64(len(msg)), 10)
+	buf = append(buf, ' ')
+	return append(buf, msg...)
+}
+
+// Close stops the delivery of messages after flushing the queued ones, and
+// closes the connection to the syslog endpoint. The flush is abandoned after
+// syslogFlushTimeout.
+func (w *syslogWriter) Close() error {
+	w.once.Do(func() { close(w.closing) })
+	<-w.closed
+	return nil
+}
+
+// teeHandler is a log handler which passes records on to multiple handlers.
+type teeHandler []slog.Handler
+
+func (h teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			if err := handler.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSyslogOctetCounting(t *testing.T) {
+	tests := []struct {
+		msg, want string
+	}{
+		{"", "0 "},
+		{"hello", "5 hello"},
+		{"<14>1 - - - - - - a message with spaces\n", "40 <14>1 - - - - - - a message with spaces\n"},
+	}
+	for _, test := range tests {
+		if have := string(appendOctetCounted(nil, []byte(test.msg))); have != test.want {
+			t.Errorf("wrong frame for %q: have %q, want %q", test.msg, have, test.want)
+		}
+	}
+}
+
+func TestSyslogStreamDelivery(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+	w, err := newSyslogWriter("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello"))
+	w.Write([]byte("hi there"))
+	w.Close()
+
+	select {
+	case data := <-received:
+		if want := "5 hello8 hi there"; data != want {
+			t.Fatalf("wrong stream data: have %q, want %q", data, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not closed by writer")
+	}
+}
+
+func TestSyslogReconnectBackoff(t *testing.T) {
+	// Find an address which refuses connections.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := &syslogWriter{network: "tcp", address: addr, stream: true}
+	deadline := time.Now().Add(time.Minute)
+
+	// Every failed reconnection doubles the delay, up to the maximum.
+	want := syslogMinRetryDelay
+	for i := 0; i < 10; i++ {
+		w.deliver([]byte("msg"), deadline)
+		if w.conn != nil {
+			t.Fatal("connected to unreachable endpoint")
+		}
+		if w.delay != want {
+			t.Fatalf("attempt %d: wrong retry delay %v, want %v", i, w.delay, want)
+		}
+		// No reconnection is attempted until the delay expires.
+		retry := w.retry
+		w.deliver([]byte("msg"), deadline)
+		if w.retry != retry || w.delay != want {
+			t.Fatalf("attempt %d: reconnected before the delay expired", i)
+		}
+		w.retry = time.Time{}
+		want = min(2*want, syslogMaxRetryDelay)
+	}
+	if w.delay != syslogMaxRetryDelay {
+		t.Fatalf("retry delay not capped: %v", w.delay)
+	}
+
+	// A successful reconnection delivers the message and resets the delay.
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w.address = ln.Addr().String()
+	w.retry = time.Time{}
+	w.deliver([]byte("hello"), deadline)
+	if w.conn == nil {
+		t.Fatal("not reconnected")
+	}
+	defer w.conn.Close()
+	if w.delay != 0 {
+		t.Fatalf("retry delay not reset: %v", w.delay)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	buf := make([]byte, len("5 hello"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "5 hello" {
+		t.Fatalf("wrong message after reconnecting: %q, %v", buf, err)
+	}
+}
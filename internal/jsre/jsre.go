@@ -45,6 +45,7 @@ type JSRE struct {
 	stopEventLoop chan bool
 	closed        chan struct{}
 	vm            *goja.Runtime
+	modules       *moduleLoader
 }
 
 // Call is the argument type of Go functions which are callable from JS.
@@ -80,6 +81,11 @@ func New(assetPath string, output io.Writer) *JSRE {
 	go re.runEventLoop()
 	re.Set("loadScript", MakeCallback(re.vm, re.loadScript))
 	re.Set("inspect", re.prettyPrintJS)
+	re.Do(func(vm *goja.Runtime) {
+		re.modules = newModuleLoader(re)
+		vm.Set("require", re.modules.require)
+		vm.RunString(asyncHelpers)
+	})
 	return re
 }
 
@@ -254,11 +260,17 @@ func (re *JSRE) Stop(waitForCallbacks bool) {
 // Exec(file) loads and runs the contents of a file
 // if a relative path is given, the jsre's assetPath is used
 func (re *JSRE) Exec(file string) error {
-	code, err := os.ReadFile(common.AbsolutePath(re.assetPath, file))
+	path := common.AbsolutePath(re.assetPath, file)
+	code, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	return re.Compile(file, string(code))
+	re.Do(func(vm *goja.Runtime) {
+		_, err = re.modules.run(path, func() (goja.Value, error) {
+			return compileAndRun(vm, file, string(code))
+		})
+	})
+	return err
 }
 
 // Run runs a piece of JS code.
@@ -324,7 +336,9 @@ func (re *JSRE) loadScript(call Call) (goja.Value, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not read file %s: %v", file, err)
 	}
-	value, err := compileAndRun(re.vm, file, string(source))
+	value, err := re.modules.run(file, func() (goja.Value, error) {
+		return compileAndRun(re.vm, file, string(source))
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error while compiling or running script: %v", err)
 	}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -128,3 +129,77 @@ func TestLoadScript(t *testing.T) {
 	}
 	jsre.Stop(false)
 }
+
+func TestRequire(t *testing.T) {
+	jsre := newWithTestJS(t, `var lib = require("./lib/math"); msg = lib.double(21) + lib.name;`)
+	defer jsre.Stop(false)
+
+	dir := filepath.Join(jsre.assetPath, "lib")
+	if err := os.Mkdir(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	// Modules resolve relative to themselves and are only executed once, circular
+	// requires seeing the exports assembled so far
+	files := map[string]string{
+		"math.js":   `var util = require("./util.js"); exports.double = function(x) { return util.mul(x, 2); }; exports.name = util.name;`,
+		"util.js":   `loads = (typeof loads === "undefined" ? 0 : loads) + 1; module.exports = { mul: function(a, b) { return a * b; }, name: require("./name").name };`,
+		"name.js":   `exports.name = "/" + typeof require("./util.js").mul;`,
+		"broken.js": `module.exports = undefinedVariable;`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jsre.Exec("test.js"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if val, _ := jsre.Run(`msg + " " + loads + " " + (require("./lib/util") === require("./lib/math.js") ? "same" : "other")`); val.String() != "42/undefined 1 other" {
+		t.Errorf("wrong result: %v", val)
+	}
+	if _, err := jsre.Run(`require("./lib/broken")`); err == nil {
+		t.Errorf("expected error for broken module")
+	}
+	if _, err := jsre.Run(`require("./lib/missing")`); err == nil {
+		t.Errorf("expected error for missing module")
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	jsre := newWithTestJS(t, `
+		test("sync pass", function() { assert.equal(1 + 1, 2); });
+		test("sync fail", function() { assert.equal(1 + 1, 3, "math"); });
+		test("async pass", async function() {
+			var get = promisify(function(x, cb) { setTimeout(function() { cb(null, x * 2); }, 10); });
+			assert.deepEqual(await get(2), 4);
+		});
+		test("async fail", async function() {
+			await promisify(function(cb) { cb(new Error("rpc failed")); })();
+		});
+		test("throws", function() { assert.throws(function() { throw new Error("expected"); }); });
+	`)
+	defer jsre.Stop(false)
+
+	results, err := jsre.RunTests("test.js", time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := map[string]string{
+		"sync pass":  "",
+		"sync fail":  "assertion failed: math (have 2, want 3)",
+		"async pass": "",
+		"async fail": "rpc failed",
+		"throws":     "",
+	}
+	if len(results) != len(want) {
+		t.Fatalf("wrong number of results: have %d, want %d", len(results), len(want))
+	}
+	for _, result := range results {
+		switch {
+		case want[result.Name] == "" && result.Err != nil:
+			t.Errorf("test %q failed: %v", result.Name, result.Err)
+		case want[result.Name] != "" && (result.Err == nil || !strings.Contains(result.Err.Error(), want[result.Name])):
+			t.Errorf("test %q: have error %v, want %q", result.Name, result.Err, want[result.Name])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package jsre
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// moduleWrapper wraps the source of a module into a function providing the
+// CommonJS module environment. The source starts on the first line to keep the
+// line numbers of error messages intact.
+const moduleWrapper = "(function(exports, require, module, __filename, __dirname) {%s\n})"
+
+// asyncHelpers defines helpers turning callback-style functions (such as the
+// asynchronous variants of the web3 RPC methods) into promise returning ones.
+const asyncHelpers = `
+function promisify(fn, self) {
+	return function() {
+		var args = Array.prototype.slice.call(arguments);
+		return new Promise(function(resolve, reject) {
+			args.push(function(err, result) {
+				if (err) {
+					reject(err);
+				} else {
+					resolve(result);
+				}
+			});
+			fn.apply(self, args);
+		});
+	};
+}
+
+function asyncify(obj) {
+	var wrapped = {};
+	for (var key in obj) {
+		var desc = Object.getOwnPropertyDescriptor(obj, key);
+		if (desc && desc.get) {
+			continue; // Skip synchronous property getters
+		}
+		if (typeof obj[key] === 'function') {
+			wrapped[key] = promisify(obj[key], obj);
+		}
+	}
+	return wrapped;
+}
+`
+
+// moduleLoader implements CommonJS style loading of local script modules via
+// require. Modules are resolved relative to the requiring module, or to the
+// currently executed script file (falling back to the asset path) at the top
+// level. Non-local names are delegated to any previously defined require.
+type moduleLoader struct {
+	re      *JSRE
+	cache   map[string]*goja.Object // Module objects by absolute path
+	dirs    []string                // Directories of the script files being executed
+	require goja.Value              // Top-level require function
+}
+
+func newModuleLoader(re *JSRE) *moduleLoader {
+	l := &moduleLoader{
+		re:    re,
+		cache: make(map[string]*goja.Object),
+	}
+	l.require = l.makeRequire("")
+	return l
+}
+
+// isLocalModule returns whether a module name refers to a script file.
+func isLocalModule(name string) bool {
+	return strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") || filepath.IsAbs(name)
+}
+
+// makeRequire creates a require function resolving relative names from dir. If
+// dir is empty, names are resolved from the currently executing script.
+func (l *moduleLoader) makeRequire(dir string) goja.Value {
+	vm := l.re.vm
+	return vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		name := call.Argument(0).String()
+		if !isLocalModule(name) {
+			// Delegate to the global require if it was replaced (e.g. by the
+			// bundled web3 modules), unless that is falling back to us already.
+			fallback := call.Argument(1).ToBoolean()
+			if global, ok := goja.AssertFunction(vm.Get("require")); ok && !fallback && !vm.Get("require").SameAs(l.require) {
+				v, err := global(goja.Undefined(), call.Arguments...)
+				if err != nil {
+					panic(err)
+				}
+				return v
+			}
+			panic(vm.NewGoError(fmt.Errorf("cannot find module '%s'", name)))
+		}
+		base := dir
+		if base == "" {
+			base = l.currentDir()
+		}
+		exports, err := l.load(name, base)
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return exports
+	})
+}
+
+// currentDir returns the directory to resolve top-level requires from.
+func (l *moduleLoader) currentDir() string {
+	if len(l.dirs) > 0 {
+		return l.dirs[len(l.dirs)-1]
+	}
+	if l.re.assetPath != "" {
+		return l.re.assetPath
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// resolveModule finds the script file of a module, trying the name as given, with a
+// .js extension and as a directory containing index.js.
+func resolveModule(name, base string) (string, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, name)
+	}
+	for _, candidate := range []string{path, path + ".js", filepath.Join(path, "index.js")} {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", fmt.Errorf("cannot find module '%s' from %s", name, base)
+}
+
+// load executes a module, returning its exports. Modules are only executed once,
+// subsequent (including circular) requires return the cached exports.
+func (l *moduleLoader) load(name, base string) (goja.Value, error) {
+	path, err := resolveModule(name, base)
+	if err != nil {
+		return nil, err
+	}
+	vm := l.re.vm
+	if module, ok := l.cache[path]; ok {
+		return module.Get("exports"), nil
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read module %s: %v", path, err)
+	}
+	wrapper, err := compileAndRun(vm, path, fmt.Sprintf(moduleWrapper, source))
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(wrapper)
+	if !ok {
+		return nil, fmt.Errorf("invalid module %s", path)
+	}
+	module := vm.NewObject()
+	exports := vm.NewObject()
+	module.Set("exports", exports)
+	module.Set("id", path)
+	l.cache[path] = module
+
+	dir := filepath.Dir(path)
+	if _, err := fn(exports, exports, l.makeRequire(dir), module, vm.ToValue(path), vm.ToValue(dir)); err != nil {
+		delete(l.cache, path)
+		return nil, err
+	}
+	return module.Get("exports"), nil
+}
+
+// run executes fn with relative requires being resolved from the directory of
+// the given script file.
+func (l *moduleLoader) run(file string, fn func() (goja.Value, error)) (goja.Value, error) {
+	l.dirs = append(l.dirs, filepath.Dir(file))
+	defer func() { l.dirs = l.dirs[:len(l.dirs)-1] }()
+	return fn()
+}
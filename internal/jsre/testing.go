@@ -0,0 +1,187 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package jsre
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// assertHelpers defines the assertion functions available to test scripts.
+const assertHelpers = `
+var assert = (function() {
+	function fail(msg, detail) {
+		throw new Error("assertion failed" + (msg ? ": " + msg : "") + (detail ? " (" + detail + ")" : ""));
+	}
+	function equal(a, b) {
+		if (a === b) {
+			return true;
+		}
+		if (a !== null && a !== undefined && typeof a.equals === 'function') {
+			try {
+				return a.equals(b);
+			} catch (e) {
+				return false;
+			}
+		}
+		return false;
+	}
+	var assert = function(cond, msg) {
+		if (!cond) {
+			fail(msg);
+		}
+	};
+	assert.ok = assert;
+	assert.fail = function(msg) {
+		fail(msg);
+	};
+	assert.equal = function(actual, expected, msg) {
+		if (!equal(actual, expected)) {
+			fail(msg, "have " + actual + ", want " + expected);
+		}
+	};
+	assert.notEqual = function(actual, expected, msg) {
+		if (equal(actual, expected)) {
+			fail(msg, "have " + actual + ", want different value");
+		}
+	};
+	assert.deepEqual = function(actual, expected, msg) {
+		var have = JSON.stringify(actual), want = JSON.stringify(expected);
+		if (have !== want) {
+			fail(msg, "have " + have + ", want " + want);
+		}
+	};
+	assert.throws = function(fn, msg) {
+		try {
+			fn();
+		} catch (e) {
+			return e;
+		}
+		fail(msg, "no exception thrown");
+	};
+	return assert;
+})();
+`
+
+// TestResult is the outcome of a single test of a test script.
+type TestResult struct {
+	Name     string        // Name the test was registered with
+	Err      error         // Failure of the test, nil if passed
+	Duration time.Duration // Time it took to run the test
+}
+
+// scriptTest is a test registered by a test script.
+type scriptTest struct {
+	name string
+	fn   goja.Callable
+}
+
+// RunTests executes a test script and then the tests it registered. Scripts
+// register tests with test(name, fn) and check expectations with the assert
+// functions. Tests returning a promise (e.g. async functions) are awaited for
+// at most the given timeout. The returned error is only set if the script
+// itself failed to run.
+func (re *JSRE) RunTests(file string, timeout time.Duration) ([]TestResult, error) {
+	var (
+		tests []scriptTest
+		err   error
+	)
+	re.Do(func(vm *goja.Runtime) {
+		vm.Set("test", func(call goja.FunctionCall) goja.Value {
+			fn, ok := goja.AssertFunction(call.Argument(1))
+			if !ok {
+				panic(vm.NewTypeError("test function required"))
+			}
+			tests = append(tests, scriptTest{name: call.Argument(0).String(), fn: fn})
+			return goja.Undefined()
+		})
+		_, err = vm.RunString(assertHelpers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := re.Exec(file); err != nil {
+		return nil, err
+	}
+	results := make([]TestResult, 0, len(tests))
+	for _, test := range tests {
+		start := time.Now()
+		results = append(results, TestResult{
+			Name:     test.name,
+			Err:      re.runTest(test.fn, timeout),
+			Duration: time.Since(start),
+		})
+	}
+	return results, nil
+}
+
+// runTest runs a single test function, waiting for the returned promise to
+// settle if the test is asynchronous.
+func (re *JSRE) runTest(fn goja.Callable, timeout time.Duration) error {
+	var (
+		promise *goja.Promise
+		err     error
+	)
+	re.Do(func(vm *goja.Runtime) {
+		var result goja.Value
+		if result, err = fn(goja.Undefined()); err == nil && result != nil {
+			promise, _ = result.Export().(*goja.Promise)
+		}
+	})
+	if err != nil || promise == nil {
+		return err
+	}
+	// Asynchronous test, wait until the promise is settled. Pending timers
+	// run on the event loop in the meantime.
+	deadline := time.Now().Add(timeout)
+	for {
+		var (
+			state  goja.PromiseState
+			result goja.Value
+		)
+		re.Do(func(vm *goja.Runtime) {
+			state, result = promise.State(), promise.Result()
+		})
+		switch state {
+		case goja.PromiseStateFulfilled:
+			return nil
+		case goja.PromiseStateRejected:
+			return promiseError(result)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("test timed out after %v", timeout)
+		}
+		select {
+		case <-re.closed:
+			return errors.New("JS runtime stopped")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// promiseError converts the rejection reason of a promise into an error.
+func promiseError(reason goja.Value) error {
+	if obj, ok := reason.(*goja.Object); ok {
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			return errors.New(stack.String())
+		}
+	}
+	return errors.New(reason.String())
+}
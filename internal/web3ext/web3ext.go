@@ -64,6 +64,11 @@ web3._extend({
 			call: 'clique_propose',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'proposeWithExpiry',
+			call: 'clique_propose',
+			params: 3
+		}),
 		new web3._extend.Method({
 			name: 'discard',
 			call: 'clique_discard',
@@ -80,12 +85,32 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'scheduleRotation',
+			call: 'clique_scheduleRotation',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'clearRotation',
+			call: 'clique_clearRotation',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getProposalHistory',
+			call: 'clique_getProposalHistory',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'proposals',
 			getter: 'clique_proposals'
 		}),
+		new web3._extend.Property({
+			name: 'rotation',
+			getter: 'clique_getRotation'
+		}),
 	]
 });
 `
@@ -200,6 +225,10 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerScores',
+			getter: 'admin_peerScores'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -279,6 +308,11 @@ web3._extend({
 			call: 'debug_vmodule',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'rotateLog',
+			call: 'debug_rotateLog',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'backtraceAt',
 			call: 'debug_backtraceAt',
@@ -500,6 +534,11 @@ web3._extend({
 			call: 'debug_getTrieFlushInterval',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'pruningStatus',
+			call: 'debug_pruningStatus',
+			params: 0
+		}),
 	],
 	properties: []
 });
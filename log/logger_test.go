@@ -50,6 +50,26 @@ func TestTerminalHandlerWithAttrs(t *testing.T) {
 	}
 }
 
+func TestSyslogHandler(t *testing.T) {
+	out := new(bytes.Buffer)
+	handler := NewSyslogHandler(out, 3, "geth").WithAttrs([]slog.Attr{slog.String("baz", "bat")})
+	logger := NewLogger(handler)
+	logger.Warn("a message", "foo", "bar baz", "num", 1000)
+
+	// The header contains the time, host and process, so only check the parts
+	// independent of the environment
+	have := out.String()
+	if !strings.HasPrefix(have, "<28>1 ") {
+		t.Errorf("wrong header: %q", have)
+	}
+	if fields := strings.SplitN(have, " ", 8); len(fields) != 8 || fields[3] != "geth" || fields[5] != "-" || fields[6] != "-" {
+		t.Errorf("wrong header fields: %q", have)
+	}
+	if want := ` - - a message baz=bat foo="bar baz" num=1000`; !strings.HasSuffix(have, want) {
+		t.Errorf("\nhave: %q\nwant suffix: %q\n", have, want)
+	}
+}
+
 // Make sure the default json handler outputs debug log lines
 func TestJSONHandler(t *testing.T) {
 	out := new(bytes.Buffer)
@@ -190,6 +190,15 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLTracing enables the tracing fields of the GraphQL API, which
+	// re-execute transactions on request. Only native tracers can be used,
+	// unless GraphQLTracingJS is set as well.
+	GraphQLTracing bool `toml:",omitempty"`
+
+	// GraphQLTracingJS allows arbitrary JavaScript tracers in GraphQL trace
+	// queries. Only enable this if the endpoint is exposed to trusted users.
+	GraphQLTracingJS bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -197,12 +197,11 @@ func (h *httpServer) start() error {
 }
 
 func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// check if ws request and serve if ws enabled
+	// check if ws request and serve if ws enabled. WebSocket requests to other
+	// paths may be served by the handlers registered in the mux.
 	ws := h.wsHandler.Load().(*rpcHandler)
-	if ws != nil && isWebsocket(r) {
-		if checkPath(r, h.wsConfig.prefix) {
-			ws.ServeHTTP(w, r)
-		}
+	if ws != nil && isWebsocket(r) && checkPath(r, h.wsConfig.prefix) {
+		ws.ServeHTTP(w, r)
 		return
 	}
 
@@ -412,6 +411,12 @@ func NewWSHandlerStack(srv http.Handler, jwtSecret []byte) http.Handler {
 	return srv
 }
 
+// NewVHostHandler returns a handler which only serves requests addressed to one of
+// the given virtual hostnames, or to an IP address.
+func NewVHostHandler(vhosts []string, next http.Handler) http.Handler {
+	return newVHostHandler(vhosts, next)
+}
+
 func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
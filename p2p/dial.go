@@ -135,6 +135,7 @@ type dialConfig struct {
 	log            log.Logger
 	clock          mclock.Clock
 	rand           *mrand.Rand
+	banned         func(enode.ID) bool // reports nodes with a too low reputation score
 }
 
 func (cfg dialConfig) withDefaults() dialConfig {
@@ -241,6 +242,8 @@ loop:
 		case node := <-nodesCh:
 			if err := d.checkDial(node); err != nil {
 				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IPAddr(), "reason", err)
+			} else if d.banned != nil && d.banned(node.ID()) {
+				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IPAddr(), "reason", errLowScore)
 			} else {
 				d.startDial(newDialTask(node, dynDialedConn))
 			}
@@ -0,0 +1,233 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+// This file implements UDP hole punching for nodes behind NAT.
+//
+// When a node A fails to contact node B, it asks a relay node R, which previously sent
+// B's record to A, to help establish the session. R forwards the request to B along with
+// A's endpoint as seen by R. B then sends a PING to A, which creates a mapping for A's
+// endpoint in B's NAT. Since A already sent packets to B, A's NAT lets the PING through,
+// and the handshake completes. The relay must be in contact with B, which is usually the
+// case because B keeps R in its table. R also reports A's endpoint to A, which feeds
+// the endpoint prediction of A just like a PONG.
+
+const (
+	// relayCacheSize is the number of nodes for which the relay is remembered.
+	relayCacheSize = 2048
+
+	// relayPunchInterval is the minimum time between hole punching attempts
+	// towards the same node.
+	relayPunchInterval = 30 * time.Second
+
+	// maxActiveRelayRequests limits the number of relayed requests which
+	// are processed concurrently.
+	maxActiveRelayRequests = 16
+)
+
+var errNoRelay = errors.New("no relay for node")
+
+type relaySystem struct {
+	transport *UDPv5
+
+	mu       sync.Mutex
+	relays   lru.BasicLRU[enode.ID, *enode.Node]    // node -> node which sent its record
+	attempts lru.BasicLRU[enode.ID, mclock.AbsTime] // node -> time of last punch attempt
+	slots    chan struct{}
+}
+
+func newRelaySystem(transport *UDPv5) *relaySystem {
+	r := &relaySystem{
+		transport: transport,
+		relays:    lru.NewBasicLRU[enode.ID, *enode.Node](relayCacheSize),
+		attempts:  lru.NewBasicLRU[enode.ID, mclock.AbsTime](relayCacheSize),
+		slots:     make(chan struct{}, maxActiveRelayRequests),
+	}
+	for i := 0; i < cap(r.slots); i++ {
+		r.slots <- struct{}{}
+	}
+	return r
+}
+
+// add records that relay sent the record of n.
+func (r *relaySystem) add(n, relay *enode.Node) {
+	if n.ID() == relay.ID() {
+		return
+	}
+	r.mu.Lock()
+	r.relays.Add(n.ID(), relay)
+	r.mu.Unlock()
+}
+
+// relayFor returns the relay of n, if a hole punching attempt is due.
+func (r *relaySystem) relayFor(n *enode.Node) *enode.Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	relay, ok := r.relays.Get(n.ID())
+	if !ok {
+		return nil
+	}
+	now := r.transport.clock.Now()
+	if last, ok := r.attempts.Get(n.ID()); ok && time.Duration(now-last) < relayPunchInterval {
+		return nil
+	}
+	r.attempts.Add(n.ID(), now)
+	return relay
+}
+
+// punch asks the relay of n to initiate hole punching. It returns nil if the
+// relay has notified n.
+func (r *relaySystem) punch(n *enode.Node) error {
+	t := r.transport
+	relay := r.relayFor(n)
+	if relay == nil {
+		return errNoRelay
+	}
+	req := &v5wire.RelayInit{Target: n.ID(), ENR: t.Self().Record()}
+	resp := t.callToNode(relay, v5wire.RelayResponseMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case p := <-resp.ch:
+		result := p.(*v5wire.RelayResponse)
+		if addr, ok := relay.UDPEndpoint(); ok && len(result.IP) > 0 && result.Port != 0 {
+			t.localNode.UDPEndpointStatement(addr, netip.AddrPortFrom(netutil.IPToAddr(result.IP), result.Port))
+		}
+		if !result.Success {
+			return errors.New("relay failed")
+		}
+		t.log.Debug("Hole punching initiated", "id", n.ID(), "relay", relay.ID())
+		return nil
+	case err := <-resp.err:
+		return err
+	}
+}
+
+// handleRelayInit forwards a hole punching request to its target.
+func (r *relaySystem) handleRelayInit(p *v5wire.RelayInit, fromID enode.ID, fromAddr netip.AddrPort) {
+	t := r.transport
+	var (
+		ip     = fromAddr.Addr().AsSlice()
+		result = &v5wire.RelayResponse{ReqID: p.ReqID, IP: ip, Port: fromAddr.Port()}
+	)
+	fail := func(reason string) {
+		t.log.Debug("Rejected "+p.Name(), "id", fromID, "addr", fromAddr, "reason", reason)
+		t.sendResponse(fromID, fromAddr, result)
+	}
+	if p.ENR == nil {
+		fail("missing record")
+		return
+	}
+	initiator, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil || initiator.ID() != fromID {
+		fail("invalid record")
+		return
+	}
+	target := t.tab.getNode(p.Target)
+	if target == nil || target.ID() == fromID {
+		fail("unknown target")
+		return
+	}
+	select {
+	case <-r.slots:
+	default:
+		fail("too many requests")
+		return
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer func() {
+			r.slots <- struct{}{}
+			t.wg.Done()
+		}()
+		notify := &v5wire.RelayNotify{ENR: p.ENR, IP: ip, Port: fromAddr.Port()}
+		resp := t.callToNode(target, v5wire.RelayResponseMsg, notify)
+		select {
+		case p := <-resp.ch:
+			result.Success = p.(*v5wire.RelayResponse).Success
+		case <-resp.err:
+		}
+		t.callDone(resp)
+		t.sendFromAnotherThread(fromID, fromAddr, result)
+	}()
+}
+
+// handleRelayNotify handles a hole punching request forwarded by a relay.
+func (r *relaySystem) handleRelayNotify(p *v5wire.RelayNotify, fromID enode.ID, fromAddr netip.AddrPort) {
+	t := r.transport
+	resp := &v5wire.RelayResponse{ReqID: p.ReqID}
+	defer t.sendResponse(fromID, fromAddr, resp)
+
+	// Only relays in our table are trusted to send notifications. This prevents
+	// the PING from being used for traffic reflection by arbitrary nodes.
+	if t.tab.getNode(fromID) == nil || p.ENR == nil {
+		return
+	}
+	initiator, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil {
+		return
+	}
+	ip, _ := netip.AddrFromSlice(p.IP)
+	addr := netip.AddrPortFrom(ip.Unmap(), p.Port)
+	if !addr.Addr().IsValid() || addr.Addr().IsUnspecified() || addr.Port() == 0 {
+		return
+	}
+	if err := netutil.CheckRelayAddr(fromAddr.Addr(), addr.Addr()); err != nil {
+		return
+	}
+	if t.netrestrict != nil && !t.netrestrict.ContainsAddr(addr.Addr()) {
+		return
+	}
+	select {
+	case <-r.slots:
+	default:
+		return
+	}
+	resp.Success = true
+
+	t.wg.Add(1)
+	go func() {
+		defer func() {
+			r.slots <- struct{}{}
+			t.wg.Done()
+		}()
+		req := &v5wire.Ping{ENRSeq: t.localNode.Node().Seq()}
+		c := &callV5{id: initiator.ID(), addr: addr, node: initiator}
+		t.initCall(c, v5wire.PongMsg, req)
+		select {
+		case <-c.ch:
+		case err := <-c.err:
+			t.log.Debug("Hole punching PING failed", "id", initiator.ID(), "addr", addr, "err", err)
+		}
+		t.callDone(c)
+	}()
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/simulations/natsim"
+)
+
+// startNATSimV5 starts a discv5 node in the simulated network. If nat is nil, the
+// node is attached to the public network and its endpoint is set statically.
+// Otherwise it is placed behind the gateway, and relies on endpoint prediction.
+func startNATSimV5(t *testing.T, network *natsim.Network, nat *natsim.NAT, addr string, bootnodes ...*enode.Node) *UDPv5 {
+	// Background revalidation is slowed down, so that it doesn't open the NAT
+	// mappings which hole punching is supposed to create.
+	cfg := Config{PrivateKey: newkey(), Bootnodes: bootnodes, PingInterval: time.Hour}
+	db, _ := enode.OpenDB("")
+	ln := enode.NewLocalNode(db, cfg.PrivateKey)
+	lprefix := fmt.Sprintf("(%s)", ln.ID().TerminalString())
+	cfg.Log = testlog.Logger(t, log.LevelTrace).With("node-id", lprefix)
+
+	var (
+		ep   = netip.MustParseAddrPort(addr)
+		conn *natsim.Conn
+		err  error
+	)
+	if nat == nil {
+		conn, err = network.Listen(ep)
+		ln.SetStaticIP(ep.Addr().AsSlice())
+		ln.Set(enr.UDP(ep.Port()))
+	} else {
+		conn, err = nat.Listen(ep)
+		ln.SetEndpointPredictionThreshold(1)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := ListenV5(conn, ln, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(udp.Close)
+	return udp
+}
+
+func newHolePunchTest(t *testing.T, behavior natsim.Behavior) (relay, a, b *UDPv5) {
+	network := natsim.NewNetwork()
+	natA, _ := network.NewNAT(netip.MustParseAddr("35.0.1.1"), behavior)
+	natB, _ := network.NewNAT(netip.MustParseAddr("35.0.2.1"), behavior)
+	relay = startNATSimV5(t, network, nil, "35.0.0.1:30303")
+	a = startNATSimV5(t, network, natA, "10.0.0.1:30303", relay.Self())
+	b = startNATSimV5(t, network, natB, "10.0.0.2:30303", relay.Self())
+
+	// Contacting the relay makes the nodes learn their external endpoint.
+	// Once it is in their record, the relay can serve it to other nodes.
+	// Only B is served, so that it can't contact A before hole punching.
+	for _, n := range []*UDPv5{a, b} {
+		if err := n.Ping(relay.Self()); err != nil {
+			t.Fatal("can't ping relay:", err)
+		}
+		if !n.Self().IPAddr().IsValid() {
+			t.Fatal("no endpoint prediction after contacting relay")
+		}
+	}
+	relay.tab.addFoundNode(b.Self(), true)
+
+	// A learns the record of B from the relay.
+	dist := uint(enode.LogDist(relay.Self().ID(), b.Self().ID()))
+	nodes, err := a.findnode(relay.Self(), []uint{dist})
+	if err != nil {
+		t.Fatal("findnode failed:", err)
+	}
+	if !containsID(nodes, b.Self().ID()) {
+		t.Fatal("relay did not return B")
+	}
+	return relay, a, b
+}
+
+func TestUDPv5_holePunch(t *testing.T) {
+	t.Parallel()
+	_, a, b := newHolePunchTest(t, natsim.PortRestricted)
+
+	if err := a.Ping(b.Self()); err != nil {
+		t.Fatal("ping after hole punching failed:", err)
+	}
+	if err := b.Ping(a.Self()); err != nil {
+		t.Fatal("reverse ping failed:", err)
+	}
+	if !punched(a, b.Self().ID()) {
+		t.Fatal("nodes connected without hole punching")
+	}
+	if punched(b, a.Self().ID()) {
+		t.Fatal("hole punching attempted for established session")
+	}
+}
+
+func punched(t *UDPv5, id enode.ID) bool {
+	t.relay.mu.Lock()
+	defer t.relay.mu.Unlock()
+	return t.relay.attempts.Contains(id)
+}
+
+func TestUDPv5_holePunchSymmetricNAT(t *testing.T) {
+	t.Parallel()
+	_, a, b := newHolePunchTest(t, natsim.Symmetric)
+
+	// Symmetric NAT uses a different external port for every destination, so the
+	// endpoints in the records are useless and hole punching fails.
+	if err := a.Ping(b.Self()); !errors.Is(err, errTimeout) {
+		t.Fatalf("wrong error for ping through symmetric NAT: %v", err)
+	}
+}
+
+// This test checks that the endpoint reported by the relay feeds the endpoint
+// prediction of the initiator.
+func TestUDPv5_holePunchEndpointStatement(t *testing.T) {
+	t.Parallel()
+	network := natsim.NewNetwork()
+	natA, _ := network.NewNAT(netip.MustParseAddr("35.0.1.1"), natsim.PortRestricted)
+	natB, _ := network.NewNAT(netip.MustParseAddr("35.0.2.1"), natsim.PortRestricted)
+	relay := startNATSimV5(t, network, nil, "35.0.0.1:30303")
+	a := startNATSimV5(t, network, natA, "10.0.0.1:30303", relay.Self())
+	b := startNATSimV5(t, network, natB, "10.0.0.2:30303", relay.Self())
+	if err := b.Ping(relay.Self()); err != nil {
+		t.Fatal("can't ping relay:", err)
+	}
+	relay.tab.addFoundNode(b.Self(), true)
+
+	// A learns the record of B without pinging the relay, so it doesn't know its
+	// own endpoint yet.
+	dist := uint(enode.LogDist(relay.Self().ID(), b.Self().ID()))
+	if _, err := a.findnode(relay.Self(), []uint{dist}); err != nil {
+		t.Fatal("findnode failed:", err)
+	}
+	if a.Self().IPAddr().IsValid() {
+		t.Fatal("endpoint predicted before hole punching")
+	}
+	if err := a.relay.punch(b.Self()); err != nil {
+		t.Fatal("hole punching failed:", err)
+	}
+	if ip := a.Self().IPAddr(); ip != netip.MustParseAddr("35.0.1.1") {
+		t.Fatalf("wrong predicted IP %v, want 35.0.1.1", ip)
+	}
+}
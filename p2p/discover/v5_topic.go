@@ -0,0 +1,463 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	topicAdLifetime    = 15 * time.Minute // how long an ad stays in the topic table
+	topicQueueLimit    = 50               // max ads per topic
+	topicTableLimit    = 5000             // max ads across all topics
+	topicRegWindow     = 10 * time.Second // time after the waiting time in which a ticket is valid
+	topicRegistrars    = 8                // number of nodes an advertiser registers with
+	topicMaxWait       = topicAdLifetime  // longer waiting times are not accepted by advertisers
+	topicSearchRefresh = 30 * time.Second // delay between topic search lookups
+)
+
+var (
+	errInvalidTicket = errors.New("invalid ticket")
+	errTicketTiming  = errors.New("ticket used outside of its registration window")
+)
+
+// TopicID identifies a topic. It is the Keccak256 hash of the topic name and
+// shares the keyspace of node IDs: ads are placed on the nodes closest to it.
+type TopicID [32]byte
+
+// NewTopic creates the topic identifier of a name.
+func NewTopic(name string) TopicID {
+	return TopicID(crypto.Keccak256Hash([]byte(name)))
+}
+
+// String returns the topic ID as a hex string.
+func (t TopicID) String() string {
+	return common.Hash(t).Hex()
+}
+
+// topicAd is a registration in the topic table.
+type topicAd struct {
+	node       *enode.Node
+	registered mclock.AbsTime
+}
+
+// topicTable stores the ads a node serves to topic searchers.
+type topicTable struct {
+	queues     map[TopicID][]*topicAd
+	count      int
+	queueLimit int
+	tableLimit int
+}
+
+func newTopicTable() *topicTable {
+	return &topicTable{
+		queues:     make(map[TopicID][]*topicAd),
+		queueLimit: topicQueueLimit,
+		tableLimit: topicTableLimit,
+	}
+}
+
+// expire removes ads which have outlived topicAdLifetime.
+func (tt *topicTable) expire(now mclock.AbsTime) {
+	for topic, queue := range tt.queues {
+		i := 0
+		for i < len(queue) && now.Sub(queue[i].registered) >= topicAdLifetime {
+			i++
+		}
+		tt.count -= i
+		if i == len(queue) {
+			delete(tt.queues, topic)
+		} else if i > 0 {
+			tt.queues[topic] = append(queue[:0], queue[i:]...)
+		}
+	}
+}
+
+// waitTime returns how long a node has to wait until an ad for it can be placed.
+func (tt *topicTable) waitTime(topic TopicID, id enode.ID, now mclock.AbsTime) time.Duration {
+	queue := tt.queues[topic]
+	for _, ad := range queue {
+		if ad.node.ID() == id {
+			return 0 // Renewal of an existing ad.
+		}
+	}
+	var oldest mclock.AbsTime
+	switch {
+	case len(queue) >= tt.queueLimit:
+		oldest = queue[0].registered
+	case tt.count >= tt.tableLimit:
+		oldest = now
+		for _, q := range tt.queues {
+			oldest = min(oldest, q[0].registered)
+		}
+	default:
+		return 0
+	}
+	return max(topicAdLifetime-now.Sub(oldest), 0)
+}
+
+// register places an ad. The caller must ensure there is space for it.
+func (tt *topicTable) register(topic TopicID, n *enode.Node, now mclock.AbsTime) {
+	queue := tt.queues[topic]
+	for i, ad := range queue {
+		if ad.node.ID() == n.ID() {
+			queue = append(queue[:i], queue[i+1:]...)
+			tt.count--
+			break
+		}
+	}
+	tt.queues[topic] = append(queue, &topicAd{node: n, registered: now})
+	tt.count++
+}
+
+// nodes returns the most recently registered nodes of a topic.
+func (tt *topicTable) nodes(topic TopicID, limit int) []*enode.Node {
+	queue := tt.queues[topic]
+	nodes := make([]*enode.Node, 0, min(len(queue), limit))
+	for i := len(queue) - 1; i >= 0 && len(nodes) < limit; i-- {
+		nodes = append(nodes, queue[i].node)
+	}
+	return nodes
+}
+
+// topicTicket is the content of a TICKET. Tickets are opaque to the advertiser
+// and authenticated by the registrar, which allows the registrar to keep no
+// state about waiting nodes.
+type topicTicket struct {
+	ID     enode.ID
+	IP     []byte
+	Topic  TopicID
+	Issued uint64 // mclock.AbsTime
+	Wait   uint64 // time.Duration
+}
+
+// topicSystem implements the registrar side of topic advertisement. It is
+// only accessed by the dispatch loop.
+type topicSystem struct {
+	transport *UDPv5
+	table     *topicTable
+	ticketKey []byte
+}
+
+func newTopicSystem(transport *UDPv5) *topicSystem {
+	key := make([]byte, 32)
+	crand.Read(key)
+	return &topicSystem{transport: transport, table: newTopicTable(), ticketKey: key}
+}
+
+// encodeTicket creates a ticket with a MAC.
+func (ts *topicSystem) encodeTicket(ticket *topicTicket) []byte {
+	enc, _ := rlp.EncodeToBytes(ticket)
+	mac := hmac.New(sha256.New, ts.ticketKey)
+	mac.Write(enc)
+	return mac.Sum(enc)
+}
+
+// decodeTicket verifies the MAC and decodes a ticket.
+func (ts *topicSystem) decodeTicket(enc []byte) (*topicTicket, error) {
+	if len(enc) < sha256.Size {
+		return nil, errInvalidTicket
+	}
+	content, sum := enc[:len(enc)-sha256.Size], enc[len(enc)-sha256.Size:]
+	mac := hmac.New(sha256.New, ts.ticketKey)
+	mac.Write(content)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, errInvalidTicket
+	}
+	var ticket topicTicket
+	if err := rlp.DecodeBytes(content, &ticket); err != nil {
+		return nil, errInvalidTicket
+	}
+	return &ticket, nil
+}
+
+// checkTicket verifies that a ticket was issued to the sender of a
+// registration, and that its waiting time has passed.
+func (ts *topicSystem) checkTicket(enc []byte, topic TopicID, fromID enode.ID, fromAddr netip.AddrPort, now mclock.AbsTime) error {
+	ticket, err := ts.decodeTicket(enc)
+	if err != nil {
+		return err
+	}
+	ip := fromAddr.Addr().AsSlice()
+	if ticket.ID != fromID || ticket.Topic != topic || string(ticket.IP) != string(ip) {
+		return errInvalidTicket
+	}
+	due := mclock.AbsTime(ticket.Issued).Add(time.Duration(ticket.Wait))
+	if now < due || now > due.Add(topicRegWindow) {
+		return errTicketTiming
+	}
+	return nil
+}
+
+// handleRegtopic processes a registration attempt.
+func (ts *topicSystem) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr netip.AddrPort) {
+	t := ts.transport
+	if p.ENR == nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", "missing record")
+		return
+	}
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err == nil && (n.ID() != fromID || n.IPAddr() != fromAddr.Addr() || n.UDP() == 0) {
+		err = errors.New("record does not match sender")
+	}
+	if err == nil && len(p.Ticket) > 0 {
+		err = ts.checkTicket(p.Ticket, p.Topic, fromID, fromAddr, t.clock.Now())
+	}
+	if err != nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+
+	now := t.clock.Now()
+	ts.table.expire(now)
+	resp := &v5wire.Ticket{ReqID: p.ReqID}
+	if wait := ts.table.waitTime(p.Topic, fromID, now); wait == 0 {
+		ts.table.register(p.Topic, n, now)
+	} else {
+		resp.WaitTime = uint32(wait / time.Millisecond)
+		resp.Ticket = ts.encodeTicket(&topicTicket{
+			ID:     fromID,
+			IP:     fromAddr.Addr().AsSlice(),
+			Topic:  p.Topic,
+			Issued: uint64(now),
+			Wait:   uint64(wait),
+		})
+	}
+	t.sendResponse(fromID, fromAddr, resp)
+}
+
+// handleTopicQuery returns the nodes registered under a topic.
+func (ts *topicSystem) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr netip.AddrPort) {
+	t := ts.transport
+	ts.table.expire(t.clock.Now())
+
+	var nodes []*enode.Node
+	for _, n := range ts.table.nodes(p.Topic, findnodeResultLimit) {
+		if n.ID() != fromID && netutil.CheckRelayAddr(fromAddr.Addr(), n.IPAddr()) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
+
+// RegisterTopic advertises the local node under the given topic. Ads are placed
+// on the nodes closest to the topic ID and renewed until ctx is canceled.
+func (t *UDPv5) RegisterTopic(ctx context.Context, topic TopicID) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(t.closeCtx, cancel)
+	defer stop()
+
+	var (
+		wg     sync.WaitGroup
+		active = make(map[enode.ID]bool)
+		done   = make(chan enode.ID)
+		lookup = time.NewTimer(0)
+	)
+	defer lookup.Stop()
+	for {
+		select {
+		case <-lookup.C:
+			for _, n := range t.newLookup(ctx, enode.ID(topic)).run() {
+				if len(active) >= topicRegistrars {
+					break
+				}
+				if !active[n.ID()] {
+					active[n.ID()] = true
+					wg.Add(1)
+					n := n
+					go func() {
+						defer wg.Done()
+						t.registerTopicAt(ctx, n, topic)
+						select {
+						case done <- n.ID():
+						case <-ctx.Done():
+						}
+					}()
+				}
+			}
+			lookup.Reset(topicSearchRefresh)
+		case id := <-done:
+			delete(active, id)
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+	}
+}
+
+// registerTopicAt keeps the local node registered with a single registrar. It
+// returns when the registrar fails to respond or ctx is canceled.
+func (t *UDPv5) registerTopicAt(ctx context.Context, n *enode.Node, topic TopicID) {
+	var ticket []byte
+	for {
+		newTicket, wait, err := t.regtopic(n, topic, ticket)
+		if err != nil {
+			t.log.Debug("Topic registration failed", "id", n.ID(), "topic", topic, "err", err)
+			return
+		}
+		if wait == 0 {
+			t.log.Debug("Registered topic", "id", n.ID(), "topic", topic)
+			ticket, wait = nil, topicAdLifetime-respTimeoutV5
+		} else if wait > topicMaxWait {
+			t.log.Debug("Topic registration waiting time too long", "id", n.ID(), "topic", topic, "wait", wait)
+			return
+		} else {
+			ticket = newTicket
+		}
+		timer := t.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// regtopic sends REGTOPIC to a node. It returns the ticket and waiting time
+// for the next attempt, or zero if the registration was accepted.
+func (t *UDPv5) regtopic(n *enode.Node, topic TopicID, ticket []byte) ([]byte, time.Duration, error) {
+	req := &v5wire.Regtopic{Topic: topic, ENR: t.localNode.Node().Record(), Ticket: ticket}
+	resp := t.callToNode(n, v5wire.TicketMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case respMsg := <-resp.ch:
+		p := respMsg.(*v5wire.Ticket)
+		return p.Ticket, time.Duration(p.WaitTime) * time.Millisecond, nil
+	case err := <-resp.err:
+		return nil, 0, err
+	}
+}
+
+// topicQuery sends TOPICQUERY to a node and waits for the responses.
+func (t *UDPv5) topicQuery(n *enode.Node, topic TopicID) ([]*enode.Node, error) {
+	resp := t.callToNode(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic})
+	return t.waitForNodes(resp, nil)
+}
+
+// TopicNodes returns an iterator that finds nodes registered under the given
+// topic. The search queries the nodes closest to the topic ID, and is repeated
+// periodically to find new registrations.
+func (t *UDPv5) TopicNodes(topic TopicID) enode.Iterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	return &topicIterator{
+		t:      t,
+		topic:  topic,
+		ctx:    ctx,
+		cancel: cancel,
+		seen:   make(map[enode.ID]bool),
+	}
+}
+
+// topicIterator runs lookups towards a topic ID and queries the nodes found by
+// them for topic registrations.
+type topicIterator struct {
+	t      *UDPv5
+	topic  TopicID
+	ctx    context.Context
+	cancel func()
+	lookup *lookup
+	seen   map[enode.ID]bool
+	buffer []*enode.Node
+	last   mclock.AbsTime
+}
+
+// Node returns the current node.
+func (it *topicIterator) Node() *enode.Node {
+	if len(it.buffer) == 0 {
+		return nil
+	}
+	return it.buffer[0]
+}
+
+// Next moves to the next node.
+func (it *topicIterator) Next() bool {
+	if len(it.buffer) > 0 {
+		it.buffer = it.buffer[1:]
+	}
+	for len(it.buffer) == 0 {
+		if it.ctx.Err() != nil {
+			it.lookup = nil
+			it.buffer = nil
+			return false
+		}
+		if it.lookup == nil {
+			it.waitRefresh()
+			it.lookup = it.t.newLookup(it.ctx, enode.ID(it.topic))
+			continue
+		}
+		if !it.lookup.advance() {
+			it.lookup = nil
+			continue
+		}
+		for _, n := range it.lookup.replyBuffer {
+			it.query(n)
+		}
+	}
+	return true
+}
+
+// waitRefresh delays the next lookup.
+func (it *topicIterator) waitRefresh() {
+	if it.last != 0 {
+		timer := it.t.clock.NewTimer(topicSearchRefresh - it.t.clock.Now().Sub(it.last))
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+		case <-it.ctx.Done():
+		}
+	}
+	it.last = it.t.clock.Now()
+}
+
+// query adds the registrations known to n to the buffer.
+func (it *topicIterator) query(n *enode.Node) {
+	nodes, err := it.t.topicQuery(n, it.topic)
+	if err != nil {
+		it.t.log.Trace("TOPICQUERY failed", "id", n.ID(), "err", err)
+	}
+	for _, rn := range nodes {
+		if !it.seen[rn.ID()] && rn.ID() != it.t.Self().ID() {
+			it.seen[rn.ID()] = true
+			it.buffer = append(it.buffer, rn)
+		}
+	}
+}
+
+// Close ends the iterator.
+func (it *topicIterator) Close() {
+	it.cancel()
+}
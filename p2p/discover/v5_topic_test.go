@@ -0,0 +1,150 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestTopicTableWaitTime(t *testing.T) {
+	var (
+		tab   = newTopicTable()
+		topic = NewTopic("test")
+		other = NewTopic("other")
+		start = mclock.AbsTime(time.Hour)
+		n1    = nodeAtDistance(enode.ID{}, 200, intIP(1))
+		n2    = nodeAtDistance(enode.ID{}, 201, intIP(2))
+		n3    = nodeAtDistance(enode.ID{}, 202, intIP(3))
+	)
+	tab.queueLimit, tab.tableLimit = 2, 3
+
+	tab.register(topic, n1, start)
+	tab.register(topic, n2, start.Add(time.Minute))
+	if wait := tab.waitTime(topic, n1.ID(), start); wait != 0 {
+		t.Fatalf("renewal has waiting time %v", wait)
+	}
+	if wait := tab.waitTime(topic, n3.ID(), start.Add(5*time.Minute)); wait != topicAdLifetime-5*time.Minute {
+		t.Fatalf("wrong waiting time %v for full queue", wait)
+	}
+	tab.register(other, n3, start.Add(2*time.Minute))
+	if wait := tab.waitTime(NewTopic("third"), n3.ID(), start); wait != topicAdLifetime {
+		t.Fatalf("wrong waiting time %v for full table", wait)
+	}
+
+	// After expiry of the first ad, there is space again.
+	now := start.Add(topicAdLifetime)
+	tab.expire(now)
+	if wait := tab.waitTime(topic, n3.ID(), now); wait != 0 {
+		t.Fatalf("wrong waiting time %v after expiry", wait)
+	}
+	if nodes := tab.nodes(topic, 10); len(nodes) != 1 || nodes[0] != n2 {
+		t.Fatalf("wrong nodes after expiry: %v", nodes)
+	}
+	if tab.count != 2 {
+		t.Fatalf("wrong ad count %d", tab.count)
+	}
+}
+
+// This test checks that incoming REGTOPIC and TOPICQUERY calls are handled correctly.
+func TestUDPv5_topicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+	test.udp.topics.table.queueLimit = 1
+
+	var (
+		topic    = NewTopic("test")
+		key2     = newkey()
+		addr2    = netip.MustParseAddrPort("10.0.1.98:30303")
+		node1    = test.getNode(test.remotekey, test.remoteaddr).Node()
+		node2    = test.getNode(key2, addr2).Node()
+		ticket   []byte
+		waitTime time.Duration
+	)
+
+	// The first registration is accepted immediately.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{1}, Topic: topic, ENR: node1.Record()})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr netip.AddrPort, _ v5wire.Nonce) {
+		if p.WaitTime != 0 || len(p.Ticket) != 0 {
+			t.Fatalf("registration not accepted: wait %d, ticket %x", p.WaitTime, p.Ticket)
+		}
+	})
+
+	// The queue is full, so the second node gets a ticket.
+	test.packetInFrom(key2, addr2, &v5wire.Regtopic{ReqID: []byte{2}, Topic: topic, ENR: node2.Record()})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr netip.AddrPort, _ v5wire.Nonce) {
+		if p.WaitTime == 0 || len(p.Ticket) == 0 {
+			t.Fatalf("expected ticket, got wait %d, ticket %x", p.WaitTime, p.Ticket)
+		}
+		ticket, waitTime = p.Ticket, time.Duration(p.WaitTime)*time.Millisecond
+	})
+	ts, now := test.udp.topics, mclock.System{}.Now()
+	if err := ts.checkTicket(ticket, topic, node2.ID(), addr2, now); err != errTicketTiming {
+		t.Errorf("early ticket use: got %v, want %v", err, errTicketTiming)
+	}
+	if err := ts.checkTicket(ticket, topic, node2.ID(), addr2, now.Add(waitTime+time.Second)); err != nil {
+		t.Errorf("ticket rejected: %v", err)
+	}
+	if err := ts.checkTicket(ticket, topic, node1.ID(), addr2, now.Add(waitTime+time.Second)); err != errInvalidTicket {
+		t.Errorf("ticket of other node: got %v, want %v", err, errInvalidTicket)
+	}
+
+	// The registered node is returned by TOPICQUERY.
+	test.packetInFrom(key2, addr2, &v5wire.TopicQuery{ReqID: []byte{3}, Topic: topic})
+	test.expectNodes([]byte{3}, 1, []*enode.Node{node1})
+	test.packetInFrom(key2, addr2, &v5wire.TopicQuery{ReqID: []byte{4}, Topic: NewTopic("other")})
+	test.expectNodes([]byte{4}, 1, nil)
+}
+
+// This test checks that topic registrations can be found through TopicNodes.
+func TestUDPv5_topicSearchE2E(t *testing.T) {
+	t.Parallel()
+
+	var (
+		topic     = NewTopic("test")
+		registrar = startLocalhostV5(t, Config{})
+		bn        = []*enode.Node{registrar.Self()}
+		advert    = startLocalhostV5(t, Config{Bootnodes: bn})
+		searcher  = startLocalhostV5(t, Config{Bootnodes: bn})
+	)
+	defer registrar.Close()
+	defer advert.Close()
+	defer searcher.Close()
+
+	_, wait, err := advert.regtopic(registrar.Self(), topic, nil)
+	if err != nil {
+		t.Fatal("registration failed:", err)
+	}
+	if wait != 0 {
+		t.Fatal("registration not accepted, waiting time", wait)
+	}
+
+	it := searcher.TopicNodes(topic)
+	defer it.Close()
+	if !it.Next() {
+		t.Fatal("iterator ended")
+	}
+	if it.Node().ID() != advert.Self().ID() {
+		t.Fatalf("wrong node %v found, want %v", it.Node().ID(), advert.Self().ID())
+	}
+}
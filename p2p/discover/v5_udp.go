@@ -78,6 +78,12 @@ type UDPv5 struct {
 	// talkreq handler registry
 	talk *talkSystem
 
+	// topic table and ticket issuer
+	topics *topicSystem
+
+	// relays for hole punching
+	relay *relaySystem
+
 	// channels into dispatch
 	packetInCh    chan ReadPacket
 	readNextCh    chan struct{}
@@ -176,6 +182,8 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		cancelCloseCtx: cancelCloseCtx,
 	}
 	t.talk = newTalkSystem(t)
+	t.topics = newTopicSystem(t)
+	t.relay = newRelaySystem(t)
 	tab, err := newTable(t, t.db, cfg)
 	if err != nil {
 		return nil, err
@@ -202,8 +210,10 @@ func (t *UDPv5) Close() {
 
 // Ping sends a ping message to the given node.
 func (t *UDPv5) Ping(n *enode.Node) error {
-	_, err := t.ping(n)
-	return err
+	return t.withHolePunch(n, func() error {
+		_, err := t.ping(n)
+		return err
+	})
 }
 
 // Resolve searches for a specific node with the given ID and tries to get the most recent
@@ -254,7 +264,15 @@ func (t *UDPv5) RegisterTalkHandler(protocol string, handler TalkRequestHandler)
 }
 
 // TalkRequest sends a talk request to a node and waits for a response.
-func (t *UDPv5) TalkRequest(n *enode.Node, protocol string, request []byte) ([]byte, error) {
+func (t *UDPv5) TalkRequest(n *enode.Node, protocol string, request []byte) (resp []byte, err error) {
+	err = t.withHolePunch(n, func() error {
+		resp, err = t.talkRequest(n, protocol, request)
+		return err
+	})
+	return resp, err
+}
+
+func (t *UDPv5) talkRequest(n *enode.Node, protocol string, request []byte) ([]byte, error) {
 	req := &v5wire.TalkRequest{Protocol: protocol, Message: request}
 	resp := t.callToNode(n, v5wire.TalkResponseMsg, req)
 	defer t.callDone(resp)
@@ -357,6 +375,18 @@ func lookupDistances(target, dest enode.ID) (dists []uint) {
 	return dists
 }
 
+// withHolePunch runs call. If the node doesn't respond and the node which sent its
+// record is known, a hole punching attempt is made through that node and the call is
+// retried. It is only used for calls explicitly requested by the user of UDPv5, so
+// that lookups and table maintenance can't trigger relay requests in bulk.
+func (t *UDPv5) withHolePunch(n *enode.Node, call func() error) error {
+	err := call()
+	if errors.Is(err, errTimeout) && t.relay.punch(n) == nil {
+		err = call()
+	}
+	return err
+}
+
 // ping calls PING on a node and waits for a PONG response.
 func (t *UDPv5) ping(n *enode.Node) (uint64, error) {
 	req := &v5wire.Ping{ENRSeq: t.localNode.Node().Seq()}
@@ -373,7 +403,11 @@ func (t *UDPv5) ping(n *enode.Node) (uint64, error) {
 
 // RequestENR requests n's record.
 func (t *UDPv5) RequestENR(n *enode.Node) (*enode.Node, error) {
-	nodes, err := t.findnode(n, []uint{0})
+	var nodes []*enode.Node
+	err := t.withHolePunch(n, func() (err error) {
+		nodes, err = t.findnode(n, []uint{0})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -409,6 +443,9 @@ func (t *UDPv5) waitForNodes(c *callV5, distances []uint) ([]*enode.Node, error)
 					continue
 				}
 				nodes = append(nodes, node)
+				if c.node != nil {
+					t.relay.add(node, c.node)
+				}
 			}
 			if total == -1 {
 				total = min(int(response.RespCount), totalNodesResponseLimit)
@@ -769,6 +806,18 @@ func (t *UDPv5) handle(p v5wire.Packet, fromID enode.ID, fromAddr netip.AddrPort
 		t.talk.handleRequest(fromID, fromAddr, p)
 	case *v5wire.TalkResponse:
 		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regtopic:
+		t.topics.handleRegtopic(p, fromID, fromAddr)
+	case *v5wire.Ticket:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.TopicQuery:
+		t.topics.handleTopicQuery(p, fromID, fromAddr)
+	case *v5wire.RelayInit:
+		t.relay.handleRelayInit(p, fromID, fromAddr)
+	case *v5wire.RelayNotify:
+		t.relay.handleRelayNotify(p, fromID, fromAddr)
+	case *v5wire.RelayResponse:
+		t.handleCallResponse(fromID, fromAddr, p)
 	}
 }
 
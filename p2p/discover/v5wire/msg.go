@@ -47,8 +47,12 @@ const (
 	NodesMsg
 	TalkRequestMsg
 	TalkResponseMsg
-	RequestTicketMsg
+	RegtopicMsg
 	TicketMsg
+	TopicQueryMsg
+	RelayInitMsg
+	RelayNotifyMsg
+	RelayResponseMsg
 
 	UnknownPacket   = byte(255) // any non-decryptable packet
 	WhoareyouPacket = byte(254) // the WHOAREYOU packet
@@ -118,6 +122,55 @@ type (
 		ReqID   []byte
 		Message []byte
 	}
+
+	// REGTOPIC requests that the recipient advertises the sender under a topic.
+	Regtopic struct {
+		ReqID  []byte
+		Topic  [32]byte
+		ENR    *enr.Record // record of the sender
+		Ticket []byte      // ticket from a previous attempt, or empty
+	}
+
+	// TICKET is the reply to REGTOPIC. A zero WaitTime means the registration was
+	// accepted. Otherwise, the registration must be retried with the ticket after
+	// the given time.
+	Ticket struct {
+		ReqID    []byte
+		Ticket   []byte
+		WaitTime uint32 // in milliseconds
+	}
+
+	// TOPICQUERY requests nodes registered under a topic. The reply is NODES.
+	TopicQuery struct {
+		ReqID []byte
+		Topic [32]byte
+	}
+
+	// RELAYINIT asks the recipient to relay a hole punching request to the target
+	// node. The reply is RELAYRESP.
+	RelayInit struct {
+		ReqID  []byte
+		Target [32]byte    // ID of the node to be contacted
+		ENR    *enr.Record // record of the sender
+	}
+
+	// RELAYNOTIFY is sent by the relay to the target of a RELAYINIT request. The
+	// recipient should send a packet to the initiator, which opens its NAT for
+	// packets from the initiator. The reply is RELAYRESP.
+	RelayNotify struct {
+		ReqID []byte
+		ENR   *enr.Record // record of the initiator
+		IP    net.IP      // These fields contain the UDP envelope address of the
+		Port  uint16      // initiator as seen by the relay.
+	}
+
+	// RELAYRESP is the reply to RELAYINIT and RELAYNOTIFY.
+	RelayResponse struct {
+		ReqID   []byte
+		Success bool
+		IP      net.IP `rlp:"optional"` // In replies to RELAYINIT, these fields contain
+		Port    uint16 `rlp:"optional"` // the envelope address of the initiator.
+	}
 )
 
 // DecodeMessage decodes the message body of a packet.
@@ -136,6 +189,18 @@ func DecodeMessage(ptype byte, body []byte) (Packet, error) {
 		dec = new(TalkRequest)
 	case TalkResponseMsg:
 		dec = new(TalkResponse)
+	case RegtopicMsg:
+		dec = new(Regtopic)
+	case TicketMsg:
+		dec = new(Ticket)
+	case TopicQueryMsg:
+		dec = new(TopicQuery)
+	case RelayInitMsg:
+		dec = new(RelayInit)
+	case RelayNotifyMsg:
+		dec = new(RelayNotify)
+	case RelayResponseMsg:
+		dec = new(RelayResponse)
 	default:
 		return nil, fmt.Errorf("unknown packet type %d", ptype)
 	}
@@ -227,3 +292,57 @@ func (p *TalkResponse) SetRequestID(id []byte) { p.ReqID = id }
 func (p *TalkResponse) AppendLogInfo(ctx []interface{}) []interface{} {
 	return append(ctx, "req", hexutil.Bytes(p.ReqID), "len", len(p.Message))
 }
+
+func (*Regtopic) Name() string             { return "REGTOPIC/v5" }
+func (*Regtopic) Kind() byte               { return RegtopicMsg }
+func (p *Regtopic) RequestID() []byte      { return p.ReqID }
+func (p *Regtopic) SetRequestID(id []byte) { p.ReqID = id }
+
+func (p *Regtopic) AppendLogInfo(ctx []interface{}) []interface{} {
+	return append(ctx, "req", hexutil.Bytes(p.ReqID), "topic", hexutil.Bytes(p.Topic[:]), "ticket", len(p.Ticket) > 0)
+}
+
+func (*Ticket) Name() string             { return "TICKET/v5" }
+func (*Ticket) Kind() byte               { return TicketMsg }
+func (p *Ticket) RequestID() []byte      { return p.ReqID }
+func (p *Ticket) SetRequestID(id []byte) { p.ReqID = id }
+
+func (p *Ticket) AppendLogInfo(ctx []interface{}) []interface{} {
+	return append(ctx, "req", hexutil.Bytes(p.ReqID), "wait", p.WaitTime)
+}
+
+func (*TopicQuery) Name() string             { return "TOPICQUERY/v5" }
+func (*TopicQuery) Kind() byte               { return TopicQueryMsg }
+func (p *TopicQuery) RequestID() []byte      { return p.ReqID }
+func (p *TopicQuery) SetRequestID(id []byte) { p.ReqID = id }
+
+func (p *TopicQuery) AppendLogInfo(ctx []interface{}) []interface{} {
+	return append(ctx, "req", hexutil.Bytes(p.ReqID), "topic", hexutil.Bytes(p.Topic[:]))
+}
+
+func (*RelayInit) Name() string             { return "RELAYINIT/v5" }
+func (*RelayInit) Kind() byte               { return RelayInitMsg }
+func (p *RelayInit) RequestID() []byte      { return p.ReqID }
+func (p *RelayInit) SetRequestID(id []byte) { p.ReqID = id }
+
+func (p *RelayInit) AppendLogInfo(ctx []interface{}) []interface{} {
+	return append(ctx, "req", hexutil.Bytes(p.ReqID), "target", hexutil.Bytes(p.Target[:]))
+}
+
+func (*RelayNotify) Name() string             { return "RELAYNOTIFY/v5" }
+func (*RelayNotify) Kind() byte               { return RelayNotifyMsg }
+func (p *RelayNotify) RequestID() []byte      { return p.ReqID }
+func (p *RelayNotify) SetRequestID(id []byte) { p.ReqID = id }
+
+func (p *RelayNotify) AppendLogInfo(ctx []interface{}) []interface{} {
+	return append(ctx, "req", hexutil.Bytes(p.ReqID), "ip", p.IP, "port", p.Port)
+}
+
+func (*RelayResponse) Name() string             { return "RELAYRESP/v5" }
+func (*RelayResponse) Kind() byte               { return RelayResponseMsg }
+func (p *RelayResponse) RequestID() []byte      { return p.ReqID }
+func (p *RelayResponse) SetRequestID(id []byte) { p.ReqID = id }
+
+func (p *RelayResponse) AppendLogInfo(ctx []interface{}) []interface{} {
+	return append(ctx, "req", hexutil.Bytes(p.ReqID), "success", p.Success, "ip", p.IP, "port", p.Port)
+}
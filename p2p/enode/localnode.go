@@ -64,6 +64,8 @@ type lnEndpoint struct {
 	track                *netutil.IPTracker
 	staticIP, fallbackIP net.IP
 	fallbackUDP          uint16 // port
+	natIP                net.IP // external IP reported by the NAT device
+	natUDP               uint16 // UDP port mapped on the NAT device
 }
 
 // NewLocalNode creates a local node.
@@ -213,6 +215,40 @@ func (ln *LocalNode) SetFallbackUDP(port int) {
 	ln.updateEndpoints()
 }
 
+// SetNATEndpoint sets the external endpoint reported by a port mapping protocol,
+// i.e. the external IP of the NAT device and the UDP port mapped by it, which is
+// zero if there is no mapping. A nil IP removes the endpoint.
+//
+// The endpoint is used until enough nodes have reported the local endpoint. If the
+// prediction confirms the external IP, the mapped port is kept, because it accepts
+// inbound packets from any node, unlike the port observed by other nodes.
+func (ln *LocalNode) SetNATEndpoint(ip net.IP, udp int) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	for _, e := range []*lnEndpoint{&ln.endpoint4, &ln.endpoint6} {
+		e.natIP, e.natUDP = nil, 0
+	}
+	if ip != nil {
+		e := ln.endpointForIP(netutil.IPToAddr(ip))
+		e.natIP, e.natUDP = ip, uint16(udp)
+	}
+	ln.updateEndpoints()
+}
+
+// SetEndpointPredictionThreshold sets the number of nodes which must agree on the
+// local UDP endpoint before it is used in the record. The default is suitable for
+// public networks. Small networks, where only a few nodes are available to report
+// the observed address, may need a lower threshold to use endpoint prediction.
+func (ln *LocalNode) SetEndpointPredictionThreshold(n int) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	ln.endpoint4.track.SetMinStatements(n)
+	ln.endpoint6.track.SetMinStatements(n)
+	ln.updateEndpoints()
+}
+
 // UDPEndpointStatement should be called whenever a statement about the local node's
 // UDP endpoint is received. It feeds the local endpoint predictor.
 func (ln *LocalNode) UDPEndpointStatement(fromaddr, endpoint netip.AddrPort) {
@@ -266,11 +302,19 @@ func (e *lnEndpoint) get() (newIP net.IP, newPort uint16) {
 	if e.fallbackIP != nil {
 		newIP = e.fallbackIP
 	}
+	if e.natIP != nil {
+		newIP = e.natIP
+		if e.natUDP != 0 {
+			newPort = e.natUDP
+		}
+	}
 	if e.staticIP != nil {
 		newIP = e.staticIP
 	} else if ap := e.track.PredictEndpoint(); ap.IsValid() {
 		newIP = ap.Addr().AsSlice()
-		newPort = ap.Port()
+		if e.natUDP == 0 || !e.natIP.Equal(newIP) {
+			newPort = ap.Port()
+		}
 	}
 	return newIP, newPort
 }
@@ -135,3 +135,53 @@ func TestLocalNodeEndpoint(t *testing.T) {
 	assert.Equal(t, fallback.Port, ln.Node().UDP())
 	assert.Equal(t, initialSeq+3, ln.Node().Seq())
 }
+
+// This test checks that lowering the prediction threshold applies
+// statements which were already received.
+func TestLocalNodeEndpointThreshold(t *testing.T) {
+	var (
+		from     = netip.MustParseAddrPort("1.2.3.4:30303")
+		endpoint = netip.MustParseAddrPort("5.6.7.8:40404")
+	)
+	ln, db := newLocalNodeForTesting()
+	defer db.Close()
+
+	ln.UDPEndpointStatement(from, endpoint)
+	assert.Equal(t, netip.Addr{}, ln.Node().IPAddr())
+
+	ln.SetEndpointPredictionThreshold(1)
+	assert.Equal(t, endpoint.Addr(), ln.Node().IPAddr())
+	assert.Equal(t, int(endpoint.Port()), ln.Node().UDP())
+}
+
+// This test checks how the endpoint reported by a port mapping protocol is
+// combined with endpoint prediction.
+func TestLocalNodeNATEndpoint(t *testing.T) {
+	var (
+		natIP = net.IP{5, 6, 7, 8}
+		from  = netip.MustParseAddrPort("1.2.3.4:30303")
+	)
+	ln, db := newLocalNodeForTesting()
+	defer db.Close()
+	ln.SetFallbackUDP(30303)
+	ln.SetEndpointPredictionThreshold(1)
+
+	// The mapped endpoint is used without prediction.
+	ln.SetNATEndpoint(natIP, 40404)
+	assert.Equal(t, netutil.IPToAddr(natIP), ln.Node().IPAddr())
+	assert.Equal(t, 40404, ln.Node().UDP())
+
+	// A prediction confirming the IP keeps the mapped port.
+	ln.UDPEndpointStatement(from, netip.MustParseAddrPort("5.6.7.8:50505"))
+	assert.Equal(t, netutil.IPToAddr(natIP), ln.Node().IPAddr())
+	assert.Equal(t, 40404, ln.Node().UDP())
+
+	// A different predicted IP overrides the mapping, e.g. behind multiple NATs.
+	ln.UDPEndpointStatement(from, netip.MustParseAddrPort("9.9.9.9:50505"))
+	assert.Equal(t, netip.MustParseAddr("9.9.9.9"), ln.Node().IPAddr())
+	assert.Equal(t, 50505, ln.Node().UDP())
+
+	// Without a mapped port, the predicted one is used.
+	ln.SetNATEndpoint(net.IP{9, 9, 9, 9}, 0)
+	assert.Equal(t, 50505, ln.Node().UDP())
+}
@@ -68,11 +68,12 @@ func TestPythonInterop(t *testing.T) {
 func TestNodeEndpoints(t *testing.T) {
 	id := HexID("00000000000000806ad9b61fa5ae014307ebdc964253adcd9f2c0a392aa11abc")
 	type endpointTest struct {
-		name    string
-		node    *Node
-		wantIP  netip.Addr
-		wantUDP int
-		wantTCP int
+		name     string
+		node     *Node
+		wantIP   netip.Addr
+		wantUDP  int
+		wantTCP  int
+		wantQUIC int
 	}
 	tests := []endpointTest{
 		{
@@ -209,6 +210,30 @@ func TestNodeEndpoints(t *testing.T) {
 			wantIP:  netip.MustParseAddr("192.168.2.2"),
 			wantUDP: 30304,
 		},
+		{
+			name: "ipv4-quic",
+			node: func() *Node {
+				var r enr.Record
+				r.Set(enr.IPv4Addr(netip.MustParseAddr("99.22.33.1")))
+				r.Set(enr.QUIC(9001))
+				r.Set(enr.QUIC6(9002))
+				return SignNull(&r, id)
+			}(),
+			wantIP:   netip.MustParseAddr("99.22.33.1"),
+			wantQUIC: 9001,
+		},
+		{
+			name: "ipv6-quic",
+			node: func() *Node {
+				var r enr.Record
+				r.Set(enr.IPv6Addr(netip.MustParseAddr("2001::ff00:0042:8329")))
+				r.Set(enr.QUIC(9001))
+				r.Set(enr.QUIC6(9002))
+				return SignNull(&r, id)
+			}(),
+			wantIP:   netip.MustParseAddr("2001::ff00:0042:8329"),
+			wantQUIC: 9002,
+		},
 	}
 
 	for _, test := range tests {
@@ -222,6 +247,9 @@ func TestNodeEndpoints(t *testing.T) {
 			if test.wantTCP != test.node.TCP() {
 				t.Errorf("node has wrong TCP port %d, want %d", test.node.TCP(), test.wantTCP)
 			}
+			if quic, _ := test.node.QUICEndpoint(); test.wantQUIC != int(quic.Port()) {
+				t.Errorf("node has wrong QUIC port %d, want %d", quic.Port(), test.wantQUIC)
+			}
 		})
 	}
 }
@@ -21,6 +21,7 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"net/netip"
 	"os"
 	"sync"
@@ -41,6 +42,7 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbScorePrefix  = "score:" // Identifier to prefix node reputation scores with
 	dbDiscoverRoot = "v4"
 	dbDiscv5Root   = "v5"
 
@@ -57,9 +59,10 @@ const (
 )
 
 const (
-	dbNodeExpiration = 24 * time.Hour // Time after which an unseen node should be dropped.
-	dbCleanupCycle   = time.Hour      // Time period for running the expiration task.
-	dbVersion        = 9
+	dbNodeExpiration  = 24 * time.Hour     // Time after which an unseen node should be dropped.
+	dbScoreExpiration = 7 * 24 * time.Hour // Time after which an unchanged score should be dropped.
+	dbCleanupCycle    = time.Hour          // Time period for running the expiration task.
+	dbVersion         = 9
 )
 
 var (
@@ -315,6 +318,7 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireScores()
 		case <-db.quit:
 			return
 		}
@@ -360,6 +364,74 @@ func (db *DB) expireNodes() {
 	}
 }
 
+// scoreKey returns the database key for the reputation score of a node.
+func scoreKey(id ID) []byte {
+	return append([]byte(dbScorePrefix), id[:]...)
+}
+
+// NodeScore retrieves the reputation score of a node, along with the time it was
+// last updated. The score is zero if none was stored.
+func (db *DB) NodeScore(id ID) (float64, time.Time) {
+	blob, err := db.lvl.Get(scoreKey(id), nil)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	score, updated, ok := decodeScore(blob)
+	if !ok {
+		return 0, time.Time{}
+	}
+	return score, updated
+}
+
+// UpdateNodeScore stores the reputation score of a node.
+func (db *DB) UpdateNodeScore(id ID, score float64, updated time.Time) error {
+	blob := make([]byte, 16)
+	binary.BigEndian.PutUint64(blob, math.Float64bits(score))
+	binary.BigEndian.PutUint64(blob[8:], uint64(updated.UnixNano()))
+	return db.lvl.Put(scoreKey(id), blob, nil)
+}
+
+// DeleteNodeScore removes the reputation score of a node.
+func (db *DB) DeleteNodeScore(id ID) error {
+	return db.lvl.Delete(scoreKey(id), nil)
+}
+
+// NodeScores calls fn for the reputation score of every node in the database.
+func (db *DB) NodeScores(fn func(id ID, score float64, updated time.Time)) {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbScorePrefix)), nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()[len(dbScorePrefix):]
+		if len(key) != len(ID{}) {
+			continue
+		}
+		if score, updated, ok := decodeScore(it.Value()); ok {
+			fn(ID(key), score, updated)
+		}
+	}
+}
+
+func decodeScore(blob []byte) (float64, time.Time, bool) {
+	if len(blob) != 16 {
+		return 0, time.Time{}, false
+	}
+	score := math.Float64frombits(binary.BigEndian.Uint64(blob))
+	updated := time.Unix(0, int64(binary.BigEndian.Uint64(blob[8:])))
+	return score, updated, true
+}
+
+// expireScores deletes the reputation scores which have not been updated for
+// some time, and have decayed to insignificance.
+func (db *DB) expireScores() {
+	threshold := time.Now().Add(-dbScoreExpiration)
+	db.NodeScores(func(id ID, score float64, updated time.Time) {
+		if updated.Before(threshold) {
+			db.DeleteNodeScore(id)
+		}
+	})
+}
+
 // LastPingReceived retrieves the time of the last ping packet received from
 // a remote node.
 func (db *DB) LastPingReceived(id ID, ip netip.Addr) time.Time {
@@ -470,3 +470,33 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+func TestDBNodeScore(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		fresh   = ID{1}
+		expired = ID{2}
+		now     = time.Now().Truncate(time.Nanosecond)
+	)
+	if score, updated := db.NodeScore(fresh); score != 0 || !updated.IsZero() {
+		t.Fatalf("unexpected score of unknown node: %v %v", score, updated)
+	}
+	if err := db.UpdateNodeScore(fresh, -12.5, now); err != nil {
+		t.Fatalf("failed to update score: %v", err)
+	}
+	if err := db.UpdateNodeScore(expired, 3, now.Add(-dbScoreExpiration-time.Hour)); err != nil {
+		t.Fatalf("failed to update score: %v", err)
+	}
+	if score, updated := db.NodeScore(fresh); score != -12.5 || !updated.Equal(now) {
+		t.Fatalf("score mismatch: have %v %v, want %v %v", score, updated, -12.5, now)
+	}
+	db.expireScores()
+
+	var ids []ID
+	db.NodeScores(func(id ID, score float64, updated time.Time) { ids = append(ids, id) })
+	if len(ids) != 1 || ids[0] != fresh {
+		t.Fatalf("wrong scores after expiration: %v", ids)
+	}
+}
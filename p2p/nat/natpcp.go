@@ -0,0 +1,302 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the MAP opcode of the Port Control Protocol (RFC 6887).
+
+const (
+	pcpPort    = 5351
+	pcpVersion = 2
+
+	pcpOpMap       = 1
+	pcpOpResponse  = 0x80
+	pcpHeaderSize  = 24
+	pcpMapDataSize = 36
+	pcpMapSize     = pcpHeaderSize + pcpMapDataSize
+
+	// Requests are retransmitted with exponential backoff, starting at
+	// pcpInitialRetry, until pcpTimeout has passed.
+	pcpInitialRetry = 250 * time.Millisecond
+	pcpTimeout      = 2 * time.Second
+
+	// The external address is learned by mapping the discard port
+	// for a short time, as suggested in RFC 6887 section 11.6.
+	pcpDiscardPort   = 9
+	pcpProbeLifetime = 60 * time.Second
+
+	pcpProtocolTCP   = 6
+	pcpProtocolUDP   = 17
+	pcpResultSuccess = 0
+	pcpMaxResultCode = 13
+)
+
+var pcpResultCodes = [pcpMaxResultCode + 1]string{
+	"SUCCESS",
+	"UNSUPP_VERSION",
+	"NOT_AUTHORIZED",
+	"MALFORMED_REQUEST",
+	"UNSUPP_OPCODE",
+	"UNSUPP_OPTION",
+	"MALFORMED_OPTION",
+	"NETWORK_FAILURE",
+	"NO_RESOURCES",
+	"UNSUPP_PROTOCOL",
+	"USER_EX_QUOTA",
+	"CANNOT_PROVIDE_EXTERNAL",
+	"ADDRESS_MISMATCH",
+	"EXCESSIVE_REMOTE_PEERS",
+}
+
+// pcpResultError is returned when the PCP server rejects a request.
+type pcpResultError uint8
+
+func (e pcpResultError) Error() string {
+	if e <= pcpMaxResultCode {
+		return "PCP error " + pcpResultCodes[e]
+	}
+	return fmt.Sprintf("PCP error %d", uint8(e))
+}
+
+// pcp implements the Port Control Protocol.
+type pcp struct {
+	gw      net.IP
+	port    int
+	timeout time.Duration
+
+	mu     sync.Mutex
+	nonces map[pcpMapKey][12]byte // mapping nonces, reused when refreshing
+}
+
+type pcpMapKey struct {
+	protocol uint8
+	intport  uint16
+}
+
+// pcpMapping is the result of a MAP request.
+type pcpMapping struct {
+	lifetime time.Duration
+	extport  uint16
+	extIP    net.IP
+}
+
+func newPCP(gw net.IP, port int) *pcp {
+	return &pcp{gw: gw, port: port, timeout: pcpTimeout, nonces: make(map[pcpMapKey][12]byte)}
+}
+
+func (n *pcp) String() string {
+	return fmt.Sprintf("PCP(%v)", n.gw)
+}
+
+func (n *pcp) ExternalIP() (net.IP, error) {
+	m, err := n.mapPort(pcpProtocolUDP, pcpDiscardPort, 0, pcpProbeLifetime)
+	if err != nil {
+		return nil, err
+	}
+	return m.extIP, nil
+}
+
+func (n *pcp) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+	if lifetime <= 0 {
+		return 0, errors.New("lifetime must not be <= 0")
+	}
+	proto, err := pcpProtocol(protocol)
+	if err != nil {
+		return 0, err
+	}
+	// Like NAT-PMP, the server may assign a different external port if the
+	// suggested one is unavailable. This is handled by the caller.
+	m, err := n.mapPort(proto, intport, extport, lifetime)
+	if err != nil {
+		return 0, err
+	}
+	return m.extport, nil
+}
+
+func (n *pcp) DeleteMapping(protocol string, extport, intport int) error {
+	proto, err := pcpProtocol(protocol)
+	if err != nil {
+		return err
+	}
+	// A mapping is deleted by requesting it again with a lifetime of zero.
+	_, err = n.mapPort(proto, intport, 0, 0)
+	if err == nil {
+		n.mu.Lock()
+		delete(n.nonces, pcpMapKey{proto, uint16(intport)})
+		n.mu.Unlock()
+	}
+	return err
+}
+
+func pcpProtocol(protocol string) (uint8, error) {
+	switch strings.ToUpper(protocol) {
+	case "TCP":
+		return pcpProtocolTCP, nil
+	case "UDP":
+		return pcpProtocolUDP, nil
+	default:
+		return 0, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+// nonce returns the mapping nonce for the given internal port. The same nonce
+// must be used for all requests concerning a mapping.
+func (n *pcp) nonce(key pcpMapKey) ([12]byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	nonce, ok := n.nonces[key]
+	if !ok {
+		if _, err := rand.Read(nonce[:]); err != nil {
+			return nonce, err
+		}
+		n.nonces[key] = nonce
+	}
+	return nonce, nil
+}
+
+// mapPort sends a MAP request and waits for the response.
+func (n *pcp) mapPort(protocol uint8, intport, extport int, lifetime time.Duration) (*pcpMapping, error) {
+	nonce, err := n.nonce(pcpMapKey{protocol, uint16(intport)})
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: n.gw, Port: n.port})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// The request must contain the address the server sees as the client
+	// address, which is the local address of the connected socket.
+	clientIP := conn.LocalAddr().(*net.UDPAddr).IP
+	req := make([]byte, pcpMapSize)
+	req[0] = pcpVersion
+	req[1] = pcpOpMap
+	binary.BigEndian.PutUint32(req[4:], uint32(lifetime/time.Second))
+	copy(req[8:24], clientIP.To16())
+	copy(req[24:36], nonce[:])
+	req[36] = protocol
+	binary.BigEndian.PutUint16(req[40:], uint16(intport))
+	binary.BigEndian.PutUint16(req[42:], uint16(extport))
+	// The suggested external address is left unspecified, in the address family
+	// of the client as required by RFC 6887.
+	if clientIP.To4() != nil {
+		copy(req[44:60], net.IPv4zero.To16())
+	} else {
+		copy(req[44:60], net.IPv6zero)
+	}
+
+	var (
+		deadline = time.Now().Add(n.timeout)
+		retry    = pcpInitialRetry
+		buf      = make([]byte, 1100)
+	)
+	for time.Now().Before(deadline) {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		if next := time.Now().Add(retry); next.Before(deadline) {
+			conn.SetReadDeadline(next)
+		} else {
+			conn.SetReadDeadline(deadline)
+		}
+		retry *= 2
+		for {
+			nbytes, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break // retransmit
+				}
+				return nil, err
+			}
+			m, err := decodePCPMapResponse(buf[:nbytes], nonce, protocol, intport)
+			if m == nil && err == nil {
+				continue // unrelated packet
+			}
+			return m, err
+		}
+	}
+	return nil, errors.New("PCP request timed out")
+}
+
+// decodePCPMapResponse parses a response to a MAP request. It returns nil, nil if
+// the packet is not a response to the request.
+func decodePCPMapResponse(resp []byte, nonce [12]byte, protocol uint8, intport int) (*pcpMapping, error) {
+	if len(resp) < pcpMapSize || resp[1] != pcpOpResponse|pcpOpMap {
+		return nil, nil
+	}
+	// Check the nonce before the result, so that spoofed error responses can't
+	// abort the request.
+	if [12]byte(resp[24:36]) != nonce || resp[36] != protocol || int(binary.BigEndian.Uint16(resp[40:])) != intport {
+		return nil, nil
+	}
+	if result := resp[3]; result != pcpResultSuccess {
+		return nil, pcpResultError(result)
+	}
+	if resp[0] != pcpVersion {
+		return nil, errors.New("invalid PCP response")
+	}
+	m := &pcpMapping{
+		lifetime: time.Duration(binary.BigEndian.Uint32(resp[4:])) * time.Second,
+		extport:  binary.BigEndian.Uint16(resp[42:]),
+		extIP:    net.IP(resp[44:60]),
+	}
+	if ip4 := m.extIP.To4(); ip4 != nil {
+		m.extIP = ip4
+	} else {
+		m.extIP = append(net.IP(nil), m.extIP...)
+	}
+	return m, nil
+}
+
+func discoverPCP() Interface {
+	// Probe all potential gateways for the external address.
+	gws := potentialGateways()
+	found := make(chan *pcp, len(gws))
+	for i := range gws {
+		gw := gws[i]
+		go func() {
+			c := newPCP(gw, pcpPort)
+			c.timeout = 1 * time.Second
+			if _, err := c.ExternalIP(); err != nil {
+				found <- nil
+			} else {
+				c.timeout = pcpTimeout
+				found <- c
+			}
+		}()
+	}
+	// Return the one that responds first.
+	for range gws {
+		if c := <-found; c != nil {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePCP is a minimal PCP server which handles MAP requests.
+type fakePCP struct {
+	conn  *net.UDPConn
+	extIP net.IP
+
+	mu       sync.Mutex
+	mappings map[uint16][12]byte // external port -> nonce
+	dropNext int                 // number of requests to ignore
+	result   uint8               // result code of responses
+	spoof    bool                // whether to precede responses with a spoofed error
+}
+
+func startFakePCP(t *testing.T) *fakePCP {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakePCP{conn: conn, extIP: net.IP{203, 0, 113, 7}, mappings: make(map[uint16][12]byte)}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakePCP) client() *pcp {
+	addr := s.conn.LocalAddr().(*net.UDPAddr)
+	return newPCP(addr.IP, addr.Port)
+}
+
+func (s *fakePCP) serve() {
+	buf := make([]byte, 1100)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if resp := s.handle(buf[:n], from); resp != nil {
+			s.mu.Lock()
+			spoof := s.spoof
+			s.mu.Unlock()
+			if spoof {
+				// An off-path attacker doesn't know the nonce of the request.
+				fake := append([]byte(nil), resp...)
+				fake[3] = 2 // NOT_AUTHORIZED
+				fake[24] ^= 0xff
+				s.conn.WriteToUDP(fake, from)
+			}
+			s.conn.WriteToUDP(resp, from)
+		}
+	}
+}
+
+func (s *fakePCP) handle(req []byte, from *net.UDPAddr) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dropNext > 0 {
+		s.dropNext--
+		return nil
+	}
+	if len(req) != pcpMapSize || req[0] != pcpVersion || req[1] != pcpOpMap {
+		return nil
+	}
+	resp := make([]byte, pcpMapSize)
+	copy(resp, req)
+	resp[1] = pcpOpResponse | pcpOpMap
+	resp[3] = s.result
+	if !net.IP(req[8:24]).Equal(from.IP) {
+		resp[3] = 12 // ADDRESS_MISMATCH
+	}
+	// The suggested external address must be of the client's address family.
+	if (net.IP(req[8:24]).To4() == nil) != (net.IP(req[44:60]).To4() == nil) {
+		resp[3] = 3 // MALFORMED_REQUEST
+	}
+	if resp[3] != pcpResultSuccess {
+		return resp
+	}
+	var (
+		nonce    = [12]byte(req[24:36])
+		lifetime = binary.BigEndian.Uint32(req[4:])
+		extport  = binary.BigEndian.Uint16(req[42:])
+	)
+	if lifetime == 0 {
+		for port, n := range s.mappings {
+			if n == nonce {
+				delete(s.mappings, port)
+			}
+		}
+		return resp
+	}
+	// Assign the next free port if the suggested one is taken by another client.
+	for {
+		if n, ok := s.mappings[extport]; !ok || n == nonce {
+			break
+		}
+		extport++
+	}
+	s.mappings[extport] = nonce
+	binary.BigEndian.PutUint16(resp[42:], extport)
+	copy(resp[44:60], s.extIP.To16())
+	return resp
+}
+
+func TestPCPExternalIP(t *testing.T) {
+	srv := startFakePCP(t)
+	ip, err := srv.client().ExternalIP()
+	if err != nil {
+		t.Fatal("ExternalIP error:", err)
+	}
+	if !ip.Equal(srv.extIP) {
+		t.Fatalf("wrong external IP %v, want %v", ip, srv.extIP)
+	}
+}
+
+func TestPCPMapping(t *testing.T) {
+	var (
+		srv = startFakePCP(t)
+		c1  = srv.client()
+		c2  = srv.client()
+	)
+	port, err := c1.AddMapping("UDP", 30303, 30303, "test", time.Minute)
+	if err != nil {
+		t.Fatal("AddMapping error:", err)
+	}
+	if port != 30303 {
+		t.Fatalf("wrong external port %d, want 30303", port)
+	}
+	// Refreshing the mapping must keep the port.
+	if port, _ = c1.AddMapping("UDP", 30303, 30303, "test", time.Minute); port != 30303 {
+		t.Fatalf("wrong external port %d after refresh, want 30303", port)
+	}
+	// Another client requesting the same port gets a different one.
+	if port, _ = c2.AddMapping("UDP", 30303, 30303, "test", time.Minute); port != 30304 {
+		t.Fatalf("wrong external port %d for second client, want 30304", port)
+	}
+	// After deleting, the port is available again.
+	if err := c1.DeleteMapping("UDP", 30303, 30303); err != nil {
+		t.Fatal("DeleteMapping error:", err)
+	}
+	c3 := srv.client()
+	if port, _ = c3.AddMapping("UDP", 30303, 30303, "test", time.Minute); port != 30303 {
+		t.Fatalf("wrong external port %d after delete, want 30303", port)
+	}
+}
+
+func TestPCPRetransmit(t *testing.T) {
+	srv := startFakePCP(t)
+	srv.dropNext = 2
+	if _, err := srv.client().AddMapping("TCP", 30303, 30303, "test", time.Minute); err != nil {
+		t.Fatal("AddMapping error:", err)
+	}
+}
+
+func TestPCPError(t *testing.T) {
+	srv := startFakePCP(t)
+	srv.result = 8 // NO_RESOURCES
+	_, err := srv.client().AddMapping("TCP", 30303, 30303, "test", time.Minute)
+	if !errors.Is(err, pcpResultError(8)) {
+		t.Fatalf("wrong error %v", err)
+	}
+	if err.Error() != "PCP error NO_RESOURCES" {
+		t.Fatalf("wrong error message %q", err)
+	}
+}
+
+func TestPCPSpoofedError(t *testing.T) {
+	srv := startFakePCP(t)
+	srv.spoof = true
+	if _, err := srv.client().AddMapping("TCP", 30303, 30303, "test", time.Minute); err != nil {
+		t.Fatal("AddMapping error:", err)
+	}
+}
@@ -59,6 +59,11 @@ func NewIPTracker(window, contactWindow time.Duration, minStatements int) *IPTra
 	}
 }
 
+// SetMinStatements changes the minimum number of statements required for a prediction.
+func (it *IPTracker) SetMinStatements(n int) {
+	it.minStatements = n
+}
+
 // PredictFullConeNAT checks whether the local host is behind full cone NAT. It predicts by
 // checking whether any statement has been received from a node we didn't contact before
 // the statement was made.
@@ -115,6 +115,11 @@ type Peer struct {
 	pingRecv chan struct{}
 	disc     chan DiscReason
 
+	// scores tracks the reputation of the peer, and localReason is the
+	// reason the session ended for, which is valid after run returns.
+	scores      *peerScores
+	localReason DiscReason
+
 	// events receives message send / receive events if set
 	events   *event.Feed
 	testPipe *MsgPipeRW // for testing
@@ -289,6 +294,7 @@ loop:
 	}
 
 	close(p.closed)
+	p.localReason = reason
 	p.rw.close(reason)
 	p.wg.Wait()
 	return remoteRequested, err
@@ -489,6 +495,23 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	}
 }
 
+// AdjustScore adds delta to the reputation score of the peer, which is retained
+// across sessions and restarts. Protocols should penalize misbehaviour with the
+// predefined adjustments like ScoreTimeout or ScoreInvalidData.
+func (p *Peer) AdjustScore(delta float64) {
+	if p.scores != nil {
+		p.scores.adjust(p.ID(), delta)
+	}
+}
+
+// Score returns the current reputation score of the peer.
+func (p *Peer) Score() float64 {
+	if p.scores == nil {
+		return 0
+	}
+	return p.scores.get(p.ID())
+}
+
 // PeerInfo represents a short summary of the information known about a connected
 // peer. Sub-protocol independent fields are contained and initialized here, with
 // protocol specifics delegated to all connected sub-protocols.
@@ -506,6 +529,7 @@ type PeerInfo struct {
 		Static        bool   `json:"static"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Score     float64                `json:"score"`     // Reputation score of the peer
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -522,6 +546,7 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Fullname(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}, len(p.running)),
+		Score:     p.Score(),
 	}
 	if p.Node().Seq() > 0 {
 		info.ENR = p.Node().String()
@@ -0,0 +1,156 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Reputation score adjustments of peer misbehaviour. Protocols apply these through
+// Peer.AdjustScore where the misbehaviour is detected, as the disconnect reason
+// alone does not tell which side was at fault.
+const (
+	ScoreTimeout         = -5.0  // Peer failed to respond in time
+	ScoreUselessResponse = -10.0 // Peer sent an unusable or unrequested response
+	ScoreInvalidData     = -50.0 // Peer sent invalid data, e.g. bad blocks
+)
+
+const (
+	// peerScoreHalfLife is the time it takes for a score to decay to half.
+	peerScoreHalfLife = 6 * time.Hour
+
+	// peerBanScore is the score at or below which nodes are neither dialed nor
+	// accepted, unless they are trusted or static.
+	peerBanScore = -100.0
+
+	// Peers earn a reward for every sessionRewardInterval they stay connected
+	// without misbehaving, up to a maximum per session.
+	sessionRewardInterval = 10 * time.Minute
+	sessionReward         = 1.0
+	maxSessionReward      = 10.0
+)
+
+var errLowScore = errors.New("node reputation score too low")
+
+// PeerScoreInfo is the reputation score of a node.
+type PeerScoreInfo struct {
+	ID      string    `json:"id"`      // Unique node identifier
+	Score   float64   `json:"score"`   // Decayed reputation score
+	Banned  bool      `json:"banned"`  // Whether connections are refused due to the score
+	Updated time.Time `json:"updated"` // Time of the last adjustment
+}
+
+// peerScores tracks the reputation of nodes. Scores are stored in the node
+// database, so they are retained across restarts, and decay exponentially
+// towards zero over time.
+type peerScores struct {
+	db  *enode.DB
+	now func() time.Time
+	log log.Logger
+	mu  sync.Mutex
+}
+
+func newPeerScores(db *enode.DB, logger log.Logger) *peerScores {
+	return &peerScores{db: db, now: time.Now, log: logger}
+}
+
+// decay returns the value of a score stored at the given time.
+func (s *peerScores) decay(score float64, updated time.Time) float64 {
+	elapsed := s.now().Sub(updated)
+	if elapsed <= 0 {
+		return score
+	}
+	return score * math.Exp2(-float64(elapsed)/float64(peerScoreHalfLife))
+}
+
+// get returns the current score of a node.
+func (s *peerScores) get(id enode.ID) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.decay(s.db.NodeScore(id))
+}
+
+// adjust adds delta to the score of a node, returning the new score.
+func (s *peerScores) adjust(id enode.ID, delta float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	score := s.decay(s.db.NodeScore(id)) + delta
+	if err := s.db.UpdateNodeScore(id, score, s.now()); err != nil {
+		s.log.Warn("Failed to store node score", "id", id, "err", err)
+	}
+	s.log.Trace("Adjusted node score", "id", id, "delta", delta, "score", score)
+	return score
+}
+
+// banned reports whether the score of a node is too low to connect to it.
+func (s *peerScores) banned(id enode.ID) bool {
+	return s.get(id) <= peerBanScore
+}
+
+// all returns the current scores of all nodes, lowest first.
+func (s *peerScores) all() []*PeerScoreInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var infos []*PeerScoreInfo
+	s.db.NodeScores(func(id enode.ID, score float64, updated time.Time) {
+		score = s.decay(score, updated)
+		infos = append(infos, &PeerScoreInfo{
+			ID:      id.String(),
+			Score:   score,
+			Banned:  score <= peerBanScore,
+			Updated: updated,
+		})
+	})
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Score < infos[j].Score })
+	return infos
+}
+
+// peerDropped adjusts the score of a disconnected peer based on how the session
+// ended. Peers disconnected due to misbehaviour are penalized, while peers which
+// stayed connected without issues are rewarded.
+func (s *peerScores) peerDropped(p *Peer, err error, requested bool, duration time.Duration) {
+	if delta := sessionScore(p.localReason, err, requested, duration); delta != 0 {
+		s.adjust(p.ID(), delta)
+	}
+}
+
+// sessionScore computes the score adjustment of a session, given the reason and
+// the error it ended with. Only failures detected by the p2p layer itself are
+// penalized here, subprotocols penalize their peers explicitly.
+func sessionScore(reason DiscReason, err error, requested bool, duration time.Duration) float64 {
+	if !requested {
+		var netErr net.Error
+		switch {
+		case reason == DiscNetworkError && errors.As(err, &netErr) && netErr.Timeout():
+			return ScoreTimeout
+		case reason == DiscProtocolError:
+			return ScoreInvalidData
+		}
+	}
+	return min(float64(duration/sessionRewardInterval)*sessionReward, maxSessionReward)
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestSessionScore(t *testing.T) {
+	tests := []struct {
+		reason    DiscReason
+		err       error
+		requested bool
+		duration  time.Duration
+		want      float64
+	}{
+		{DiscNetworkError, timeoutError{}, false, time.Hour, ScoreTimeout},
+		{DiscNetworkError, errors.New("EOF"), false, 25 * time.Minute, 2},
+		{DiscUselessPeer, DiscUselessPeer, false, 24 * time.Hour, maxSessionReward},
+		{DiscUselessPeer, DiscUselessPeer, false, time.Minute, 0},
+		{DiscProtocolError, errProtocolReturned, false, time.Minute, ScoreInvalidData},
+		{DiscProtocolError, errProtocolReturned, true, time.Minute, 0},
+		{DiscSubprotocolError, DiscSubprotocolError, false, time.Minute, 0},
+		{DiscQuitting, DiscQuitting, false, 24 * time.Hour, maxSessionReward},
+	}
+	for i, test := range tests {
+		if have := sessionScore(test.reason, test.err, test.requested, test.duration); have != test.want {
+			t.Errorf("test %d: wrong score %v, want %v", i, have, test.want)
+		}
+	}
+}
+
+func TestPeerScoresDecay(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		now    = time.Now()
+		scores = newPeerScores(db, testlog.Logger(t, log.LvlTrace))
+		id     = enode.ID{1}
+	)
+	scores.now = func() time.Time { return now }
+
+	if score := scores.adjust(id, 2*peerBanScore); score != 2*peerBanScore {
+		t.Fatalf("wrong score after adjustment: %v", score)
+	}
+	if !scores.banned(id) {
+		t.Fatal("node not banned")
+	}
+	now = now.Add(peerScoreHalfLife)
+	if score := scores.get(id); math.Abs(score-peerBanScore) > 1e-9 {
+		t.Fatalf("wrong score after one half-life: %v", score)
+	}
+	now = now.Add(time.Minute)
+	if scores.banned(id) {
+		t.Fatal("node still banned after decay")
+	}
+	all := scores.all()
+	if len(all) != 1 || all[0].ID != id.String() || all[0].Banned {
+		t.Fatalf("wrong score list: %+v", all)
+	}
+}
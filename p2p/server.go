@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"cmp"
 	"crypto/ecdsa"
+	"crypto/tls"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -41,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/quic-go/quic-go"
 )
 
 const (
@@ -148,6 +150,11 @@ type Config struct {
 	// for TCP and DiscAddr for the UDP discovery protocol.
 	DiscAddr string
 
+	// If QUICListenAddr is set to a non-nil address, the server also accepts
+	// connections over QUIC on this UDP address, and dials nodes announcing a
+	// QUIC endpoint over QUIC. The address must differ from the discovery one.
+	QUICListenAddr string `toml:",omitempty"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -185,12 +192,16 @@ type Server struct {
 	running bool
 
 	listener     net.Listener
+	quicListener *quicListener
+	quicTr       *quic.Transport
+	quicTLS      *tls.Config
 	ourHandshake *protoHandshake
 	loopWG       sync.WaitGroup // loop, listenLoop
 	peerFeed     event.Feed
 	log          log.Logger
 
 	nodedb    *enode.DB
+	scores    *peerScores
 	localnode *enode.LocalNode
 	discv4    *discover.UDPv4
 	discv5    *discover.UDPv5
@@ -210,7 +221,8 @@ type Server struct {
 	checkpointPostHandshake chan *conn
 	checkpointAddPeer       chan *conn
 
-	// State of run loop and listenLoop.
+	// State of the listenLoops.
+	inboundMu      sync.Mutex
 	inboundHistory expHeap
 }
 
@@ -425,9 +437,15 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.quicListener != nil {
+		srv.quicListener.Close()
+	}
 	close(srv.quit)
 	srv.lock.Unlock()
 	srv.loopWG.Wait()
+	if srv.quicTr != nil {
+		closeQUICTransport(srv.quicTr)
+	}
 }
 
 // sharedUDPConn implements a shared connection. Write sends messages to the underlying connection while read returns
@@ -481,7 +499,7 @@ func (srv *Server) Start() (err error) {
 		return errors.New("Server.PrivateKey must be set to a non-nil key")
 	}
 	if srv.newTransport == nil {
-		srv.newTransport = newRLPX
+		srv.newTransport = newTransport
 	}
 	if srv.listenFunc == nil {
 		srv.listenFunc = net.Listen
@@ -505,6 +523,11 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 	}
+	if srv.QUICListenAddr != "" {
+		if err := srv.setupQUICListening(); err != nil {
+			return err
+		}
+	}
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
@@ -530,6 +553,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.scores = newPeerScores(db, srv.log)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	// TODO: check conflicts
@@ -613,6 +637,7 @@ func (srv *Server) setupDialScheduler() {
 		netRestrict:    srv.NetRestrict,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
+		banned:         srv.scores.banned,
 	}
 	if srv.discv4 != nil {
 		config.resolver = srv.discv4
@@ -620,6 +645,9 @@ func (srv *Server) setupDialScheduler() {
 	if config.dialer == nil {
 		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
 	}
+	if srv.quicTr != nil {
+		config.dialer = quicDialer{fallback: config.dialer, tr: srv.quicTr, tls: srv.quicTLS}
+	}
 	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn)
 	for _, n := range srv.StaticNodes {
 		srv.dialsched.addStatic(n)
@@ -668,7 +696,46 @@ func (srv *Server) setupListening() error {
 	}
 
 	srv.loopWG.Add(1)
-	go srv.listenLoop()
+	go srv.listenLoop(listener)
+	return nil
+}
+
+func (srv *Server) setupQUICListening() error {
+	tlsConf, err := newQUICTLSConfig()
+	if err != nil {
+		return err
+	}
+	addr, err := net.ResolveUDPAddr("udp", srv.QUICListenAddr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	// The listener and the dialer share the socket, which keeps NAT mappings
+	// created by outbound connections usable for inbound ones.
+	tr := &quic.Transport{Conn: conn}
+	listener, err := newQUICListener(tr, tlsConf, srv.maxPendingPeers())
+	if err != nil {
+		closeQUICTransport(tr)
+		return err
+	}
+	srv.quicTr, srv.quicTLS, srv.quicListener = tr, tlsConf, listener
+	laddr := conn.LocalAddr().(*net.UDPAddr)
+	srv.QUICListenAddr = laddr.String()
+
+	// Update the local node record and map the QUIC listening port if NAT is configured.
+	srv.localnode.Set(enr.QUIC(laddr.Port))
+	if !laddr.IP.IsLoopback() && !laddr.IP.IsPrivate() {
+		srv.portMappingRegister <- &portMapping{
+			protocol: "UDP",
+			name:     quicMappingName,
+			port:     laddr.Port,
+		}
+	}
+	srv.loopWG.Add(1)
+	go srv.listenLoop(listener)
 	return nil
 }
 
@@ -797,6 +864,7 @@ running:
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			delete(peers, pd.ID())
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
+			srv.scores.peerDropped(pd.Peer, pd.err, pd.requested, time.Duration(d))
 			srv.dialsched.peerRemoved(pd.rw)
 			if pd.Inbound() {
 				inboundCount--
@@ -841,6 +909,8 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn) && c.is(inboundConn) && srv.scores.banned(c.node.ID()):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -856,16 +926,22 @@ func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *
 	return srv.postHandshakeChecks(peers, inboundCount, c)
 }
 
+// maxPendingPeers returns the maximum number of inbound connections which may be
+// in the handshake phase at the same time on a listener.
+func (srv *Server) maxPendingPeers() int {
+	if srv.MaxPendingPeers > 0 {
+		return srv.MaxPendingPeers
+	}
+	return defaultMaxPendingPeers
+}
+
 // listenLoop runs in its own goroutine and accepts
 // inbound connections.
-func (srv *Server) listenLoop() {
-	srv.log.Debug("TCP listener up", "addr", srv.listener.Addr())
+func (srv *Server) listenLoop(listener net.Listener) {
+	srv.log.Debug("Listener up", "addr", listener.Addr())
 
 	// The slots channel limits accepts of new connections.
-	tokens := defaultMaxPendingPeers
-	if srv.MaxPendingPeers > 0 {
-		tokens = srv.MaxPendingPeers
-	}
+	tokens := srv.maxPendingPeers()
 	slots := make(chan struct{}, tokens)
 	for i := 0; i < tokens; i++ {
 		slots <- struct{}{}
@@ -890,7 +966,7 @@ func (srv *Server) listenLoop() {
 			lastLog time.Time
 		)
 		for {
-			fd, err = srv.listener.Accept()
+			fd, err = listener.Accept()
 			if netutil.IsTemporaryError(err) {
 				if time.Since(lastLog) > 1*time.Second {
 					srv.log.Debug("Temporary read error", "err", err)
@@ -935,6 +1011,9 @@ func (srv *Server) checkInboundConn(remoteIP netip.Addr) error {
 		return errors.New("not in netrestrict list")
 	}
 	// Reject Internet peers that try too often.
+	srv.inboundMu.Lock()
+	defer srv.inboundMu.Unlock()
+
 	now := srv.clock.Now()
 	srv.inboundHistory.expire(now, nil)
 	if !netutil.AddrIsLAN(remoteIP) && srv.inboundHistory.contains(remoteIP.String()) {
@@ -1045,6 +1124,7 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.scores = srv.scores
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
@@ -1102,6 +1182,15 @@ type NodeInfo struct {
 	Protocols  map[string]interface{} `json:"protocols"`
 }
 
+// PeerScores returns the reputation scores of all nodes recorded in the node
+// database, lowest first.
+func (srv *Server) PeerScores() []*PeerScoreInfo {
+	if srv.scores == nil {
+		return nil
+	}
+	return srv.scores.all()
+}
+
 // NodeInfo gathers and returns a collection of metadata known about the host.
 func (srv *Server) NodeInfo() *NodeInfo {
 	// Gather and assemble the generic node infos
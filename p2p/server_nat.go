@@ -33,6 +33,10 @@ const (
 	extipRetryInterval     = 2 * time.Minute
 )
 
+// quicMappingName is the name of the port mapping of the QUIC listener, which
+// shares the UDP protocol with the discovery port mapping.
+const quicMappingName = "ethereum p2p quic"
+
 type portMapping struct {
 	protocol string
 	name     string
@@ -46,11 +50,11 @@ type portMapping struct {
 // setupPortMapping starts the port mapping loop if necessary.
 // Note: this needs to be called after the LocalNode instance has been set on the server.
 func (srv *Server) setupPortMapping() {
-	// portMappingRegister will receive up to two values: one for the TCP port if
-	// listening is enabled, and one more for enabling UDP port mapping if discovery is
-	// enabled. We make it buffered to avoid blocking setup while a mapping request is in
-	// progress.
-	srv.portMappingRegister = make(chan *portMapping, 2)
+	// portMappingRegister will receive up to three values: one for the TCP port if
+	// listening is enabled, one for the QUIC port if QUIC listening is enabled, and
+	// one more for enabling UDP port mapping if discovery is enabled. We make it
+	// buffered to avoid blocking setup while a mapping request is in progress.
+	srv.portMappingRegister = make(chan *portMapping, 3)
 
 	switch srv.NAT.(type) {
 	case nil:
@@ -91,11 +95,22 @@ func (srv *Server) portMappingLoop() {
 	}
 
 	var (
-		mappings  = make(map[string]*portMapping, 2)
+		mappings  = make(map[string]*portMapping, 3) // keyed by name, protocols may be shared
 		refresh   = mclock.NewAlarm(srv.clock)
 		extip     = mclock.NewAlarm(srv.clock)
 		lastExtIP net.IP
 	)
+	// The external IP and the mapped discovery port are reported to the local
+	// node, which uses them alongside endpoint prediction.
+	updateNATEndpoint := func() {
+		var port int
+		for _, m := range mappings {
+			if m.protocol == "UDP" && m.name != quicMappingName {
+				port = m.extPort
+			}
+		}
+		srv.localnode.SetNATEndpoint(lastExtIP, port)
+	}
 	extip.Schedule(srv.clock.Now())
 	defer func() {
 		refresh.Stop()
@@ -131,7 +146,7 @@ func (srv *Server) portMappingLoop() {
 			}
 			// Here, we either failed to get the external IP, or it has changed.
 			lastExtIP = ip
-			srv.localnode.SetStaticIP(ip)
+			updateNATEndpoint()
 			// Ensure port mappings are refreshed in case we have moved to a new network.
 			for _, m := range mappings {
 				m.nextTime = srv.clock.Now()
@@ -141,7 +156,7 @@ func (srv *Server) portMappingLoop() {
 			if m.protocol != "TCP" && m.protocol != "UDP" {
 				panic("unknown NAT protocol name: " + m.protocol)
 			}
-			mappings[m.protocol] = m
+			mappings[m.name] = m
 			m.nextTime = srv.clock.Now()
 
 		case <-refresh.C():
@@ -162,6 +177,7 @@ func (srv *Server) portMappingLoop() {
 					log.Debug("Couldn't add port mapping", "err", err)
 					m.extPort = 0
 					m.nextTime = srv.clock.Now().Add(portMapRetryInterval)
+					updateNATEndpoint()
 					continue
 				}
 				// It was mapped!
@@ -175,11 +191,13 @@ func (srv *Server) portMappingLoop() {
 				}
 
 				// Update port in local ENR.
-				switch m.protocol {
-				case "TCP":
+				switch {
+				case m.protocol == "TCP":
 					srv.localnode.Set(enr.TCP(m.extPort))
-				case "UDP":
-					srv.localnode.SetFallbackUDP(m.extPort)
+				case m.name == quicMappingName:
+					srv.localnode.Set(enr.QUIC(m.extPort))
+				default:
+					updateNATEndpoint()
 				}
 			}
 		}
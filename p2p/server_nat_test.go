@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/internal/testlog"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 )
 
 func TestServerPortMapping(t *testing.T) {
@@ -76,6 +77,46 @@ func TestServerPortMapping(t *testing.T) {
 	}
 }
 
+// This test checks that the QUIC port mapping doesn't replace the discovery port
+// mapping, even though both use the UDP protocol.
+func TestServerPortMappingQUIC(t *testing.T) {
+	clock := new(mclock.Simulated)
+	mockNAT := &mockNAT{mappedPort: 30000}
+	srv := Server{
+		Config: Config{
+			PrivateKey:     newkey(),
+			NoDial:         true,
+			ListenAddr:     ":0",
+			QUICListenAddr: ":0",
+			NAT:            mockNAT,
+			Logger:         testlog.Logger(t, log.LvlTrace),
+			clock:          clock,
+		},
+	}
+	err := srv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	deadline := clock.Now().Add(portMapRefreshInterval)
+	for clock.Now() < deadline && mockNAT.mapRequests.Load() < 3 {
+		time.Sleep(10 * time.Millisecond)
+		clock.Run(1 * time.Second)
+	}
+	if reqCount := mockNAT.mapRequests.Load(); reqCount != 3 {
+		t.Error("wrong request count:", reqCount)
+	}
+	node := srv.LocalNode().Node()
+	if node.UDP() != 30000 {
+		t.Error("wrong UDP port in ENR:", node.UDP())
+	}
+	var quic enr.QUIC
+	if err := node.Load(&quic); err != nil || quic != 30000 {
+		t.Error("wrong QUIC port in ENR:", quic, err)
+	}
+}
+
 type mockNAT struct {
 	mappedPort    uint16
 	mapRequests   atomic.Int32
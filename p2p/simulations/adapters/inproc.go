@@ -56,6 +56,15 @@ func NewSimAdapter(services LifecycleConstructors) *SimAdapter {
 	}
 }
 
+// NewQUICAdapter creates a SimAdapter which connects nodes over localhost QUIC
+// connections instead of in-memory pipes, so that the QUIC transport of the
+// devp2p server is exercised.
+func NewQUICAdapter(services LifecycleConstructors) *SimAdapter {
+	s := NewSimAdapter(services)
+	s.pipe = p2p.QUICPipe
+	return s
+}
+
 // Name returns the name of the adapter for logging purposes
 func (s *SimAdapter) Name() string {
 	return "sim-adapter"
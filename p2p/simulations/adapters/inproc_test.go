@@ -20,9 +20,11 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"sync"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
 )
 
@@ -55,6 +57,40 @@ func TestTCPPipe(t *testing.T) {
 	}
 }
 
+func TestQUICPipe(t *testing.T) {
+	c1, c2, err := p2p.QUICPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	msgs := 50
+	size := 1024
+	go func() {
+		for i := 0; i < msgs; i++ {
+			msg := make([]byte, size)
+			binary.PutUvarint(msg, uint64(i))
+			if _, err := c2.Write(msg); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < msgs; i++ {
+		msg := make([]byte, size)
+		binary.PutUvarint(msg, uint64(i))
+		out := make([]byte, size)
+		if _, err := io.ReadFull(c1, out); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(msg, out) {
+			t.Fatalf("expected %#v, got %#v", msg, out)
+		}
+	}
+}
+
 func TestTCPPipeBidirections(t *testing.T) {
 	c1, c2, err := pipes.TCPPipe()
 	if err != nil {
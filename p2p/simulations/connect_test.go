@@ -24,13 +24,19 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
 )
 
+var noopServices = adapters.LifecycleConstructors{
+	"noopwoop": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+		return NewNoopService(nil), nil
+	},
+}
+
 func newTestNetwork(t *testing.T, nodeCount int) (*Network, []enode.ID) {
 	t.Helper()
-	adapter := adapters.NewSimAdapter(adapters.LifecycleConstructors{
-		"noopwoop": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
-			return NewNoopService(nil), nil
-		},
-	})
+	return newTestNetworkWithAdapter(t, adapters.NewSimAdapter(noopServices), nodeCount)
+}
+
+func newTestNetworkWithAdapter(t *testing.T, adapter adapters.NodeAdapter, nodeCount int) (*Network, []enode.ID) {
+	t.Helper()
 
 	// create network
 	network := NewNetwork(adapter, &NetworkConfig{
@@ -133,6 +139,18 @@ func TestConnectNodesFull(t *testing.T) {
 	}
 }
 
+func TestConnectNodesFullQUIC(t *testing.T) {
+	net, ids := newTestNetworkWithAdapter(t, adapters.NewQUICAdapter(noopServices), 4)
+	defer net.Shutdown()
+
+	err := net.ConnectNodesFull(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	VerifyFull(t, net, ids)
+}
+
 func TestConnectNodesChain(t *testing.T) {
 	net, ids := newTestNetwork(t, 10)
 	defer net.Shutdown()
@@ -0,0 +1,345 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package natsim simulates a UDP network with NAT gateways in-process.
+//
+// Sockets created by the simulator can be used as the connection of a discovery
+// listener. Hosts are either attached to the public network directly, or placed
+// behind a NAT gateway, which translates their addresses and filters unsolicited
+// inbound packets according to its behavior.
+package natsim
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// Behavior is the address mapping and filtering behavior of a NAT gateway,
+// following the classification of RFC 3489.
+type Behavior int
+
+const (
+	// FullCone maps all packets from a private endpoint to the same external port
+	// and accepts inbound packets from any host on that port.
+	FullCone Behavior = iota
+
+	// AddressRestricted is like FullCone, but only accepts inbound packets from
+	// IP addresses the private endpoint has sent packets to.
+	AddressRestricted
+
+	// PortRestricted is like AddressRestricted, but inbound packets must also
+	// come from a port the private endpoint has sent packets to.
+	PortRestricted
+
+	// Symmetric uses a different external port for each remote endpoint, and
+	// only accepts inbound packets from that endpoint.
+	Symmetric
+)
+
+func (b Behavior) String() string {
+	switch b {
+	case FullCone:
+		return "full-cone"
+	case AddressRestricted:
+		return "address-restricted"
+	case PortRestricted:
+		return "port-restricted"
+	case Symmetric:
+		return "symmetric"
+	default:
+		return fmt.Sprintf("Behavior(%d)", int(b))
+	}
+}
+
+const (
+	// firstMappedPort is the first external port assigned by NAT gateways.
+	firstMappedPort = 40000
+
+	// connQueueSize is the number of packets buffered by a socket.
+	// Packets are dropped when the buffer is full.
+	connQueueSize = 256
+)
+
+var errInUse = errors.New("address already in use")
+
+// Network is a simulated public network.
+type Network struct {
+	mu    sync.Mutex
+	hosts map[netip.AddrPort]*Conn
+	nats  map[netip.Addr]*NAT
+}
+
+// NewNetwork creates an empty network.
+func NewNetwork() *Network {
+	return &Network{
+		hosts: make(map[netip.AddrPort]*Conn),
+		nats:  make(map[netip.Addr]*NAT),
+	}
+}
+
+// Listen creates a socket on the given public endpoint.
+func (n *Network) Listen(addr netip.AddrPort) (*Conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.hosts[addr] != nil || n.nats[addr.Addr()] != nil {
+		return nil, errInUse
+	}
+	c := newConn(n, nil, addr)
+	n.hosts[addr] = c
+	return c, nil
+}
+
+// NewNAT creates a NAT gateway with the given public IP address.
+func (n *Network) NewNAT(ip netip.Addr, behavior Behavior) (*NAT, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.nats[ip] != nil {
+		return nil, errInUse
+	}
+	for addr := range n.hosts {
+		if addr.Addr() == ip {
+			return nil, errInUse
+		}
+	}
+	nat := &NAT{
+		net:      n,
+		ip:       ip,
+		behavior: behavior,
+		hosts:    make(map[netip.AddrPort]*Conn),
+		out:      make(map[mappingKey]*mapping),
+		in:       make(map[uint16]*mapping),
+		nextPort: firstMappedPort,
+	}
+	n.nats[ip] = nat
+	return nat, nil
+}
+
+// send routes a packet sent by c.
+func (n *Network) send(c *Conn, to netip.AddrPort, data []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	to = netip.AddrPortFrom(to.Addr().Unmap(), to.Port())
+	from := c.addr
+	if c.nat != nil {
+		// Hosts behind the same gateway reach each other directly.
+		if dst := c.nat.hosts[to]; dst != nil {
+			dst.enqueue(from, data)
+			return
+		}
+		from = c.nat.translateOut(c.addr, to)
+	}
+	if dst := n.hosts[to]; dst != nil {
+		dst.enqueue(from, data)
+	} else if nat := n.nats[to.Addr()]; nat != nil {
+		if dst := nat.translateIn(from, to.Port()); dst != nil {
+			dst.enqueue(from, data)
+		}
+	}
+}
+
+// NAT is a simulated NAT gateway.
+type NAT struct {
+	net      *Network
+	ip       netip.Addr
+	behavior Behavior
+
+	// These fields are protected by the network lock.
+	hosts    map[netip.AddrPort]*Conn
+	out      map[mappingKey]*mapping
+	in       map[uint16]*mapping
+	nextPort uint16
+}
+
+type mappingKey struct {
+	private netip.AddrPort
+	remote  netip.AddrPort // set for symmetric NAT only
+}
+
+type mapping struct {
+	private netip.AddrPort
+	port    uint16
+	remotes map[netip.AddrPort]struct{} // endpoints contacted through the mapping
+}
+
+// IP returns the public IP address of the gateway.
+func (nat *NAT) IP() netip.Addr {
+	return nat.ip
+}
+
+// Behavior returns the mapping behavior of the gateway.
+func (nat *NAT) Behavior() Behavior {
+	return nat.behavior
+}
+
+// Listen creates a socket on the given private endpoint behind the gateway.
+func (nat *NAT) Listen(addr netip.AddrPort) (*Conn, error) {
+	nat.net.mu.Lock()
+	defer nat.net.mu.Unlock()
+
+	if nat.hosts[addr] != nil {
+		return nil, errInUse
+	}
+	c := newConn(nat.net, nat, addr)
+	nat.hosts[addr] = c
+	return c, nil
+}
+
+// ExternalAddr returns the external endpoint which is used for packets sent from
+// the private endpoint to the remote endpoint, if the mapping exists.
+func (nat *NAT) ExternalAddr(private, remote netip.AddrPort) (netip.AddrPort, bool) {
+	nat.net.mu.Lock()
+	defer nat.net.mu.Unlock()
+
+	m := nat.out[nat.mappingKey(private, remote)]
+	if m == nil {
+		return netip.AddrPort{}, false
+	}
+	return netip.AddrPortFrom(nat.ip, m.port), true
+}
+
+func (nat *NAT) mappingKey(private, remote netip.AddrPort) mappingKey {
+	if nat.behavior == Symmetric {
+		return mappingKey{private, remote}
+	}
+	return mappingKey{private: private}
+}
+
+// translateOut returns the external source endpoint of an outbound packet,
+// creating the mapping if necessary.
+func (nat *NAT) translateOut(private, remote netip.AddrPort) netip.AddrPort {
+	key := nat.mappingKey(private, remote)
+	m := nat.out[key]
+	if m == nil {
+		for nat.in[nat.nextPort] != nil {
+			nat.nextPort++
+		}
+		m = &mapping{private: private, port: nat.nextPort, remotes: make(map[netip.AddrPort]struct{})}
+		nat.nextPort++
+		nat.out[key] = m
+		nat.in[m.port] = m
+	}
+	m.remotes[remote] = struct{}{}
+	return netip.AddrPortFrom(nat.ip, m.port)
+}
+
+// translateIn returns the recipient of an inbound packet, or nil if the
+// packet is filtered.
+func (nat *NAT) translateIn(remote netip.AddrPort, port uint16) *Conn {
+	m := nat.in[port]
+	if m == nil || !nat.allowed(m, remote) {
+		return nil
+	}
+	return nat.hosts[m.private]
+}
+
+func (nat *NAT) allowed(m *mapping, remote netip.AddrPort) bool {
+	switch nat.behavior {
+	case FullCone:
+		return true
+	case AddressRestricted:
+		for r := range m.remotes {
+			if r.Addr() == remote.Addr() {
+				return true
+			}
+		}
+		return false
+	default:
+		_, ok := m.remotes[remote]
+		return ok
+	}
+}
+
+// Conn is a simulated UDP socket.
+type Conn struct {
+	net  *Network
+	nat  *NAT
+	addr netip.AddrPort
+
+	in        chan packet
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type packet struct {
+	from netip.AddrPort
+	data []byte
+}
+
+func newConn(n *Network, nat *NAT, addr netip.AddrPort) *Conn {
+	return &Conn{
+		net:    n,
+		nat:    nat,
+		addr:   addr,
+		in:     make(chan packet, connQueueSize),
+		closed: make(chan struct{}),
+	}
+}
+
+// enqueue delivers a packet to the socket. It is called with the network lock held.
+func (c *Conn) enqueue(from netip.AddrPort, data []byte) {
+	select {
+	case c.in <- packet{from, data}:
+	default:
+	}
+}
+
+// ReadFromUDPAddrPort reads the next packet.
+func (c *Conn) ReadFromUDPAddrPort(b []byte) (int, netip.AddrPort, error) {
+	select {
+	case p := <-c.in:
+		return copy(b, p.data), p.from, nil
+	case <-c.closed:
+		return 0, netip.AddrPort{}, net.ErrClosed
+	}
+}
+
+// WriteToUDPAddrPort sends a packet.
+func (c *Conn) WriteToUDPAddrPort(b []byte, addr netip.AddrPort) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	c.net.send(c, addr, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+// LocalAddr returns the endpoint of the socket. For sockets behind a NAT gateway,
+// this is the private endpoint.
+func (c *Conn) LocalAddr() net.Addr {
+	return net.UDPAddrFromAddrPort(c.addr)
+}
+
+// Close closes the socket.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		c.net.mu.Lock()
+		if c.nat != nil {
+			delete(c.nat.hosts, c.addr)
+		} else {
+			delete(c.net.hosts, c.addr)
+		}
+		c.net.mu.Unlock()
+		close(c.closed)
+	})
+	return nil
+}
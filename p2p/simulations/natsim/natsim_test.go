@@ -0,0 +1,147 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package natsim
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+var (
+	natIP      = netip.MustParseAddr("203.0.113.1")
+	privateEP  = netip.MustParseAddrPort("10.0.0.2:30303")
+	publicEP1  = netip.MustParseAddrPort("198.51.100.1:30303")
+	publicEP1b = netip.MustParseAddrPort("198.51.100.1:30304")
+	publicEP2  = netip.MustParseAddrPort("198.51.100.2:30303")
+)
+
+type natTestEnv struct {
+	t       *testing.T
+	nat     *NAT
+	private *Conn
+	public  map[netip.AddrPort]*Conn
+}
+
+func newNATTestEnv(t *testing.T, behavior Behavior) *natTestEnv {
+	n := NewNetwork()
+	nat, err := n.NewNAT(natIP, behavior)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &natTestEnv{t: t, nat: nat, public: make(map[netip.AddrPort]*Conn)}
+	if env.private, err = nat.Listen(privateEP); err != nil {
+		t.Fatal(err)
+	}
+	for _, ep := range []netip.AddrPort{publicEP1, publicEP1b, publicEP2} {
+		c, err := n.Listen(ep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.public[ep] = c
+	}
+	return env
+}
+
+// sendOut sends a packet from the private host to a public host and returns
+// the source endpoint seen by the recipient.
+func (env *natTestEnv) sendOut(to netip.AddrPort) netip.AddrPort {
+	env.private.WriteToUDPAddrPort([]byte("out"), to)
+	from, ok := receive(env.public[to])
+	if !ok {
+		env.t.Fatalf("packet to %v not delivered", to)
+	}
+	return from
+}
+
+// sendIn sends a packet from a public host to the given external endpoint and
+// reports whether it reached the private host.
+func (env *natTestEnv) sendIn(from, to netip.AddrPort) bool {
+	env.public[from].WriteToUDPAddrPort([]byte("in"), to)
+	src, ok := receive(env.private)
+	if ok && src != from {
+		env.t.Fatalf("wrong source address %v, want %v", src, from)
+	}
+	return ok
+}
+
+func receive(c *Conn) (netip.AddrPort, bool) {
+	result := make(chan netip.AddrPort, 1)
+	go func() {
+		buf := make([]byte, 16)
+		_, from, err := c.ReadFromUDPAddrPort(buf)
+		if err == nil {
+			result <- from
+		}
+	}()
+	select {
+	case from := <-result:
+		return from, true
+	case <-time.After(50 * time.Millisecond):
+		// Unblock the reader by injecting a dummy packet, which is discarded.
+		c.enqueue(netip.AddrPort{}, nil)
+		<-result
+		return netip.AddrPort{}, false
+	}
+}
+
+func TestNATBehavior(t *testing.T) {
+	tests := []struct {
+		behavior      Behavior
+		samePort      bool // same external port for different destinations
+		fromOtherIP   bool // unsolicited packet from other IP accepted
+		fromOtherPort bool // packet from other port of contacted IP accepted
+		fromContacted bool // packet from contacted endpoint accepted
+	}{
+		{FullCone, true, true, true, true},
+		{AddressRestricted, true, false, true, true},
+		{PortRestricted, true, false, false, true},
+		{Symmetric, false, false, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.behavior.String(), func(t *testing.T) {
+			env := newNATTestEnv(t, test.behavior)
+
+			// Before any outbound packet, nothing is accepted.
+			if env.sendIn(publicEP1, netip.AddrPortFrom(natIP, firstMappedPort)) {
+				t.Fatal("unsolicited packet accepted before mapping exists")
+			}
+			ext := env.sendOut(publicEP1)
+			if ext.Addr() != natIP {
+				t.Fatalf("wrong external IP %v", ext.Addr())
+			}
+			if got, _ := env.nat.ExternalAddr(privateEP, publicEP1); got != ext {
+				t.Fatalf("ExternalAddr returned %v, want %v", got, ext)
+			}
+			if ext2 := env.sendOut(publicEP1b); (ext2 == ext) != test.samePort {
+				t.Fatalf("external endpoint for second destination: %v, first %v", ext2, ext)
+			}
+			if ok := env.sendIn(publicEP2, ext); ok != test.fromOtherIP {
+				t.Errorf("packet from other IP: accepted=%t, want %t", ok, test.fromOtherIP)
+			}
+			if ok := env.sendIn(publicEP1, ext); ok != test.fromContacted {
+				t.Errorf("packet from contacted endpoint: accepted=%t, want %t", ok, test.fromContacted)
+			}
+			// For the port test, contact only publicEP1 through a fresh mapping.
+			env2 := newNATTestEnv(t, test.behavior)
+			ext = env2.sendOut(publicEP1)
+			if ok := env2.sendIn(publicEP1b, ext); ok != test.fromOtherPort {
+				t.Errorf("packet from other port: accepted=%t, want %t", ok, test.fromOtherPort)
+			}
+		})
+	}
+}
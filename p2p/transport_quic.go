@@ -0,0 +1,658 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/golang/snappy"
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	// quicALPN is the application protocol negotiated for devp2p over QUIC.
+	quicALPN = "devp2p"
+
+	// quicControlVersion is sent by the dialer at the beginning of the control
+	// stream, which also makes the stream visible to the remote end.
+	quicControlVersion = 1
+
+	// quicBindingMsg is exchanged on the control stream right after the RLPx
+	// handshake, before any other message. It carries keying material exported
+	// from the TLS session of the QUIC connection.
+	quicBindingMsg   = 0x0f
+	quicBindingLabel = "EXPORTER-devp2p-quic-binding"
+
+	// quicDiscFlag marks the QUIC application error codes which carry a devp2p
+	// disconnect reason.
+	quicDiscFlag = 0x100
+
+	// maxQUICMsgSize is the maximum size of a message sent on a protocol stream,
+	// which matches the RLPx frame size limit.
+	maxQUICMsgSize = 1<<24 - 1
+)
+
+var errQUICBinding = errors.New("RLPx session not bound to QUIC connection")
+
+// newQUICConfig creates the QUIC configuration of devp2p connections. Peers may
+// only open the control stream and unidirectional protocol streams.
+func newQUICConfig() *quic.Config {
+	return &quic.Config{
+		HandshakeIdleTimeout:  handshakeTimeout,
+		MaxIdleTimeout:        frameReadTimeout,
+		KeepAlivePeriod:       pingInterval,
+		MaxIncomingStreams:    1,
+		MaxIncomingUniStreams: 64,
+	}
+}
+
+// newQUICTLSConfig creates the TLS configuration of devp2p connections over QUIC.
+// The certificate is ephemeral and self-signed, and the remote certificate is
+// never verified. Peers are authenticated by the RLPx handshake instead, which
+// is bound to the TLS session, see quicTransport.doEncHandshake.
+func newQUICTLSConfig() (*tls.Config, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: priv}},
+		NextProtos:         []string{quicALPN},
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true, // nolint: gosec
+	}, nil
+}
+
+// quicConn is the control stream of a devp2p connection over QUIC, carrying the
+// RLPx handshake and the base protocol. It implements net.Conn, so that QUIC
+// connections can be set up by the server just like TCP ones.
+type quicConn struct {
+	quic.Stream
+	conn quic.Connection
+
+	reason    atomic.Int32 // disconnect reason sent when closing, -1 if none
+	closeOnce sync.Once
+	onClose   func() // releases the resources of the connection, used by QUICPipe
+}
+
+func newQUICConn(conn quic.Connection, stream quic.Stream) *quicConn {
+	c := &quicConn{Stream: stream, conn: conn}
+	c.reason.Store(-1)
+	return c
+}
+
+func (c *quicConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+func (c *quicConn) Read(b []byte) (int, error) {
+	n, err := c.Stream.Read(b)
+	return n, quicReadError(err)
+}
+
+// setReason sets the disconnect reason announced when closing the connection.
+func (c *quicConn) setReason(r DiscReason) {
+	c.reason.Store(int32(r))
+}
+
+// Close closes the whole QUIC connection. Pending stream data is not guaranteed
+// to be delivered, so the disconnect reason is sent as the application error code.
+func (c *quicConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		var code quic.ApplicationErrorCode
+		if r := c.reason.Load(); r >= 0 {
+			code = quicDiscFlag | quic.ApplicationErrorCode(r)
+		}
+		err = c.conn.CloseWithError(code, "")
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return err
+}
+
+// quicReadError converts the error of a connection closed by the remote end with
+// a disconnect reason into the reason.
+func quicReadError(err error) error {
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && appErr.Remote && appErr.ErrorCode&quicDiscFlag != 0 {
+		return DiscReason(appErr.ErrorCode &^ quicDiscFlag)
+	}
+	return err
+}
+
+// asQUICConn returns the QUIC control stream of a connection, if it is one.
+func asQUICConn(fd net.Conn) (*quicConn, bool) {
+	if mc, ok := fd.(*meteredConn); ok {
+		fd = mc.Conn
+	}
+	qc, ok := fd.(*quicConn)
+	return qc, ok
+}
+
+// dialQUIC establishes a QUIC connection to the given endpoint and opens its
+// control stream.
+func dialQUIC(ctx context.Context, tr *quic.Transport, addr netip.AddrPort, tlsConf *tls.Config) (*quicConn, error) {
+	conn, err := tr.Dial(ctx, net.UDPAddrFromAddrPort(addr), tlsConf, newQUICConfig())
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		conn.CloseWithError(0, "")
+		return nil, err
+	}
+	stream.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+	if _, err := stream.Write([]byte{quicControlVersion}); err != nil {
+		conn.CloseWithError(0, "")
+		return nil, err
+	}
+	return newQUICConn(conn, stream), nil
+}
+
+// acceptQUIC waits for the control stream of an inbound QUIC connection.
+func acceptQUIC(ctx context.Context, conn quic.Connection) (*quicConn, error) {
+	stream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	var version [1]byte
+	if _, err := io.ReadFull(stream, version[:]); err != nil {
+		return nil, err
+	}
+	if version[0] != quicControlVersion {
+		return nil, fmt.Errorf("unsupported control stream version %d", version[0])
+	}
+	return newQUICConn(conn, stream), nil
+}
+
+// quicListener accepts devp2p connections over QUIC. It implements net.Listener,
+// returning the control streams of inbound connections.
+type quicListener struct {
+	ln        *quic.Listener
+	conns     chan *quicConn
+	slots     chan struct{} // limits connections waiting for their control stream
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newQUICListener(tr *quic.Transport, tlsConf *tls.Config, maxPending int) (*quicListener, error) {
+	ln, err := tr.Listen(tlsConf, newQUICConfig())
+	if err != nil {
+		return nil, err
+	}
+	l := &quicListener{
+		ln:     ln,
+		conns:  make(chan *quicConn),
+		slots:  make(chan struct{}, maxPending),
+		closed: make(chan struct{}),
+	}
+	go l.loop()
+	return l, nil
+}
+
+// loop accepts QUIC connections, waiting for their control streams in the
+// background so that slow peers can't block other connections. Like inbound TCP
+// connections, at most maxPending connections are set up at any time.
+func (l *quicListener) loop() {
+	defer l.Close()
+
+	for {
+		// Wait for a free slot before accepting.
+		select {
+		case l.slots <- struct{}{}:
+		case <-l.closed:
+			return
+		}
+		conn, err := l.ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			defer func() { <-l.slots }()
+			ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+			defer cancel()
+			fd, err := acceptQUIC(ctx, conn)
+			if err != nil {
+				log.Trace("Failed to accept QUIC control stream", "addr", conn.RemoteAddr(), "err", err)
+				conn.CloseWithError(0, "")
+				return
+			}
+			select {
+			case l.conns <- fd:
+			case <-l.closed:
+				fd.Close()
+			}
+		}()
+	}
+}
+
+func (l *quicListener) Accept() (net.Conn, error) {
+	select {
+	case fd := <-l.conns:
+		return fd, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *quicListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.ln.Close()
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *quicListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// quicDialer dials nodes announcing a QUIC endpoint over QUIC, falling back to
+// the wrapped dialer if that fails or if the node has no QUIC endpoint.
+type quicDialer struct {
+	fallback NodeDialer
+	tr       *quic.Transport
+	tls      *tls.Config
+}
+
+func (d quicDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	if addr, ok := dest.QUICEndpoint(); ok {
+		qctx, cancel := context.WithTimeout(ctx, defaultDialTimeout)
+		fd, err := dialQUIC(qctx, d.tr, addr, d.tls)
+		cancel()
+		if err == nil {
+			return fd, nil
+		}
+		log.Trace("QUIC dial failed, falling back to TCP", "id", dest.ID(), "addr", addr, "err", err)
+	}
+	return d.fallback.Dial(ctx, dest)
+}
+
+// QUICPipe creates an in process full duplex pipe based on a localhost QUIC
+// connection. The returned connections are control streams, which are set up
+// as devp2p connections over QUIC by Server.SetupConn.
+func QUICPipe() (net.Conn, net.Conn, error) {
+	tlsConf, err := newQUICTLSConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	ltr, err := newLocalQUICTransport()
+	if err != nil {
+		return nil, nil, err
+	}
+	dtr, err := newLocalQUICTransport()
+	if err != nil {
+		closeQUICTransport(ltr)
+		return nil, nil, err
+	}
+	ln, err := ltr.Listen(tlsConf, newQUICConfig())
+	if err != nil {
+		closeQUICTransport(ltr)
+		closeQUICTransport(dtr)
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	var (
+		aconn *quicConn
+		aerr  = make(chan error, 1)
+	)
+	go func() {
+		conn, err := ln.Accept(ctx)
+		if err == nil {
+			if aconn, err = acceptQUIC(ctx, conn); err != nil {
+				conn.CloseWithError(0, "")
+			}
+		}
+		aerr <- err
+	}()
+	dconn, err := dialQUIC(ctx, dtr, ln.Addr().(*net.UDPAddr).AddrPort(), tlsConf)
+	if err == nil {
+		err = <-aerr
+	} else {
+		ln.Close()
+		<-aerr
+	}
+	if err != nil {
+		if dconn != nil {
+			dconn.Close()
+		}
+		ln.Close()
+		closeQUICTransport(ltr)
+		closeQUICTransport(dtr)
+		return nil, nil, err
+	}
+	aconn.onClose = func() {
+		ln.Close()
+		closeQUICTransport(ltr)
+	}
+	dconn.onClose = func() { closeQUICTransport(dtr) }
+	return aconn, dconn, nil
+}
+
+func newLocalQUICTransport() (*quic.Transport, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		return nil, err
+	}
+	return &quic.Transport{Conn: conn}, nil
+}
+
+// closeQUICTransport closes a QUIC transport along with its socket, which isn't
+// owned by the transport.
+func closeQUICTransport(tr *quic.Transport) {
+	tr.Close()
+	tr.Conn.Close()
+}
+
+// quicTransport is the transport of devp2p connections over QUIC. The RLPx
+// handshake and the base protocol run on the control stream. After the protocol
+// handshake, the messages of each subprotocol are sent on a separate
+// unidirectional stream, so that protocols don't block each other.
+//
+// The QUIC connection itself is not authenticated. Instead, the RLPx session is
+// bound to the TLS session of the connection by exchanging exported keying
+// material on the control stream, which a man-in-the-middle can't forward
+// without breaking either the RLPx or the TLS encryption.
+type quicTransport struct {
+	*rlpxTransport
+	fd *quicConn
+
+	snappy  bool
+	started atomic.Bool
+
+	smu     sync.Mutex
+	streams map[Cap]*quicSendStream
+
+	in        chan quicReadResult
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type quicSendStream struct {
+	mu     sync.Mutex
+	stream quic.SendStream
+	buf    []byte
+}
+
+type quicReadResult struct {
+	msg Msg
+	err error
+}
+
+func newQUICTransport(fd net.Conn, qc *quicConn, dialDest *ecdsa.PublicKey) transport {
+	return &quicTransport{
+		rlpxTransport: &rlpxTransport{conn: rlpx.NewConn(fd, dialDest)},
+		fd:            qc,
+		streams:       make(map[Cap]*quicSendStream),
+		in:            make(chan quicReadResult),
+		closed:        make(chan struct{}),
+	}
+}
+
+// newTransport creates the transport of a connection, depending on whether it
+// is a TCP connection or the control stream of a QUIC connection.
+func newTransport(fd net.Conn, dialDest *ecdsa.PublicKey) transport {
+	if qc, ok := asQUICConn(fd); ok {
+		return newQUICTransport(fd, qc, dialDest)
+	}
+	return newRLPX(fd, dialDest)
+}
+
+func (t *quicTransport) doEncHandshake(prv *ecdsa.PrivateKey) (*ecdsa.PublicKey, error) {
+	pub, err := t.rlpxTransport.doEncHandshake(prv)
+	if err != nil {
+		return nil, err
+	}
+	tlsState := t.fd.conn.ConnectionState().TLS
+	binding, err := tlsState.ExportKeyingMaterial(quicBindingLabel, nil, 32)
+	if err != nil {
+		return nil, err
+	}
+	werr := make(chan error, 1)
+	go func() {
+		_, err := t.conn.Write(quicBindingMsg, binding)
+		werr <- err
+	}()
+	code, data, _, err := t.conn.Read()
+	if err != nil {
+		<-werr
+		return nil, err
+	}
+	if err := <-werr; err != nil {
+		return nil, err
+	}
+	if code != quicBindingMsg || !bytes.Equal(data, binding) {
+		return nil, errQUICBinding
+	}
+	return pub, nil
+}
+
+func (t *quicTransport) doProtoHandshake(our *protoHandshake) (*protoHandshake, error) {
+	their, err := t.rlpxTransport.doProtoHandshake(our)
+	if err != nil {
+		return nil, err
+	}
+	t.snappy = their.Version >= snappyProtocolVersion
+	if !t.started.Swap(true) {
+		go t.readControl()
+		go t.acceptStreams()
+	}
+	return their, nil
+}
+
+// readControl reads the messages of the control stream.
+func (t *quicTransport) readControl() {
+	for {
+		msg, err := t.rlpxTransport.ReadMsg()
+		if !t.deliver(msg, err) || err != nil {
+			return
+		}
+	}
+}
+
+// acceptStreams accepts the protocol streams opened by the remote end.
+func (t *quicTransport) acceptStreams() {
+	for {
+		stream, err := t.fd.conn.AcceptUniStream(t.fd.conn.Context())
+		if err != nil {
+			t.deliver(Msg{}, quicReadError(err))
+			return
+		}
+		go t.readStream(stream)
+	}
+}
+
+// readStream reads the messages of a protocol stream.
+func (t *quicTransport) readStream(stream quic.ReceiveStream) {
+	r := bufio.NewReader(stream)
+	for {
+		msg, err := t.readFrame(r)
+		if err != nil {
+			stream.CancelRead(0)
+			t.deliver(Msg{}, quicReadError(err))
+			return
+		}
+		if !t.deliver(msg, nil) {
+			return
+		}
+	}
+}
+
+// readFrame reads a message from a protocol stream.
+func (t *quicTransport) readFrame(r *bufio.Reader) (Msg, error) {
+	code, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Msg{}, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Msg{}, err
+	}
+	if size > maxQUICMsgSize {
+		return Msg{}, errors.New("message too big")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Msg{}, err
+	}
+	wireSize := uvarintSize(code) + uvarintSize(size) + int(size)
+	if t.snappy {
+		actualSize, err := snappy.DecodedLen(data)
+		if err != nil {
+			return Msg{}, err
+		}
+		if actualSize > maxQUICMsgSize {
+			return Msg{}, errors.New("message too big")
+		}
+		if data, err = snappy.Decode(nil, data); err != nil {
+			return Msg{}, err
+		}
+	}
+	return Msg{
+		ReceivedAt: time.Now(),
+		Code:       code,
+		Size:       uint32(len(data)),
+		meterSize:  uint32(wireSize),
+		Payload:    bytes.NewReader(data),
+	}, nil
+}
+
+// deliver hands a read result to ReadMsg. It reports false if the transport was
+// closed in the meantime.
+func (t *quicTransport) deliver(msg Msg, err error) bool {
+	select {
+	case t.in <- quicReadResult{msg, err}:
+		return true
+	case <-t.closed:
+		return false
+	}
+}
+
+func (t *quicTransport) ReadMsg() (Msg, error) {
+	if !t.started.Load() {
+		return t.rlpxTransport.ReadMsg()
+	}
+	select {
+	case r := <-t.in:
+		return r.msg, r.err
+	case <-t.closed:
+		return Msg{}, net.ErrClosed
+	}
+}
+
+func (t *quicTransport) WriteMsg(msg Msg) error {
+	// Base protocol messages are sent on the control stream.
+	if msg.meterCap.Name == "" || !t.started.Load() {
+		return t.rlpxTransport.WriteMsg(msg)
+	}
+	s, err := t.sendStream(msg.meterCap)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]byte, msg.Size)
+	if _, err := io.ReadFull(msg.Payload, data); err != nil {
+		return err
+	}
+	if t.snappy {
+		data = snappy.Encode(nil, data)
+	}
+	s.buf = binary.AppendUvarint(s.buf[:0], msg.Code)
+	s.buf = binary.AppendUvarint(s.buf, uint64(len(data)))
+	s.buf = append(s.buf, data...)
+
+	s.stream.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+	if _, err := s.stream.Write(s.buf); err != nil {
+		return err
+	}
+	msg.meterSize = uint32(len(s.buf))
+	if metrics.Enabled {
+		m := fmt.Sprintf("%s/%s/%d/%#02x", egressMeterName, msg.meterCap.Name, msg.meterCap.Version, msg.meterCode)
+		metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
+		metrics.GetOrRegisterMeter(m+"/packets", nil).Mark(1)
+	}
+	return nil
+}
+
+// sendStream returns the stream carrying the messages of the given protocol,
+// opening it on first use.
+func (t *quicTransport) sendStream(cap Cap) (*quicSendStream, error) {
+	t.smu.Lock()
+	defer t.smu.Unlock()
+
+	if s := t.streams[cap]; s != nil {
+		return s, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), frameWriteTimeout)
+	defer cancel()
+	stream, err := t.fd.conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &quicSendStream{stream: stream}
+	t.streams[cap] = s
+	return s, nil
+}
+
+func (t *quicTransport) close(err error) {
+	t.closeOnce.Do(func() { close(t.closed) })
+	if r, ok := err.(DiscReason); ok && r != DiscNetworkError {
+		t.fd.setReason(r)
+	}
+	t.rlpxTransport.close(err)
+}
+
+func uvarintSize(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
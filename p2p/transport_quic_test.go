@@ -0,0 +1,168 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func TestServerQUIC(t *testing.T) {
+	received := make(chan string, 4)
+	echoProtocol := func(name string) Protocol {
+		return Protocol{
+			Name:    name,
+			Version: 1,
+			Length:  1,
+			Run: func(p *Peer, rw MsgReadWriter) error {
+				if err := Send(rw, 0, name); err != nil {
+					return err
+				}
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				var s string
+				if err := msg.Decode(&s); err != nil {
+					return err
+				}
+				received <- s
+				_, err = rw.ReadMsg()
+				return err
+			},
+		}
+	}
+	newServer := func() *Server {
+		srv := &Server{Config: Config{
+			PrivateKey:     newkey(),
+			MaxPeers:       10,
+			NoDiscovery:    true,
+			ListenAddr:     "127.0.0.1:0",
+			QUICListenAddr: "127.0.0.1:0",
+			Protocols:      []Protocol{echoProtocol("a"), echoProtocol("b")},
+			Logger:         testlog.Logger(t, log.LvlTrace),
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("could not start: %v", err)
+		}
+		t.Cleanup(srv.Stop)
+		return srv
+	}
+	srv1, srv2 := newServer(), newServer()
+	if _, ok := srv1.Self().QUICEndpoint(); !ok {
+		t.Fatal("QUIC endpoint missing from local node record")
+	}
+	events := make(chan *PeerEvent, 10)
+	sub := srv1.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	// Dial and wait for both protocols to exchange messages in both directions.
+	srv2.AddPeer(srv1.Self())
+	for i := 0; i < 4; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for protocol messages")
+		}
+	}
+	peers := srv1.Peers()
+	if len(peers) != 1 {
+		t.Fatalf("wrong peer count %d", len(peers))
+	}
+	if _, ok := peers[0].RemoteAddr().(*net.UDPAddr); !ok {
+		t.Fatalf("peer not connected over QUIC: %v", peers[0].RemoteAddr())
+	}
+
+	// Check that the disconnect reason reaches the remote end.
+	srv2.RemovePeer(srv1.Self())
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != PeerEventTypeDrop {
+				continue
+			}
+			if ev.Error != DiscRequested.Error() {
+				t.Fatalf("wrong disconnect reason %q", ev.Error)
+			}
+			return
+		case <-timeout:
+			t.Fatal("timeout waiting for disconnect")
+		}
+	}
+}
+
+func TestQUICListenerPendingLimit(t *testing.T) {
+	tlsConf, err := newQUICTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ltr, err := newLocalQUICTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeQUICTransport(ltr)
+	dtr, err := newLocalQUICTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeQUICTransport(dtr)
+
+	ln, err := newQUICListener(ltr, tlsConf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	addr := ln.Addr().(*net.UDPAddr)
+
+	// Occupy the only slot with a connection that never opens its control stream.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stalled, err := dtr.Dial(ctx, addr, tlsConf, newQUICConfig())
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if fd, err := ln.Accept(); err == nil {
+			accepted <- fd
+		}
+	}()
+	fd, err := dialQUIC(ctx, dtr, addr.AddrPort(), tlsConf)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer fd.Close()
+	select {
+	case <-accepted:
+		t.Fatal("connection accepted while the pending limit was reached")
+	case <-time.After(200 * time.Millisecond):
+	}
+	// Dropping the stalled connection frees the slot for the waiting one.
+	stalled.CloseWithError(0, "")
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not accepted after the slot was freed")
+	}
+}
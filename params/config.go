@@ -17,6 +17,7 @@
 package params
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -380,15 +381,25 @@ func (c EthashConfig) String() string {
 
 // CliqueConfig is the consensus engine configs for proof-of-authority based sealing.
 type CliqueConfig struct {
-	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
-	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+	Period          uint64   `json:"period"`                    // Number of seconds between blocks to enforce
+	Epoch           uint64   `json:"epoch"`                     // Epoch length to reset votes and checkpoint
+	VoteExpiry      uint64   `json:"voteExpiry,omitempty"`      // Number of blocks after which pending votes are discarded (0 = end of epoch)
+	VoteExpiryBlock *big.Int `json:"voteExpiryBlock,omitempty"` // Block number from which pending votes expire (nil = never)
 }
 
 // String implements the stringer interface, returning the consensus engine details.
 func (c CliqueConfig) String() string {
+	if c.VoteExpiry != 0 {
+		return fmt.Sprintf("clique(period: %d, epoch: %d, voteExpiry: %d, voteExpiryBlock: %v)", c.Period, c.Epoch, c.VoteExpiry, c.VoteExpiryBlock)
+	}
 	return fmt.Sprintf("clique(period: %d, epoch: %d)", c.Period, c.Epoch)
 }
 
+// IsVoteExpiry returns whether pending votes expire at the given block.
+func (c *CliqueConfig) IsVoteExpiry(num uint64) bool {
+	return c.VoteExpiry != 0 && c.VoteExpiryBlock != nil && c.VoteExpiryBlock.Uint64() <= num
+}
+
 // Description returns a human-readable description of ChainConfig.
 func (c *ChainConfig) Description() string {
 	var banner string
@@ -676,6 +687,10 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	// Vote expiry changes the clique signer set, so it needs an activation block
+	if c.Clique != nil && c.Clique.VoteExpiry != 0 && c.Clique.VoteExpiryBlock == nil {
+		return errors.New("clique vote expiry configured without activation block (voteExpiryBlock)")
+	}
 	return nil
 }
 
@@ -735,6 +750,14 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkBlockIncompatible(c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock, headNumber) {
 		return newBlockCompatError("Merge netsplit fork block", c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock)
 	}
+	if c.Clique != nil && newcfg.Clique != nil {
+		if isForkBlockIncompatible(c.Clique.VoteExpiryBlock, newcfg.Clique.VoteExpiryBlock, headNumber) {
+			return newBlockCompatError("Clique vote expiry block", c.Clique.VoteExpiryBlock, newcfg.Clique.VoteExpiryBlock)
+		}
+		if isBlockForked(c.Clique.VoteExpiryBlock, headNumber) && c.Clique.VoteExpiry != newcfg.Clique.VoteExpiry {
+			return newBlockCompatError("Clique vote expiry", c.Clique.VoteExpiryBlock, newcfg.Clique.VoteExpiryBlock)
+		}
+	}
 	if isForkTimestampIncompatible(c.ShanghaiTime, newcfg.ShanghaiTime, headTimestamp) {
 		return newTimestampCompatError("Shanghai fork timestamp", c.ShanghaiTime, newcfg.ShanghaiTime)
 	}
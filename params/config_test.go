@@ -93,6 +93,34 @@ func TestCheckCompatible(t *testing.T) {
 				RewindToBlock: 30,
 			},
 		},
+		{
+			stored:    &ChainConfig{Clique: &CliqueConfig{Epoch: 30000}},
+			new:       &ChainConfig{Clique: &CliqueConfig{Epoch: 30000, VoteExpiry: 10, VoteExpiryBlock: big.NewInt(50)}},
+			headBlock: 40,
+			wantErr:   nil,
+		},
+		{
+			stored:    &ChainConfig{Clique: &CliqueConfig{Epoch: 30000}},
+			new:       &ChainConfig{Clique: &CliqueConfig{Epoch: 30000, VoteExpiry: 10, VoteExpiryBlock: big.NewInt(30)}},
+			headBlock: 40,
+			wantErr: &ConfigCompatError{
+				What:          "Clique vote expiry block",
+				StoredBlock:   nil,
+				NewBlock:      big.NewInt(30),
+				RewindToBlock: 29,
+			},
+		},
+		{
+			stored:    &ChainConfig{Clique: &CliqueConfig{Epoch: 30000, VoteExpiry: 10, VoteExpiryBlock: big.NewInt(30)}},
+			new:       &ChainConfig{Clique: &CliqueConfig{Epoch: 30000, VoteExpiry: 20, VoteExpiryBlock: big.NewInt(30)}},
+			headBlock: 40,
+			wantErr: &ConfigCompatError{
+				What:          "Clique vote expiry",
+				StoredBlock:   big.NewInt(30),
+				NewBlock:      big.NewInt(30),
+				RewindToBlock: 29,
+			},
+		},
 		{
 			stored:        &ChainConfig{ShanghaiTime: newUint64(10)},
 			new:           &ChainConfig{ShanghaiTime: newUint64(20)},
@@ -46,7 +46,7 @@ const (
 	// ExternalAPIVersion -- see extapi_changelog.md
 	ExternalAPIVersion = "6.1.0"
 	// InternalAPIVersion -- see intapi_changelog.md
-	InternalAPIVersion = "7.0.1"
+	InternalAPIVersion = "7.1.0"
 )
 
 // ExternalAPI defines the external API through which signing requests are made.
@@ -98,6 +98,14 @@ type UIClientAPI interface {
 	RegisterUIServer(api *UIServerAPI)
 }
 
+// SignTxFailureListener is an optional interface of UIClientAPI implementations,
+// which need to know when a transaction they approved could not be signed.
+type SignTxFailureListener interface {
+	// OnSignTxFailed notifies the UI about an approved transaction which failed
+	// to be signed.
+	OnSignTxFailed(request *SignTxRequest, err error)
+}
+
 // Validator defines the methods required to validate a transaction against some
 // sanity defaults as well as any underlying 4byte method database.
 //
@@ -243,6 +251,9 @@ type (
 		Callinfo    []apitypes.ValidationInfo `json:"call_info"`
 		Hash        hexutil.Bytes             `json:"hash"`
 		Meta        Metadata                  `json:"meta"`
+
+		// Domain is the EIP-712 domain, set for typed data requests only.
+		Domain *apitypes.TypedDataDomain `json:"domain,omitempty"`
 	}
 	SignDataResponse struct {
 		Approved bool `json:"approved"`
@@ -541,11 +552,8 @@ func (api *SignerAPI) lookupOrQueryPassword(address common.Address, title, promp
 }
 
 // SignTransaction signs the given Transaction and returns it both as json and rlp-encoded form
-func (api *SignerAPI) SignTransaction(ctx context.Context, args apitypes.SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error) {
-	var (
-		err    error
-		result SignTxResponse
-	)
+func (api *SignerAPI) SignTransaction(ctx context.Context, args apitypes.SendTxArgs, methodSelector *string) (_ *ethapi.SignTransactionResult, err error) {
+	var result SignTxResponse
 	msgs, err := api.validator.ValidateTransaction(methodSelector, &args)
 	if err != nil {
 		return nil, err
@@ -578,6 +586,14 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args apitypes.SendTxA
 	if !result.Approved {
 		return nil, ErrRequestDenied
 	}
+	// Let the UI know if the approved transaction can't be signed
+	if listener, ok := api.UI.(SignTxFailureListener); ok {
+		defer func() {
+			if err != nil {
+				listener.OnSignTxFailed(&req, err)
+			}
+		}()
+	}
 	// Log changes made by the UI to the signing-request
 	logDiff(&req, &result)
 	var (
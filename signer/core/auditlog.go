@@ -114,6 +114,17 @@ func (l *AuditLogger) Version(ctx context.Context) (string, error) {
 	return data, err
 }
 
+// ApprovalAuditor records the decisions of approval backends which involve
+// external parties, such as the votes on requests in the quorum UI.
+type ApprovalAuditor interface {
+	AuditApproval(event string, id string, ctx ...interface{})
+}
+
+// AuditApproval records an approval event for the request with the given id.
+func (l *AuditLogger) AuditApproval(event string, id string, ctx ...interface{}) {
+	l.log.Info("Approval", append([]interface{}{"type", event, "id", id}, ctx...)...)
+}
+
 func NewAuditLogger(path string, api ExternalAPI) (*AuditLogger, error) {
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
@@ -0,0 +1,361 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errQuorumTimeout    = errors.New("quorum approval timed out")
+	errUnknownRequest   = errors.New("unknown or expired request")
+	errNotApprover      = errors.New("signer is not an approver")
+	errAlreadyVoted     = errors.New("approver has already voted")
+	errInvalidSignature = errors.New("invalid signature")
+)
+
+// QuorumConfig configures the quorum UI.
+type QuorumConfig struct {
+	Approvers []common.Address // accounts allowed to vote on requests
+	Threshold int              // number of approvals required
+	Timeout   time.Duration    // time after which pending requests are rejected
+}
+
+func (cfg *QuorumConfig) validate() error {
+	if len(cfg.Approvers) == 0 {
+		return errors.New("no approvers configured")
+	}
+	seen := make(map[common.Address]bool)
+	for _, a := range cfg.Approvers {
+		if seen[a] {
+			return fmt.Errorf("duplicate approver %v", a)
+		}
+		seen[a] = true
+	}
+	if cfg.Threshold < 1 || cfg.Threshold > len(cfg.Approvers) {
+		return fmt.Errorf("invalid threshold %d for %d approvers", cfg.Threshold, len(cfg.Approvers))
+	}
+	if cfg.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+// QuorumUI is an implementation of UIClientAPI which releases requests once M of N
+// approvers have signed off on them. Pending requests are served over the quorum API,
+// and approvers vote by signing the request hash with their own keys. Interactions
+// which are not approvals, like password input, are forwarded to the next UI.
+type QuorumUI struct {
+	next  UIClientAPI
+	cfg   QuorumConfig
+	feed  event.Feed
+	audit ApprovalAuditor
+
+	mu      sync.Mutex
+	pending map[string]*quorumRequest
+}
+
+// QuorumRequest is a request awaiting approval, as served by the quorum API.
+type QuorumRequest struct {
+	ID         string           `json:"id"`
+	Method     string           `json:"method"`
+	Request    json.RawMessage  `json:"request"`
+	Hash       common.Hash      `json:"hash"`
+	Expires    time.Time        `json:"expires"`
+	Approvals  []common.Address `json:"approvals"`
+	Rejections []common.Address `json:"rejections"`
+}
+
+type quorumRequest struct {
+	QuorumRequest
+	done chan bool // receives the outcome
+}
+
+// NewQuorumUI creates a quorum UI in front of next.
+func NewQuorumUI(next UIClientAPI, cfg QuorumConfig) (*QuorumUI, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &QuorumUI{next: next, cfg: cfg, pending: make(map[string]*quorumRequest)}, nil
+}
+
+// SetAuditor configures where votes and outcomes are recorded.
+func (ui *QuorumUI) SetAuditor(a ApprovalAuditor) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.audit = a
+}
+
+// APIs returns the quorum API, which is used by approvers.
+func (ui *QuorumUI) APIs() []rpc.API {
+	return []rpc.API{{Namespace: "quorum", Service: &QuorumAPI{ui}}}
+}
+
+// QuorumVoteHash returns the hash which an approver signs to vote on a request. It is
+// the EIP-191 hash of the text "clef quorum <approve|reject> <id> <request hash>",
+// which can be signed with any tool that supports personal_sign.
+func QuorumVoteHash(id string, hash common.Hash, approve bool) []byte {
+	return accounts.TextHash([]byte(quorumVoteText(id, hash, approve)))
+}
+
+func quorumVoteText(id string, hash common.Hash, approve bool) string {
+	vote := "reject"
+	if approve {
+		vote = "approve"
+	}
+	return fmt.Sprintf("clef quorum %s %s %s", vote, id, hash.Hex())
+}
+
+func (ui *QuorumUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	approved, err := ui.submit("ApproveTx", request)
+	if err != nil || !approved {
+		return SignTxResponse{Approved: false}, err
+	}
+	return SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+}
+
+func (ui *QuorumUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	approved, err := ui.submit("ApproveSignData", request)
+	return SignDataResponse{Approved: approved}, err
+}
+
+func (ui *QuorumUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	approved, err := ui.submit("ApproveListing", request)
+	if err != nil || !approved {
+		return ListResponse{}, err
+	}
+	return ListResponse{Accounts: request.Accounts}, nil
+}
+
+// submit posts a request for approval and waits for the outcome.
+func (ui *QuorumUI) submit(method string, request interface{}) (bool, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return false, err
+	}
+	var id [16]byte
+	rand.Read(id[:])
+	req := &quorumRequest{
+		QuorumRequest: QuorumRequest{
+			ID:         hexutil.Encode(id[:]),
+			Method:     method,
+			Request:    data,
+			Hash:       crypto.Keccak256Hash(data),
+			Expires:    time.Now().Add(ui.cfg.Timeout),
+			Approvals:  []common.Address{},
+			Rejections: []common.Address{},
+		},
+		done: make(chan bool, 1),
+	}
+	ui.mu.Lock()
+	ui.pending[req.ID] = req
+	ui.auditLocked("submitted", req.ID, "method", method, "hash", req.Hash)
+	view := req.QuorumRequest
+	ui.mu.Unlock()
+
+	log.Info("Request awaiting quorum approval", "id", req.ID, "method", method, "hash", req.Hash)
+	ui.feed.Send(view)
+
+	timer := time.NewTimer(ui.cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case approved := <-req.done:
+		return approved, nil
+	case <-timer.C:
+		ui.mu.Lock()
+		defer ui.mu.Unlock()
+		// The request may have been decided just before the timer fired.
+		select {
+		case approved := <-req.done:
+			return approved, nil
+		default:
+		}
+		delete(ui.pending, req.ID)
+		ui.auditLocked("expired", req.ID)
+		return false, errQuorumTimeout
+	}
+}
+
+// vote records the vote of an approver.
+func (ui *QuorumUI) vote(id string, approve bool, sig hexutil.Bytes) error {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	req := ui.pending[id]
+	if req == nil {
+		return errUnknownRequest
+	}
+	voter, err := recoverVoter(QuorumVoteHash(id, req.Hash, approve), sig)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(ui.cfg.Approvers, voter) {
+		return errNotApprover
+	}
+	if slices.Contains(req.Approvals, voter) || slices.Contains(req.Rejections, voter) {
+		return errAlreadyVoted
+	}
+	if approve {
+		req.Approvals = append(req.Approvals, voter)
+		ui.auditLocked("approved", id, "approver", voter, "approvals", len(req.Approvals))
+	} else {
+		req.Rejections = append(req.Rejections, voter)
+		ui.auditLocked("rejected", id, "approver", voter, "rejections", len(req.Rejections))
+	}
+	// The request is decided once enough approvals are collected, or when so many
+	// approvers have rejected it that the threshold can no longer be reached.
+	switch {
+	case len(req.Approvals) >= ui.cfg.Threshold:
+		ui.auditLocked("released", id, "approvers", req.Approvals)
+		req.done <- true
+	case len(req.Rejections) > len(ui.cfg.Approvers)-ui.cfg.Threshold:
+		ui.auditLocked("denied", id, "rejecters", req.Rejections)
+		req.done <- false
+	default:
+		return nil
+	}
+	delete(ui.pending, id)
+	return nil
+}
+
+func recoverVoter(hash []byte, sig hexutil.Bytes) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errInvalidSignature
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, errInvalidSignature
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+func (ui *QuorumUI) auditLocked(event, id string, ctx ...interface{}) {
+	if ui.audit != nil {
+		ui.audit.AuditApproval(event, id, ctx...)
+	}
+}
+
+// pendingRequests returns the requests awaiting approval, oldest first.
+func (ui *QuorumUI) pendingRequests() []QuorumRequest {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	list := make([]QuorumRequest, 0, len(ui.pending))
+	for _, req := range ui.pending {
+		r := req.QuorumRequest
+		r.Approvals = slices.Clone(r.Approvals)
+		r.Rejections = slices.Clone(r.Rejections)
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Expires.Before(list[j].Expires) })
+	return list
+}
+
+// ApproveNewAccount is not handled by the quorum, since it requires setting a password.
+func (ui *QuorumUI) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	return ui.next.ApproveNewAccount(request)
+}
+
+func (ui *QuorumUI) ShowError(message string) {
+	ui.next.ShowError(message)
+}
+
+func (ui *QuorumUI) ShowInfo(message string) {
+	ui.next.ShowInfo(message)
+}
+
+func (ui *QuorumUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	ui.next.OnApprovedTx(tx)
+}
+
+func (ui *QuorumUI) OnSignerStartup(info StartupInfo) {
+	ui.next.OnSignerStartup(info)
+}
+
+func (ui *QuorumUI) OnInputRequired(info UserInputRequest) (UserInputResponse, error) {
+	return ui.next.OnInputRequired(info)
+}
+
+func (ui *QuorumUI) RegisterUIServer(api *UIServerAPI) {
+	ui.next.RegisterUIServer(api)
+}
+
+// QuorumAPI is the API used by approvers to list and vote on pending requests.
+type QuorumAPI struct {
+	ui *QuorumUI
+}
+
+// Pending returns the requests awaiting approval.
+func (api *QuorumAPI) Pending() []QuorumRequest {
+	return api.ui.pendingRequests()
+}
+
+// Approve records an approval. The signature must be made over QuorumVoteHash.
+func (api *QuorumAPI) Approve(id string, sig hexutil.Bytes) error {
+	return api.ui.vote(id, true, sig)
+}
+
+// Reject records a rejection. The signature must be made over QuorumVoteHash.
+func (api *QuorumAPI) Reject(id string, sig hexutil.Bytes) error {
+	return api.ui.vote(id, false, sig)
+}
+
+// Requests creates a subscription which is notified of new requests.
+func (api *QuorumAPI) Requests(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	var (
+		sub = notifier.CreateSubscription()
+		ch  = make(chan QuorumRequest, 16)
+		s   = api.ui.feed.Subscribe(ch)
+	)
+	go func() {
+		defer s.Unsubscribe()
+		for {
+			select {
+			case req := <-ch:
+				notifier.Notify(sub.ID, req)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
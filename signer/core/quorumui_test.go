@@ -0,0 +1,275 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+type testAuditor struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (a *testAuditor) AuditApproval(event string, id string, ctx ...interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event)
+}
+
+func (a *testAuditor) String() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return strings.Join(a.events, ",")
+}
+
+type quorumTest struct {
+	ui      *QuorumUI
+	keys    []*ecdsa.PrivateKey
+	client  *rpc.Client
+	audit   *testAuditor
+	newReqs chan QuorumRequest
+}
+
+func newQuorumTest(t *testing.T, n, m int, timeout time.Duration) *quorumTest {
+	qt := &quorumTest{audit: new(testAuditor), newReqs: make(chan QuorumRequest, 10)}
+	cfg := QuorumConfig{Threshold: m, Timeout: timeout}
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		qt.keys = append(qt.keys, key)
+		cfg.Approvers = append(cfg.Approvers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	ui, err := NewQuorumUI(NewCommandlineUI(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui.SetAuditor(qt.audit)
+	qt.ui = ui
+
+	srv := rpc.NewServer()
+	for _, api := range ui.APIs() {
+		srv.RegisterName(api.Namespace, api.Service)
+	}
+	qt.client = rpc.DialInProc(srv)
+	sub, err := qt.client.Subscribe(context.Background(), "quorum", qt.newReqs, "requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sub.Unsubscribe()
+		qt.client.Close()
+		srv.Stop()
+	})
+	return qt
+}
+
+func (qt *quorumTest) vote(req QuorumRequest, approver int, approve bool) error {
+	sig, _ := crypto.Sign(QuorumVoteHash(req.ID, req.Hash, approve), qt.keys[approver])
+	method := "quorum_reject"
+	if approve {
+		method = "quorum_approve"
+	}
+	return qt.client.Call(nil, method, req.ID, hexutil.Bytes(sig))
+}
+
+// approveTx runs ApproveTx in the background and returns the announced request.
+func (qt *quorumTest) approveTx(t *testing.T) (QuorumRequest, chan error) {
+	to := common.NewMixedcaseAddress(common.HexToAddress("0x1337"))
+	req := &SignTxRequest{Transaction: apitypes.SendTxArgs{To: &to}}
+	result := make(chan error, 1)
+	go func() {
+		resp, err := qt.ui.ApproveTx(req)
+		if err == nil && !resp.Approved {
+			err = ErrRequestDenied
+		}
+		result <- err
+	}()
+	select {
+	case r := <-qt.newReqs:
+		if r.Hash != crypto.Keccak256Hash(r.Request) {
+			t.Fatal("request hash mismatch")
+		}
+		return r, result
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not announced")
+		return QuorumRequest{}, nil
+	}
+}
+
+func waitResult(t *testing.T, result chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not decided")
+		return nil
+	}
+}
+
+func TestQuorumApprove(t *testing.T) {
+	t.Parallel()
+	qt := newQuorumTest(t, 3, 2, time.Minute)
+	req, result := qt.approveTx(t)
+
+	if err := qt.vote(req, 0, true); err != nil {
+		t.Fatal("first approval failed:", err)
+	}
+	if err := qt.vote(req, 0, true); err == nil || err.Error() != errAlreadyVoted.Error() {
+		t.Fatalf("wrong error for double vote: %v", err)
+	}
+	var pending []QuorumRequest
+	if err := qt.client.Call(&pending, "quorum_pending"); err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || len(pending[0].Approvals) != 1 || pending[0].Approvals[0] != qt.ui.cfg.Approvers[0] {
+		t.Fatalf("wrong pending requests %+v", pending)
+	}
+	select {
+	case err := <-result:
+		t.Fatal("request decided after one approval:", err)
+	default:
+	}
+	if err := qt.vote(req, 2, true); err != nil {
+		t.Fatal("second approval failed:", err)
+	}
+	if err := waitResult(t, result); err != nil {
+		t.Fatal("request not approved:", err)
+	}
+	if err := qt.vote(req, 1, true); err == nil || err.Error() != errUnknownRequest.Error() {
+		t.Fatalf("wrong error for vote on decided request: %v", err)
+	}
+	if want := "submitted,approved,approved,released"; qt.audit.String() != want {
+		t.Fatalf("wrong audit trail %q, want %q", qt.audit, want)
+	}
+}
+
+func TestQuorumReject(t *testing.T) {
+	t.Parallel()
+	qt := newQuorumTest(t, 3, 2, time.Minute)
+	req, result := qt.approveTx(t)
+
+	// With 2 of 3 required, two rejections make approval impossible.
+	if err := qt.vote(req, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := qt.vote(req, 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := qt.vote(req, 2, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitResult(t, result); !errors.Is(err, ErrRequestDenied) {
+		t.Fatalf("wrong result %v", err)
+	}
+	if want := "submitted,rejected,approved,rejected,denied"; qt.audit.String() != want {
+		t.Fatalf("wrong audit trail %q, want %q", qt.audit, want)
+	}
+}
+
+func TestQuorumInvalidVotes(t *testing.T) {
+	t.Parallel()
+	qt := newQuorumTest(t, 2, 1, time.Minute)
+	req, result := qt.approveTx(t)
+
+	// Signature by a key which is not an approver.
+	outsider, _ := crypto.GenerateKey()
+	sig, _ := crypto.Sign(QuorumVoteHash(req.ID, req.Hash, true), outsider)
+	err := qt.client.Call(nil, "quorum_approve", req.ID, hexutil.Bytes(sig))
+	if err == nil || err.Error() != errNotApprover.Error() {
+		t.Fatalf("wrong error for outsider vote: %v", err)
+	}
+	// Signature over the rejection used as an approval recovers a different
+	// address, so it can't be replayed.
+	sig, _ = crypto.Sign(QuorumVoteHash(req.ID, req.Hash, false), qt.keys[0])
+	err = qt.client.Call(nil, "quorum_approve", req.ID, hexutil.Bytes(sig))
+	if err == nil || err.Error() != errNotApprover.Error() {
+		t.Fatalf("wrong error for mismatched vote: %v", err)
+	}
+	err = qt.client.Call(nil, "quorum_approve", req.ID, hexutil.Bytes{1, 2, 3})
+	if err == nil || err.Error() != errInvalidSignature.Error() {
+		t.Fatalf("wrong error for malformed signature: %v", err)
+	}
+	// Signatures with V in 27/28 form are accepted.
+	sig, _ = crypto.Sign(QuorumVoteHash(req.ID, req.Hash, true), qt.keys[1])
+	sig[64] += 27
+	if err := qt.client.Call(nil, "quorum_approve", req.ID, hexutil.Bytes(sig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitResult(t, result); err != nil {
+		t.Fatal("request not approved:", err)
+	}
+}
+
+func TestQuorumTimeout(t *testing.T) {
+	t.Parallel()
+	qt := newQuorumTest(t, 2, 2, 100*time.Millisecond)
+	req, result := qt.approveTx(t)
+
+	if err := qt.vote(req, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitResult(t, result); !errors.Is(err, errQuorumTimeout) {
+		t.Fatalf("wrong result %v", err)
+	}
+	if len(qt.ui.pendingRequests()) != 0 {
+		t.Fatal("expired request still pending")
+	}
+	if want := "submitted,approved,expired"; qt.audit.String() != want {
+		t.Fatalf("wrong audit trail %q, want %q", qt.audit, want)
+	}
+}
+
+func TestQuorumConfig(t *testing.T) {
+	t.Parallel()
+	a, b := common.HexToAddress("0xa"), common.HexToAddress("0xb")
+	tests := []struct {
+		cfg QuorumConfig
+		err string
+	}{
+		{QuorumConfig{Threshold: 1, Timeout: time.Second}, "no approvers configured"},
+		{QuorumConfig{Approvers: []common.Address{a, a}, Threshold: 1, Timeout: time.Second}, "duplicate approver"},
+		{QuorumConfig{Approvers: []common.Address{a, b}, Threshold: 3, Timeout: time.Second}, "invalid threshold 3 for 2 approvers"},
+		{QuorumConfig{Approvers: []common.Address{a, b}, Threshold: 0, Timeout: time.Second}, "invalid threshold 0"},
+		{QuorumConfig{Approvers: []common.Address{a, b}, Threshold: 2}, "timeout must be positive"},
+	}
+	for _, test := range tests {
+		_, err := NewQuorumUI(NewCommandlineUI(), test.cfg)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("config %+v: got error %v, want %q", test.cfg, err, test.err)
+		}
+	}
+}
+
+func ExampleQuorumVoteHash() {
+	id := "0x00112233445566778899aabbccddeeff"
+	hash := common.HexToHash("0x01")
+	fmt.Println(quorumVoteText(id, hash, true))
+	// Output: clef quorum approve 0x00112233445566778899aabbccddeeff 0x0000000000000000000000000000000000000000000000000000000000000001
+}
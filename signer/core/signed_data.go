@@ -286,7 +286,8 @@ func typedDataRequest(data any) (*SignDataRequest, error) {
 		ContentType: apitypes.DataTyped.Mime,
 		Rawdata:     []byte(rawData),
 		Messages:    messages,
-		Hash:        sighash}, nil
+		Hash:        sighash,
+		Domain:      &typedData.Domain}, nil
 }
 
 // EcRecover recovers the address associated with the given sig.
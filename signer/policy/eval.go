@@ -0,0 +1,261 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// SelectorDB resolves 4-byte method selectors to method signatures.
+// fourbyte.Database implements this interface.
+type SelectorDB interface {
+	Selector(id []byte) (string, error)
+}
+
+// Result is the outcome of evaluating a request against the policy.
+type Result struct {
+	Action Action `json:"action"`
+	Rule   string `json:"rule,omitempty"`   // name of the approving rule
+	Reason string `json:"reason,omitempty"` // why the request was not approved
+}
+
+func (r Result) String() string {
+	switch {
+	case r.Rule != "":
+		return fmt.Sprintf("%s (rule %s)", r.Action, r.Rule)
+	case r.Reason != "":
+		return fmt.Sprintf("%s (%s)", r.Action, r.Reason)
+	default:
+		return string(r.Action)
+	}
+}
+
+// Evaluator checks requests against a policy.
+type Evaluator struct {
+	policy  *Policy
+	chainID *big.Int
+	limits  *storage.DailyCounter
+	db      SelectorDB
+}
+
+// NewEvaluator creates an evaluator. The chain ID is the one configured in the
+// signer, which applies to transactions that don't specify one. Daily limit
+// counters are kept in the given storage. The selector database is used to
+// resolve method names, and may be nil if the policy only uses selectors
+// and signatures.
+func NewEvaluator(p *Policy, chainID *big.Int, counters storage.Storage, db SelectorDB) *Evaluator {
+	return &Evaluator{
+		policy:  p,
+		chainID: chainID,
+		limits:  storage.NewDailyCounter(counters, "policy-limit/"),
+		db:      db,
+	}
+}
+
+// EvalTx checks a transaction against the policy. If it is approved by a rule with
+// a daily limit, the value is reserved from the limit of the rule. The reservation
+// must be released if the transaction is not signed after all, see ReleaseTx.
+func (e *Evaluator) EvalTx(req *core.SignTxRequest) Result {
+	tx := &req.Transaction
+	chainID := e.chainID
+	if tx.ChainID != nil {
+		chainID = tx.ChainID.ToInt()
+	}
+	if !e.chainAllowed(chainID) {
+		return Result{Action: Reject, Reason: fmt.Sprintf("chain ID %v not allowed", chainID)}
+	}
+	var (
+		from    = tx.From.Address()
+		value   = tx.Value.ToInt()
+		data    = txData(tx)
+		reasons []string
+	)
+	for i, r := range e.policy.Transactions {
+		if reason := e.matchTx(r, from, tx.To, value, data); reason != "" {
+			if len(e.policy.Transactions) == 1 {
+				reasons = append(reasons, reason)
+			} else {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", r.name(i), reason))
+			}
+			continue
+		}
+		if r.DailyLimit != nil {
+			key := r.Name + "/" + from.Hex()
+			if !e.limits.Add(key, value, r.DailyLimit.Int()) {
+				reasons = append(reasons, fmt.Sprintf("%s: daily limit exceeded", r.name(i)))
+				continue
+			}
+		}
+		return Result{Action: Approve, Rule: r.name(i)}
+	}
+	return e.fallback(reasons, "no transaction rule")
+}
+
+// ReleaseTx gives back the value of an approved transaction which was not signed
+// to the daily limit of the rule which approved it. Rules without a daily limit
+// are ignored.
+func (e *Evaluator) ReleaseTx(rule string, from common.Address, value *big.Int) {
+	for _, r := range e.policy.Transactions {
+		if r.Name == rule && r.DailyLimit != nil {
+			e.limits.Sub(r.Name+"/"+from.Hex(), value)
+			return
+		}
+	}
+}
+
+// matchTx checks whether the rule matches a transaction. It returns the reason
+// if the rule doesn't match.
+func (e *Evaluator) matchTx(r *TxRule, from common.Address, to *common.MixedcaseAddress, value *big.Int, data []byte) string {
+	if len(r.From) > 0 && !slices.Contains(r.From, from) {
+		return "sender not allowed"
+	}
+	if to == nil {
+		if !r.AllowContractCreation {
+			return "contract creation not allowed"
+		}
+	} else if len(r.To) > 0 && !slices.Contains(r.To, to.Address()) {
+		return "recipient not allowed"
+	}
+	if r.MaxValue != nil && value.Cmp(r.MaxValue.Int()) > 0 {
+		return "value exceeds maximum"
+	}
+	if to != nil && len(data) > 0 && !e.methodAllowed(r, data) {
+		return "method not allowed"
+	}
+	return ""
+}
+
+func (e *Evaluator) methodAllowed(r *TxRule, data []byte) bool {
+	if r.anyMethod {
+		return true
+	}
+	if len(data) < 4 {
+		return false
+	}
+	sel := [4]byte(data[:4])
+	if slices.Contains(r.selectors, sel) {
+		return true
+	}
+	if len(r.names) == 0 || e.db == nil {
+		return false
+	}
+	sig, err := e.db.Selector(sel[:])
+	if err != nil {
+		return false
+	}
+	name, _, _ := strings.Cut(sig, "(")
+	return slices.Contains(r.names, name)
+}
+
+// EvalSignData checks a data signing request against the policy.
+func (e *Evaluator) EvalSignData(req *core.SignDataRequest) Result {
+	if req.Domain != nil && req.Domain.ChainId != nil {
+		chainID := (*big.Int)(req.Domain.ChainId)
+		if !e.chainAllowed(chainID) {
+			return Result{Action: Reject, Reason: fmt.Sprintf("EIP-712 domain chain ID %v not allowed", chainID)}
+		}
+	}
+	from := req.Address.Address()
+	for i, r := range e.policy.SignData {
+		if len(r.From) > 0 && !slices.Contains(r.From, from) {
+			continue
+		}
+		if len(r.ContentTypes) > 0 && !slices.Contains(r.ContentTypes, req.ContentType) {
+			continue
+		}
+		if len(r.EIP712Domains) > 0 && !domainAllowed(r.EIP712Domains, req.Domain) {
+			continue
+		}
+		return Result{Action: Approve, Rule: r.name(i)}
+	}
+	return e.fallback(nil, "no signData rule")
+}
+
+func domainAllowed(allowed []*Domain, d *apitypes.TypedDataDomain) bool {
+	if d == nil {
+		return false
+	}
+	for _, a := range allowed {
+		if a.matches(d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Domain) matches(d *apitypes.TypedDataDomain) bool {
+	if a.Name != nil && *a.Name != d.Name {
+		return false
+	}
+	if a.Version != nil && *a.Version != d.Version {
+		return false
+	}
+	if a.ChainID != nil && (d.ChainId == nil || a.ChainID.Int().Cmp((*big.Int)(d.ChainId)) != 0) {
+		return false
+	}
+	if a.VerifyingContract != nil {
+		if !common.IsHexAddress(d.VerifyingContract) || common.HexToAddress(d.VerifyingContract) != *a.VerifyingContract {
+			return false
+		}
+	}
+	if a.Salt != nil && !strings.EqualFold(*a.Salt, d.Salt) {
+		return false
+	}
+	return true
+}
+
+// EvalListing checks an account listing request against the policy.
+func (e *Evaluator) EvalListing(req *core.ListRequest) Result {
+	return Result{Action: e.policy.Listing}
+}
+
+func (e *Evaluator) chainAllowed(id *big.Int) bool {
+	if len(e.policy.ChainIDs) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(e.policy.ChainIDs, func(a *Amount) bool {
+		return a.Int().Cmp(id) == 0
+	})
+}
+
+func (e *Evaluator) fallback(reasons []string, norule string) Result {
+	res := Result{Action: e.policy.Default, Reason: norule}
+	if len(reasons) > 0 {
+		res.Reason = strings.Join(reasons, "; ")
+	}
+	return res
+}
+
+// txData returns the calldata of a transaction. Like the signer, it
+// prefers the input field over the data field.
+func txData(tx *apitypes.SendTxArgs) []byte {
+	if tx.Input != nil {
+		return *tx.Input
+	}
+	if tx.Data != nil {
+		return *tx.Data
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package policy implements declarative approval policies for clef.
+//
+// A policy is a YAML (or JSON) document which lists the transactions and signing
+// requests that may be approved automatically. Unlike the JavaScript rules of
+// package signer/rules, a policy cannot run code, which makes it easy to review.
+// Requests which are not covered by the policy are either rejected or passed on
+// to the next UI, depending on the default action of the policy.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is the outcome of a policy evaluation.
+type Action string
+
+const (
+	Approve Action = "approve" // approve the request without asking
+	Reject  Action = "reject"  // reject the request without asking
+	Manual  Action = "manual"  // pass the request on to the next UI
+)
+
+func (a *Action) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	switch act := Action(strings.ToLower(s)); act {
+	case Approve, Reject, Manual:
+		*a = act
+		return nil
+	default:
+		return fmt.Errorf("line %d: invalid action %q", node.Line, s)
+	}
+}
+
+// Policy is a declarative approval policy.
+type Policy struct {
+	// Default is the action for requests which are not approved by any rule.
+	// It must be "reject" or "manual". When it is "manual", the policy can be
+	// combined with a JavaScript ruleset, which then sees the remaining requests.
+	Default Action `yaml:"default"`
+
+	// ChainIDs restricts transactions and EIP-712 domains to the given chains.
+	// Requests for other chains are always rejected.
+	ChainIDs []*Amount `yaml:"chainIds"`
+
+	// Listing is the action for account listing requests. If unset,
+	// the default action applies.
+	Listing Action `yaml:"listing"`
+
+	Transactions []*TxRule       `yaml:"transactions"`
+	SignData     []*SignDataRule `yaml:"signData"`
+}
+
+// TxRule approves transactions matching all of its conditions.
+type TxRule struct {
+	// Name identifies the rule in logs. It is required for rules with a daily
+	// limit, because the limit counters are stored by rule name.
+	Name string `yaml:"name"`
+
+	// From and To restrict the sender and recipient. Empty lists match any address.
+	From []common.Address `yaml:"from"`
+	To   []common.Address `yaml:"to"`
+
+	// MaxValue is the maximum value of a single transaction.
+	MaxValue *Amount `yaml:"maxValue"`
+
+	// DailyLimit is the maximum total value approved by the rule per sender and day.
+	DailyLimit *Amount `yaml:"dailyLimit"`
+
+	// Methods lists the contract methods which may be called. Entries are either
+	// 4-byte selectors (0xa9059cbb), method signatures (transfer(address,uint256)),
+	// or method names (transfer), which are resolved through the 4byte database.
+	// The entry "*" allows any calldata. If no methods are given, only transactions
+	// without calldata match.
+	Methods []string `yaml:"methods"`
+
+	// AllowContractCreation must be set for the rule to match contract creations.
+	AllowContractCreation bool `yaml:"allowContractCreation"`
+
+	selectors [][4]byte
+	names     []string
+	anyMethod bool
+}
+
+// SignDataRule approves data signing requests matching all of its conditions.
+type SignDataRule struct {
+	Name string `yaml:"name"`
+
+	// From restricts the signing account. An empty list matches any account.
+	From []common.Address `yaml:"from"`
+
+	// ContentTypes restricts the type of data, e.g. "text/plain" or "data/typed".
+	// An empty list matches any type.
+	ContentTypes []string `yaml:"contentTypes"`
+
+	// EIP712Domains restricts typed data to the given domains. If set, requests
+	// which are not EIP-712 typed data never match the rule.
+	EIP712Domains []*Domain `yaml:"eip712Domains"`
+}
+
+// Domain matches an EIP-712 domain. Only the fields which are set are compared.
+type Domain struct {
+	Name              *string         `yaml:"name"`
+	Version           *string         `yaml:"version"`
+	ChainID           *Amount         `yaml:"chainId"`
+	VerifyingContract *common.Address `yaml:"verifyingContract"`
+	Salt              *string         `yaml:"salt"`
+}
+
+// Amount is an integer which can be written in decimal or hex, optionally
+// followed by a unit: wei, gwei or ether (e.g. "1.5 ether").
+type Amount big.Int
+
+var units = map[string]*big.Int{
+	"wei":   big.NewInt(1),
+	"gwei":  big.NewInt(1e9),
+	"ether": big.NewInt(1e18),
+}
+
+func (a *Amount) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	v, err := parseAmount(s)
+	if err != nil {
+		return fmt.Errorf("line %d: %v", node.Line, err)
+	}
+	*a = Amount(*v)
+	return nil
+}
+
+func (a *Amount) Int() *big.Int {
+	return (*big.Int)(a)
+}
+
+func (a *Amount) String() string {
+	return a.Int().String()
+}
+
+func parseAmount(s string) (*big.Int, error) {
+	num, unit, _ := strings.Cut(strings.TrimSpace(s), " ")
+	if unit = strings.TrimSpace(unit); unit == "" {
+		if v, ok := new(big.Int).SetString(num, 0); ok && v.Sign() >= 0 {
+			return v, nil
+		}
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	mul, ok := units[strings.ToLower(unit)]
+	if !ok {
+		return nil, fmt.Errorf("invalid unit %q", unit)
+	}
+	r, ok := new(big.Rat).SetString(num)
+	if !ok || r.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(mul))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("amount %q is not a whole number of wei", s)
+	}
+	return r.Num(), nil
+}
+
+// Load reads a policy from a file.
+func Load(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a policy. Since JSON is a subset of YAML, the
+// policy can be given in either format.
+func Parse(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	p := new(Policy)
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+	if err := p.init(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+	return p, nil
+}
+
+func (p *Policy) init() error {
+	switch p.Default {
+	case "":
+		return errors.New("missing default action")
+	case Approve:
+		return errors.New("default action must be reject or manual")
+	}
+	if p.Listing == "" {
+		p.Listing = p.Default
+	}
+	names := make(map[string]bool)
+	for i, r := range p.Transactions {
+		if r.Name == "" && r.DailyLimit != nil {
+			return fmt.Errorf("transaction rule %d: daily limit requires a name", i)
+		}
+		if r.Name != "" {
+			if names[r.Name] {
+				return fmt.Errorf("duplicate transaction rule name %q", r.Name)
+			}
+			names[r.Name] = true
+		}
+		for _, m := range r.Methods {
+			if err := r.addMethod(m); err != nil {
+				return fmt.Errorf("transaction rule %s: %v", r.name(i), err)
+			}
+		}
+	}
+	for i, r := range p.SignData {
+		for _, d := range r.EIP712Domains {
+			if d == nil {
+				return fmt.Errorf("signData rule %s: empty EIP-712 domain", r.name(i))
+			}
+		}
+	}
+	return nil
+}
+
+func (r *TxRule) name(index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+func (r *SignDataRule) name(index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+func (r *TxRule) addMethod(m string) error {
+	switch {
+	case m == "*":
+		r.anyMethod = true
+	case strings.HasPrefix(m, "0x"):
+		sel, err := hexutil.Decode(m)
+		if err != nil || len(sel) != 4 {
+			return fmt.Errorf("invalid selector %q", m)
+		}
+		r.selectors = append(r.selectors, [4]byte(sel))
+	case strings.Contains(m, "("):
+		if !strings.HasSuffix(m, ")") || strings.ContainsAny(m, " ") {
+			return fmt.Errorf("invalid method signature %q", m)
+		}
+		r.selectors = append(r.selectors, [4]byte(crypto.Keccak256([]byte(m))[:4]))
+	case m == "":
+		return errors.New("empty method")
+	default:
+		r.names = append(r.names, m)
+	}
+	return nil
+}
@@ -0,0 +1,497 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+const testPolicy = `
+default: reject
+chainIds: [1, "0x5"]
+listing: approve
+transactions:
+  - name: payroll
+    from: ["0x000000000000000000000000000000000000aaaa"]
+    to: ["0x000000000000000000000000000000000000bbbb", "0x000000000000000000000000000000000000cccc"]
+    maxValue: 1 ether
+    dailyLimit: 2.5 ether
+  - name: tokens
+    to: ["0x000000000000000000000000000000000000dddd"]
+    maxValue: 0
+    methods: ["transfer(address,uint256)", "0x095ea7b3", "transferFrom"]
+  - name: deploy
+    from: ["0x000000000000000000000000000000000000ffff"]
+    allowContractCreation: true
+signData:
+  - name: permit
+    contentTypes: ["data/typed"]
+    eip712Domains:
+      - name: Permit2
+        chainId: 1
+        verifyingContract: "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+  - name: text
+    from: ["0x000000000000000000000000000000000000aaaa"]
+    contentTypes: ["text/plain"]
+`
+
+var (
+	addrA = common.HexToAddress("0xaaaa")
+	addrB = common.HexToAddress("0xbbbb")
+	addrC = common.HexToAddress("0xcccc")
+	addrD = common.HexToAddress("0xdddd")
+	addrE = common.HexToAddress("0xeeee")
+	addrF = common.HexToAddress("0xffff")
+
+	permit2 = "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+)
+
+// testSelectors is a fake 4byte database.
+type testSelectors map[[4]byte]string
+
+func (db testSelectors) Selector(id []byte) (string, error) {
+	if sig, ok := db[[4]byte(id)]; ok {
+		return sig, nil
+	}
+	return "", errors.New("not found")
+}
+
+func newTestEvaluator(t *testing.T, policy string) *Evaluator {
+	p, err := Parse([]byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := testSelectors{
+		[4]byte(hexutil.MustDecode("0x23b872dd")): "transferFrom(address,address,uint256)",
+	}
+	return NewEvaluator(p, big.NewInt(1), storage.NewEphemeralStorage(), db)
+}
+
+func ether(n float64) *big.Int {
+	v, _ := new(big.Float).Mul(big.NewFloat(n), big.NewFloat(1e18)).Int(nil)
+	return v
+}
+
+func txRequest(from common.Address, to *common.Address, value *big.Int, data string) *core.SignTxRequest {
+	req := &core.SignTxRequest{
+		Transaction: apitypes.SendTxArgs{
+			From:  common.NewMixedcaseAddress(from),
+			Value: hexutil.Big(*value),
+		},
+	}
+	if to != nil {
+		mto := common.NewMixedcaseAddress(*to)
+		req.Transaction.To = &mto
+	}
+	if data != "" {
+		input := hexutil.Bytes(hexutil.MustDecode(data))
+		req.Transaction.Input = &input
+	}
+	return req
+}
+
+func TestParseAmount(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{input: "0", want: "0"},
+		{input: "1000", want: "1000"},
+		{input: "0x10", want: "16"},
+		{input: "1 ether", want: "1000000000000000000"},
+		{input: "1.5 ether", want: "1500000000000000000"},
+		{input: "30 gwei", want: "30000000000"},
+		{input: "7 wei", want: "7"},
+		{input: "1.5 wei", err: true},
+		{input: "-1", err: true},
+		{input: "1 btc", err: true},
+		{input: "abc", err: true},
+	}
+	for _, test := range tests {
+		v, err := parseAmount(test.input)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", test.input, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+		} else if v.String() != test.want {
+			t.Errorf("%q: got %v, want %s", test.input, v, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy string
+		err    string
+	}{
+		{"chainIds: [1]", "missing default action"},
+		{"default: approve", "default action must be reject or manual"},
+		{"default: maybe", "invalid action"},
+		{"default: reject\nunknown: 1", "field unknown not found"},
+		{"default: reject\ntransactions: [{dailyLimit: 1 ether}]", "daily limit requires a name"},
+		{"default: reject\ntransactions: [{name: a}, {name: a}]", "duplicate transaction rule name"},
+		{"default: reject\ntransactions: [{methods: ['0x1234']}]", "invalid selector"},
+		{"default: reject\ntransactions: [{methods: ['transfer(address, uint256)']}]", "invalid method signature"},
+		{"default: reject\ntransactions: [{to: ['0x12']}]", "hex string has length 2"},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.policy))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("policy %q: got error %v, want %q", test.policy, err, test.err)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	t.Parallel()
+	p, err := Parse([]byte(`{"default": "manual", "transactions": [{"to": ["0x000000000000000000000000000000000000bbbb"], "maxValue": "0x10"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Listing != Manual {
+		t.Errorf("wrong listing action %q", p.Listing)
+	}
+	if r := p.Transactions[0]; r.To[0] != addrB || r.MaxValue.Int().Int64() != 16 {
+		t.Errorf("wrong rule %+v", r)
+	}
+}
+
+func TestEvalTx(t *testing.T) {
+	t.Parallel()
+	e := newTestEvaluator(t, testPolicy)
+	tests := []struct {
+		name    string
+		req     *core.SignTxRequest
+		chainID int64
+		want    Result
+	}{
+		{
+			name: "payroll",
+			req:  txRequest(addrA, &addrB, ether(1), ""),
+			want: Result{Action: Approve, Rule: "payroll"},
+		},
+		{
+			name:    "wrong chain",
+			req:     txRequest(addrA, &addrB, ether(1), ""),
+			chainID: 10,
+			want:    Result{Action: Reject, Reason: "chain ID 10 not allowed"},
+		},
+		{
+			name:    "other allowed chain",
+			req:     txRequest(addrA, &addrC, ether(1), ""),
+			chainID: 5,
+			want:    Result{Action: Approve, Rule: "payroll"},
+		},
+		{
+			name: "value too high",
+			req:  txRequest(addrA, &addrB, ether(1.5), ""),
+			want: Result{Action: Reject, Reason: "payroll: value exceeds maximum; tokens: recipient not allowed; deploy: sender not allowed"},
+		},
+		{
+			name: "unknown recipient",
+			req:  txRequest(addrA, &addrE, ether(0.1), ""),
+			want: Result{Action: Reject, Reason: "payroll: recipient not allowed; tokens: recipient not allowed; deploy: sender not allowed"},
+		},
+		{
+			name: "token transfer",
+			req:  txRequest(addrE, &addrD, new(big.Int), "0xa9059cbb0000"),
+			want: Result{Action: Approve, Rule: "tokens"},
+		},
+		{
+			name: "token approve",
+			req:  txRequest(addrE, &addrD, new(big.Int), "0x095ea7b3"),
+			want: Result{Action: Approve, Rule: "tokens"},
+		},
+		{
+			name: "method name from 4byte",
+			req:  txRequest(addrE, &addrD, new(big.Int), "0x23b872dd"),
+			want: Result{Action: Approve, Rule: "tokens"},
+		},
+		{
+			name: "unknown method",
+			req:  txRequest(addrE, &addrD, new(big.Int), "0x12345678"),
+			want: Result{Action: Reject, Reason: "payroll: sender not allowed; tokens: method not allowed; deploy: sender not allowed"},
+		},
+		{
+			name: "calldata to payroll recipient",
+			req:  txRequest(addrA, &addrB, new(big.Int), "0xa9059cbb"),
+			want: Result{Action: Reject, Reason: "payroll: method not allowed; tokens: recipient not allowed; deploy: sender not allowed"},
+		},
+		{
+			name: "contract creation",
+			req:  txRequest(addrF, nil, new(big.Int), "0x6000"),
+			want: Result{Action: Approve, Rule: "deploy"},
+		},
+		{
+			name: "contract creation not allowed",
+			req:  txRequest(addrA, nil, new(big.Int), "0x6000"),
+			want: Result{Action: Reject, Reason: "payroll: contract creation not allowed; tokens: contract creation not allowed; deploy: sender not allowed"},
+		},
+	}
+	for _, test := range tests {
+		if test.chainID != 0 {
+			test.req.Transaction.ChainID = (*hexutil.Big)(big.NewInt(test.chainID))
+		}
+		if got := e.EvalTx(test.req); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEvalTxDailyLimit(t *testing.T) {
+	t.Parallel()
+	e := newTestEvaluator(t, testPolicy)
+	for i, value := range []float64{1, 1, 0.5} {
+		if res := e.EvalTx(txRequest(addrA, &addrB, ether(value), "")); res.Action != Approve {
+			t.Fatalf("tx %d not approved: %v", i, res)
+		}
+	}
+	res := e.EvalTx(txRequest(addrA, &addrC, big.NewInt(1), ""))
+	if res.Action != Reject || !strings.Contains(res.Reason, "payroll: daily limit exceeded") {
+		t.Fatalf("tx over daily limit: %v", res)
+	}
+	// Released reservations can be approved again
+	e.ReleaseTx("payroll", addrA, ether(0.5))
+	if res := e.EvalTx(txRequest(addrA, &addrB, ether(0.5), "")); res.Action != Approve {
+		t.Fatalf("tx within released limit not approved: %v", res)
+	}
+}
+
+// signedUI is a UI which ignores everything but signed transactions.
+type signedUI struct {
+	core.UIClientAPI
+	signed int
+}
+
+func (ui *signedUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	ui.signed++
+}
+
+// newLimitedUI creates a policy UI with a daily limit of 1 ether for the
+// transactions of a new key.
+func newLimitedUI(t *testing.T) (*UI, *signedUI, *ecdsa.PrivateKey) {
+	key, _ := crypto.GenerateKey()
+	p, err := Parse([]byte(fmt.Sprintf("default: reject\ntransactions:\n  - name: payroll\n    from: [%q]\n    dailyLimit: 1 ether\n", crypto.PubkeyToAddress(key.PublicKey).Hex())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := new(signedUI)
+	return NewUI(next, NewEvaluator(p, big.NewInt(1), storage.NewEphemeralStorage(), nil)), next, key
+}
+
+func limitedRequest(key *ecdsa.PrivateKey, nonce uint64, value *big.Int) *core.SignTxRequest {
+	req := txRequest(crypto.PubkeyToAddress(key.PublicKey), &addrB, value, "")
+	req.Transaction.Nonce = hexutil.Uint64(nonce)
+	return req
+}
+
+func TestUIDailyLimit(t *testing.T) {
+	t.Parallel()
+	ui, next, key := newLimitedUI(t)
+
+	approve := func(req *core.SignTxRequest) bool {
+		res, err := ui.ApproveTx(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Approved
+	}
+	// The approval reserves the limit until the signing fails
+	req := limitedRequest(key, 0, ether(1))
+	if !approve(req) {
+		t.Fatal("tx not approved")
+	}
+	if approve(limitedRequest(key, 1, big.NewInt(1))) {
+		t.Fatal("tx over reserved daily limit approved")
+	}
+	ui.OnSignTxFailed(req, errors.New("signing failed"))
+	if !approve(limitedRequest(key, 0, ether(1))) {
+		t.Fatal("tx not approved after failed signing")
+	}
+	// A signed transaction keeps its reservation
+	tx := types.MustSignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{Nonce: 0, To: &addrB, Value: ether(1)})
+	ui.OnApprovedTx(ethapi.SignTransactionResult{Tx: tx})
+	if next.signed != 1 {
+		t.Fatal("signed tx not passed on")
+	}
+	if len(ui.pending) != 0 {
+		t.Fatalf("approvals left pending: %d", len(ui.pending))
+	}
+	if approve(limitedRequest(key, 1, big.NewInt(1))) {
+		t.Fatal("tx over daily limit approved")
+	}
+}
+
+func TestUIDailyLimitConcurrent(t *testing.T) {
+	t.Parallel()
+	ui, _, key := newLimitedUI(t)
+
+	var (
+		approved atomic.Int32
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(nonce uint64) {
+			defer wg.Done()
+			res, err := ui.ApproveTx(limitedRequest(key, nonce, ether(0.25)))
+			if err == nil && res.Approved {
+				approved.Add(1)
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+	if n := approved.Load(); n != 4 {
+		t.Fatalf("approved %d transactions of 0.25 ether within a daily limit of 1 ether", n)
+	}
+}
+
+func TestEvalSignData(t *testing.T) {
+	t.Parallel()
+	e := newTestEvaluator(t, testPolicy)
+	domain := func(name string, chainID int64, contract string) *apitypes.TypedDataDomain {
+		return &apitypes.TypedDataDomain{Name: name, ChainId: math.NewHexOrDecimal256(chainID), VerifyingContract: contract}
+	}
+	tests := []struct {
+		name string
+		req  *core.SignDataRequest
+		want Result
+	}{
+		{
+			name: "permit",
+			req:  &core.SignDataRequest{ContentType: apitypes.DataTyped.Mime, Domain: domain("Permit2", 1, strings.ToLower(permit2))},
+			want: Result{Action: Approve, Rule: "permit"},
+		},
+		{
+			name: "permit on other chain",
+			req:  &core.SignDataRequest{ContentType: apitypes.DataTyped.Mime, Domain: domain("Permit2", 5, permit2)},
+			want: Result{Action: Reject, Reason: "no signData rule"},
+		},
+		{
+			name: "disallowed chain",
+			req:  &core.SignDataRequest{ContentType: apitypes.DataTyped.Mime, Domain: domain("Permit2", 137, permit2)},
+			want: Result{Action: Reject, Reason: "EIP-712 domain chain ID 137 not allowed"},
+		},
+		{
+			name: "other contract",
+			req:  &core.SignDataRequest{ContentType: apitypes.DataTyped.Mime, Domain: domain("Permit2", 1, "0x000000000000000000000000000000000000dddd")},
+			want: Result{Action: Reject, Reason: "no signData rule"},
+		},
+		{
+			name: "text",
+			req:  &core.SignDataRequest{ContentType: apitypes.TextPlain.Mime, Address: common.NewMixedcaseAddress(addrA)},
+			want: Result{Action: Approve, Rule: "text"},
+		},
+		{
+			name: "text from other account",
+			req:  &core.SignDataRequest{ContentType: apitypes.TextPlain.Mime, Address: common.NewMixedcaseAddress(addrB)},
+			want: Result{Action: Reject, Reason: "no signData rule"},
+		},
+		{
+			name: "clique header",
+			req:  &core.SignDataRequest{ContentType: apitypes.ApplicationClique.Mime, Address: common.NewMixedcaseAddress(addrA)},
+			want: Result{Action: Reject, Reason: "no signData rule"},
+		},
+	}
+	for _, test := range tests {
+		if got := e.EvalSignData(test.req); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// recordingUI records the requests passed on by the policy.
+type recordingUI struct {
+	core.UIClientAPI
+	calls []string
+}
+
+func (ui *recordingUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	ui.calls = append(ui.calls, "ApproveTx")
+	return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+}
+
+func (ui *recordingUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	ui.calls = append(ui.calls, "ApproveSignData")
+	return core.SignDataResponse{Approved: true}, nil
+}
+
+func (ui *recordingUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	ui.calls = append(ui.calls, "ApproveListing")
+	return core.ListResponse{Accounts: request.Accounts}, nil
+}
+
+func TestUI(t *testing.T) {
+	t.Parallel()
+	policy := strings.Replace(testPolicy, "default: reject", "default: manual", 1)
+	policy = strings.Replace(policy, "listing: approve", "listing: reject", 1)
+	var (
+		next = new(recordingUI)
+		ui   = NewUI(next, newTestEvaluator(t, policy))
+	)
+	// Approved by the policy.
+	resp, err := ui.ApproveTx(txRequest(addrA, &addrB, ether(1), ""))
+	if err != nil || !resp.Approved || resp.Transaction.To.Address() != addrB {
+		t.Fatalf("tx not approved: %v %v", resp, err)
+	}
+	// Rejected by the chain restriction, even though the default action is manual.
+	req := txRequest(addrA, &addrB, ether(1), "")
+	req.Transaction.ChainID = (*hexutil.Big)(big.NewInt(10))
+	if resp, err = ui.ApproveTx(req); err != nil || resp.Approved {
+		t.Fatalf("tx on wrong chain approved: %v %v", resp, err)
+	}
+	// Not covered by the policy, passed on.
+	if resp, err = ui.ApproveTx(txRequest(addrA, &addrE, ether(1), "")); err != nil || !resp.Approved {
+		t.Fatalf("manual tx not approved by next UI: %v %v", resp, err)
+	}
+	if sresp, err := ui.ApproveSignData(&core.SignDataRequest{ContentType: apitypes.TextPlain.Mime}); err != nil || !sresp.Approved {
+		t.Fatalf("manual data signing not approved by next UI: %v %v", sresp, err)
+	}
+	list := &core.ListRequest{Accounts: []accounts.Account{{Address: addrA}}}
+	if lresp, err := ui.ApproveListing(list); err != nil || len(lresp.Accounts) != 0 {
+		t.Fatalf("listing not rejected: %v %v", lresp, err)
+	}
+	want := []string{"ApproveTx", "ApproveSignData"}
+	if strings.Join(next.calls, ",") != strings.Join(want, ",") {
+		t.Fatalf("wrong calls to next UI: %v, want %v", next.calls, want)
+	}
+}
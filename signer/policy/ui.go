@@ -0,0 +1,184 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"math/big"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+// UI is an implementation of UIClientAPI which approves and rejects requests
+// according to a policy. Requests with the manual action are passed on to the
+// next UI, which can be a JavaScript ruleset or a user interface.
+type UI struct {
+	next core.UIClientAPI
+	eval *Evaluator
+
+	// Transactions approved by the policy which are not signed yet. Their value
+	// is reserved from the daily limit of the approving rule until signing fails.
+	pending map[pendingTx][]*approvedTx
+	lock    sync.Mutex
+}
+
+// pendingTx identifies an approved transaction which is not yet signed.
+type pendingTx struct {
+	from  common.Address
+	nonce uint64
+}
+
+// approvedTx is the approval of a pending transaction.
+type approvedTx struct {
+	request *core.SignTxRequest
+	rule    string
+	value   *big.Int
+}
+
+// NewUI creates a policy UI in front of next.
+func NewUI(next core.UIClientAPI, eval *Evaluator) *UI {
+	return &UI{next: next, eval: eval, pending: make(map[pendingTx][]*approvedTx)}
+}
+
+func (ui *UI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	res := ui.eval.EvalTx(request)
+	ui.logResult("transaction", res, "from", request.Transaction.From.Address(), "to", request.Transaction.To)
+	switch res.Action {
+	case Approve:
+		// Track the approval until the transaction is signed
+		tx := &request.Transaction
+		id := pendingTx{tx.From.Address(), uint64(tx.Nonce)}
+
+		ui.lock.Lock()
+		ui.pending[id] = append(ui.pending[id], &approvedTx{request, res.Rule, tx.Value.ToInt()})
+		ui.lock.Unlock()
+		return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+	case Reject:
+		return core.SignTxResponse{Approved: false}, nil
+	default:
+		return ui.next.ApproveTx(request)
+	}
+}
+
+func (ui *UI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	res := ui.eval.EvalSignData(request)
+	ui.logResult("data signing", res, "address", request.Address.Address(), "type", request.ContentType)
+	switch res.Action {
+	case Approve:
+		return core.SignDataResponse{Approved: true}, nil
+	case Reject:
+		return core.SignDataResponse{Approved: false}, nil
+	default:
+		return ui.next.ApproveSignData(request)
+	}
+}
+
+func (ui *UI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	res := ui.eval.EvalListing(request)
+	ui.logResult("listing", res)
+	switch res.Action {
+	case Approve:
+		return core.ListResponse{Accounts: request.Accounts}, nil
+	case Reject:
+		return core.ListResponse{}, nil
+	default:
+		return ui.next.ApproveListing(request)
+	}
+}
+
+func (ui *UI) logResult(kind string, res Result, ctx ...interface{}) {
+	switch res.Action {
+	case Approve:
+		log.Info("Policy approved "+kind, append(ctx, "rule", res.Rule)...)
+	case Reject:
+		log.Info("Policy rejected "+kind, append(ctx, "reason", res.Reason)...)
+	default:
+		log.Debug("Policy deferred "+kind, append(ctx, "reason", res.Reason)...)
+	}
+}
+
+// ApproveNewAccount is not handled by the policy, since it requires setting a password.
+func (ui *UI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return ui.next.ApproveNewAccount(request)
+}
+
+func (ui *UI) ShowError(message string) {
+	ui.next.ShowError(message)
+}
+
+func (ui *UI) ShowInfo(message string) {
+	ui.next.ShowInfo(message)
+}
+
+func (ui *UI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	// The transaction is signed, its reservation becomes final
+	if from, err := types.Sender(types.LatestSignerForChainID(tx.Tx.ChainId()), tx.Tx); err == nil {
+		ui.takeApproval(pendingTx{from, tx.Tx.Nonce()}, func(a *approvedTx) bool {
+			return a.value.Cmp(tx.Tx.Value()) == 0
+		})
+	}
+	ui.next.OnApprovedTx(tx)
+}
+
+// OnSignTxFailed releases the reservation of an approved transaction which could
+// not be signed.
+func (ui *UI) OnSignTxFailed(request *core.SignTxRequest, err error) {
+	tx := &request.Transaction
+	approval := ui.takeApproval(pendingTx{tx.From.Address(), uint64(tx.Nonce)}, func(a *approvedTx) bool {
+		return a.request == request
+	})
+	if approval != nil {
+		ui.eval.ReleaseTx(approval.rule, tx.From.Address(), approval.value)
+	} else if listener, ok := ui.next.(core.SignTxFailureListener); ok {
+		listener.OnSignTxFailed(request, err)
+	}
+}
+
+// takeApproval removes the first pending approval of the given transaction which
+// matches the filter, returning it or nil if there is none.
+func (ui *UI) takeApproval(id pendingTx, match func(*approvedTx) bool) *approvedTx {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	for i, approval := range ui.pending[id] {
+		if match(approval) {
+			ui.pending[id] = slices.Delete(ui.pending[id], i, i+1)
+			if len(ui.pending[id]) == 0 {
+				delete(ui.pending, id)
+			}
+			return approval
+		}
+	}
+	return nil
+}
+
+func (ui *UI) OnSignerStartup(info core.StartupInfo) {
+	ui.next.OnSignerStartup(info)
+}
+
+func (ui *UI) OnInputRequired(info core.UserInputRequest) (core.UserInputResponse, error) {
+	return ui.next.OnInputRequired(info)
+}
+
+func (ui *UI) RegisterUIServer(api *core.UIServerAPI) {
+	ui.next.RegisterUIServer(api)
+}
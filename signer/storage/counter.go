@@ -0,0 +1,98 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DailyCounter accumulates amounts per key in a Storage backend. The amount of a key
+// is reset when the (UTC) day changes. Entries are stored as "<date>:<amount>", with
+// the amount in decimal.
+type DailyCounter struct {
+	storage Storage
+	prefix  string
+	now     func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewDailyCounter creates a counter which stores its entries in the given storage,
+// with the prefix prepended to all keys.
+func NewDailyCounter(storage Storage, prefix string) *DailyCounter {
+	return &DailyCounter{storage: storage, prefix: prefix, now: time.Now}
+}
+
+// Get returns the amount accumulated for key today.
+func (c *DailyCounter) Get(key string) *big.Int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(key, c.today())
+}
+
+// Add adds amount to the counter of key, unless the result would exceed limit. It
+// reports whether the amount was added. A nil limit means no limit.
+func (c *DailyCounter) Add(key string, amount, limit *big.Int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	day := c.today()
+	total := new(big.Int).Add(c.get(key, day), amount)
+	if limit != nil && total.Cmp(limit) > 0 {
+		return false
+	}
+	c.storage.Put(c.prefix+key, day+":"+total.String())
+	return true
+}
+
+// Sub subtracts amount from the counter of key, e.g. for releasing an amount which
+// was added but not used after all. The counter never drops below zero.
+func (c *DailyCounter) Sub(key string, amount *big.Int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	day := c.today()
+	total := new(big.Int).Sub(c.get(key, day), amount)
+	if total.Sign() < 0 {
+		total.SetUint64(0)
+	}
+	c.storage.Put(c.prefix+key, day+":"+total.String())
+}
+
+func (c *DailyCounter) today() string {
+	return c.now().UTC().Format(time.DateOnly)
+}
+
+func (c *DailyCounter) get(key, day string) *big.Int {
+	v, err := c.storage.Get(c.prefix + key)
+	if err != nil {
+		return new(big.Int)
+	}
+	date, amount, ok := strings.Cut(v, ":")
+	if !ok || date != day {
+		return new(big.Int)
+	}
+	n, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDailyCounter(t *testing.T) {
+	t.Parallel()
+	var (
+		db    = NewEphemeralStorage()
+		now   = time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+		c     = NewDailyCounter(db, "counter-")
+		limit = big.NewInt(100)
+	)
+	c.now = func() time.Time { return now }
+
+	if !c.Add("a", big.NewInt(60), limit) {
+		t.Fatal("first add rejected")
+	}
+	if c.Add("a", big.NewInt(50), limit) {
+		t.Fatal("add over limit accepted")
+	}
+	if !c.Add("a", big.NewInt(40), limit) {
+		t.Fatal("add up to limit rejected")
+	}
+	if got := c.Get("a"); got.Cmp(limit) != 0 {
+		t.Fatalf("wrong amount %v, want %v", got, limit)
+	}
+	if got := c.Get("b"); got.Sign() != 0 {
+		t.Fatalf("wrong amount %v for other key", got)
+	}
+	if v, _ := db.Get("counter-a"); v != "2024-05-01:100" {
+		t.Fatalf("wrong stored value %q", v)
+	}
+	// Subtracting releases the amount again, but never below zero.
+	c.Sub("a", big.NewInt(40))
+	if got := c.Get("a"); got.Cmp(big.NewInt(60)) != 0 {
+		t.Fatalf("wrong amount %v after sub, want 60", got)
+	}
+	c.Sub("b", big.NewInt(40))
+	if got := c.Get("b"); got.Sign() != 0 {
+		t.Fatalf("wrong amount %v after sub below zero", got)
+	}
+
+	// The counter resets on the next day.
+	now = now.Add(2 * time.Hour)
+	if got := c.Get("a"); got.Sign() != 0 {
+		t.Fatalf("wrong amount %v after day change", got)
+	}
+	if !c.Add("a", big.NewInt(100), limit) {
+		t.Fatal("add rejected after day change")
+	}
+	// A nil limit means unlimited.
+	if !c.Add("a", big.NewInt(1000), nil) {
+		t.Fatal("add without limit rejected")
+	}
+}
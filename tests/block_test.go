@@ -17,11 +17,17 @@
 package tests
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
 	"math/rand"
+	"path/filepath"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
 )
 
 func TestBlockchain(t *testing.T) {
@@ -57,6 +63,108 @@ func TestBlockchain(t *testing.T) {
 	// which run natively, so there's no reason to run them here.
 }
 
+// TestBlockchainParallel runs the blockchain tests and re-executes every imported
+// block with the parallel processor, cross-checking its results against the ones
+// of the sequential processor.
+func TestBlockchainParallel(t *testing.T) {
+	if !hasTestFiles(blockTestDir) {
+		t.Skipf("no blockchain tests found in %s", blockTestDir)
+	}
+	bt := new(testMatcher)
+
+	bt.skipLoad(`^GeneralStateTests/VMTests/vmPerformance`)
+	bt.skipLoad(`.*bcForgedTest/bcForkUncle\.json`)
+	bt.skipLoad(`.*/stTimeConsuming/.*`)
+	bt.skipLoad(`.*randomStatetest94.json.*`)
+
+	bt.slow(`.*bcExploitTest/DelegateCallSpam.json`)
+	bt.slow(`.*bcExploitTest/ShanghaiLove.json`)
+	bt.slow(`.*bcExploitTest/SuicideIssue.json`)
+	bt.slow(`.*/bcForkStressTest/`)
+	bt.slow(`.*/bcGasPricerTest/RPC_API_Test.json`)
+	bt.slow(`.*/bcWalletTest/`)
+
+	bt.walk(t, blockTestDir, func(t *testing.T, name string, test *BlockTest) {
+		var perr error
+		err := test.Run(false, rawdb.HashScheme, false, nil, func(res error, chain *core.BlockChain) {
+			if res == nil {
+				perr = checkParallelProcessing(chain)
+			}
+		})
+		if err := bt.checkFailure(t, err); err != nil {
+			t.Error(err)
+		}
+		if perr != nil {
+			t.Error(perr)
+		}
+	})
+}
+
+// hasTestFiles reports whether the directory contains any JSON test files.
+func hasTestFiles(dir string) bool {
+	var found bool
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".json" {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// checkParallelProcessing re-executes all the canonical blocks of the chain with
+// both the sequential and the parallel processor, and checks that they produce
+// the same receipts, logs, gas usage and state root.
+func checkParallelProcessing(chain *core.BlockChain) error {
+	var (
+		config     = chain.Config()
+		sequential = core.NewStateProcessor(config, chain.HeaderChain())
+		parallel   = core.NewParallelStateProcessor(config, chain.HeaderChain(), 4)
+	)
+	for number := uint64(1); number <= chain.CurrentBlock().Number.Uint64(); number++ {
+		block := chain.GetBlockByNumber(number)
+		parent := chain.GetHeaderByHash(block.ParentHash())
+
+		seqState, err := chain.StateAt(parent.Root)
+		if err != nil {
+			return err
+		}
+		seqReceipts, seqLogs, seqGas, seqErr := sequential.Process(block, seqState, vm.Config{})
+
+		parState, err := chain.StateAt(parent.Root)
+		if err != nil {
+			return err
+		}
+		parReceipts, parLogs, parGas, parErr := parallel.Process(block, parState, vm.Config{})
+
+		if (seqErr == nil) != (parErr == nil) {
+			return fmt.Errorf("block %d: error mismatch: have %v, want %v", number, parErr, seqErr)
+		}
+		if seqErr != nil {
+			continue
+		}
+		if seqGas != parGas {
+			return fmt.Errorf("block %d: gas mismatch: have %d, want %d", number, parGas, seqGas)
+		}
+		have, _ := json.Marshal(parReceipts)
+		want, _ := json.Marshal(seqReceipts)
+		if string(have) != string(want) {
+			return fmt.Errorf("block %d: receipt mismatch: have %s, want %s", number, have, want)
+		}
+		have, _ = json.Marshal(parLogs)
+		want, _ = json.Marshal(seqLogs)
+		if string(have) != string(want) {
+			return fmt.Errorf("block %d: log mismatch: have %s, want %s", number, have, want)
+		}
+		deleteEmpty := config.IsEIP158(block.Number())
+		if have, want := parState.IntermediateRoot(deleteEmpty), seqState.IntermediateRoot(deleteEmpty); have != want {
+			return fmt.Errorf("block %d: state root mismatch: have %x, want %x", number, have, want)
+		}
+	}
+	return nil
+}
+
 // TestExecutionSpecBlocktests runs the test fixtures from execution-spec-tests.
 func TestExecutionSpecBlocktests(t *testing.T) {
 	if !common.FileExist(executionSpecBlockchainTestDir) {
@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"sort"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -406,6 +407,42 @@ func AvailableForks() []string {
 	return availableForks
 }
 
+// ForkName returns the name of the fork active in the given block, as used by
+// the state tests and evm t8n.
+func ForkName(config *params.ChainConfig, header *types.Header) string {
+	rules := config.Rules(header.Number, header.Difficulty.Sign() == 0, header.Time)
+	switch {
+	case rules.IsPrague:
+		return "Prague"
+	case rules.IsCancun:
+		return "Cancun"
+	case rules.IsShanghai:
+		return "Shanghai"
+	case rules.IsMerge:
+		return "Paris"
+	case rules.IsLondon:
+		return "London"
+	case rules.IsBerlin:
+		return "Berlin"
+	case rules.IsIstanbul:
+		return "Istanbul"
+	case rules.IsPetersburg:
+		return "ConstantinopleFix"
+	case rules.IsConstantinople:
+		return "Constantinople"
+	case rules.IsByzantium:
+		return "Byzantium"
+	case rules.IsEIP158:
+		return "EIP158"
+	case rules.IsEIP150:
+		return "EIP150"
+	case rules.IsHomestead:
+		return "Homestead"
+	default:
+		return "Frontier"
+	}
+}
+
 // UnsupportedForkError is returned when a test requests a fork that isn't implemented.
 type UnsupportedForkError struct {
 	Name string
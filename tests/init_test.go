@@ -30,6 +30,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -290,3 +292,17 @@ func TestMatcherRunonlylist(t *testing.T) {
 		}
 	})
 }
+
+func TestForkName(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"Frontier", "Homestead", "EIP150", "EIP158", "Byzantium", "Constantinople", "ConstantinopleFix", "Istanbul", "Berlin", "London", "Paris", "Shanghai", "Cancun", "Prague"} {
+		config := Forks[name]
+		header := &types.Header{Number: common.Big0, Difficulty: common.Big1}
+		if config.MergeNetsplitBlock != nil {
+			header.Difficulty = common.Big0
+		}
+		if have := ForkName(config, header); have != name {
+			t.Errorf("wrong fork name: have %s, want %s", have, name)
+		}
+	}
+}
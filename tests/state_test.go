@@ -19,6 +19,7 @@ package tests
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/holiman/uint256"
 )
@@ -102,6 +104,76 @@ func TestExecutionSpecState(t *testing.T) {
 	})
 }
 
+// TestStateTestFill checks that filled state tests survive a JSON round trip and
+// pass when executed.
+func TestStateTestFill(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		header   = &types.Header{
+			Coinbase:   common.HexToAddress("0xc014ba5e"),
+			Difficulty: new(big.Int),
+			Number:     big.NewInt(100),
+			GasLimit:   30_000_000,
+			Time:       1000,
+			BaseFee:    big.NewInt(7),
+		}
+		pre = types.GenesisAlloc{
+			sender: {Balance: big.NewInt(1_000_000_000_000_000)},
+			// Logs and stores the call value
+			contract: {Balance: new(big.Int), Code: common.FromHex("0x3460005534600052602060006000a1")},
+		}
+		tx = types.MustSignNewTx(key, types.LatestSigner(Forks["Cancun"]), &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			To:        &contract,
+			Value:     big.NewInt(42),
+			Gas:       100_000,
+			GasFeeCap: big.NewInt(10),
+			GasTipCap: big.NewInt(1),
+		})
+	)
+	test, err := NewStateTest(pre, header, tx, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.Fill([]string{"London", "Cancun", "Berlin"}, rawdb.HashScheme); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := json.Marshal(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filled StateTest
+	if err := json.Unmarshal(blob, &filled); err != nil {
+		t.Fatal(err)
+	}
+	if have := len(filled.Subtests()); have != 3 {
+		t.Fatalf("wrong number of subtests: have %d, want 3", have)
+	}
+	for _, subtest := range filled.Subtests() {
+		if err := filled.Run(subtest, vm.Config{}, false, rawdb.HashScheme, func(err error, st *StateTestState) {}); err != nil {
+			t.Errorf("%s: %v", subtest.Fork, err)
+		}
+	}
+	if root := filled.json.Post["London"][0].Root; root == (common.UnprefixedHash{}) {
+		t.Errorf("missing post state root")
+	}
+	if logs := filled.json.Post["London"][0].Logs; common.Hash(logs) == rlpHash([]*types.Log{}) {
+		t.Errorf("missing logs")
+	}
+	// Dynamic fee transactions are rejected on Berlin, leaving the state untouched
+	berlin := filled.json.Post["Berlin"][0]
+	if have, want := berlin.ExpectException, "TransactionException.TYPE_NOT_SUPPORTED"; have != want {
+		t.Errorf("wrong exception: have %q, want %q", have, want)
+	}
+	st := MakePreState(rawdb.NewMemoryDatabase(), pre, false, rawdb.HashScheme)
+	defer st.Close()
+	if have, want := common.Hash(berlin.Root), st.StateDB.IntermediateRoot(true); have != want {
+		t.Errorf("wrong exception state root: have %x, want %x", have, want)
+	}
+}
+
 func execStateTest(t *testing.T, st *testMatcher, test *StateTest) {
 	for _, subtest := range test.Subtests() {
 		subtest := subtest
@@ -50,7 +50,8 @@ import (
 // StateTest checks transaction processing without block context.
 // See https://github.com/ethereum/EIPs/issues/176 for the test format specification.
 type StateTest struct {
-	json stJSON
+	json    stJSON
+	txbytes hexutil.Bytes // Signed transaction of filled tests, if it's recoverable
 }
 
 // StateSubtest selects a specific configuration of a General State Test.
@@ -63,6 +64,10 @@ func (t *StateTest) UnmarshalJSON(in []byte) error {
 	return json.Unmarshal(in, &t.json)
 }
 
+func (t *StateTest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&t.json)
+}
+
 type stJSON struct {
 	Env  stEnv                    `json:"env"`
 	Pre  types.GenesisAlloc       `json:"pre"`
@@ -80,7 +85,7 @@ type stPostState struct {
 		Data  int `json:"data"`
 		Gas   int `json:"gas"`
 		Value int `json:"value"`
-	}
+	} `json:"indexes"`
 }
 
 //go:generate go run github.com/fjl/gencodec -type stEnv -field-override stEnvMarshaling -out gen_stenv.go
@@ -260,7 +265,7 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 		// Here, we just do this shortcut smaller fix, since state tests do not
 		// utilize those codepaths
 		if len(msg.BlobHashes)*params.BlobTxBlobGasPerBlob > params.MaxBlobGasPerBlock {
-			return st, common.Hash{}, errors.New("blob gas exceeds maximum")
+			return st, common.Hash{}, errBlobGasExceeded
 		}
 	}
 
@@ -323,6 +328,143 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 	return st, root, err
 }
 
+// NewStateTest creates a state test executing a single signed transaction on top
+// of the given prestate, in the block environment of the given header. The test
+// has no post states yet, they are computed by Fill.
+func NewStateTest(pre types.GenesisAlloc, header *types.Header, tx *types.Transaction, sender common.Address) (*StateTest, error) {
+	txbytes, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	t := &StateTest{json: stJSON{
+		Env: stEnv{
+			Coinbase:      header.Coinbase,
+			Difficulty:    header.Difficulty,
+			GasLimit:      header.GasLimit,
+			Number:        header.Number.Uint64(),
+			Timestamp:     header.Time,
+			BaseFee:       header.BaseFee,
+			ExcessBlobGas: header.ExcessBlobGas,
+		},
+		Pre: pre,
+		Tx: stTransaction{
+			Nonce:               tx.Nonce(),
+			Data:                []string{hexutil.Encode(tx.Data())},
+			GasLimit:            []uint64{tx.Gas()},
+			Value:               []string{hexutil.EncodeBig(tx.Value())},
+			Sender:              &sender,
+			BlobVersionedHashes: tx.BlobHashes(),
+			BlobGasFeeCap:       tx.BlobGasFeeCap(),
+		},
+		Post: make(map[string][]stPostState),
+	}}
+	if header.Difficulty == nil || header.Difficulty.Sign() == 0 {
+		t.json.Env.Random = header.MixDigest.Big()
+	}
+	if to := tx.To(); to != nil {
+		t.json.Tx.To = to.Hex()
+	}
+	if tx.Type() == types.LegacyTxType {
+		t.json.Tx.GasPrice = tx.GasPrice()
+	} else {
+		accessList := tx.AccessList()
+		t.json.Tx.AccessLists = []*types.AccessList{&accessList}
+		if tx.Type() == types.AccessListTxType {
+			t.json.Tx.GasPrice = tx.GasPrice()
+		} else {
+			t.json.Tx.MaxFeePerGas = tx.GasFeeCap()
+			t.json.Tx.MaxPriorityFeePerGas = tx.GasTipCap()
+		}
+	}
+	// The signed transaction is only included if the sender can be recovered
+	// with the chain id used by the state tests.
+	if from, err := types.Sender(types.LatestSigner(Forks["Cancun"]), tx); err == nil && from == sender {
+		t.txbytes = txbytes
+	}
+	return t, nil
+}
+
+// errBlobGasExceeded is returned if a transaction exceeds the blob gas limit of
+// a block.
+var errBlobGasExceeded = errors.New("blob gas exceeds maximum")
+
+// stateExceptions maps the transaction validation errors to the names of the
+// exceptions used by the execution-spec-tests fixtures.
+var stateExceptions = []struct {
+	err  error
+	name string
+}{
+	{core.ErrNonceTooLow, "TransactionException.NONCE_MISMATCH_TOO_LOW"},
+	{core.ErrNonceTooHigh, "TransactionException.NONCE_MISMATCH_TOO_HIGH"},
+	{core.ErrNonceMax, "TransactionException.NONCE_IS_MAX"},
+	{core.ErrGasLimitReached, "TransactionException.GAS_ALLOWANCE_EXCEEDED"},
+	{core.ErrInsufficientFunds, "TransactionException.INSUFFICIENT_ACCOUNT_FUNDS"},
+	{core.ErrInsufficientFundsForTransfer, "TransactionException.INSUFFICIENT_ACCOUNT_FUNDS"},
+	{core.ErrIntrinsicGas, "TransactionException.INTRINSIC_GAS_TOO_LOW"},
+	{core.ErrMaxInitCodeSizeExceeded, "TransactionException.INITCODE_SIZE_EXCEEDED"},
+	{core.ErrTipAboveFeeCap, "TransactionException.PRIORITY_GREATER_THAN_MAX_FEE_PER_GAS"},
+	{core.ErrFeeCapTooLow, "TransactionException.INSUFFICIENT_MAX_FEE_PER_GAS"},
+	{core.ErrSenderNoEOA, "TransactionException.SENDER_NOT_EOA"},
+	{core.ErrBlobFeeCapTooLow, "TransactionException.INSUFFICIENT_MAX_FEE_PER_BLOB_GAS"},
+	{core.ErrMissingBlobHashes, "TransactionException.TYPE_3_TX_ZERO_BLOBS"},
+	{core.ErrBlobTxCreate, "TransactionException.TYPE_3_TX_CONTRACT_CREATION"},
+	{errBlobGasExceeded, "TransactionException.TYPE_3_TX_MAX_BLOB_GAS_ALLOWANCE_EXCEEDED"},
+	{types.ErrTxTypeNotSupported, "TransactionException.TYPE_NOT_SUPPORTED"},
+	{types.ErrInvalidChainId, "TransactionException.INVALID_CHAINID"},
+	{types.ErrInvalidSig, "TransactionException.INVALID_SIGNATURE_VRS"},
+}
+
+// exceptionName returns the standard name of the exception for the given
+// transaction validation error.
+func exceptionName(err error) (string, error) {
+	for _, exception := range stateExceptions {
+		if errors.Is(err, exception.err) {
+			return exception.name, nil
+		}
+	}
+	return "", fmt.Errorf("no standard exception for error: %v", err)
+}
+
+// Fill executes the test on the given forks and records the resulting post state
+// roots and logs hashes as the expectations of the test. If the transaction is
+// rejected on a fork, the corresponding exception is recorded as the expected
+// one, along with the unchanged state root.
+func (t *StateTest) Fill(forks []string, scheme string) error {
+	if t.json.Post == nil {
+		t.json.Post = make(map[string][]stPostState)
+	}
+	for _, fork := range forks {
+		if _, _, err := GetChainConfig(fork); err != nil {
+			return err
+		}
+		// Executing the test may fill in default fee fields, keep them out of
+		// the output.
+		tx := t.json.Tx
+		t.json.Post[fork] = []stPostState{{TxBytes: t.txbytes}}
+
+		st, root, err := t.RunNoVerify(StateSubtest{Fork: fork}, vm.Config{}, false, scheme)
+		post := &t.json.Post[fork][0]
+		if err != nil {
+			// Transactions rejected before execution leave the state untouched
+			if root == (common.Hash{}) {
+				config, _, _ := GetChainConfig(fork)
+				root = st.StateDB.IntermediateRoot(config.IsEIP158(new(big.Int).SetUint64(t.json.Env.Number)))
+			}
+			name, nerr := exceptionName(err)
+			if nerr != nil {
+				st.Close()
+				return fmt.Errorf("fork %s: %v", fork, nerr)
+			}
+			post.ExpectException = name
+		}
+		post.Root = common.UnprefixedHash(root)
+		post.Logs = common.UnprefixedHash(rlpHash(st.StateDB.Logs()))
+		st.Close()
+		t.json.Tx = tx
+	}
+	return nil
+}
+
 func (t *StateTest) gasLimit(subtest StateSubtest) uint64 {
 	return t.json.Tx.GasLimit[t.json.Post[subtest.Fork][subtest.Index].Indexes.Gas]
 }
@@ -413,6 +555,10 @@ func (tx *stTransaction) toMessage(ps stPostState, baseFee *big.Int) (*core.Mess
 			tx.MaxFeePerGas)
 	}
 	if gasPrice == nil {
+		if tx.MaxFeePerGas != nil {
+			// Dynamic fee transaction before London
+			return nil, fmt.Errorf("%w: no gas price provided", types.ErrTxTypeNotSupported)
+		}
 		return nil, errors.New("no gas price provided")
 	}
 
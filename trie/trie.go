@@ -44,11 +44,15 @@ type Trie struct {
 	// trie is not usable(latest states is invisible).
 	committed bool
 
-	// Keep track of the number leaves which have been inserted since the last
+	// Keep track of the number of leaves which have been inserted since the last
 	// hashing operation. This number will not directly map to the number of
 	// actually unhashed nodes.
 	unhashed int
 
+	// Keep track of the number of leaves which have been inserted since the last
+	// commit operation, used to decide whether the commit runs in parallel.
+	uncommitted int
+
 	// reader is the handler trie can retrieve nodes from.
 	reader *trieReader
 
@@ -64,12 +68,13 @@ func (t *Trie) newFlag() nodeFlag {
 // Copy returns a copy of Trie.
 func (t *Trie) Copy() *Trie {
 	return &Trie{
-		root:      t.root,
-		owner:     t.owner,
-		committed: t.committed,
-		unhashed:  t.unhashed,
-		reader:    t.reader,
-		tracer:    t.tracer.copy(),
+		root:        t.root,
+		owner:       t.owner,
+		committed:   t.committed,
+		unhashed:    t.unhashed,
+		uncommitted: t.uncommitted,
+		reader:      t.reader,
+		tracer:      t.tracer.copy(),
 	}
 }
 
@@ -309,6 +314,7 @@ func (t *Trie) Update(key, value []byte) error {
 
 func (t *Trie) update(key, value []byte) error {
 	t.unhashed++
+	t.uncommitted++
 	k := keybytesToHex(key)
 	if len(value) != 0 {
 		_, n, err := t.insert(t.root, nil, k, valueNode(value))
@@ -423,6 +429,7 @@ func (t *Trie) Delete(key []byte) error {
 		return ErrCommitted
 	}
 	t.unhashed++
+	t.uncommitted++
 	k := keybytesToHex(key)
 	_, n, err := t.delete(t.root, nil, k)
 	if err != nil {
@@ -610,6 +617,7 @@ func (t *Trie) Hash() common.Hash {
 func (t *Trie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet) {
 	defer func() {
 		t.committed = true
+		t.uncommitted = 0
 	}()
 	// Trie is empty and can be classified into two types of situations:
 	// (a) The trie was empty and no update happens => return nil
@@ -642,7 +650,8 @@ func (t *Trie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet) {
 	for _, path := range t.tracer.deletedNodes() {
 		nodes.AddNode([]byte(path), trienode.NewDeleted())
 	}
-	t.root = newCommitter(nodes, t.tracer, collectLeaf).Commit(t.root)
+	// If the number of changes is below 100, we let one thread handle it
+	t.root = newCommitter(nodes, t.tracer, collectLeaf).Commit(t.root, t.uncommitted >= 100)
 	return rootHash, nodes
 }
 
@@ -678,6 +687,7 @@ func (t *Trie) Reset() {
 	t.root = nil
 	t.owner = common.Hash{}
 	t.unhashed = 0
+	t.uncommitted = 0
 	t.tracer.reset()
 	t.committed = false
 }
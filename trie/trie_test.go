@@ -727,6 +727,33 @@ func benchmarkCommitAfterHash(b *testing.B, collectLeaf bool) {
 	trie.Commit(collectLeaf)
 }
 
+// Benchmarks the trie Commit with the sequential and the parallel committer
+// on a freshly hashed trie of b.N accounts.
+func BenchmarkCommitParallel(b *testing.B) {
+	b.Run("sequential", func(b *testing.B) {
+		benchmarkCommitParallel(b, false)
+	})
+	b.Run("parallel", func(b *testing.B) {
+		benchmarkCommitParallel(b, true)
+	})
+}
+
+func benchmarkCommitParallel(b *testing.B, parallel bool) {
+	addresses, accounts := makeAccounts(10000)
+	trie := NewEmpty(newTestDatabase(rawdb.NewMemoryDatabase(), rawdb.HashScheme))
+	for i := 0; i < len(addresses); i++ {
+		trie.MustUpdate(crypto.Keccak256(addresses[i][:]), accounts[i])
+	}
+	trie.Hash()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nodes := trienode.NewNodeSet(common.Hash{})
+		newCommitter(nodes, trie.tracer, true).Commit(trie.root, parallel)
+	}
+}
+
 func TestTinyTrie(t *testing.T) {
 	// Create a realistic account trie to hash
 	_, accounts := makeAccounts(5)
@@ -1207,3 +1234,47 @@ func FuzzTrie(f *testing.F) {
 		}
 	})
 }
+
+// Tests that the parallel committer produces exactly the same nodeset as the
+// sequential one, including the order of the collected leaves.
+func TestCommitParallel(t *testing.T) {
+	addresses, accounts := makeAccounts(2000)
+	trie := NewEmpty(newTestDatabase(rawdb.NewMemoryDatabase(), rawdb.HashScheme))
+	for i := 0; i < len(addresses); i++ {
+		trie.MustUpdate(crypto.Keccak256(addresses[i][:]), accounts[i])
+	}
+	trie.Hash()
+
+	var (
+		seqNodes = trienode.NewNodeSet(common.Hash{})
+		parNodes = trienode.NewNodeSet(common.Hash{})
+		seqRoot  = newCommitter(seqNodes, trie.tracer, true).Commit(trie.root, false)
+		parRoot  = newCommitter(parNodes, trie.tracer, true).Commit(trie.root, true)
+	)
+	if !bytes.Equal(seqRoot, parRoot) {
+		t.Fatalf("root mismatch: sequential %x, parallel %x", seqRoot, parRoot)
+	}
+	if len(seqNodes.Nodes) != len(parNodes.Nodes) {
+		t.Fatalf("node count mismatch: sequential %d, parallel %d", len(seqNodes.Nodes), len(parNodes.Nodes))
+	}
+	for path, n := range seqNodes.Nodes {
+		pn, ok := parNodes.Nodes[path]
+		if !ok {
+			t.Fatalf("missing node %x in parallel set", path)
+		}
+		if n.Hash != pn.Hash || !bytes.Equal(n.Blob, pn.Blob) {
+			t.Fatalf("node %x mismatch", path)
+		}
+	}
+	if len(seqNodes.Leaves) != len(parNodes.Leaves) {
+		t.Fatalf("leaf count mismatch: sequential %d, parallel %d", len(seqNodes.Leaves), len(parNodes.Leaves))
+	}
+	for i := range seqNodes.Leaves {
+		if seqNodes.Leaves[i].Parent != parNodes.Leaves[i].Parent || !bytes.Equal(seqNodes.Leaves[i].Blob, parNodes.Leaves[i].Blob) {
+			t.Fatalf("leaf %d mismatch", i)
+		}
+	}
+	if seqUpdates, _ := seqNodes.Size(); seqUpdates == 0 {
+		t.Fatal("no node committed")
+	}
+}
@@ -124,6 +124,17 @@ func (set *NodeSet) Merge(owner common.Hash, nodes map[string]*Node) error {
 	return nil
 }
 
+// MergeSet merges all the nodes and leaves of the provided set belonging to the
+// same trie into this set. Leaves are appended after the existing ones, so the
+// merge order is preserved.
+func (set *NodeSet) MergeSet(other *NodeSet) error {
+	if err := set.Merge(other.Owner, other.Nodes); err != nil {
+		return err
+	}
+	set.Leaves = append(set.Leaves, other.Leaves...)
+	return nil
+}
+
 // AddLeaf adds the provided leaf node into set. TODO(rjl493456442) how can
 // we get rid of it?
 func (set *NodeSet) AddLeaf(parent common.Hash, blob []byte) {
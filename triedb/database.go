@@ -254,6 +254,30 @@ func (db *Database) Dereference(root common.Hash) error {
 	return nil
 }
 
+// SetFlushHook installs a callback which is invoked for every trie node that
+// is persisted into the disk database. It's only supported by hash-based
+// database and will return an error for others.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) error {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	hdb.SetFlushHook(hook)
+	return nil
+}
+
+// DeleteNodes removes the given trie nodes from the persistent database unless
+// they are still referenced in memory or the retain callback reports them as
+// live. It's only supported by hash-based database and will return an error
+// for others.
+func (db *Database) DeleteNodes(hashes []common.Hash, retain func(hash common.Hash) bool) (int, error) {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return 0, errors.New("not supported")
+	}
+	return hdb.DeleteNodes(hashes, retain)
+}
+
 // Recover rollbacks the database to a specified historical point. The state is
 // supported as the rollback destination only if it's canonical state and the
 // corresponding trie histories are existent. It's only supported by path-based
@@ -277,6 +301,38 @@ func (db *Database) Recoverable(root common.Hash) (bool, error) {
 	return pdb.Recoverable(root), nil
 }
 
+// HistoricState returns a read-only view of the specified state below the
+// persistent state, reconstructed from the state histories. It's only supported
+// by path-based database and will return an error for others.
+func (db *Database) HistoricState(root common.Hash) (*pathdb.HistoricState, error) {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return nil, errors.New("not supported")
+	}
+	return pdb.HistoricState(root)
+}
+
+// CachedHistoricState returns the read-only view of the specified state below
+// the persistent state if it was already reconstructed, or nil otherwise. It's
+// only supported by path-based database and will return nil for others.
+func (db *Database) CachedHistoricState(root common.Hash) *pathdb.HistoricState {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return nil
+	}
+	return pdb.CachedHistoricState(root)
+}
+
+// HistoricStateWindow returns the number of states below the persistent state
+// which can be accessed via HistoricState, or 0 if not supported.
+func (db *Database) HistoricStateWindow() uint64 {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return 0
+	}
+	return pdb.HistoricStateWindow()
+}
+
 // Disable deactivates the database and invalidates all available state layers
 // as stale to prevent access to the persistent state, which is in the syncing
 // stage.
@@ -59,6 +59,8 @@ var (
 	memcacheCommitTimeTimer  = metrics.NewRegisteredResettingTimer("hashdb/memcache/commit/time", nil)
 	memcacheCommitNodesMeter = metrics.NewRegisteredMeter("hashdb/memcache/commit/nodes", nil)
 	memcacheCommitBytesMeter = metrics.NewRegisteredMeter("hashdb/memcache/commit/bytes", nil)
+
+	pruneDeleteNodesMeter = metrics.NewRegisteredMeter("hashdb/prune/delete/nodes", nil)
 )
 
 // Config contains the settings for database.
@@ -96,6 +98,8 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 
+	flushHook func(hash common.Hash) // Optional callback invoked for every node persisted to disk
+
 	lock sync.RWMutex
 }
 
@@ -344,6 +348,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.dirties[oldest]
 		rawdb.WriteLegacyTrieNode(batch, oldest, node.node)
+		if db.flushHook != nil {
+			db.flushHook(oldest)
+		}
 
 		// If we exceeded the ideal batch size, commit and reset
 		if batch.ValueSize() >= ethdb.IdealBatchSize {
@@ -467,6 +474,9 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 	}
 	// If we've reached an optimal batch size, commit and start over
 	rawdb.WriteLegacyTrieNode(batch, hash, node.node)
+	if db.flushHook != nil {
+		db.flushHook(hash)
+	}
 	if batch.ValueSize() >= ethdb.IdealBatchSize {
 		if err := batch.Write(); err != nil {
 			return err
@@ -592,6 +602,52 @@ func (db *Database) Update(root common.Hash, parent common.Hash, block uint64, n
 	return nil
 }
 
+// SetFlushHook installs a callback which is invoked for every trie node that is
+// persisted into the disk database, either by Cap or Commit. The callback runs
+// with the database lock held, it must not call back into the database. Passing
+// nil removes any previously installed hook.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.flushHook = hook
+}
+
+// DeleteNodes removes the given trie nodes from the persistent database. Nodes
+// that are still tracked in the dirty cache, or for which the retain callback
+// reports true, are left untouched. The retain check and the deletion happen
+// atomically with respect to node flushes, so a node which is concurrently
+// re-persisted (and reported through the flush hook) is never lost.
+//
+// The number of nodes scheduled for deletion is returned.
+func (db *Database) DeleteNodes(hashes []common.Hash, retain func(hash common.Hash) bool) (int, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var (
+		deleted int
+		batch   = db.diskdb.NewBatch()
+	)
+	for _, hash := range hashes {
+		if _, ok := db.dirties[hash]; ok {
+			continue
+		}
+		if retain != nil && retain(hash) {
+			continue
+		}
+		rawdb.DeleteLegacyTrieNode(batch, hash)
+		if db.cleans != nil {
+			db.cleans.Del(hash[:])
+		}
+		deleted++
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	pruneDeleteNodesMeter.Mark(int64(deleted))
+	return deleted, nil
+}
+
 // Size returns the current storage size of the memory cache in front of the
 // persistent database layer.
 //
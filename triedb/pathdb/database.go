@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -132,15 +133,17 @@ type Database struct {
 	// readOnly is the flag whether the mutation is allowed to be applied.
 	// It will be set automatically when the database is journaled during
 	// the shutdown to reject all following unexpected mutations.
-	readOnly   bool                         // Flag if database is opened in read only mode
-	waitSync   bool                         // Flag if database is deactivated due to initial state sync
-	isVerkle   bool                         // Flag if database is used for verkle tree
-	bufferSize int                          // Memory allowance (in bytes) for caching dirty nodes
-	config     *Config                      // Configuration for database
-	diskdb     ethdb.Database               // Persistent storage for matured trie nodes
-	tree       *layerTree                   // The group for all known layers
-	freezer    ethdb.ResettableAncientStore // Freezer for storing trie histories, nil possible in tests
-	lock       sync.RWMutex                 // Lock to prevent mutations from happening at the same time
+	readOnly   bool                               // Flag if database is opened in read only mode
+	waitSync   bool                               // Flag if database is deactivated due to initial state sync
+	isVerkle   bool                               // Flag if database is used for verkle tree
+	bufferSize int                                // Memory allowance (in bytes) for caching dirty nodes
+	config     *Config                            // Configuration for database
+	diskdb     ethdb.Database                     // Persistent storage for matured trie nodes
+	tree       *layerTree                         // The group for all known layers
+	freezer    ethdb.ResettableAncientStore       // Freezer for storing trie histories, nil possible in tests
+	historic   *lru.Cache[uint64, *HistoricState] // Recently constructed historic state views, keyed by state id
+	rebaseLock sync.Mutex                         // Lock to prevent rebasing the same historic state concurrently
+	lock       sync.RWMutex                       // Lock to prevent mutations from happening at the same time
 }
 
 // New attempts to load an already existing layer from a persistent key-value
@@ -166,6 +169,7 @@ func New(diskdb ethdb.Database, config *Config, isVerkle bool) *Database {
 		bufferSize: config.DirtyCacheSize,
 		config:     config,
 		diskdb:     diskdb,
+		historic:   lru.NewCache[uint64, *HistoricState](historicStateCacheSize),
 	}
 	// Construct the layer tree by resolving the in-disk singleton state
 	// and in-memory layer journal.
@@ -0,0 +1,324 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// historicStateCacheSize is the number of recently constructed historic state
+// views to keep around, as remote peers tend to request the same state over and
+// over again.
+const historicStateCacheSize = 4
+
+// HistoricState is a read-only view of a state below the disk layer. It is made
+// up of the persistent tries with all the state changes since the requested
+// state reverted in memory, using the prev-values kept in the state histories.
+//
+// The view is bound to the disk layer it was constructed on top of and becomes
+// unusable once that layer is flushed or reverted. Trie accesses will fail with
+// an error in that case, rather than returning inconsistent data. The collected
+// changes are retained though, so that the cached view can be rebased onto the
+// new disk layer by only reverting the state histories added since.
+type HistoricState struct {
+	root     common.Hash                // Root hash of the historic state
+	id       uint64                     // State id of the historic state
+	base     common.Hash                // Root hash of the disk layer the view is built on
+	head     uint64                     // State id of the disk layer the view is built on
+	db       *Database                  // Database for resolving the untouched trie nodes
+	accounts *trie.Trie                 // Account trie with the historic accounts patched in
+	storages map[common.Hash]*trie.Trie // Storage tries with the historic slots patched in
+
+	// Historic values of all the state entries modified since the historic state
+	accountChanges map[common.Address][]byte
+	storageChanges map[common.Address]map[common.Hash][]byte
+}
+
+// Root returns the state root of the historic state.
+func (s *HistoricState) Root() common.Hash {
+	return s.root
+}
+
+// AccountTrie returns the account trie of the historic state. The returned trie
+// is a private copy, safe for concurrent use with other copies, but must not be
+// committed.
+func (s *HistoricState) AccountTrie() *trie.Trie {
+	return s.accounts.Copy()
+}
+
+// StorageTrie returns the storage trie of the specified account in the historic
+// state. The returned trie is a private copy, safe for concurrent use with other
+// copies, but must not be committed.
+func (s *HistoricState) StorageTrie(accountHash common.Hash) (*trie.Trie, error) {
+	if st, ok := s.storages[accountHash]; ok {
+		return st.Copy(), nil
+	}
+	// The storage was not modified since the historic state, resolve the trie
+	// from the disk layer directly.
+	blob, err := s.accounts.Copy().Get(accountHash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("account %#x is not existent", accountHash)
+	}
+	var acc types.StateAccount
+	if err := rlp.DecodeBytes(blob, &acc); err != nil {
+		return nil, err
+	}
+	return trie.New(trie.StorageTrieID(s.base, accountHash, acc.Root), s.db)
+}
+
+// CachedHistoricState returns the read-only view of the specified historic state
+// if it was already constructed, or nil if not. A view constructed on top of an
+// older disk layer is rebased onto the current one, which only reverts the state
+// histories added since. Contrary to HistoricState, it never constructs a view
+// from scratch.
+func (db *Database) CachedHistoricState(root common.Hash) *HistoricState {
+	root = types.TrieRootHash(root)
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return nil
+	}
+	s, ok := db.historic.Get(*id)
+	if !ok || s.root != root {
+		return nil
+	}
+	dl := db.tree.bottom()
+	if s.base == dl.rootHash() {
+		return s
+	}
+	if s.head >= dl.stateID() || *id >= dl.stateID() {
+		return nil // disk layer reverted, can't rebase
+	}
+	// Rebase the view, unless another caller did it in the meantime
+	db.rebaseLock.Lock()
+	defer db.rebaseLock.Unlock()
+
+	if s, ok = db.historic.Get(*id); !ok || s.root != root {
+		return nil
+	}
+	if s.base == dl.rootHash() {
+		return s
+	}
+	rebased, err := db.buildHistoricState(s, dl)
+	if err != nil {
+		log.Debug("Failed to rebase historic state", "root", root, "id", *id, "err", err)
+		return nil
+	}
+	return rebased
+}
+
+// HistoricState constructs a read-only view of the specified historic state, by
+// reverting the state histories above it in memory. Only canonical states below
+// the disk layer, within the retained state histories are supported.
+func (db *Database) HistoricState(root common.Hash) (*HistoricState, error) {
+	if db.isVerkle {
+		return nil, errors.New("verkle is not supported")
+	}
+	if db.freezer == nil {
+		return nil, errors.New("state history is not available")
+	}
+	if s := db.CachedHistoricState(root); s != nil {
+		return s, nil
+	}
+	root = types.TrieRootHash(root)
+	dl := db.tree.bottom()
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	if *id >= dl.stateID() {
+		return nil, fmt.Errorf("state %#x is not below the disk layer", root)
+	}
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	if *id < tail {
+		return nil, fmt.Errorf("state %#x is too old, id: %d, oldest: %d", root, *id, tail)
+	}
+	return db.buildHistoricState(&HistoricState{
+		root:           root,
+		id:             *id,
+		base:           root,
+		head:           *id,
+		accountChanges: make(map[common.Address][]byte),
+		storageChanges: make(map[common.Address]map[common.Hash][]byte),
+	}, dl)
+}
+
+// buildHistoricState constructs a view of the historic state on top of the given
+// disk layer, reverting all the changes already collected in the given view along
+// with the ones in the state histories between the view's base and the disk layer.
+func (db *Database) buildHistoricState(prev *HistoricState, dl *diskLayer) (*HistoricState, error) {
+	var (
+		start    = time.Now()
+		parent   = prev.base
+		accounts = maps.Clone(prev.accountChanges)
+		storages = make(map[common.Address]map[common.Hash][]byte, len(prev.storageChanges))
+	)
+	for addr, slots := range prev.storageChanges {
+		storages[addr] = maps.Clone(slots)
+	}
+	// Collect the oldest prev-value of all the state entries modified since the
+	// historic state, which is exactly the value in the historic state.
+	for n := prev.head + 1; n <= dl.stateID(); n++ {
+		h, err := readHistory(db.freezer, n)
+		if err != nil {
+			return nil, err
+		}
+		if h.meta.parent != parent {
+			return nil, errUnexpectedHistory
+		}
+		parent = h.meta.root
+
+		for addr, blob := range h.accounts {
+			if _, ok := accounts[addr]; !ok {
+				accounts[addr] = blob
+			}
+		}
+		for addr, slots := range h.storages {
+			if _, ok := storages[addr]; !ok {
+				storages[addr] = make(map[common.Hash][]byte)
+			}
+			for key, val := range slots {
+				if _, ok := storages[addr][key]; !ok {
+					storages[addr][key] = val
+				}
+			}
+		}
+	}
+	if parent != dl.rootHash() {
+		return nil, errUnexpectedHistory
+	}
+	// Revert all the collected changes on top of the disk layer tries
+	base, err := trie.New(trie.TrieID(dl.rootHash()), db)
+	if err != nil {
+		return nil, err
+	}
+	s := &HistoricState{
+		root:           prev.root,
+		id:             prev.id,
+		base:           dl.rootHash(),
+		head:           dl.stateID(),
+		db:             db,
+		accounts:       base.Copy(),
+		storages:       make(map[common.Hash]*trie.Trie),
+		accountChanges: accounts,
+		storageChanges: storages,
+	}
+	h := newHasher()
+	defer h.release()
+
+	for addr, blob := range accounts {
+		addrHash := h.hash(addr.Bytes())
+		if len(blob) == 0 {
+			if err := s.accounts.Delete(addrHash.Bytes()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		prev, err := types.FullAccount(blob)
+		if err != nil {
+			return nil, err
+		}
+		if slots := storages[addr]; len(slots) > 0 {
+			st, err := db.historicStorage(base, addrHash, slots)
+			if err != nil {
+				return nil, err
+			}
+			if st.Hash() != prev.Root {
+				return nil, fmt.Errorf("storage root mismatch, account: %#x, want: %#x, got: %#x", addrHash, prev.Root, st.Hash())
+			}
+			s.storages[addrHash] = st
+		}
+		full, err := rlp.EncodeToBytes(prev)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.accounts.Update(addrHash.Bytes(), full); err != nil {
+			return nil, err
+		}
+	}
+	if have := s.accounts.Hash(); have != s.root {
+		return nil, fmt.Errorf("state root mismatch, want: %#x, got: %#x", s.root, have)
+	}
+	db.historic.Add(s.id, s)
+	log.Debug("Constructed historic state", "root", s.root, "id", s.id, "depth", s.head-s.id, "reverted", s.head-prev.head, "accounts", len(accounts), "elapsed", common.PrettyDuration(time.Since(start)))
+	return s, nil
+}
+
+// historicStorage opens the storage trie of the given account in the base state
+// and reverts the given storage changes in it.
+func (db *Database) historicStorage(base *trie.Trie, addrHash common.Hash, slots map[common.Hash][]byte) (*trie.Trie, error) {
+	blob, err := base.Get(addrHash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	storageRoot := types.EmptyRootHash
+	if len(blob) != 0 {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return nil, err
+		}
+		storageRoot = acc.Root
+	}
+	st, err := trie.New(trie.StorageTrieID(base.Hash(), addrHash, storageRoot), db)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range slots {
+		if len(val) == 0 {
+			err = st.Delete(key.Bytes())
+		} else {
+			err = st.Update(key.Bytes(), val)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// HistoricStateWindow returns the number of states below the disk layer which
+// can be served as historic state views, i.e. the states covered by the retained
+// state histories, or 0 if not supported.
+func (db *Database) HistoricStateWindow() uint64 {
+	if db.isVerkle || db.freezer == nil {
+		return 0
+	}
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return 0
+	}
+	head := db.tree.bottom().stateID()
+	if head <= tail {
+		return 0
+	}
+	return head - tail
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestHistoricState(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	bottom := tester.bottomIndex()
+	for i := 0; i < bottom; i++ {
+		root := tester.roots[i]
+		if tester.db.CachedHistoricState(root) != nil {
+			t.Fatalf("State %d: historic state cached before construction", i)
+		}
+		hs, err := tester.db.HistoricState(root)
+		if err != nil {
+			t.Fatalf("Failed to construct historic state %d: %v", i, err)
+		}
+		if tester.db.CachedHistoricState(root) != hs {
+			t.Fatalf("State %d: constructed historic state not cached", i)
+		}
+		// Iterate the whole account trie, ensuring it contains exactly the
+		// accounts of the historic state.
+		var (
+			accounts = tester.snapAccounts[root]
+			it       = trie.NewIterator(hs.AccountTrie().MustNodeIterator(nil))
+			count    int
+		)
+		for it.Next() {
+			if !bytes.Equal(it.Value, accounts[common.BytesToHash(it.Key)]) {
+				t.Fatalf("State %d: account %x mismatch", i, it.Key)
+			}
+			count++
+		}
+		if it.Err != nil {
+			t.Fatalf("State %d: failed to iterate accounts: %v", i, it.Err)
+		}
+		if count != len(accounts) {
+			t.Fatalf("State %d: account count mismatch, want %d, got %d", i, len(accounts), count)
+		}
+		for addrHash, slots := range tester.snapStorages[root] {
+			st, err := hs.StorageTrie(addrHash)
+			if err != nil {
+				t.Fatalf("State %d: failed to open storage of %x: %v", i, addrHash, err)
+			}
+			for hash, slot := range slots {
+				blob, err := st.Get(hash.Bytes())
+				if err != nil || !bytes.Equal(blob, slot) {
+					t.Fatalf("State %d: slot %x of %x mismatch: %v", i, hash, addrHash, err)
+				}
+			}
+		}
+	}
+	// The states above the disk layer and the unknown ones are not available
+	// as historic states.
+	for _, root := range []common.Hash{tester.roots[bottom], tester.lastHash(), {0x1}} {
+		if _, err := tester.db.HistoricState(root); err == nil {
+			t.Fatalf("Historic state %x is unexpectedly available", root)
+		}
+	}
+}
+
+func TestHistoricStateRebase(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	root := tester.roots[0]
+	hs, err := tester.db.HistoricState(root)
+	if err != nil {
+		t.Fatalf("Failed to construct historic state: %v", err)
+	}
+	// Move the disk layer by stacking a few more layers and flushing them all
+	bottom := tester.db.tree.bottom().rootHash()
+	for i := 0; i < 4; i++ {
+		parent := tester.lastHash()
+		next, nodes, states := tester.generate(parent)
+		if err := tester.db.Update(next, parent, uint64(len(tester.roots)), nodes, states); err != nil {
+			t.Fatalf("Failed to update state changes: %v", err)
+		}
+		tester.roots = append(tester.roots, next)
+	}
+	if err := tester.db.Commit(tester.lastHash(), false); err != nil {
+		t.Fatalf("Failed to flush layers: %v", err)
+	}
+	if tester.db.tree.bottom().rootHash() == bottom {
+		t.Fatal("Disk layer not moved")
+	}
+	// All the states, including the empty one before the first layer, are
+	// covered by the retained state histories.
+	if want := uint64(len(tester.roots)); tester.db.HistoricStateWindow() != want {
+		t.Fatalf("Historic state window mismatch: have %d, want %d", tester.db.HistoricStateWindow(), want)
+	}
+	// The cached view must be rebased onto the new disk layer
+	rebased := tester.db.CachedHistoricState(root)
+	if rebased == nil || rebased == hs {
+		t.Fatal("Historic state not rebased")
+	}
+	if tester.db.CachedHistoricState(root) != rebased {
+		t.Fatal("Rebased historic state not cached")
+	}
+	var (
+		accounts = tester.snapAccounts[root]
+		it       = trie.NewIterator(rebased.AccountTrie().MustNodeIterator(nil))
+		count    int
+	)
+	for it.Next() {
+		if !bytes.Equal(it.Value, accounts[common.BytesToHash(it.Key)]) {
+			t.Fatalf("Account %x mismatch", it.Key)
+		}
+		count++
+	}
+	if it.Err != nil {
+		t.Fatalf("Failed to iterate accounts: %v", it.Err)
+	}
+	if count != len(accounts) {
+		t.Fatalf("Account count mismatch, want %d, got %d", len(accounts), count)
+	}
+}